@@ -1,7 +1,11 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log"
+	"os"
 
 	"github.com/gin-gonic/gin"
 
@@ -35,24 +39,180 @@ func main() {
 		log.Fatalf("failed to run migrations: %v", err)
 	}
 
+	if len(os.Args) > 1 && os.Args[1] == "integrity" {
+		code := runIntegrity(storage.NewIntegrityService(db), os.Args[2:])
+		sqlDB.Close()
+		os.Exit(code)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "admin" {
+		code := runAdmin(storage.NewUserRepository(db), os.Args[2:])
+		sqlDB.Close()
+		os.Exit(code)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "rates" {
+		code := runRatesImport(storage.NewExchangeRateStore(db, cfg.ExchangeRateCacheTTL), os.Args[2:])
+		sqlDB.Close()
+		os.Exit(code)
+	}
+
 	gin.SetMode(cfg.GinMode)
 
 	jwtService := storage.NewJWTService(cfg.JWT.SecretKey, cfg.JWT.TokenDuration)
-	authService := storage.NewAuthService(db)
+	if cfg.JWT.SigningKeysDir != "" {
+		keyring, err := storage.LoadKeyring(cfg.JWT.SigningKeysDir, cfg.JWT.SigningKeyID)
+		if err != nil {
+			log.Fatalf("failed to load JWT signing keys: %v", err)
+		}
+		jwtService = storage.NewJWTServiceWithKeyring(keyring, cfg.JWT.TokenDuration)
+	}
+	passwordHasher, err := storage.NewPasswordHasher(cfg.Password.Algorithm, storage.Argon2idParams{
+		Memory:      cfg.Password.Argon2Memory,
+		Iterations:  cfg.Password.Argon2Iterations,
+		Parallelism: cfg.Password.Argon2Parallelism,
+	}, cfg.Password.BcryptCost)
+	if err != nil {
+		log.Fatalf("failed to configure password hashing: %v", err)
+	}
+
+	authService := storage.NewAuthService(db, passwordHasher)
+	refreshTokenService := storage.NewRefreshTokenService(db, cfg.JWT.RefreshTokenDuration)
+	revocationStore := storage.NewTokenRevocationStore(db, cfg.JWT.RevocationCacheTTL)
+	exchangeRates := storage.NewExchangeRateStore(db, cfg.ExchangeRateCacheTTL)
 	accountService := storage.NewAccountService(db, cfg.AllowNegativeBalance)
+	accountService.UseExchangeRates(exchangeRates)
+	categoryService := storage.NewCategoryService(db)
+	incomeSourceService := storage.NewIncomeSourceService(db)
+	integrityService := storage.NewIntegrityService(db)
+	reportService := storage.NewReportService(db)
+	idempotencyStore := storage.NewIdempotencyStore(db, cfg.IdempotencyKeyTTL)
 
 	timeProvider := services.SystemTimeProvider{}
 
-	authHandler := handlers.NewAuthHandler(authService, jwtService)
+	authHandler := handlers.NewAuthHandler(authService, accountService, jwtService, refreshTokenService, revocationStore)
 	accountHandler := handlers.NewAccountHandler(accountService, timeProvider)
+	transferHandler := handlers.NewTransferHandler(accountService, timeProvider)
+	categoryHandler := handlers.NewCategoryHandler(categoryService)
+	incomeSourceHandler := handlers.NewIncomeSourceHandler(incomeSourceService)
+	adminHandler := handlers.NewAdminHandler(integrityService)
+	exchangeRateHandler := handlers.NewExchangeRateHandler(exchangeRates, timeProvider)
+	reportHandler := handlers.NewReportHandler(reportService, timeProvider)
 
 	engine := router.New(router.Dependencies{
-		Auth:       authHandler,
-		Account:    accountHandler,
-		JWTService: jwtService,
+		Auth:          authHandler,
+		Account:       accountHandler,
+		Transfer:      transferHandler,
+		Categories:    categoryHandler,
+		IncomeSources: incomeSourceHandler,
+		Admin:         adminHandler,
+		ExchangeRates: exchangeRateHandler,
+		Reports:       reportHandler,
+		JWTService:    jwtService,
+		Revocations:   revocationStore,
+		Idempotency:   idempotencyStore,
+		Users:         authService,
 	})
 
 	if err := engine.Run(":" + cfg.HTTPPort); err != nil {
 		log.Fatalf("failed to start HTTP server: %v", err)
 	}
 }
+
+// runIntegrity implements the "integrity" subcommand: it reports accounts whose balances
+// do not match their transactions and, with -repair, fixes them. It returns the process
+// exit code, which is non-zero when unrepaired discrepancies remain.
+func runIntegrity(service *storage.IntegrityService, args []string) int {
+	flags := flag.NewFlagSet("integrity", flag.ExitOnError)
+	repair := flags.Bool("repair", false, "reset discrepant balances and record an audit entry")
+	actor := flags.String("actor", "cli", "name recorded as the actor of repairs")
+	_ = flags.Parse(args)
+
+	ctx := context.Background()
+	var (
+		report *storage.IntegrityReport
+		err    error
+	)
+	if *repair {
+		report, err = service.Repair(ctx, *actor)
+	} else {
+		report, err = service.Check(ctx)
+	}
+	if err != nil {
+		log.Printf("integrity check failed: %v", err)
+		return 1
+	}
+
+	fmt.Printf("checked %d accounts, %d discrepancies\n", report.AccountsChecked, len(report.Discrepancies))
+	for _, d := range report.Discrepancies {
+		fmt.Printf("account %d (user %d, %s): stored=%d ledger=%d transactions=%d\n",
+			d.AccountID, d.UserID, d.CurrencyISOCode, d.StoredCents, d.LedgerCents, d.TransactionsCents)
+	}
+	if report.Repaired {
+		fmt.Println("discrepancies repaired")
+	}
+
+	if len(report.Discrepancies) > 0 && !report.Repaired {
+		return 1
+	}
+	return 0
+}
+
+// runAdmin implements the "admin" subcommand: it grants the admin role to the user with
+// the -grant email, or revokes it from the -revoke one.
+func runAdmin(users *storage.UserRepository, args []string) int {
+	flags := flag.NewFlagSet("admin", flag.ExitOnError)
+	grant := flags.String("grant", "", "email of the user to make an admin")
+	revoke := flags.String("revoke", "", "email of the user to remove from the admins")
+	_ = flags.Parse(args)
+
+	if (*grant == "") == (*revoke == "") {
+		log.Print("admin: exactly one of -grant and -revoke is required")
+		return 2
+	}
+	email, admin := *grant, true
+	if *revoke != "" {
+		email, admin = *revoke, false
+	}
+
+	if err := users.SetAdmin(context.Background(), email, admin); err != nil {
+		log.Printf("admin: %s: %v", email, err)
+		return 1
+	}
+
+	if admin {
+		fmt.Printf("%s is now an admin\n", email)
+	} else {
+		fmt.Printf("%s is no longer an admin\n", email)
+	}
+	return 0
+}
+
+// runRatesImport implements the "rates" subcommand: it imports a daily rate dump, e.g.
+// the ECB or NBU history, from -file or standard input.
+func runRatesImport(store *storage.ExchangeRateStore, args []string) int {
+	flags := flag.NewFlagSet("rates", flag.ExitOnError)
+	format := flags.String("format", storage.RateFormatCSV, "dump layout: csv, ecb or nbu")
+	path := flags.String("file", "", "file to import; standard input when empty")
+	_ = flags.Parse(args)
+
+	input := os.Stdin
+	if *path != "" {
+		file, err := os.Open(*path)
+		if err != nil {
+			log.Printf("rate import failed: %v", err)
+			return 1
+		}
+		defer file.Close()
+		input = file
+	}
+
+	imported, err := store.Import(context.Background(), input, *format)
+	if err != nil {
+		log.Printf("rate import failed: %v", err)
+		return 1
+	}
+
+	fmt.Printf("imported %d exchange rates\n", imported)
+	return 0
+}
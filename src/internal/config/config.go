@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -16,14 +17,31 @@ type Config struct {
 	HTTPPort             string
 	GinMode              string
 	AllowNegativeBalance bool
+	IdempotencyKeyTTL    time.Duration
+	ExchangeRateCacheTTL time.Duration
 	Database             DatabaseConfig
 	JWT                  JWTConfig
+	Password             PasswordConfig
 }
 
-// JWTConfig holds settings for JSON Web Token authentication.
+// PasswordConfig selects and tunes the password hashing algorithm.
+type PasswordConfig struct {
+	Algorithm         string
+	Argon2Memory      uint32
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+	BcryptCost        int
+}
+
+// JWTConfig holds settings for JSON Web Token authentication. When SigningKeysDir is
+// set, tokens are signed with the PEM keys it contains instead of SecretKey.
 type JWTConfig struct {
-	SecretKey     string
-	TokenDuration time.Duration
+	SecretKey            string
+	SigningKeysDir       string
+	SigningKeyID         string
+	TokenDuration        time.Duration
+	RefreshTokenDuration time.Duration
+	RevocationCacheTTL   time.Duration
 }
 
 // DatabaseConfig captures connection-related settings for the relational database.
@@ -55,6 +73,18 @@ func Load() (Config, error) {
 		AllowNegativeBalance: getEnvBool("ALLOW_NEGATIVE_BALANCE", false),
 	}
 
+	idempotencyTTL, err := getEnvDuration("IDEMPOTENCY_KEY_TTL", 24*time.Hour)
+	if err != nil {
+		return Config{}, fmt.Errorf("parse IDEMPOTENCY_KEY_TTL: %w", err)
+	}
+	cfg.IdempotencyKeyTTL = idempotencyTTL
+
+	rateCacheTTL, err := getEnvDuration("EXCHANGE_RATE_CACHE_TTL", 10*time.Minute)
+	if err != nil {
+		return Config{}, fmt.Errorf("parse EXCHANGE_RATE_CACHE_TTL: %w", err)
+	}
+	cfg.ExchangeRateCacheTTL = rateCacheTTL
+
 	if cfg.HTTPPort == "" {
 		return Config{}, errors.New("HTTP_PORT must not be empty")
 	}
@@ -71,6 +101,12 @@ func Load() (Config, error) {
 	}
 	cfg.JWT = jwtCfg
 
+	passwordCfg, err := loadPasswordConfig()
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.Password = passwordCfg
+
 	return cfg, nil
 }
 
@@ -165,17 +201,71 @@ func getEnvBool(key string, fallback bool) bool {
 
 func loadJWTConfig() (JWTConfig, error) {
 	secret := getEnv("JWT_SECRET_KEY", "")
-	if secret == "" {
+	keysDir := getEnv("JWT_SIGNING_KEYS_DIR", "")
+	keyID := getEnv("JWT_SIGNING_KEY_ID", "")
+	if keysDir == "" && secret == "" {
 		return JWTConfig{}, errors.New("JWT_SECRET_KEY must not be empty")
 	}
+	if keysDir != "" && keyID == "" {
+		return JWTConfig{}, errors.New("JWT_SIGNING_KEY_ID must be set when JWT_SIGNING_KEYS_DIR is used")
+	}
 
-	duration, err := getEnvDuration("JWT_TOKEN_DURATION", 24*time.Hour)
+	duration, err := getEnvDuration("JWT_TOKEN_DURATION", 15*time.Minute)
 	if err != nil {
 		return JWTConfig{}, fmt.Errorf("parse JWT_TOKEN_DURATION: %w", err)
 	}
 
+	refreshDuration, err := getEnvDuration("JWT_REFRESH_TOKEN_DURATION", 30*24*time.Hour)
+	if err != nil {
+		return JWTConfig{}, fmt.Errorf("parse JWT_REFRESH_TOKEN_DURATION: %w", err)
+	}
+
+	revocationCacheTTL, err := getEnvDuration("JWT_REVOCATION_CACHE_TTL", 30*time.Second)
+	if err != nil {
+		return JWTConfig{}, fmt.Errorf("parse JWT_REVOCATION_CACHE_TTL: %w", err)
+	}
+
 	return JWTConfig{
-		SecretKey:     secret,
-		TokenDuration: duration,
+		SecretKey:            secret,
+		SigningKeysDir:       keysDir,
+		SigningKeyID:         keyID,
+		TokenDuration:        duration,
+		RefreshTokenDuration: refreshDuration,
+		RevocationCacheTTL:   revocationCacheTTL,
+	}, nil
+}
+
+func loadPasswordConfig() (PasswordConfig, error) {
+	algorithm := strings.ToLower(strings.TrimSpace(getEnv("PASSWORD_HASH_ALGORITHM", "argon2id")))
+	if algorithm != "argon2id" && algorithm != "bcrypt" {
+		return PasswordConfig{}, fmt.Errorf("PASSWORD_HASH_ALGORITHM must be argon2id or bcrypt, got %q", algorithm)
+	}
+
+	memory, err := getEnvInt("ARGON2_MEMORY_KB", 64*1024)
+	if err != nil || memory <= 0 || memory > 4*1024*1024 {
+		return PasswordConfig{}, fmt.Errorf("parse ARGON2_MEMORY_KB: must be between 1 and 4194304")
+	}
+
+	iterations, err := getEnvInt("ARGON2_ITERATIONS", 3)
+	if err != nil || iterations <= 0 {
+		return PasswordConfig{}, fmt.Errorf("parse ARGON2_ITERATIONS: must be a positive integer")
+	}
+
+	parallelism, err := getEnvInt("ARGON2_PARALLELISM", 2)
+	if err != nil || parallelism <= 0 || parallelism > 255 {
+		return PasswordConfig{}, fmt.Errorf("parse ARGON2_PARALLELISM: must be between 1 and 255")
+	}
+
+	bcryptCost, err := getEnvInt("BCRYPT_COST", 12)
+	if err != nil || bcryptCost < 4 || bcryptCost > 31 {
+		return PasswordConfig{}, fmt.Errorf("parse BCRYPT_COST: must be between 4 and 31")
+	}
+
+	return PasswordConfig{
+		Algorithm:         algorithm,
+		Argon2Memory:      uint32(memory),
+		Argon2Iterations:  uint32(iterations),
+		Argon2Parallelism: uint8(parallelism),
+		BcryptCost:        bcryptCost,
 	}, nil
 }
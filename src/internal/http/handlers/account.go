@@ -8,6 +8,7 @@ import (
 	"bckndlab3/src/internal/http/middleware"
 	"bckndlab3/src/internal/http/requests"
 	"bckndlab3/src/internal/http/responses"
+	"bckndlab3/src/internal/models"
 	"bckndlab3/src/internal/services"
 	"bckndlab3/src/internal/storage"
 )
@@ -23,11 +24,164 @@ func NewAccountHandler(service *storage.AccountService, timeProvider services.Ti
 }
 
 func (h *AccountHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("", h.ListAccounts)
+	router.POST("", h.CreateAccount)
 	router.POST("/incomes", h.CreateIncome)
 	router.POST("/expenses", h.CreateExpense)
 	router.GET("/balance", h.GetBalance)
+	router.GET("/balance/history", h.GetBalanceHistory)
 	router.GET("/incomes", h.ListIncomes)
 	router.GET("/expenses", h.ListExpenses)
+	router.GET("/transactions", h.ListTransactions)
+	router.GET("/incomes/:id", h.GetIncome)
+	router.PATCH("/incomes/:id", h.UpdateIncome)
+	router.DELETE("/incomes/:id", h.DeleteIncome)
+	router.GET("/expenses/:id", h.GetExpense)
+	router.PATCH("/expenses/:id", h.UpdateExpense)
+	router.DELETE("/expenses/:id", h.DeleteExpense)
+	router.GET("/:id", h.GetAccount)
+	router.PATCH("/:id", h.UpdateAccount)
+	router.DELETE("/:id", h.DeleteAccount)
+}
+
+func (h *AccountHandler) ListAccounts(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": gin.H{"code": "unauthorized", "message": "user not authenticated"},
+		})
+		return
+	}
+
+	accounts, err := h.Service.ListAccounts(c.Request.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewAccountListResponse(accounts))
+}
+
+func (h *AccountHandler) CreateAccount(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": gin.H{"code": "unauthorized", "message": "user not authenticated"},
+		})
+		return
+	}
+
+	var req requests.AccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	account, err := h.Service.CreateAccount(c.Request.Context(), userID, req.ToModel())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("ETag", versionETag(account.Version))
+	c.JSON(http.StatusCreated, responses.NewAccountResponse(account))
+}
+
+func (h *AccountHandler) GetAccount(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": gin.H{"code": "unauthorized", "message": "user not authenticated"},
+		})
+		return
+	}
+
+	accountID, err := requests.ParseUintParam(c, "id")
+	if err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	account, err := h.Service.GetAccount(c.Request.Context(), userID, accountID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if notModified(c, versionETag(account.Version)) {
+		return
+	}
+	c.JSON(http.StatusOK, responses.NewAccountResponse(account))
+}
+
+func (h *AccountHandler) UpdateAccount(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": gin.H{"code": "unauthorized", "message": "user not authenticated"},
+		})
+		return
+	}
+
+	accountID, err := requests.ParseUintParam(c, "id")
+	if err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	var req requests.AccountUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	ifVersion, err := ifMatchVersion(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	account, err := h.Service.UpdateAccount(c.Request.Context(), userID, accountID, ifVersion, storage.AccountUpdate{
+		Name:      req.Name,
+		Type:      req.Type,
+		IsDefault: req.IsDefault,
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("ETag", versionETag(account.Version))
+	c.JSON(http.StatusOK, responses.NewAccountResponse(account))
+}
+
+func (h *AccountHandler) DeleteAccount(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": gin.H{"code": "unauthorized", "message": "user not authenticated"},
+		})
+		return
+	}
+
+	accountID, err := requests.ParseUintParam(c, "id")
+	if err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	ifVersion, err := ifMatchVersion(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := h.Service.DeleteAccount(c.Request.Context(), userID, accountID, ifVersion); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
 }
 
 func (h *AccountHandler) CreateIncome(c *gin.Context) {
@@ -45,13 +199,25 @@ func (h *AccountHandler) CreateIncome(c *gin.Context) {
 		return
 	}
 
-	incomeModel := req.ToModel(h.Time.Now())
-	income, balance, err := h.Service.CreditIncome(c.Request.Context(), userID, incomeModel)
+	account, err := h.resolveAccount(c, userID, req.AccountID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	incomeModel, err := req.ToModel(h.Time.Now(), account.CurrencyISOCode)
+	if err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	income, balance, err := h.Service.CreditIncome(c.Request.Context(), userID, account.ID, incomeModel)
 	if err != nil {
 		c.Error(err)
 		return
 	}
 
+	c.Header("ETag", versionETag(income.Version))
 	c.JSON(http.StatusCreated, responses.NewIncomeResponse(income, balance))
 }
 
@@ -70,16 +236,275 @@ func (h *AccountHandler) CreateExpense(c *gin.Context) {
 		return
 	}
 
-	expenseModel := req.ToModel(h.Time.Now())
-	expense, balance, err := h.Service.DebitExpense(c.Request.Context(), userID, expenseModel)
+	account, err := h.resolveAccount(c, userID, req.AccountID)
 	if err != nil {
 		c.Error(err)
 		return
 	}
 
+	expenseModel, err := req.ToModel(h.Time.Now(), account.CurrencyISOCode)
+	if err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	expense, balance, err := h.Service.DebitExpense(c.Request.Context(), userID, account.ID, expenseModel)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("ETag", versionETag(expense.Version))
 	c.JSON(http.StatusCreated, responses.NewExpenseResponse(expense, balance))
 }
 
+func (h *AccountHandler) GetIncome(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": gin.H{"code": "unauthorized", "message": "user not authenticated"},
+		})
+		return
+	}
+
+	incomeID, err := requests.ParseUintParam(c, "id")
+	if err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	income, err := h.Service.GetIncome(c.Request.Context(), userID, incomeID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if notModified(c, versionETag(income.Version)) {
+		return
+	}
+	c.JSON(http.StatusOK, responses.NewIncomeListItem(income))
+}
+
+func (h *AccountHandler) UpdateIncome(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": gin.H{"code": "unauthorized", "message": "user not authenticated"},
+		})
+		return
+	}
+
+	incomeID, err := requests.ParseUintParam(c, "id")
+	if err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	var req requests.IncomeUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	var (
+		amountCents *int64
+		splits      *[]models.IncomeSplit
+	)
+	if req.Amount != nil || req.Splits != nil {
+		current, err := h.Service.GetIncome(c.Request.Context(), userID, incomeID)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		amountCents, err = req.AmountCents(current.EntryCurrency())
+		if err != nil {
+			c.Error(responses.NewValidationError(err))
+			return
+		}
+		splits, err = req.SplitModels(current.EntryCurrency())
+		if err != nil {
+			c.Error(responses.NewValidationError(err))
+			return
+		}
+	}
+
+	ifVersion, err := ifMatchVersion(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	income, balance, err := h.Service.UpdateIncome(c.Request.Context(), userID, incomeID, ifVersion, storage.IncomeUpdate{
+		AmountCents: amountCents,
+		CategoryID:  req.CategoryID,
+		SourceID:    req.SourceID,
+		Source:      req.Source,
+		ReceivedAt:  req.ReceivedAtTime(),
+		Notes:       req.Notes,
+		Tags:        req.Tags,
+		Splits:      splits,
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("ETag", versionETag(income.Version))
+	c.JSON(http.StatusOK, responses.NewIncomeResponse(income, balance))
+}
+
+func (h *AccountHandler) DeleteIncome(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": gin.H{"code": "unauthorized", "message": "user not authenticated"},
+		})
+		return
+	}
+
+	incomeID, err := requests.ParseUintParam(c, "id")
+	if err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	ifVersion, err := ifMatchVersion(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if _, err := h.Service.DeleteIncome(c.Request.Context(), userID, incomeID, ifVersion); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AccountHandler) GetExpense(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": gin.H{"code": "unauthorized", "message": "user not authenticated"},
+		})
+		return
+	}
+
+	expenseID, err := requests.ParseUintParam(c, "id")
+	if err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	expense, err := h.Service.GetExpense(c.Request.Context(), userID, expenseID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if notModified(c, versionETag(expense.Version)) {
+		return
+	}
+	c.JSON(http.StatusOK, responses.NewExpenseListItem(expense))
+}
+
+func (h *AccountHandler) UpdateExpense(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": gin.H{"code": "unauthorized", "message": "user not authenticated"},
+		})
+		return
+	}
+
+	expenseID, err := requests.ParseUintParam(c, "id")
+	if err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	var req requests.ExpenseUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	var (
+		amountCents *int64
+		splits      *[]models.ExpenseSplit
+	)
+	if req.Amount != nil || req.Splits != nil {
+		current, err := h.Service.GetExpense(c.Request.Context(), userID, expenseID)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		amountCents, err = req.AmountCents(current.EntryCurrency())
+		if err != nil {
+			c.Error(responses.NewValidationError(err))
+			return
+		}
+		splits, err = req.SplitModels(current.EntryCurrency())
+		if err != nil {
+			c.Error(responses.NewValidationError(err))
+			return
+		}
+	}
+
+	ifVersion, err := ifMatchVersion(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	expense, balance, err := h.Service.UpdateExpense(c.Request.Context(), userID, expenseID, ifVersion, storage.ExpenseUpdate{
+		AmountCents: amountCents,
+		Category:    req.Category,
+		CategoryID:  req.CategoryID,
+		IncurredAt:  req.IncurredAtTime(),
+		Description: req.Description,
+		Tags:        req.Tags,
+		Splits:      splits,
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("ETag", versionETag(expense.Version))
+	c.JSON(http.StatusOK, responses.NewExpenseResponse(expense, balance))
+}
+
+func (h *AccountHandler) DeleteExpense(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": gin.H{"code": "unauthorized", "message": "user not authenticated"},
+		})
+		return
+	}
+
+	expenseID, err := requests.ParseUintParam(c, "id")
+	if err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	ifVersion, err := ifMatchVersion(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if _, err := h.Service.DeleteExpense(c.Request.Context(), userID, expenseID, ifVersion); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
 func (h *AccountHandler) GetBalance(c *gin.Context) {
 	userID, ok := middleware.GetUserID(c)
 	if !ok {
@@ -89,16 +514,41 @@ func (h *AccountHandler) GetBalance(c *gin.Context) {
 		return
 	}
 
-	account, err := h.Service.GetAccountByUserID(c.Request.Context(), userID)
+	requested, err := requests.ParseUintQuery(c, "account_id")
+	if err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	var query requests.BalanceQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	account, err := h.resolveAccount(c, userID, requested)
 	if err != nil {
 		c.Error(err)
 		return
 	}
 
+	if at := query.Time(); !at.IsZero() {
+		account, balance, code, err := h.Service.BalanceAt(c.Request.Context(), userID, account.ID, at)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		c.JSON(http.StatusOK, responses.NewHistoricalBalanceResponse(account, balance, code, at))
+		return
+	}
+
+	if notModified(c, versionETag(account.Version)) {
+		return
+	}
 	c.JSON(http.StatusOK, responses.NewBalanceResponse(account))
 }
 
-func (h *AccountHandler) ListIncomes(c *gin.Context) {
+func (h *AccountHandler) GetBalanceHistory(c *gin.Context) {
 	userID, ok := middleware.GetUserID(c)
 	if !ok {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
@@ -106,21 +556,57 @@ func (h *AccountHandler) ListIncomes(c *gin.Context) {
 		})
 		return
 	}
-	limit := requests.ParseLimitQuery(c, "limit", 50)
 
-	account, err := h.Service.GetAccountByUserID(c.Request.Context(), userID)
+	requested, err := requests.ParseUintQuery(c, "account_id")
+	if err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	var query requests.BalanceHistoryQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	account, err := h.resolveAccount(c, userID, requested)
 	if err != nil {
 		c.Error(err)
 		return
 	}
 
-	incomes, err := h.Service.ListIncomes(c.Request.Context(), account.ID, limit)
+	from, to := query.Period()
+	history, err := h.Service.BalanceHistory(c.Request.Context(), userID, account.ID, from, to, query.IntervalOrDefault())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewBalanceHistoryResponse(history))
+}
+
+func (h *AccountHandler) ListIncomes(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": gin.H{"code": "unauthorized", "message": "user not authenticated"},
+		})
+		return
+	}
+
+	filter, err := transactionFilter(c)
+	if err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	page, err := h.Service.ListIncomes(c.Request.Context(), userID, filter)
 	if err != nil {
 		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, responses.NewIncomeListResponse(incomes))
+	c.JSON(http.StatusOK, responses.NewIncomeListResponse(page))
 }
 
 func (h *AccountHandler) ListExpenses(c *gin.Context) {
@@ -131,19 +617,101 @@ func (h *AccountHandler) ListExpenses(c *gin.Context) {
 		})
 		return
 	}
-	limit := requests.ParseLimitQuery(c, "limit", 50)
 
-	account, err := h.Service.GetAccountByUserID(c.Request.Context(), userID)
+	filter, err := transactionFilter(c)
+	if err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	page, err := h.Service.ListExpenses(c.Request.Context(), userID, filter)
 	if err != nil {
 		c.Error(err)
 		return
 	}
 
-	expenses, err := h.Service.ListExpenses(c.Request.Context(), account.ID, limit)
+	c.JSON(http.StatusOK, responses.NewExpenseListResponse(page))
+}
+
+func (h *AccountHandler) ListTransactions(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": gin.H{"code": "unauthorized", "message": "user not authenticated"},
+		})
+		return
+	}
+
+	accountID, err := requests.ParseUintQuery(c, "account_id")
+	if err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	var query requests.TransactionFeedQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	from, to := query.Period()
+	page, err := h.Service.ListTransactions(c.Request.Context(), userID, storage.FeedFilter{
+		AccountID: accountID,
+		Types:     query.Types,
+		From:      from,
+		To:        to,
+		Ascending: query.Ascending(),
+		Cursor:    query.Cursor,
+		Limit:     requests.ParseLimitQuery(c, "limit", storage.DefaultPageSize),
+	})
 	if err != nil {
 		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, responses.NewExpenseListResponse(expenses))
+	c.JSON(http.StatusOK, responses.NewTransactionFeedResponse(page))
+}
+
+// transactionFilter reads the filter, sort order and page of an income or expense listing
+// from the query string.
+func transactionFilter(c *gin.Context) (storage.TransactionFilter, error) {
+	accountID, err := requests.ParseUintQuery(c, "account_id")
+	if err != nil {
+		return storage.TransactionFilter{}, err
+	}
+
+	var query requests.TransactionListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		return storage.TransactionFilter{}, err
+	}
+	minAmount, maxAmount, err := query.AmountRange()
+	if err != nil {
+		return storage.TransactionFilter{}, err
+	}
+
+	from, to := query.Period()
+	return storage.TransactionFilter{
+		AccountID:    accountID,
+		Tags:         query.Tags,
+		MatchAllTags: query.MatchAllTags(),
+		CategoryID:   query.CategoryID,
+		SourceID:     query.SourceID,
+		From:         from,
+		To:           to,
+		MinAmount:    minAmount,
+		MaxAmount:    maxAmount,
+		Search:       query.Search,
+		Sort:         query.Sort,
+		Ascending:    query.Ascending(),
+		Cursor:       query.Cursor,
+		Limit:        requests.ParseLimitQuery(c, "limit", storage.DefaultPageSize),
+	}, nil
+}
+
+// resolveAccount returns the requested account, falling back to the user's default account.
+func (h *AccountHandler) resolveAccount(c *gin.Context, userID, requested uint) (*models.Account, error) {
+	if requested != 0 {
+		return h.Service.GetAccount(c.Request.Context(), userID, requested)
+	}
+	return h.Service.GetAccountByUserID(c.Request.Context(), userID)
 }
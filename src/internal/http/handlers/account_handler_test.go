@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 	"time"
 
@@ -23,6 +24,8 @@ import (
 	"bckndlab3/src/internal/storage"
 )
 
+const testAdminEmail = "admin@example.com"
+
 type fixedTimeProvider struct {
 	value time.Time
 }
@@ -34,6 +37,7 @@ type testEnv struct {
 	authService    *storage.AuthService
 	accountService *storage.AccountService
 	jwtService     *storage.JWTService
+	refreshTokens  *storage.RefreshTokenService
 	engine         *gin.Engine
 	frozen         time.Time
 }
@@ -54,16 +58,29 @@ func setupHandlerTest(t *testing.T) *testEnv {
 	require.NoError(t, err)
 	t.Cleanup(func() { _ = sqlDB.Close() })
 
-	authService := storage.NewAuthService(db)
+	authService := storage.NewAuthService(db, storage.NewArgon2idHasher(storage.Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1}))
+	exchangeRates := storage.NewExchangeRateStore(db, time.Minute)
 	accountService := storage.NewAccountService(db, false)
+	accountService.UseExchangeRates(exchangeRates)
 	jwtService := storage.NewJWTService("test-secret-key", 24*time.Hour)
+	refreshTokenService := storage.NewRefreshTokenService(db, 30*24*time.Hour)
+	revocationStore := storage.NewTokenRevocationStore(db, time.Minute)
 
 	frozen := time.Date(2025, time.November, 5, 12, 0, 0, 0, time.UTC)
 
 	engine := router.New(router.Dependencies{
-		Auth:       handlers.NewAuthHandler(authService, jwtService),
-		Account:    handlers.NewAccountHandler(accountService, fixedTimeProvider{value: frozen}),
-		JWTService: jwtService,
+		Auth:          handlers.NewAuthHandler(authService, accountService, jwtService, refreshTokenService, revocationStore),
+		Account:       handlers.NewAccountHandler(accountService, fixedTimeProvider{value: frozen}),
+		Transfer:      handlers.NewTransferHandler(accountService, fixedTimeProvider{value: frozen}),
+		Categories:    handlers.NewCategoryHandler(storage.NewCategoryService(db)),
+		IncomeSources: handlers.NewIncomeSourceHandler(storage.NewIncomeSourceService(db)),
+		Admin:         handlers.NewAdminHandler(storage.NewIntegrityService(db)),
+		ExchangeRates: handlers.NewExchangeRateHandler(exchangeRates, fixedTimeProvider{value: frozen}),
+		Reports:       handlers.NewReportHandler(storage.NewReportService(db), fixedTimeProvider{value: frozen}),
+		JWTService:    jwtService,
+		Revocations:   revocationStore,
+		Idempotency:   storage.NewIdempotencyStore(db, time.Hour),
+		Users:         authService,
 	})
 
 	return &testEnv{
@@ -71,6 +88,7 @@ func setupHandlerTest(t *testing.T) *testEnv {
 		authService:    authService,
 		accountService: accountService,
 		jwtService:     jwtService,
+		refreshTokens:  refreshTokenService,
 		engine:         engine,
 		frozen:         frozen,
 	}
@@ -81,6 +99,14 @@ func (e *testEnv) authHeader(userID uint, email string) string {
 	return "Bearer " + token
 }
 
+func (e *testEnv) defaultAccountID(t *testing.T, userID uint) uint {
+	t.Helper()
+
+	account, err := e.accountService.GetAccountByUserID(context.Background(), userID)
+	require.NoError(t, err)
+	return account.ID
+}
+
 type incomeResponse struct {
 	BalanceCents int64  `json:"balance_cents"`
 	ReceivedAt   string `json:"received_at"`
@@ -97,7 +123,23 @@ type errorEnvelope struct {
 }
 
 type incomeListItem struct {
-	Amount float64 `json:"amount"`
+	Amount          float64 `json:"amount"`
+	CurrencyISOCode string  `json:"currency_iso_code"`
+}
+
+// transactionPage mirrors the envelope of income and expense listings.
+type transactionPage[T any] struct {
+	Items      []T     `json:"items"`
+	NextCursor *string `json:"next_cursor"`
+	Totals     struct {
+		Count   int64 `json:"count"`
+		Amounts []struct {
+			CurrencyISOCode string `json:"currency_iso_code"`
+			Count           int64  `json:"count"`
+			AmountCents     int64  `json:"amount_cents"`
+			AmountDecimal   string `json:"amount_decimal"`
+		} `json:"amounts"`
+	} `json:"totals"`
 }
 
 type balanceResponse struct {
@@ -183,10 +225,11 @@ func TestAccountHandlerIncomeExpenseFlow(t *testing.T) {
 		env.engine.ServeHTTP(res, req)
 		require.Equal(t, http.StatusOK, res.Code)
 
-		var payload []incomeListItem
+		var payload transactionPage[incomeListItem]
 		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &payload))
-		require.Len(t, payload, 1)
-		require.InDelta(t, 100.5, payload[0].Amount, 0.001)
+		require.Len(t, payload.Items, 1)
+		require.InDelta(t, 100.5, payload.Items[0].Amount, 0.001)
+		require.Nil(t, payload.NextCursor)
 	})
 
 	t.Run("balance endpoint", func(t *testing.T) {
@@ -233,7 +276,7 @@ func TestAccountHandlerCreateIncomeValidationError(t *testing.T) {
 	require.Equal(t, "validation_error", payload.Error.Code)
 }
 
-func TestAccountHandlerGetBalanceNotFound(t *testing.T) {
+func TestAccountHandlerGetBalanceUnknownUser(t *testing.T) {
 	env := setupHandlerTest(t)
 
 	// Generate token for non-existent user
@@ -242,11 +285,11 @@ func TestAccountHandlerGetBalanceNotFound(t *testing.T) {
 	res := httptest.NewRecorder()
 
 	env.engine.ServeHTTP(res, req)
-	require.Equal(t, http.StatusNotFound, res.Code)
+	require.Equal(t, http.StatusUnauthorized, res.Code)
 
 	var payload errorEnvelope
 	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &payload))
-	require.Equal(t, "not_found", payload.Error.Code)
+	require.Equal(t, "unauthorized", payload.Error.Code)
 }
 
 func TestAccountHandlerListIncomesRespectLimit(t *testing.T) {
@@ -263,7 +306,7 @@ func TestAccountHandlerListIncomesRespectLimit(t *testing.T) {
 			Source:      fmt.Sprintf("src-%d", i),
 			ReceivedAt:  env.frozen.Add(time.Duration(i) * time.Hour),
 		}
-		_, _, err := env.accountService.CreditIncome(ctx, user.ID, income)
+		_, _, err := env.accountService.CreditIncome(ctx, user.ID, env.defaultAccountID(t, user.ID), income)
 		require.NoError(t, err)
 	}
 
@@ -274,13 +317,12 @@ func TestAccountHandlerListIncomesRespectLimit(t *testing.T) {
 	env.engine.ServeHTTP(res, req)
 	require.Equal(t, http.StatusOK, res.Code)
 
-	var payload []struct {
-		Amount float64 `json:"amount"`
-	}
+	var payload transactionPage[incomeListItem]
 	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &payload))
-	require.Len(t, payload, 2)
-	require.InDelta(t, 300.0, payload[0].Amount, 0.001)
-	require.InDelta(t, 200.0, payload[1].Amount, 0.001)
+	require.Len(t, payload.Items, 2)
+	require.InDelta(t, 300.0, payload.Items[0].Amount, 0.001)
+	require.InDelta(t, 200.0, payload.Items[1].Amount, 0.001)
+	require.NotNil(t, payload.NextCursor)
 }
 
 func TestAccountHandlerListExpensesRespectLimit(t *testing.T) {
@@ -290,7 +332,7 @@ func TestAccountHandlerListExpensesRespectLimit(t *testing.T) {
 	user, err := env.authService.RegisterUser(ctx, "limit-expenses@example.com", "password123", "uah")
 	require.NoError(t, err)
 
-	_, _, err = env.accountService.CreditIncome(ctx, user.ID, &models.Income{AmountCents: 100000, Source: "seed", ReceivedAt: env.frozen})
+	_, _, err = env.accountService.CreditIncome(ctx, user.ID, env.defaultAccountID(t, user.ID), &models.Income{AmountCents: 100000, Source: "seed", ReceivedAt: env.frozen})
 	require.NoError(t, err)
 
 	costs := []int64{1000, 2000, 3000}
@@ -300,7 +342,7 @@ func TestAccountHandlerListExpensesRespectLimit(t *testing.T) {
 			Category:    fmt.Sprintf("cat-%d", i),
 			IncurredAt:  env.frozen.Add(time.Duration(i) * time.Hour),
 		}
-		_, _, err := env.accountService.DebitExpense(ctx, user.ID, expense)
+		_, _, err := env.accountService.DebitExpense(ctx, user.ID, env.defaultAccountID(t, user.ID), expense)
 		require.NoError(t, err)
 	}
 
@@ -311,11 +353,711 @@ func TestAccountHandlerListExpensesRespectLimit(t *testing.T) {
 	env.engine.ServeHTTP(res, req)
 	require.Equal(t, http.StatusOK, res.Code)
 
-	var payload []struct {
-		Amount float64 `json:"amount"`
-	}
+	var payload transactionPage[incomeListItem]
 	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &payload))
-	require.Len(t, payload, 2)
-	require.InDelta(t, 30.0, payload[0].Amount, 0.001)
-	require.InDelta(t, 20.0, payload[1].Amount, 0.001)
+	require.Len(t, payload.Items, 2)
+	require.InDelta(t, 30.0, payload.Items[0].Amount, 0.001)
+	require.InDelta(t, 20.0, payload.Items[1].Amount, 0.001)
+	require.NotNil(t, payload.NextCursor)
+}
+
+func TestAccountHandlerListPagination(t *testing.T) {
+	env := setupHandlerTest(t)
+
+	ctx := context.Background()
+	user, err := env.authService.RegisterUser(ctx, "pagination@example.com", "password123", "uah")
+	require.NoError(t, err)
+	authHeader := env.authHeader(user.ID, user.Email)
+
+	for i, cents := range []int64{1000, 2500, 4000} {
+		income := &models.Income{
+			AmountCents: cents,
+			Source:      fmt.Sprintf("client-%d", i),
+			Notes:       fmt.Sprintf("invoice %d", i),
+			ReceivedAt:  env.frozen.AddDate(0, 0, i),
+		}
+		_, _, err := env.accountService.CreditIncome(ctx, user.ID, env.defaultAccountID(t, user.ID), income)
+		require.NoError(t, err)
+	}
+
+	list := func(query string) transactionPage[incomeListItem] {
+		t.Helper()
+		res := authorizedRequest(env, http.MethodGet, "/api/v1/accounts/incomes"+query, authHeader)
+		require.Equal(t, http.StatusOK, res.Code, res.Body.String())
+		var page transactionPage[incomeListItem]
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &page))
+		return page
+	}
+
+	page := list("?limit=2&sort=amount&order=asc")
+	require.Len(t, page.Items, 2)
+	require.InDelta(t, 10.0, page.Items[0].Amount, 0.001)
+	require.Equal(t, int64(3), page.Totals.Count)
+	require.Len(t, page.Totals.Amounts, 1)
+	require.Equal(t, "75.00", page.Totals.Amounts[0].AmountDecimal)
+	require.NotNil(t, page.NextCursor)
+
+	page = list("?limit=2&sort=amount&order=asc&cursor=" + url.QueryEscape(*page.NextCursor))
+	require.Len(t, page.Items, 1)
+	require.InDelta(t, 40.0, page.Items[0].Amount, 0.001)
+	require.Nil(t, page.NextCursor)
+
+	day := env.frozen.AddDate(0, 0, 1).Format(time.DateOnly)
+	page = list("?from=" + day + "&to=" + day)
+	require.Len(t, page.Items, 1, "the to day is included")
+	require.InDelta(t, 25.0, page.Items[0].Amount, 0.001)
+	require.Equal(t, int64(1), page.Totals.Count)
+	require.Len(t, list("?q=INVOICE%202").Items, 1)
+	require.Len(t, list("?min_amount=25").Items, 2)
+	require.Len(t, list("?min_amount=25.00&max_amount=39.99").Items, 1)
+
+	// Bounds apply to each record in its own currency, also without account_id.
+	yen, err := env.accountService.CreateAccount(ctx, user.ID, &models.Account{Name: "Yen", Type: models.AccountTypeCash, CurrencyISOCode: "JPY"})
+	require.NoError(t, err)
+	_, _, err = env.accountService.CreditIncome(ctx, user.ID, yen.ID, &models.Income{AmountCents: 2500, Source: "gift", ReceivedAt: env.frozen})
+	require.NoError(t, err)
+	page = list("?min_amount=25&max_amount=30")
+	require.Len(t, page.Items, 1, "2500 JPY is not within 25 and 30")
+	require.Equal(t, "UAH", page.Items[0].CurrencyISOCode)
+	require.Len(t, list("?min_amount=2500").Items, 1)
+	require.Len(t, list(fmt.Sprintf("?account_id=%d&min_amount=2500", yen.ID)).Items, 1)
+	require.Len(t, list("?min_amount=0.001").Items, 4)
+
+	for _, query := range []string{"?sort=source", "?order=up", "?from=yesterday", "?min_amount=-1", "?min_amount=1e3", "?min_amount=30&max_amount=20", "?cursor=bogus", "?from=2026-02-02&to=2026-02-01"} {
+		res := authorizedRequest(env, http.MethodGet, "/api/v1/accounts/incomes"+query, authHeader)
+		require.Equal(t, http.StatusBadRequest, res.Code, query)
+	}
+}
+
+func TestAccountHandlerTransactionFeed(t *testing.T) {
+	env := setupHandlerTest(t)
+
+	ctx := context.Background()
+	user, err := env.authService.RegisterUser(ctx, "feed@example.com", "password123", "uah")
+	require.NoError(t, err)
+	authHeader := env.authHeader(user.ID, user.Email)
+	mainID := env.defaultAccountID(t, user.ID)
+	savings, err := env.accountService.CreateAccount(ctx, user.ID, &models.Account{Name: "Savings", Type: models.AccountTypeSavings})
+	require.NoError(t, err)
+
+	_, _, err = env.accountService.CreditIncome(ctx, user.ID, mainID, &models.Income{AmountCents: 50000, Source: "Employer", ReceivedAt: env.frozen})
+	require.NoError(t, err)
+	_, _, err = env.accountService.DebitExpense(ctx, user.ID, mainID, &models.Expense{AmountCents: 1250, Category: "Coffee", IncurredAt: env.frozen.Add(time.Hour)})
+	require.NoError(t, err)
+	_, _, _, err = env.accountService.Transfer(ctx, user.ID, &models.Transfer{FromAccountID: mainID, ToAccountID: savings.ID, AmountCents: 20000, TransferredAt: env.frozen.Add(2 * time.Hour)})
+	require.NoError(t, err)
+
+	type feedItem struct {
+		Type                  string `json:"type"`
+		AccountID             uint   `json:"account_id"`
+		CounterpartyAccountID *uint  `json:"counterparty_account_id"`
+		AmountDecimal         string `json:"amount_decimal"`
+		Title                 string `json:"title"`
+		BalanceAfterDecimal   string `json:"balance_after_decimal"`
+	}
+	list := func(query string) transactionPage[feedItem] {
+		t.Helper()
+		res := authorizedRequest(env, http.MethodGet, "/api/v1/accounts/transactions"+query, authHeader)
+		require.Equal(t, http.StatusOK, res.Code, res.Body.String())
+		var page transactionPage[feedItem]
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &page))
+		return page
+	}
+
+	page := list("?limit=3")
+	require.Len(t, page.Items, 3)
+	require.NotNil(t, page.NextCursor)
+	require.Equal(t, feedItem{Type: "transfer", AccountID: savings.ID, CounterpartyAccountID: &mainID, AmountDecimal: "200.00", BalanceAfterDecimal: "200.00"}, page.Items[0])
+	require.Equal(t, "-200.00", page.Items[1].AmountDecimal)
+	require.Equal(t, "287.50", page.Items[1].BalanceAfterDecimal)
+	require.Equal(t, feedItem{Type: "expense", AccountID: mainID, AmountDecimal: "-12.50", Title: "Coffee", BalanceAfterDecimal: "487.50"}, page.Items[2])
+
+	page = list("?limit=3&cursor=" + url.QueryEscape(*page.NextCursor))
+	require.Len(t, page.Items, 1)
+	require.Equal(t, feedItem{Type: "income", AccountID: mainID, AmountDecimal: "500.00", Title: "Employer", BalanceAfterDecimal: "500.00"}, page.Items[0])
+	require.Nil(t, page.NextCursor)
+
+	require.Len(t, list("?type=income&type=expense").Items, 2)
+	require.Len(t, list(fmt.Sprintf("?account_id=%d", savings.ID)).Items, 1)
+	require.Equal(t, "income", list("?order=asc&limit=1").Items[0].Type)
+
+	for _, query := range []string{"?type=refund", "?order=newest", "?to=tomorrow", "?cursor=bogus", "?account_id=x"} {
+		res := authorizedRequest(env, http.MethodGet, "/api/v1/accounts/transactions"+query, authHeader)
+		require.Equal(t, http.StatusBadRequest, res.Code, query)
+	}
+}
+
+func TestAccountHandlerTransactionFeedAcrossConversion(t *testing.T) {
+	env := setupHandlerTest(t)
+
+	ctx := context.Background()
+	user, err := env.authService.RegisterUser(ctx, "feed-conversion@example.com", "password123", "uah")
+	require.NoError(t, err)
+	authHeader := env.authHeader(user.ID, user.Email)
+	mainID := env.defaultAccountID(t, user.ID)
+
+	_, _, err = env.accountService.CreditIncome(ctx, user.ID, mainID, &models.Income{AmountCents: 100000, Source: "Employer", ReceivedAt: env.frozen})
+	require.NoError(t, err)
+	_, _, err = env.accountService.ChangeDefaultCurrency(ctx, user.ID, storage.CurrencyChange{Currency: "kwd", Convert: true, Rate: "0.0075"})
+	require.NoError(t, err)
+
+	type feedItem struct {
+		Type                   string `json:"type"`
+		AmountDecimal          string `json:"amount_decimal"`
+		CurrencyISOCode        string `json:"currency_iso_code"`
+		FromAmountDecimal      string `json:"from_amount_decimal"`
+		FromCurrencyISOCode    string `json:"from_currency_iso_code"`
+		BalanceAfterDecimal    string `json:"balance_after_decimal"`
+		BalanceCurrencyISOCode string `json:"balance_currency_iso_code"`
+	}
+	res := authorizedRequest(env, http.MethodGet, "/api/v1/accounts/transactions?order=asc", authHeader)
+	require.Equal(t, http.StatusOK, res.Code, res.Body.String())
+	var page transactionPage[feedItem]
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &page))
+	require.Equal(t, []feedItem{
+		{Type: "income", AmountDecimal: "1000.00", CurrencyISOCode: "UAH", BalanceAfterDecimal: "1000.00", BalanceCurrencyISOCode: "UAH"},
+		{Type: "conversion", AmountDecimal: "7.500", CurrencyISOCode: "KWD", FromAmountDecimal: "-1000.00", FromCurrencyISOCode: "UAH", BalanceAfterDecimal: "7.500", BalanceCurrencyISOCode: "KWD"},
+	}, page.Items)
+}
+
+func TestAccountHandlerBalanceHistory(t *testing.T) {
+	env := setupHandlerTest(t)
+
+	ctx := context.Background()
+	user, err := env.authService.RegisterUser(ctx, "history@example.com", "password123", "usd")
+	require.NoError(t, err)
+	authHeader := env.authHeader(user.ID, user.Email)
+	accountID := env.defaultAccountID(t, user.ID)
+
+	_, _, err = env.accountService.CreditIncome(ctx, user.ID, accountID, &models.Income{AmountCents: 10000, Source: "Refund", ReceivedAt: env.frozen.AddDate(0, 0, -16)})
+	require.NoError(t, err)
+	_, _, err = env.accountService.CreditIncome(ctx, user.ID, accountID, &models.Income{AmountCents: 50000, Source: "Employer", ReceivedAt: env.frozen})
+	require.NoError(t, err)
+	_, _, err = env.accountService.DebitExpense(ctx, user.ID, accountID, &models.Expense{AmountCents: 1250, Category: "Coffee", IncurredAt: env.frozen.AddDate(0, 0, -2)})
+	require.NoError(t, err)
+
+	balanceAt := func(at string) string {
+		t.Helper()
+		res := authorizedRequest(env, http.MethodGet, "/api/v1/accounts/balance?at="+url.QueryEscape(at), authHeader)
+		require.Equal(t, http.StatusOK, res.Code, res.Body.String())
+		var body struct {
+			BalanceDecimal string `json:"balance_decimal"`
+			At             string `json:"at"`
+		}
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &body))
+		require.NotEmpty(t, body.At)
+		return body.BalanceDecimal
+	}
+	require.Equal(t, "87.50", balanceAt("2025-11-04"))
+	require.Equal(t, "87.50", balanceAt("2025-11-05T11:00:00Z"))
+	require.Equal(t, "587.50", balanceAt("2025-11-05"))
+	require.Equal(t, "0.00", balanceAt("2025-10-01"))
+
+	type point struct {
+		Start         string `json:"start"`
+		End           string `json:"end"`
+		Balance       string `json:"balance_decimal"`
+		ChangeDecimal string `json:"change_decimal"`
+	}
+	type history struct {
+		Interval       string  `json:"interval"`
+		OpeningDecimal string  `json:"opening_balance_decimal"`
+		Points         []point `json:"points"`
+	}
+	getHistory := func(query string) history {
+		t.Helper()
+		res := authorizedRequest(env, http.MethodGet, "/api/v1/accounts/balance/history"+query, authHeader)
+		require.Equal(t, http.StatusOK, res.Code, res.Body.String())
+		var body history
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &body))
+		return body
+	}
+
+	daily := getHistory("?from=2025-11-01&to=2025-11-05")
+	require.Equal(t, "day", daily.Interval)
+	require.Equal(t, "100.00", daily.OpeningDecimal)
+	require.Len(t, daily.Points, 5)
+	require.Equal(t, point{Start: "2025-11-03", End: "2025-11-03", Balance: "87.50", ChangeDecimal: "-12.50"}, daily.Points[2])
+	require.Equal(t, "587.50", daily.Points[4].Balance)
+
+	weekly := getHistory(fmt.Sprintf("?from=2025-10-21&to=2025-11-05&interval=week&account_id=%d", accountID))
+	require.Equal(t, "0.00", weekly.OpeningDecimal)
+	require.Equal(t, []point{
+		{Start: "2025-10-20", End: "2025-10-26", Balance: "100.00", ChangeDecimal: "100.00"},
+		{Start: "2025-10-27", End: "2025-11-02", Balance: "100.00", ChangeDecimal: "0.00"},
+		{Start: "2025-11-03", End: "2025-11-09", Balance: "587.50", ChangeDecimal: "487.50"},
+	}, weekly.Points)
+
+	for _, path := range []string{
+		"/api/v1/accounts/balance?at=yesterday",
+		"/api/v1/accounts/balance/history?to=2025-11-05",
+		"/api/v1/accounts/balance/history?from=2025-11-01&to=2025-11-05&interval=year",
+		"/api/v1/accounts/balance/history?from=2025-11-05&to=2025-11-01",
+	} {
+		res := authorizedRequest(env, http.MethodGet, path, authHeader)
+		require.Equal(t, http.StatusBadRequest, res.Code, path)
+	}
+}
+
+func TestAccountHandlerTagFilters(t *testing.T) {
+	env := setupHandlerTest(t)
+
+	ctx := context.Background()
+	user, err := env.authService.RegisterUser(ctx, "tag-filters@example.com", "password123", "usd")
+	require.NoError(t, err)
+	authHeader := env.authHeader(user.ID, user.Email)
+	_, _, err = env.accountService.CreditIncome(ctx, user.ID, env.defaultAccountID(t, user.ID), &models.Income{AmountCents: 100000, Source: "seed", ReceivedAt: env.frozen})
+	require.NoError(t, err)
+
+	for _, payload := range []map[string]any{
+		{"amount": "300", "category": "Flights", "tags": []string{"trip-2026", "Reimbursable"}},
+		{"amount": "500", "category": "Hotels", "tags": []string{"trip-2026"}},
+		{"amount": "7", "category": "Coffee"},
+	} {
+		res := jsonRequest(t, env, http.MethodPost, "/api/v1/accounts/expenses", authHeader, payload)
+		require.Equal(t, http.StatusCreated, res.Code, res.Body.String())
+	}
+
+	res := jsonRequest(t, env, http.MethodPost, "/api/v1/accounts/expenses", authHeader, map[string]any{"amount": "1", "category": "Coffee", "tags": []string{""}})
+	require.Equal(t, http.StatusBadRequest, res.Code)
+
+	type taggedItem struct {
+		ID       uint     `json:"id"`
+		Category string   `json:"category"`
+		Tags     []string `json:"tags"`
+	}
+	list := func(query string) []taggedItem {
+		t.Helper()
+		res := authorizedRequest(env, http.MethodGet, "/api/v1/accounts/expenses"+query, authHeader)
+		require.Equal(t, http.StatusOK, res.Code, res.Body.String())
+		var page transactionPage[taggedItem]
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &page))
+		return page.Items
+	}
+
+	all := list("")
+	require.Len(t, all, 3)
+	require.NotNil(t, all[0].Tags, "untagged expenses list an empty array")
+	require.Len(t, list("?tag=trip-2026&tag=reimbursable"), 2)
+	matched := list("?tag=trip-2026&tag=reimbursable&tag_match=all")
+	require.Len(t, matched, 1)
+	require.Equal(t, "Flights", matched[0].Category)
+	require.Equal(t, []string{"reimbursable", "trip-2026"}, matched[0].Tags)
+
+	res = authorizedRequest(env, http.MethodGet, "/api/v1/accounts/expenses?tag=trip-2026&tag_match=some", authHeader)
+	require.Equal(t, http.StatusBadRequest, res.Code)
+
+	res = jsonRequest(t, env, http.MethodPatch, fmt.Sprintf("/api/v1/accounts/expenses/%d", matched[0].ID), authHeader, map[string]any{"tags": []string{}})
+	require.Equal(t, http.StatusOK, res.Code, res.Body.String())
+	require.Len(t, list("?tag=reimbursable"), 0)
+}
+
+func TestAccountHandlerSplitExpense(t *testing.T) {
+	env := setupHandlerTest(t)
+
+	ctx := context.Background()
+	user, err := env.authService.RegisterUser(ctx, "split-receipt@example.com", "password123", "usd")
+	require.NoError(t, err)
+	authHeader := env.authHeader(user.ID, user.Email)
+	_, _, err = env.accountService.CreditIncome(ctx, user.ID, env.defaultAccountID(t, user.ID), &models.Income{AmountCents: 100000, Source: "seed", ReceivedAt: env.frozen})
+	require.NoError(t, err)
+
+	res := jsonRequest(t, env, http.MethodPost, "/api/v1/accounts/expenses", authHeader, map[string]any{
+		"amount": "84.20",
+		"splits": []map[string]any{
+			{"category": "Groceries", "amount": "52.10"},
+			{"category": "Household", "amount": "20"},
+		},
+	})
+	require.Equal(t, http.StatusBadRequest, res.Code, "the lines do not add up to the amount")
+
+	res = jsonRequest(t, env, http.MethodPost, "/api/v1/accounts/expenses", authHeader, map[string]any{
+		"amount": "84.20",
+		"splits": []map[string]any{
+			{"category": "Groceries", "amount": "52.10", "note": "weekly shop"},
+			{"category": "Household", "amount": "20"},
+			{"category": "Pharmacy", "amount": "12.10"},
+		},
+	})
+	require.Equal(t, http.StatusCreated, res.Code, res.Body.String())
+
+	type splitLine struct {
+		CategoryID    *uint  `json:"category_id"`
+		Category      string `json:"category"`
+		AmountCents   int64  `json:"amount_cents"`
+		AmountDecimal string `json:"amount_decimal"`
+		Note          string `json:"note"`
+	}
+	var expense struct {
+		ID       uint        `json:"id"`
+		Category string      `json:"category"`
+		Splits   []splitLine `json:"splits"`
+	}
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &expense))
+	require.Equal(t, "Groceries", expense.Category)
+	require.Len(t, expense.Splits, 3)
+	require.Equal(t, "52.10", expense.Splits[0].AmountDecimal)
+	require.Equal(t, "weekly shop", expense.Splits[0].Note)
+	require.NotNil(t, expense.Splits[2].CategoryID)
+
+	path := fmt.Sprintf("/api/v1/accounts/expenses/%d", expense.ID)
+	res = jsonRequest(t, env, http.MethodPatch, path, authHeader, map[string]any{"amount": "90"})
+	require.Equal(t, http.StatusBadRequest, res.Code, "the lines must change with the amount")
+
+	res = jsonRequest(t, env, http.MethodPatch, path, authHeader, map[string]any{
+		"amount": "90",
+		"splits": []map[string]any{
+			{"category": "Groceries", "amount": "60"},
+			{"category": "Household", "amount": "30"},
+		},
+	})
+	require.Equal(t, http.StatusOK, res.Code, res.Body.String())
+
+	res = authorizedRequest(env, http.MethodGet, path, authHeader)
+	require.Equal(t, http.StatusOK, res.Code)
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &expense))
+	require.Len(t, expense.Splits, 2)
+	require.Equal(t, int64(3000), expense.Splits[1].AmountCents)
+}
+
+func TestAccountHandlerAccountCRUD(t *testing.T) {
+	env := setupHandlerTest(t)
+
+	ctx := context.Background()
+	user, err := env.authService.RegisterUser(ctx, "accounts-crud@example.com", "password123", "uah")
+	require.NoError(t, err)
+	authHeader := env.authHeader(user.ID, user.Email)
+
+	do := func(method, path string, payload any) *httptest.ResponseRecorder {
+		var body io.Reader
+		if payload != nil {
+			raw, err := json.Marshal(payload)
+			require.NoError(t, err)
+			body = bytes.NewReader(raw)
+		}
+		req := httptest.NewRequest(method, path, body)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", authHeader)
+		res := httptest.NewRecorder()
+		env.engine.ServeHTTP(res, req)
+		return res
+	}
+
+	type accountPayload struct {
+		ID              uint   `json:"id"`
+		Name            string `json:"name"`
+		Type            string `json:"type"`
+		IsDefault       bool   `json:"is_default"`
+		CurrencyISOCode string `json:"currency_iso_code"`
+	}
+
+	res := do(http.MethodPost, "/api/v1/accounts", map[string]any{"name": "Cash", "type": "cash", "currency": "usd"})
+	require.Equal(t, http.StatusCreated, res.Code)
+	var cash accountPayload
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &cash))
+	require.Equal(t, "cash", cash.Type)
+	require.Equal(t, "USD", cash.CurrencyISOCode)
+	require.False(t, cash.IsDefault)
+
+	res = do(http.MethodPost, "/api/v1/accounts", map[string]any{"name": "Broken", "type": "crypto"})
+	require.Equal(t, http.StatusBadRequest, res.Code)
+
+	res = do(http.MethodGet, "/api/v1/accounts", nil)
+	require.Equal(t, http.StatusOK, res.Code)
+	var list []accountPayload
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &list))
+	require.Len(t, list, 2)
+	require.True(t, list[0].IsDefault)
+
+	res = do(http.MethodPost, "/api/v1/accounts/incomes", map[string]any{"account_id": cash.ID, "amount": 12.5, "source": "Tips"})
+	require.Equal(t, http.StatusCreated, res.Code)
+
+	res = do(http.MethodGet, fmt.Sprintf("/api/v1/accounts/balance?account_id=%d", cash.ID), nil)
+	require.Equal(t, http.StatusOK, res.Code)
+	var balance balanceResponse
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &balance))
+	require.Equal(t, int64(1250), balance.BalanceCents)
+
+	res = do(http.MethodPatch, fmt.Sprintf("/api/v1/accounts/%d", cash.ID), map[string]any{"name": "Pocket cash"})
+	require.Equal(t, http.StatusOK, res.Code)
+	var updated accountPayload
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &updated))
+	require.Equal(t, "Pocket cash", updated.Name)
+
+	res = do(http.MethodDelete, fmt.Sprintf("/api/v1/accounts/%d", cash.ID), nil)
+	require.Equal(t, http.StatusBadRequest, res.Code)
+
+	res = do(http.MethodGet, "/api/v1/accounts/999999", nil)
+	require.Equal(t, http.StatusNotFound, res.Code)
+}
+
+func jsonRequest(t *testing.T, env *testEnv, method, path, authHeader string, payload any) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var body io.Reader
+	if payload != nil {
+		raw, err := json.Marshal(payload)
+		require.NoError(t, err)
+		body = bytes.NewReader(raw)
+	}
+	req := httptest.NewRequest(method, path, body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", authHeader)
+	res := httptest.NewRecorder()
+	env.engine.ServeHTTP(res, req)
+	return res
+}
+
+func TestAccountHandlerEditAndDeleteTransactions(t *testing.T) {
+	env := setupHandlerTest(t)
+
+	ctx := context.Background()
+	user, err := env.authService.RegisterUser(ctx, "edit-tx@example.com", "password123", "usd")
+	require.NoError(t, err)
+	other, err := env.authService.RegisterUser(ctx, "edit-tx-other@example.com", "password123", "usd")
+	require.NoError(t, err)
+	authHeader := env.authHeader(user.ID, user.Email)
+	otherHeader := env.authHeader(other.ID, other.Email)
+
+	type itemPayload struct {
+		ID           uint    `json:"id"`
+		Amount       float64 `json:"amount"`
+		Source       string  `json:"source"`
+		Category     string  `json:"category"`
+		BalanceCents int64   `json:"balance_cents"`
+	}
+
+	res := jsonRequest(t, env, http.MethodPost, "/api/v1/accounts/incomes", authHeader, map[string]any{"amount": 100, "source": "Salary"})
+	require.Equal(t, http.StatusCreated, res.Code)
+	var income itemPayload
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &income))
+
+	res = jsonRequest(t, env, http.MethodPost, "/api/v1/accounts/expenses", authHeader, map[string]any{"amount": 30, "category": "Food"})
+	require.Equal(t, http.StatusCreated, res.Code)
+	var expense itemPayload
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &expense))
+
+	incomePath := fmt.Sprintf("/api/v1/accounts/incomes/%d", income.ID)
+	expensePath := fmt.Sprintf("/api/v1/accounts/expenses/%d", expense.ID)
+
+	res = jsonRequest(t, env, http.MethodGet, incomePath, otherHeader, nil)
+	require.Equal(t, http.StatusNotFound, res.Code)
+	res = jsonRequest(t, env, http.MethodPatch, expensePath, otherHeader, map[string]any{"amount": 1})
+	require.Equal(t, http.StatusNotFound, res.Code)
+
+	res = jsonRequest(t, env, http.MethodGet, incomePath, authHeader, nil)
+	require.Equal(t, http.StatusOK, res.Code)
+	var fetched itemPayload
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &fetched))
+	require.Equal(t, "Salary", fetched.Source)
+
+	res = jsonRequest(t, env, http.MethodPatch, incomePath, authHeader, map[string]any{"amount": 80, "source": "Bonus"})
+	require.Equal(t, http.StatusOK, res.Code)
+	var updated itemPayload
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &updated))
+	require.Equal(t, 80.0, updated.Amount)
+	require.Equal(t, "Bonus", updated.Source)
+	require.Equal(t, int64(5000), updated.BalanceCents)
+
+	res = jsonRequest(t, env, http.MethodPatch, expensePath, authHeader, map[string]any{"amount": 90})
+	require.Equal(t, http.StatusBadRequest, res.Code)
+	var envelope errorEnvelope
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &envelope))
+	require.Equal(t, "insufficient_funds", envelope.Error.Code)
+
+	res = jsonRequest(t, env, http.MethodPatch, expensePath, authHeader, map[string]any{"amount": 0})
+	require.Equal(t, http.StatusBadRequest, res.Code)
+
+	res = jsonRequest(t, env, http.MethodDelete, expensePath, authHeader, nil)
+	require.Equal(t, http.StatusNoContent, res.Code)
+	res = jsonRequest(t, env, http.MethodGet, expensePath, authHeader, nil)
+	require.Equal(t, http.StatusNotFound, res.Code)
+
+	res = jsonRequest(t, env, http.MethodGet, "/api/v1/accounts/balance", authHeader, nil)
+	require.Equal(t, http.StatusOK, res.Code)
+	var balance balanceResponse
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &balance))
+	require.Equal(t, int64(8000), balance.BalanceCents)
+}
+
+func TestAccountHandlerDecimalAmounts(t *testing.T) {
+	env := setupHandlerTest(t)
+
+	ctx := context.Background()
+	user, err := env.authService.RegisterUser(ctx, "decimal@example.com", "password123", "usd")
+	require.NoError(t, err)
+	authHeader := env.authHeader(user.ID, user.Email)
+
+	var income struct {
+		AmountCents    int64  `json:"amount_cents"`
+		AmountDecimal  string `json:"amount_decimal"`
+		BalanceDecimal string `json:"balance_decimal"`
+	}
+	for _, amount := range []any{"0.10", "0.2", 0.3} {
+		res := jsonRequest(t, env, http.MethodPost, "/api/v1/accounts/incomes", authHeader, map[string]any{"amount": amount, "source": "Coins"})
+		require.Equal(t, http.StatusCreated, res.Code, res.Body.String())
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &income))
+	}
+	require.Equal(t, int64(30), income.AmountCents)
+	require.Equal(t, "0.30", income.AmountDecimal)
+	require.Equal(t, "0.60", income.BalanceDecimal)
+
+	// Beyond float64 precision the amount must still round-trip exactly.
+	res := jsonRequest(t, env, http.MethodPost, "/api/v1/accounts/incomes", authHeader, map[string]any{"amount": "90071992547409.93", "source": "Lottery"})
+	require.Equal(t, http.StatusCreated, res.Code, res.Body.String())
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &income))
+	require.Equal(t, int64(9007199254740993), income.AmountCents)
+	require.Equal(t, "90071992547409.93", income.AmountDecimal)
+
+	for _, amount := range []any{"1.005", 2.999, "0", "0.00", "-5", "1e3", "12,50", ""} {
+		res := jsonRequest(t, env, http.MethodPost, "/api/v1/accounts/expenses", authHeader, map[string]any{"amount": amount, "category": "Food"})
+		require.Equal(t, http.StatusBadRequest, res.Code, "amount %v", amount)
+		var envelope errorEnvelope
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &envelope))
+		require.Equal(t, "validation_error", envelope.Error.Code)
+	}
+
+	res = jsonRequest(t, env, http.MethodPost, "/api/v1/accounts/expenses", authHeader, map[string]any{"amount": "1.500", "category": "Food"})
+	require.Equal(t, http.StatusCreated, res.Code, "trailing zeros are not excess precision")
+}
+
+func TestAccountHandlerCurrencyMinorUnits(t *testing.T) {
+	env := setupHandlerTest(t)
+
+	res := postJSON(t, env, "/api/v1/auth/register", map[string]any{"email": "xyz@example.com", "password": "password123", "default_currency": "XYZ"})
+	require.Equal(t, http.StatusBadRequest, res.Code)
+
+	ctx := context.Background()
+	user, err := env.authService.RegisterUser(ctx, "yen@example.com", "password123", "jpy")
+	require.NoError(t, err)
+	authHeader := env.authHeader(user.ID, user.Email)
+	yenAccountID := env.defaultAccountID(t, user.ID)
+
+	res = jsonRequest(t, env, http.MethodPost, "/api/v1/accounts", authHeader, map[string]any{"name": "Dinars", "currency": "XYZ"})
+	require.Equal(t, http.StatusBadRequest, res.Code)
+	res = jsonRequest(t, env, http.MethodPost, "/api/v1/accounts", authHeader, map[string]any{"name": "Dinars", "currency": "kwd"})
+	require.Equal(t, http.StatusCreated, res.Code, res.Body.String())
+	var dinars struct {
+		ID uint `json:"id"`
+	}
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &dinars))
+
+	var income struct {
+		AmountCents     int64  `json:"amount_cents"`
+		AmountDecimal   string `json:"amount_decimal"`
+		CurrencyISOCode string `json:"currency_iso_code"`
+		BalanceDecimal  string `json:"balance_decimal"`
+	}
+	res = jsonRequest(t, env, http.MethodPost, "/api/v1/accounts/incomes", authHeader, map[string]any{"amount": "1500", "source": "Gift"})
+	require.Equal(t, http.StatusCreated, res.Code, res.Body.String())
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &income))
+	require.Equal(t, int64(1500), income.AmountCents)
+	require.Equal(t, "1500", income.AmountDecimal)
+	require.Equal(t, "JPY", income.CurrencyISOCode)
+
+	res = jsonRequest(t, env, http.MethodPost, "/api/v1/accounts/expenses", authHeader, map[string]any{"amount": "15.5", "category": "Food"})
+	require.Equal(t, http.StatusBadRequest, res.Code, "yen have no minor unit")
+
+	res = jsonRequest(t, env, http.MethodPost, "/api/v1/accounts/incomes", authHeader, map[string]any{"account_id": dinars.ID, "amount": "1.234", "source": "Gift"})
+	require.Equal(t, http.StatusCreated, res.Code, res.Body.String())
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &income))
+	require.Equal(t, int64(1234), income.AmountCents)
+	require.Equal(t, "1.234", income.AmountDecimal)
+	require.Equal(t, "1.234", income.BalanceDecimal)
+
+	// 1000 JPY at 0.002 KWD per yen is 2.000 KWD, i.e. 2000 fils.
+	res = jsonRequest(t, env, http.MethodPost, "/api/v1/transfers", authHeader, map[string]any{
+		"from_account_id": yenAccountID,
+		"to_account_id":   dinars.ID,
+		"amount":          "1000",
+		"rate":            "0.002",
+	})
+	require.Equal(t, http.StatusCreated, res.Code, res.Body.String())
+	var transfer struct {
+		ToAmountCents      int64  `json:"to_amount_cents"`
+		ToAmountDecimal    string `json:"to_amount_decimal"`
+		FromBalanceDecimal string `json:"from_balance_decimal"`
+		ToBalanceDecimal   string `json:"to_balance_decimal"`
+	}
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &transfer))
+	require.Equal(t, int64(2000), transfer.ToAmountCents)
+	require.Equal(t, "2.000", transfer.ToAmountDecimal)
+	require.Equal(t, "500", transfer.FromBalanceDecimal)
+	require.Equal(t, "3.234", transfer.ToBalanceDecimal)
+}
+
+func TestAccountHandlerForeignCurrencyExpense(t *testing.T) {
+	env := setupHandlerTest(t)
+	ctx := context.Background()
+
+	user, err := env.authService.RegisterUser(ctx, "traveller@example.com", "password123", "uah")
+	require.NoError(t, err)
+	authHeader := env.authHeader(user.ID, user.Email)
+	_, _, err = env.accountService.CreditIncome(ctx, user.ID, env.defaultAccountID(t, user.ID), &models.Income{AmountCents: 1000000, Source: "Salary"})
+	require.NoError(t, err)
+
+	type expenseItem struct {
+		ID                      uint   `json:"id"`
+		AmountCents             int64  `json:"amount_cents"`
+		AmountDecimal           string `json:"amount_decimal"`
+		CurrencyISOCode         string `json:"currency_iso_code"`
+		OriginalAmountCents     int64  `json:"original_amount_cents"`
+		OriginalAmountDecimal   string `json:"original_amount_decimal"`
+		OriginalCurrencyISOCode string `json:"original_currency_iso_code"`
+		ExchangeRate            string `json:"exchange_rate"`
+	}
+
+	res := jsonRequest(t, env, http.MethodPost, "/api/v1/accounts/expenses", authHeader, map[string]any{
+		"amount": "12.50", "currency": "EUR", "category": "Museum",
+	})
+	require.Equal(t, http.StatusUnprocessableEntity, res.Code, "no stored EUR rate yet")
+
+	res = jsonRequest(t, env, http.MethodPost, "/api/v1/accounts/expenses", authHeader, map[string]any{
+		"amount": "12.50", "rate": "45", "category": "Museum",
+	})
+	require.Equal(t, http.StatusBadRequest, res.Code, "a rate needs a currency")
+
+	res = jsonRequest(t, env, http.MethodPost, "/api/v1/accounts/expenses", authHeader, map[string]any{
+		"amount": "12.50", "currency": "eur", "rate": "45.2", "category": "Museum",
+	})
+	require.Equal(t, http.StatusCreated, res.Code, res.Body.String())
+	var created expenseItem
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &created))
+	require.Equal(t, expenseItem{
+		ID:                      created.ID,
+		AmountCents:             56500,
+		AmountDecimal:           "565.00",
+		CurrencyISOCode:         "UAH",
+		OriginalAmountCents:     1250,
+		OriginalAmountDecimal:   "12.50",
+		OriginalCurrencyISOCode: "EUR",
+		ExchangeRate:            "45.2",
+	}, created)
+
+	// The amount of a foreign-currency expense is edited in its original currency.
+	res = jsonRequest(t, env, http.MethodPatch, fmt.Sprintf("/api/v1/accounts/expenses/%d", created.ID), authHeader, map[string]any{"amount": "10"})
+	require.Equal(t, http.StatusOK, res.Code, res.Body.String())
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &created))
+	require.Equal(t, int64(1000), created.OriginalAmountCents)
+	require.Equal(t, int64(45200), created.AmountCents)
+
+	res = jsonRequest(t, env, http.MethodPost, "/api/v1/accounts/expenses", authHeader, map[string]any{"amount": "20", "category": "Food"})
+	require.Equal(t, http.StatusCreated, res.Code)
+
+	res = authorizedRequest(env, http.MethodGet, "/api/v1/accounts/expenses", authHeader)
+	require.Equal(t, http.StatusOK, res.Code)
+	var page transactionPage[expenseItem]
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &page))
+	items := page.Items
+	require.Len(t, items, 2)
+	byID := map[uint]expenseItem{items[0].ID: items[0], items[1].ID: items[1]}
+	require.Equal(t, "EUR", byID[created.ID].OriginalCurrencyISOCode)
+	for id, item := range byID {
+		if id == created.ID {
+			continue
+		}
+		require.Equal(t, "UAH", item.OriginalCurrencyISOCode, "plain expenses report the account currency")
+		require.Equal(t, item.AmountCents, item.OriginalAmountCents)
+		require.Equal(t, "1", item.ExchangeRate)
+	}
 }
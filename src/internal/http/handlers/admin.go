@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"bckndlab3/src/internal/http/middleware"
+	"bckndlab3/src/internal/http/responses"
+	"bckndlab3/src/internal/storage"
+)
+
+// AdminHandler exposes operational endpoints reserved for administrators.
+type AdminHandler struct {
+	Integrity *storage.IntegrityService
+}
+
+func NewAdminHandler(integrity *storage.IntegrityService) *AdminHandler {
+	return &AdminHandler{Integrity: integrity}
+}
+
+func (h *AdminHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/integrity", h.CheckIntegrity)
+	router.POST("/integrity/repair", h.RepairIntegrity)
+}
+
+// CheckIntegrity reports accounts whose balances do not match their transactions.
+func (h *AdminHandler) CheckIntegrity(c *gin.Context) {
+	report, err := h.Integrity.Check(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewIntegrityReportResponse(report))
+}
+
+// RepairIntegrity resets discrepant balances and records the authenticated admin as the actor.
+func (h *AdminHandler) RepairIntegrity(c *gin.Context) {
+	actor, _ := middleware.GetEmail(c)
+
+	report, err := h.Integrity.Repair(c.Request.Context(), actor)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewIntegrityReportResponse(report))
+}
@@ -0,0 +1,97 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"bckndlab3/src/internal/models"
+	"bckndlab3/src/internal/storage"
+)
+
+type integrityReportResponse struct {
+	AccountsChecked int  `json:"accounts_checked"`
+	Repaired        bool `json:"repaired"`
+	Discrepancies   []struct {
+		AccountID         uint  `json:"account_id"`
+		StoredCents       int64 `json:"stored_cents"`
+		TransactionsCents int64 `json:"transactions_cents"`
+	} `json:"discrepancies"`
+}
+
+// registerAdmin registers the test admin and grants the admin role, as the admin
+// subcommand does.
+func registerAdmin(t *testing.T, env *testEnv) *models.User {
+	t.Helper()
+
+	admin, err := env.authService.RegisterUser(context.Background(), testAdminEmail, "strongpass", "usd")
+	require.NoError(t, err)
+	require.NoError(t, storage.NewUserRepository(env.db).SetAdmin(context.Background(), testAdminEmail, true))
+	return admin
+}
+
+func TestAdminHandlerIntegrity(t *testing.T) {
+	env := setupHandlerTest(t)
+	ctx := context.Background()
+
+	admin := registerAdmin(t, env)
+	user, err := env.authService.RegisterUser(ctx, "regular@example.com", "strongpass", "usd")
+	require.NoError(t, err)
+	accountID := env.defaultAccountID(t, user.ID)
+
+	rec := authorizedRequest(env, http.MethodGet, "/api/v1/admin/integrity", env.authHeader(user.ID, user.Email))
+	require.Equal(t, http.StatusForbidden, rec.Code)
+
+	require.NoError(t, env.db.Exec("UPDATE accounts SET balance_cents = ? WHERE id = ?", 250, accountID).Error)
+
+	adminHeader := env.authHeader(admin.ID, admin.Email)
+	rec = authorizedRequest(env, http.MethodGet, "/api/v1/admin/integrity", adminHeader)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var report integrityReportResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	require.Equal(t, 2, report.AccountsChecked)
+	require.Len(t, report.Discrepancies, 1)
+	require.Equal(t, accountID, report.Discrepancies[0].AccountID)
+	require.Equal(t, int64(250), report.Discrepancies[0].StoredCents)
+	require.Zero(t, report.Discrepancies[0].TransactionsCents)
+
+	rec = authorizedRequest(env, http.MethodPost, "/api/v1/admin/integrity/repair", adminHeader)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	require.True(t, report.Repaired)
+
+	rec = authorizedRequest(env, http.MethodGet, "/api/v1/admin/integrity", adminHeader)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	require.Empty(t, report.Discrepancies)
+}
+
+func TestAdminHandlerRequiresStoredRole(t *testing.T) {
+	env := setupHandlerTest(t)
+	ctx := context.Background()
+
+	// Owning the admin email is not enough: the role is granted to the user record.
+	user, err := env.authService.RegisterUser(ctx, testAdminEmail, "strongpass", "usd")
+	require.NoError(t, err)
+	header := env.authHeader(user.ID, user.Email)
+	rec := authorizedRequest(env, http.MethodGet, "/api/v1/admin/integrity", header)
+	require.Equal(t, http.StatusForbidden, rec.Code)
+
+	users := storage.NewUserRepository(env.db)
+	require.NoError(t, users.SetAdmin(ctx, testAdminEmail, true))
+	rec = authorizedRequest(env, http.MethodGet, "/api/v1/admin/integrity", header)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	// Re-registering a deleted admin's email does not inherit the role.
+	require.NoError(t, env.authService.DeleteUser(ctx, user.ID))
+	successor, err := env.authService.RegisterUser(ctx, testAdminEmail, "strongpass", "usd")
+	require.NoError(t, err)
+	rec = authorizedRequest(env, http.MethodGet, "/api/v1/admin/integrity", env.authHeader(successor.ID, successor.Email))
+	require.Equal(t, http.StatusForbidden, rec.Code)
+
+	require.ErrorIs(t, users.SetAdmin(ctx, "nobody@example.com", true), storage.ErrNotFound)
+}
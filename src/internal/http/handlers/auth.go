@@ -1,26 +1,42 @@
 package handlers
 
 import (
+	"errors"
+	"io"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"bckndlab3/src/internal/http/middleware"
 	"bckndlab3/src/internal/http/requests"
 	"bckndlab3/src/internal/http/responses"
+	"bckndlab3/src/internal/models"
 	"bckndlab3/src/internal/storage"
 )
 
 // AuthHandler manages authentication related endpoints.
 type AuthHandler struct {
-	AuthService *storage.AuthService
-	JWTService  *storage.JWTService
+	AuthService   *storage.AuthService
+	Accounts      *storage.AccountService
+	JWTService    *storage.JWTService
+	RefreshTokens *storage.RefreshTokenService
+	Revocations   *storage.TokenRevocationStore
 }
 
-func NewAuthHandler(authService *storage.AuthService, jwtService *storage.JWTService) *AuthHandler {
+func NewAuthHandler(
+	authService *storage.AuthService,
+	accounts *storage.AccountService,
+	jwtService *storage.JWTService,
+	refreshTokens *storage.RefreshTokenService,
+	revocations *storage.TokenRevocationStore,
+) *AuthHandler {
 	return &AuthHandler{
-		AuthService: authService,
-		JWTService:  jwtService,
+		AuthService:   authService,
+		Accounts:      accounts,
+		JWTService:    jwtService,
+		RefreshTokens: refreshTokens,
+		Revocations:   revocations,
 	}
 }
 
@@ -28,11 +44,20 @@ func NewAuthHandler(authService *storage.AuthService, jwtService *storage.JWTSer
 func (h *AuthHandler) RegisterPublicRoutes(router *gin.RouterGroup) {
 	router.POST("/register", h.Register)
 	router.POST("/login", h.Login)
+	router.POST("/refresh", h.Refresh)
 }
 
 // RegisterProtectedRoutes sets up routes that require authentication.
 func (h *AuthHandler) RegisterProtectedRoutes(router *gin.RouterGroup) {
+	router.PATCH("/me", h.UpdateProfile)
 	router.DELETE("/me", h.Delete)
+	router.POST("/logout", h.Logout)
+	router.POST("/logout-all", h.LogoutAll)
+}
+
+// RegisterWellKnownRoutes sets up discovery documents served from the site root.
+func (h *AuthHandler) RegisterWellKnownRoutes(router gin.IRoutes) {
+	router.GET("/.well-known/jwks.json", h.JWKS)
 }
 
 // Register creates a new user.
@@ -65,16 +90,96 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	token, err := h.JWTService.GenerateToken(user.ID, user.Email)
+	deviceName := req.DeviceName
+	if deviceName == "" {
+		deviceName = c.GetHeader("User-Agent")
+	}
+
+	refreshToken, _, err := h.RefreshTokens.Issue(c.Request.Context(), user.ID, deviceName)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	payload, err := h.issueTokenPair(user, refreshToken)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	userPayload := responses.NewUserResponse(user)
+	payload.User = &userPayload
+
+	c.JSON(http.StatusOK, payload)
+}
+
+// Refresh exchanges a refresh token for a new access/refresh token pair.
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req requests.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	refreshToken, record, err := h.RefreshTokens.Rotate(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	user, err := h.AuthService.GetUser(c.Request.Context(), record.UserID)
 	if err != nil {
 		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"token": token,
-		"user":  responses.NewUserResponse(user),
+	payload, err := h.issueTokenPair(user, refreshToken)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, payload)
+}
+
+func (h *AuthHandler) issueTokenPair(user *models.User, refreshToken string) (responses.TokenResponse, error) {
+	accessToken, err := h.JWTService.GenerateToken(user.ID, user.Email)
+	if err != nil {
+		return responses.TokenResponse{}, err
+	}
+	return responses.NewTokenResponse(accessToken, h.JWTService.TokenDuration(), refreshToken, h.RefreshTokens.TTL()), nil
+}
+
+// UpdateProfile changes the user's default currency, converting the default account
+// balance when requested.
+func (h *AuthHandler) UpdateProfile(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": gin.H{
+				"code":    "unauthorized",
+				"message": "user not authenticated",
+			},
+		})
+		return
+	}
+
+	var req requests.ProfileUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	user, account, err := h.Accounts.ChangeDefaultCurrency(c.Request.Context(), userID, storage.CurrencyChange{
+		Currency: req.DefaultCurrency,
+		Convert:  req.ConvertBalance,
+		Rate:     req.Rate,
 	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewProfileResponse(user, account))
 }
 
 // Delete removes the authenticated user.
@@ -94,6 +199,101 @@ func (h *AuthHandler) Delete(c *gin.Context) {
 		c.Error(err)
 		return
 	}
+	h.Revocations.ForgetUser(userID)
 
 	c.Status(http.StatusNoContent)
 }
+
+// Logout revokes the presented access token and, when supplied, the refresh token
+// of the same session.
+func (h *AuthHandler) Logout(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": gin.H{
+				"code":    "unauthorized",
+				"message": "user not authenticated",
+			},
+		})
+		return
+	}
+
+	var req requests.LogoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	if err := h.revokeCurrentToken(c, userID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	if req.RefreshToken != "" {
+		if err := h.RefreshTokens.RevokeToken(c.Request.Context(), userID, req.RefreshToken); err != nil {
+			c.Error(err)
+			return
+		}
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// LogoutAll revokes every access and refresh token issued to the authenticated user.
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": gin.H{
+				"code":    "unauthorized",
+				"message": "user not authenticated",
+			},
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.RefreshTokens.RevokeAllForUser(ctx, userID); err != nil {
+		c.Error(err)
+		return
+	}
+	if err := h.Revocations.RevokeAllForUser(ctx, userID); err != nil {
+		c.Error(err)
+		return
+	}
+	// The cutoff has second precision, so revoke the calling token explicitly as well.
+	if err := h.revokeCurrentToken(c, userID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AuthHandler) revokeCurrentToken(c *gin.Context, userID uint) error {
+	tokenID, ok := middleware.GetTokenID(c)
+	if !ok {
+		return nil
+	}
+	expiresAt, ok := middleware.GetTokenExpiry(c)
+	if !ok {
+		expiresAt = time.Now().UTC().Add(h.JWTService.TokenDuration())
+	}
+	return h.Revocations.Revoke(c.Request.Context(), tokenID, userID, expiresAt)
+}
+
+// JWKS publishes the public keys that verify access tokens, including keys that were
+// rotated out but may still have unexpired tokens in circulation.
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	keys := h.JWTService.Keyring().PublicKeys()
+
+	payload := responses.JWKSResponse{Keys: make([]responses.JWK, 0, len(keys))}
+	for _, key := range keys {
+		if jwk, ok := responses.NewJWK(key.ID, key.Method.Alg(), key.PublicKey()); ok {
+			payload.Keys = append(payload.Keys, jwk)
+		}
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, payload)
+}
@@ -1,9 +1,12 @@
 package handlers_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -38,3 +41,217 @@ func TestAuthHandlerDeleteUserUnauthorized(t *testing.T) {
 	env.engine.ServeHTTP(res, req)
 	require.Equal(t, http.StatusUnauthorized, res.Code)
 }
+
+func TestAuthHandlerRegisterAcceptsLongPasswordWithArgon2id(t *testing.T) {
+	env := setupHandlerTest(t)
+
+	res := postJSON(t, env, "/api/v1/auth/register", map[string]any{"email": "long@example.com", "password": strings.Repeat("п", 30)})
+	require.Equal(t, http.StatusCreated, res.Code, "argon2id does not limit the password length")
+
+	res = postJSON(t, env, "/api/v1/auth/register", map[string]any{"email": "longer@example.com", "password": strings.Repeat("p", 200)})
+	require.Equal(t, http.StatusCreated, res.Code)
+}
+
+func TestAuthHandlerUpdateProfileCurrency(t *testing.T) {
+	env := setupHandlerTest(t)
+
+	ctx := context.Background()
+	user, err := env.authService.RegisterUser(ctx, "profile@example.com", "strongpass", "uah")
+	require.NoError(t, err)
+	authHeader := env.authHeader(user.ID, user.Email)
+
+	res := jsonRequest(t, env, http.MethodPatch, "/api/v1/auth/me", authHeader, map[string]any{"default_currency": "eur"})
+	require.Equal(t, http.StatusOK, res.Code, "an empty balance is simply relabelled")
+
+	res = jsonRequest(t, env, http.MethodPost, "/api/v1/accounts/incomes", authHeader, map[string]any{"amount": "100.00", "source": "Salary"})
+	require.Equal(t, http.StatusCreated, res.Code)
+
+	res = jsonRequest(t, env, http.MethodPatch, "/api/v1/auth/me", authHeader, map[string]any{"default_currency": "usd"})
+	require.Equal(t, http.StatusBadRequest, res.Code)
+	var envelope errorEnvelope
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &envelope))
+	require.Equal(t, "precondition_failed", envelope.Error.Code)
+
+	res = jsonRequest(t, env, http.MethodPatch, "/api/v1/auth/me", authHeader, map[string]any{"default_currency": "XYZ", "convert_balance": true, "rate": "1"})
+	require.Equal(t, http.StatusBadRequest, res.Code)
+
+	res = jsonRequest(t, env, http.MethodPatch, "/api/v1/auth/me", authHeader, map[string]any{"default_currency": "usd", "convert_balance": true, "rate": "1.08"})
+	require.Equal(t, http.StatusOK, res.Code, res.Body.String())
+	var profile struct {
+		DefaultCurrency string `json:"default_currency"`
+		DefaultAccount  struct {
+			BalanceCents    int64  `json:"balance_cents"`
+			CurrencyISOCode string `json:"currency_iso_code"`
+		} `json:"default_account"`
+	}
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &profile))
+	require.Equal(t, "USD", profile.DefaultCurrency)
+	require.Equal(t, "USD", profile.DefaultAccount.CurrencyISOCode)
+	require.Equal(t, int64(10800), profile.DefaultAccount.BalanceCents)
+}
+
+type tokenPairResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+}
+
+func postJSON(t *testing.T, env *testEnv, path string, payload any) *httptest.ResponseRecorder {
+	t.Helper()
+
+	body, err := json.Marshal(payload)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	res := httptest.NewRecorder()
+
+	env.engine.ServeHTTP(res, req)
+	return res
+}
+
+func TestAuthHandlerLoginAndRefresh(t *testing.T) {
+	env := setupHandlerTest(t)
+
+	ctx := context.Background()
+	_, err := env.authService.RegisterUser(ctx, "handler-refresh@example.com", "strongpass", "usd")
+	require.NoError(t, err)
+
+	res := postJSON(t, env, "/api/v1/auth/login", map[string]any{
+		"email":       "handler-refresh@example.com",
+		"password":    "strongpass",
+		"device_name": "test-device",
+	})
+	require.Equal(t, http.StatusOK, res.Code)
+
+	var login tokenPairResponse
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &login))
+	require.NotEmpty(t, login.AccessToken)
+	require.NotEmpty(t, login.RefreshToken)
+	require.Equal(t, "Bearer", login.TokenType)
+
+	res = postJSON(t, env, "/api/v1/auth/refresh", map[string]any{"refresh_token": login.RefreshToken})
+	require.Equal(t, http.StatusOK, res.Code)
+
+	var refreshed tokenPairResponse
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &refreshed))
+	require.NotEmpty(t, refreshed.AccessToken)
+	require.NotEqual(t, login.RefreshToken, refreshed.RefreshToken)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/accounts/balance", nil)
+	req.Header.Set("Authorization", "Bearer "+refreshed.AccessToken)
+	balanceRes := httptest.NewRecorder()
+	env.engine.ServeHTTP(balanceRes, req)
+	require.Equal(t, http.StatusOK, balanceRes.Code)
+
+	// Replaying the rotated token is rejected and kills the new token as well.
+	res = postJSON(t, env, "/api/v1/auth/refresh", map[string]any{"refresh_token": login.RefreshToken})
+	require.Equal(t, http.StatusUnauthorized, res.Code)
+
+	res = postJSON(t, env, "/api/v1/auth/refresh", map[string]any{"refresh_token": refreshed.RefreshToken})
+	require.Equal(t, http.StatusUnauthorized, res.Code)
+}
+
+func authorizedRequest(env *testEnv, method, path, authHeader string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", authHeader)
+	res := httptest.NewRecorder()
+	env.engine.ServeHTTP(res, req)
+	return res
+}
+
+func TestAuthHandlerLogoutRevokesToken(t *testing.T) {
+	env := setupHandlerTest(t)
+
+	ctx := context.Background()
+	user, err := env.authService.RegisterUser(ctx, "handler-logout@example.com", "strongpass", "usd")
+	require.NoError(t, err)
+
+	current := env.authHeader(user.ID, user.Email)
+	other := env.authHeader(user.ID, user.Email)
+
+	res := authorizedRequest(env, http.MethodPost, "/api/v1/auth/logout", current)
+	require.Equal(t, http.StatusNoContent, res.Code)
+
+	res = authorizedRequest(env, http.MethodGet, "/api/v1/accounts/balance", current)
+	require.Equal(t, http.StatusUnauthorized, res.Code)
+
+	res = authorizedRequest(env, http.MethodGet, "/api/v1/accounts/balance", other)
+	require.Equal(t, http.StatusOK, res.Code)
+}
+
+func TestAuthHandlerLogoutAllRevokesEverySession(t *testing.T) {
+	env := setupHandlerTest(t)
+
+	ctx := context.Background()
+	user, err := env.authService.RegisterUser(ctx, "handler-logout-all@example.com", "strongpass", "usd")
+	require.NoError(t, err)
+
+	res := postJSON(t, env, "/api/v1/auth/login", map[string]any{
+		"email":    "handler-logout-all@example.com",
+		"password": "strongpass",
+	})
+	require.Equal(t, http.StatusOK, res.Code)
+	var login tokenPairResponse
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &login))
+
+	current := env.authHeader(user.ID, user.Email)
+	res = authorizedRequest(env, http.MethodPost, "/api/v1/auth/logout-all", current)
+	require.Equal(t, http.StatusNoContent, res.Code)
+
+	res = authorizedRequest(env, http.MethodGet, "/api/v1/accounts/balance", current)
+	require.Equal(t, http.StatusUnauthorized, res.Code)
+
+	res = postJSON(t, env, "/api/v1/auth/refresh", map[string]any{"refresh_token": login.RefreshToken})
+	require.Equal(t, http.StatusUnauthorized, res.Code)
+
+	// The other device's token, issued moments before the logout-all, is revoked as well.
+	res = authorizedRequest(env, http.MethodGet, "/api/v1/accounts/balance", "Bearer "+login.AccessToken)
+	require.Equal(t, http.StatusUnauthorized, res.Code)
+
+	// Signing in again right away yields a usable token.
+	res = postJSON(t, env, "/api/v1/auth/login", map[string]any{
+		"email":    "handler-logout-all@example.com",
+		"password": "strongpass",
+	})
+	require.Equal(t, http.StatusOK, res.Code)
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &login))
+	res = authorizedRequest(env, http.MethodGet, "/api/v1/accounts/balance", "Bearer "+login.AccessToken)
+	require.Equal(t, http.StatusOK, res.Code)
+}
+
+func TestAuthHandlerDeletedUserTokenRejected(t *testing.T) {
+	env := setupHandlerTest(t)
+
+	ctx := context.Background()
+	user, err := env.authService.RegisterUser(ctx, "handler-deleted@example.com", "strongpass", "usd")
+	require.NoError(t, err)
+
+	deleting := env.authHeader(user.ID, user.Email)
+	remaining := env.authHeader(user.ID, user.Email)
+
+	res := authorizedRequest(env, http.MethodGet, "/api/v1/accounts/balance", remaining)
+	require.Equal(t, http.StatusOK, res.Code)
+
+	res = authorizedRequest(env, http.MethodDelete, "/api/v1/auth/me", deleting)
+	require.Equal(t, http.StatusNoContent, res.Code)
+
+	res = authorizedRequest(env, http.MethodGet, "/api/v1/accounts/balance", remaining)
+	require.Equal(t, http.StatusUnauthorized, res.Code)
+}
+
+func TestAuthHandlerJWKSWithoutAsymmetricKeys(t *testing.T) {
+	env := setupHandlerTest(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	res := httptest.NewRecorder()
+	env.engine.ServeHTTP(res, req)
+	require.Equal(t, http.StatusOK, res.Code)
+
+	var payload struct {
+		Keys []map[string]any `json:"keys"`
+	}
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &payload))
+	require.NotNil(t, payload.Keys)
+	require.Empty(t, payload.Keys, "shared HMAC secrets must never be published")
+}
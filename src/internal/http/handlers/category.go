@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"bckndlab3/src/internal/http/middleware"
+	"bckndlab3/src/internal/http/requests"
+	"bckndlab3/src/internal/http/responses"
+	"bckndlab3/src/internal/storage"
+)
+
+// CategoryHandler manages the user's income and expense category catalogue.
+type CategoryHandler struct {
+	Service *storage.CategoryService
+}
+
+func NewCategoryHandler(service *storage.CategoryService) *CategoryHandler {
+	return &CategoryHandler{Service: service}
+}
+
+func (h *CategoryHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("", h.ListCategories)
+	router.POST("", h.CreateCategory)
+	router.GET("/:id", h.GetCategory)
+	router.PATCH("/:id", h.UpdateCategory)
+	router.DELETE("/:id", h.DeleteCategory)
+}
+
+func (h *CategoryHandler) ListCategories(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": gin.H{"code": "unauthorized", "message": "user not authenticated"},
+		})
+		return
+	}
+
+	var query requests.CategoryListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	categories, err := h.Service.ListCategories(c.Request.Context(), userID, query.Type)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewCategoryListResponse(categories))
+}
+
+func (h *CategoryHandler) CreateCategory(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": gin.H{"code": "unauthorized", "message": "user not authenticated"},
+		})
+		return
+	}
+
+	var req requests.CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	category, err := h.Service.CreateCategory(c.Request.Context(), userID, req.ToModel())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("ETag", versionETag(category.Version))
+	c.JSON(http.StatusCreated, responses.NewCategoryResponse(category))
+}
+
+func (h *CategoryHandler) GetCategory(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": gin.H{"code": "unauthorized", "message": "user not authenticated"},
+		})
+		return
+	}
+
+	categoryID, err := requests.ParseUintParam(c, "id")
+	if err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	category, err := h.Service.GetCategory(c.Request.Context(), userID, categoryID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if notModified(c, versionETag(category.Version)) {
+		return
+	}
+	c.JSON(http.StatusOK, responses.NewCategoryResponse(category))
+}
+
+func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": gin.H{"code": "unauthorized", "message": "user not authenticated"},
+		})
+		return
+	}
+
+	categoryID, err := requests.ParseUintParam(c, "id")
+	if err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	var req requests.CategoryUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	ifVersion, err := ifMatchVersion(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	category, err := h.Service.UpdateCategory(c.Request.Context(), userID, categoryID, ifVersion, storage.CategoryUpdate{
+		Name:     req.Name,
+		ParentID: req.ParentID,
+		Icon:     req.Icon,
+		Color:    req.Color,
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("ETag", versionETag(category.Version))
+	c.JSON(http.StatusOK, responses.NewCategoryResponse(category))
+}
+
+func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": gin.H{"code": "unauthorized", "message": "user not authenticated"},
+		})
+		return
+	}
+
+	categoryID, err := requests.ParseUintParam(c, "id")
+	if err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	ifVersion, err := ifMatchVersion(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := h.Service.DeleteCategory(c.Request.Context(), userID, categoryID, ifVersion); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,97 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"bckndlab3/src/internal/models"
+)
+
+type categoryItem struct {
+	ID       uint   `json:"id"`
+	ParentID *uint  `json:"parent_id"`
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	Color    string `json:"color"`
+	Version  uint   `json:"version"`
+}
+
+func TestCategoryHandlerCRUD(t *testing.T) {
+	env := setupHandlerTest(t)
+	ctx := context.Background()
+
+	user, err := env.authService.RegisterUser(ctx, "catalogue@example.com", "password123", "usd")
+	require.NoError(t, err)
+	authHeader := env.authHeader(user.ID, user.Email)
+	_, _, err = env.accountService.CreditIncome(ctx, user.ID, env.defaultAccountID(t, user.ID), &models.Income{AmountCents: 10000, Source: "Salary"})
+	require.NoError(t, err)
+
+	res := authorizedRequest(env, http.MethodGet, "/api/v1/categories?type=expense", authHeader)
+	require.Equal(t, http.StatusOK, res.Code)
+	var categories []categoryItem
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &categories))
+	require.NotEmpty(t, categories, "defaults are seeded on registration")
+	var transport categoryItem
+	for _, category := range categories {
+		require.Equal(t, "expense", category.Type)
+		if category.Name == "Transport" {
+			transport = category
+		}
+	}
+	require.NotZero(t, transport.ID)
+
+	res = jsonRequest(t, env, http.MethodPost, "/api/v1/categories", authHeader, map[string]any{"name": "Bikes", "type": "expense", "color": "red"})
+	require.Equal(t, http.StatusBadRequest, res.Code)
+	res = jsonRequest(t, env, http.MethodPost, "/api/v1/categories", authHeader, map[string]any{"name": "transport", "type": "expense"})
+	require.Equal(t, http.StatusConflict, res.Code)
+
+	res = jsonRequest(t, env, http.MethodPost, "/api/v1/categories", authHeader, map[string]any{
+		"name": "Bike repairs", "type": "expense", "parent_id": transport.ID, "color": "#00AA00",
+	})
+	require.Equal(t, http.StatusCreated, res.Code, res.Body.String())
+	var bikes categoryItem
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &bikes))
+	require.Equal(t, transport.ID, *bikes.ParentID)
+	require.Equal(t, "#00AA00", bikes.Color)
+
+	res = jsonRequest(t, env, http.MethodPost, "/api/v1/accounts/expenses", authHeader, map[string]any{"amount": "30", "category_id": bikes.ID})
+	require.Equal(t, http.StatusCreated, res.Code, res.Body.String())
+	var expense struct {
+		ID         uint   `json:"id"`
+		CategoryID *uint  `json:"category_id"`
+		Category   string `json:"category"`
+	}
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &expense))
+	require.Equal(t, bikes.ID, *expense.CategoryID)
+	require.Equal(t, "Bike repairs", expense.Category)
+
+	res = jsonRequest(t, env, http.MethodPost, "/api/v1/accounts/expenses", authHeader, map[string]any{"amount": "30"})
+	require.Equal(t, http.StatusBadRequest, res.Code, "a category id or name is required")
+
+	path := fmt.Sprintf("/api/v1/categories/%d", bikes.ID)
+	res = jsonRequest(t, env, http.MethodPatch, path, authHeader, map[string]any{"name": "Cycling", "parent_id": 0})
+	require.Equal(t, http.StatusOK, res.Code, res.Body.String())
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &bikes))
+	require.Nil(t, bikes.ParentID)
+	require.Equal(t, "Cycling", bikes.Name)
+
+	res = authorizedRequest(env, http.MethodGet, fmt.Sprintf("/api/v1/accounts/expenses/%d", expense.ID), authHeader)
+	require.Equal(t, http.StatusOK, res.Code)
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &expense))
+	require.Equal(t, "Cycling", expense.Category)
+
+	res = authorizedRequest(env, http.MethodDelete, path, authHeader)
+	require.Equal(t, http.StatusBadRequest, res.Code, "the category is still in use")
+
+	res = authorizedRequest(env, http.MethodDelete, fmt.Sprintf("/api/v1/accounts/expenses/%d", expense.ID), authHeader)
+	require.Equal(t, http.StatusNoContent, res.Code)
+	res = authorizedRequest(env, http.MethodDelete, path, authHeader)
+	require.Equal(t, http.StatusNoContent, res.Code)
+	res = authorizedRequest(env, http.MethodGet, path, authHeader)
+	require.Equal(t, http.StatusNotFound, res.Code)
+}
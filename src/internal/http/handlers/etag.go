@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"bckndlab3/src/internal/storage"
+)
+
+// versionETag formats a resource version as a strong entity tag.
+func versionETag(version uint) string {
+	return strconv.Quote(strconv.FormatUint(uint64(version), 10))
+}
+
+// ifMatchVersion returns the version required by the If-Match header, or zero when the
+// header is absent or "*". Tags that cannot match any version, such as weak or
+// malformed tags, yield ErrVersionMismatch.
+func ifMatchVersion(c *gin.Context) (uint, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+
+	unquoted, err := strconv.Unquote(header)
+	if err != nil {
+		return 0, storage.ErrVersionMismatch
+	}
+	version, err := strconv.ParseUint(unquoted, 10, 64)
+	if err != nil || version == 0 {
+		return 0, storage.ErrVersionMismatch
+	}
+	return uint(version), nil
+}
+
+// notModified answers 304 Not Modified when the If-None-Match header lists etag.
+// Otherwise it sets the ETag header for the upcoming response and returns false.
+func notModified(c *gin.Context, etag string) bool {
+	c.Header("ETag", etag)
+
+	header := c.GetHeader("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func requestWithHeaders(t *testing.T, env *testEnv, method, path string, payload any, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var body io.Reader
+	if payload != nil {
+		raw, err := json.Marshal(payload)
+		require.NoError(t, err)
+		body = bytes.NewReader(raw)
+	}
+	req := httptest.NewRequest(method, path, body)
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	res := httptest.NewRecorder()
+	env.engine.ServeHTTP(res, req)
+	return res
+}
+
+func TestAccountHandlerETags(t *testing.T) {
+	env := setupHandlerTest(t)
+
+	ctx := context.Background()
+	user, err := env.authService.RegisterUser(ctx, "etag@example.com", "password123", "usd")
+	require.NoError(t, err)
+	auth := env.authHeader(user.ID, user.Email)
+	accountPath := fmt.Sprintf("/api/v1/accounts/%d", env.defaultAccountID(t, user.ID))
+
+	res := requestWithHeaders(t, env, http.MethodGet, "/api/v1/accounts/balance", nil, map[string]string{"Authorization": auth})
+	require.Equal(t, http.StatusOK, res.Code)
+	balanceTag := res.Header().Get("ETag")
+	require.Equal(t, `"1"`, balanceTag)
+
+	res = requestWithHeaders(t, env, http.MethodGet, "/api/v1/accounts/balance", nil, map[string]string{"Authorization": auth, "If-None-Match": balanceTag})
+	require.Equal(t, http.StatusNotModified, res.Code)
+	require.Empty(t, res.Body.String())
+
+	res = requestWithHeaders(t, env, http.MethodPost, "/api/v1/accounts/incomes", map[string]any{"amount": 10, "source": "Tips"}, map[string]string{"Authorization": auth})
+	require.Equal(t, http.StatusCreated, res.Code)
+	var income struct {
+		ID uint `json:"id"`
+	}
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &income))
+	incomeTag := res.Header().Get("ETag")
+
+	res = requestWithHeaders(t, env, http.MethodGet, "/api/v1/accounts/balance", nil, map[string]string{"Authorization": auth, "If-None-Match": balanceTag})
+	require.Equal(t, http.StatusOK, res.Code, "a balance change invalidates the tag")
+	require.NotEqual(t, balanceTag, res.Header().Get("ETag"))
+
+	res = requestWithHeaders(t, env, http.MethodPatch, accountPath, map[string]any{"name": "Stale"}, map[string]string{"Authorization": auth, "If-Match": balanceTag})
+	require.Equal(t, http.StatusPreconditionFailed, res.Code)
+	var envelope errorEnvelope
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &envelope))
+	require.Equal(t, "version_mismatch", envelope.Error.Code)
+
+	res = requestWithHeaders(t, env, http.MethodGet, accountPath, nil, map[string]string{"Authorization": auth})
+	require.Equal(t, http.StatusOK, res.Code)
+	accountTag := res.Header().Get("ETag")
+
+	res = requestWithHeaders(t, env, http.MethodPatch, accountPath, map[string]any{"name": "Fresh"}, map[string]string{"Authorization": auth, "If-Match": accountTag})
+	require.Equal(t, http.StatusOK, res.Code)
+	require.NotEqual(t, accountTag, res.Header().Get("ETag"))
+
+	incomePath := fmt.Sprintf("/api/v1/accounts/incomes/%d", income.ID)
+	res = requestWithHeaders(t, env, http.MethodGet, incomePath, nil, map[string]string{"Authorization": auth, "If-None-Match": incomeTag})
+	require.Equal(t, http.StatusNotModified, res.Code)
+
+	res = requestWithHeaders(t, env, http.MethodDelete, incomePath, nil, map[string]string{"Authorization": auth, "If-Match": `"99"`})
+	require.Equal(t, http.StatusPreconditionFailed, res.Code)
+	res = requestWithHeaders(t, env, http.MethodDelete, incomePath, nil, map[string]string{"Authorization": auth, "If-Match": incomeTag})
+	require.Equal(t, http.StatusNoContent, res.Code)
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"bckndlab3/src/internal/currency"
+	"bckndlab3/src/internal/http/requests"
+	"bckndlab3/src/internal/http/responses"
+	"bckndlab3/src/internal/services"
+	"bckndlab3/src/internal/storage"
+)
+
+// maxRateImportBytes caps the size of an uploaded rate dump. The full ECB history is
+// well under this.
+const maxRateImportBytes = 16 << 20
+
+// ExchangeRateHandler exposes historical rate lookups and the admin rate maintenance
+// endpoints.
+type ExchangeRateHandler struct {
+	Rates *storage.ExchangeRateStore
+	Time  services.TimeProvider
+}
+
+func NewExchangeRateHandler(rates *storage.ExchangeRateStore, timeProvider services.TimeProvider) *ExchangeRateHandler {
+	return &ExchangeRateHandler{Rates: rates, Time: timeProvider}
+}
+
+// RegisterRoutes registers the lookup available to every authenticated user.
+func (h *ExchangeRateHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("", h.GetRate)
+}
+
+// RegisterAdminRoutes registers the rate maintenance endpoints.
+func (h *ExchangeRateHandler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	router.GET("", h.ListRates)
+	router.PUT("", h.UpsertRates)
+	router.POST("/import", h.ImportRates)
+}
+
+// GetRate returns the rate between two currencies in effect on a day, today by default.
+func (h *ExchangeRateHandler) GetRate(c *gin.Context) {
+	var query requests.ExchangeRateQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	from, to := currency.Normalize(query.From), currency.Normalize(query.To)
+	day := query.Day(h.Time.Now())
+	rate, err := h.Rates.Rate(c.Request.Context(), from, to, day)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewExchangeRateQuoteResponse(from, to, day, rate))
+}
+
+// ListRates returns stored rates, newest first.
+func (h *ExchangeRateHandler) ListRates(c *gin.Context) {
+	var query requests.ExchangeRateListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	from, to := query.Range()
+	limit := requests.ParseLimitQuery(c, "limit", 100)
+	rates, err := h.Rates.List(c.Request.Context(), query.Base, query.Quote, from, to, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewExchangeRateResponses(rates))
+}
+
+// UpsertRates creates or replaces manual rates.
+func (h *ExchangeRateHandler) UpsertRates(c *gin.Context) {
+	var req requests.ExchangeRatesUpsertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	if err := h.Rates.Upsert(c.Request.Context(), req.ToModels()); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ExchangeRateImportResponse{Imported: len(req.Rates)})
+}
+
+// ImportRates stores the rates of a CSV dump sent as the request body. The format query
+// parameter selects the layout: csv (default), ecb or nbu.
+func (h *ExchangeRateHandler) ImportRates(c *gin.Context) {
+	format := c.DefaultQuery("format", storage.RateFormatCSV)
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, maxRateImportBytes)
+
+	imported, err := h.Rates.Import(c.Request.Context(), body, format)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.ExchangeRateImportResponse{Imported: imported})
+}
@@ -0,0 +1,87 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type exchangeRateQuote struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Date string `json:"date"`
+	Rate string `json:"rate"`
+}
+
+func TestExchangeRateHandlerUpsertImportAndLookup(t *testing.T) {
+	env := setupHandlerTest(t)
+	ctx := context.Background()
+
+	admin := registerAdmin(t, env)
+	user, err := env.authService.RegisterUser(ctx, "rates@example.com", "strongpass", "usd")
+	require.NoError(t, err)
+	adminHeader := env.authHeader(admin.ID, admin.Email)
+	userHeader := env.authHeader(user.ID, user.Email)
+
+	payload := map[string]any{"rates": []map[string]any{
+		{"base": "usd", "quote": "uah", "rate": "41.5", "date": "2025-11-03"},
+	}}
+	rec := jsonRequest(t, env, http.MethodPut, "/api/v1/admin/exchange-rates", userHeader, payload)
+	require.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = jsonRequest(t, env, http.MethodPut, "/api/v1/admin/exchange-rates", adminHeader, map[string]any{"rates": []map[string]any{
+		{"base": "USD", "quote": "USD", "rate": "1", "date": "2025-11-03"},
+	}})
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = jsonRequest(t, env, http.MethodPut, "/api/v1/admin/exchange-rates", adminHeader, payload)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/exchange-rates/import?format=ecb", strings.NewReader("Date,USD,GBP,UAH\n2025-11-04,1.16,0.88,48.1\n"))
+	req.Header.Set("Authorization", adminHeader)
+	req.Header.Set("Content-Type", "text/csv")
+	rec = httptest.NewRecorder()
+	env.engine.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.JSONEq(t, `{"imported": 3}`, rec.Body.String())
+
+	rec = authorizedRequest(env, http.MethodGet, "/api/v1/admin/exchange-rates?quote=UAH", adminHeader)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var stored []struct {
+		Base   string `json:"base"`
+		Date   string `json:"date"`
+		Source string `json:"source"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stored))
+	require.Len(t, stored, 2, "the GBP fixing is quoted against EUR")
+	require.Equal(t, "2025-11-04", stored[0].Date)
+	require.Equal(t, "ecb", stored[0].Source)
+	require.Equal(t, "manual", stored[1].Source)
+
+	// Without a date the handler's clock is used: 2025-11-05 falls back to the ECB fixing
+	// of the day before, crossed through EUR as there is no direct GBP/UAH quote.
+	rec = authorizedRequest(env, http.MethodGet, "/api/v1/exchange-rates?from=gbp&to=uah", userHeader)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var quote exchangeRateQuote
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &quote))
+	require.Equal(t, exchangeRateQuote{From: "GBP", To: "UAH", Date: "2025-11-05", Rate: "54.659090909091"}, quote)
+
+	rec = authorizedRequest(env, http.MethodGet, "/api/v1/exchange-rates?from=USD&to=UAH&date=2025-11-03", userHeader)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &quote))
+	require.Equal(t, "41.5", quote.Rate)
+
+	rec = authorizedRequest(env, http.MethodGet, "/api/v1/exchange-rates?from=USD&to=UAH&date=2025-01-01", userHeader)
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	var envelope errorEnvelope
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &envelope))
+	require.Equal(t, "exchange_rate_unavailable", envelope.Error.Code)
+
+	rec = authorizedRequest(env, http.MethodGet, "/api/v1/exchange-rates?from=USD&to=XYZ", userHeader)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,194 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"bckndlab3/src/internal/http/middleware"
+	"bckndlab3/src/internal/models"
+	"bckndlab3/src/internal/storage"
+)
+
+func idempotentPost(env *testEnv, path, authHeader, key string, payload map[string]any) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(payload)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", authHeader)
+	req.Header.Set(middleware.IdempotencyKeyHeader, key)
+	res := httptest.NewRecorder()
+	env.engine.ServeHTTP(res, req)
+	return res
+}
+
+func TestIdempotencyKeyReplaysExpense(t *testing.T) {
+	env := setupHandlerTest(t)
+
+	ctx := context.Background()
+	user, err := env.authService.RegisterUser(ctx, "retry@example.com", "password123", "usd")
+	require.NoError(t, err)
+	authHeader := env.authHeader(user.ID, user.Email)
+
+	res := jsonRequest(t, env, http.MethodPost, "/api/v1/accounts/incomes", authHeader, map[string]any{"amount": 100, "source": "Salary"})
+	require.Equal(t, http.StatusCreated, res.Code)
+
+	payload := map[string]any{"amount": 40, "category": "Food"}
+	first := idempotentPost(env, "/api/v1/accounts/expenses", authHeader, "expense-1", payload)
+	require.Equal(t, http.StatusCreated, first.Code)
+	require.Empty(t, first.Header().Get(middleware.IdempotentReplayedHeader))
+
+	retry := idempotentPost(env, "/api/v1/accounts/expenses", authHeader, "expense-1", payload)
+	require.Equal(t, http.StatusCreated, retry.Code)
+	require.Equal(t, "true", retry.Header().Get(middleware.IdempotentReplayedHeader))
+	require.JSONEq(t, first.Body.String(), retry.Body.String())
+	require.NotEmpty(t, first.Header().Get("ETag"))
+	require.Equal(t, first.Header().Get("ETag"), retry.Header().Get("ETag"))
+
+	res = idempotentPost(env, "/api/v1/accounts/expenses", authHeader, "expense-1", map[string]any{"amount": 41, "category": "Food"})
+	require.Equal(t, http.StatusUnprocessableEntity, res.Code)
+	var envelope errorEnvelope
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &envelope))
+	require.Equal(t, "idempotency_key_mismatch", envelope.Error.Code)
+
+	// Failed requests are not remembered and can be retried with the same key.
+	res = idempotentPost(env, "/api/v1/accounts/expenses", authHeader, "expense-2", map[string]any{"amount": 500, "category": "Car"})
+	require.Equal(t, http.StatusBadRequest, res.Code)
+	res = idempotentPost(env, "/api/v1/accounts/expenses", authHeader, "expense-2", map[string]any{"amount": 500, "category": "Car"})
+	require.Equal(t, http.StatusBadRequest, res.Code)
+	require.Empty(t, res.Header().Get(middleware.IdempotentReplayedHeader))
+
+	res = authorizedRequest(env, http.MethodGet, "/api/v1/accounts/balance", authHeader)
+	require.Equal(t, http.StatusOK, res.Code)
+	var balance balanceResponse
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &balance))
+	require.Equal(t, int64(6000), balance.BalanceCents, "the expense must be debited once")
+}
+
+// idempotentEngine serves handler on POST /work behind the Idempotency middleware for the
+// given user, recovering from panics like the production router.
+func idempotentEngine(env *testEnv, userID uint, handler gin.HandlerFunc) *gin.Engine {
+	engine := gin.New()
+	engine.Use(gin.Recovery())
+	authenticate := func(c *gin.Context) {
+		c.Set(middleware.ContextUserID, userID)
+		c.Next()
+	}
+	engine.POST("/work", authenticate, middleware.Idempotency(storage.NewIdempotencyStore(env.db, time.Hour)), handler)
+	return engine
+}
+
+func postWork(engine *gin.Engine, ctx context.Context, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/work", bytes.NewReader([]byte(`{}`))).WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.IdempotencyKeyHeader, key)
+	res := httptest.NewRecorder()
+	engine.ServeHTTP(res, req)
+	return res
+}
+
+func TestIdempotencyKeyReleasedAfterPanic(t *testing.T) {
+	env := setupHandlerTest(t)
+	user, err := env.authService.RegisterUser(context.Background(), "panic@example.com", "password123", "usd")
+	require.NoError(t, err)
+
+	calls := 0
+	engine := idempotentEngine(env, user.ID, func(c *gin.Context) {
+		calls++
+		if calls == 1 {
+			panic("handler failed")
+		}
+		c.JSON(http.StatusCreated, gin.H{"calls": calls})
+	})
+
+	res := postWork(engine, context.Background(), "panic-1")
+	require.Equal(t, http.StatusInternalServerError, res.Code)
+
+	res = postWork(engine, context.Background(), "panic-1")
+	require.Equal(t, http.StatusCreated, res.Code, "the retry runs instead of waiting for the key to expire")
+	require.Equal(t, 2, calls)
+}
+
+func TestIdempotencyKeyStoresResponseAfterClientDisconnects(t *testing.T) {
+	env := setupHandlerTest(t)
+	user, err := env.authService.RegisterUser(context.Background(), "gone@example.com", "password123", "usd")
+	require.NoError(t, err)
+
+	// The client goes away while the handler runs; the response is stored regardless.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	calls := 0
+	engine := idempotentEngine(env, user.ID, func(c *gin.Context) {
+		calls++
+		cancel()
+		c.JSON(http.StatusCreated, gin.H{"calls": calls})
+	})
+
+	res := postWork(engine, ctx, "gone-1")
+	require.Equal(t, http.StatusCreated, res.Code)
+
+	res = postWork(engine, context.Background(), "gone-1")
+	require.Equal(t, http.StatusCreated, res.Code)
+	require.Equal(t, "true", res.Header().Get(middleware.IdempotentReplayedHeader))
+	require.Equal(t, 1, calls)
+}
+
+func TestIdempotencyKeyKeptReservedWhenResponseIsNotStored(t *testing.T) {
+	env := setupHandlerTest(t)
+
+	ctx := context.Background()
+	user, err := env.authService.RegisterUser(ctx, "unstored@example.com", "password123", "usd")
+	require.NoError(t, err)
+	authHeader := env.authHeader(user.ID, user.Email)
+
+	// Every attempt to store a response fails after the income has been committed.
+	require.NoError(t, env.db.Callback().Update().Before("gorm:update").Register("test:fail_idempotency_complete", func(db *gorm.DB) {
+		if db.Statement.Table == "idempotency_keys" {
+			_ = db.AddError(errors.New("storage unavailable"))
+		}
+	}))
+
+	payload := map[string]any{"amount": 100, "source": "Salary"}
+	res := idempotentPost(env, "/api/v1/accounts/incomes", authHeader, "income-1", payload)
+	require.Equal(t, http.StatusCreated, res.Code)
+
+	res = idempotentPost(env, "/api/v1/accounts/incomes", authHeader, "income-1", payload)
+	require.Equal(t, http.StatusConflict, res.Code)
+
+	var count int64
+	require.NoError(t, env.db.Model(&models.Income{}).Where("user_id = ?", user.ID).Count(&count).Error)
+	require.Equal(t, int64(1), count, "the retry must not record the income again")
+}
+
+func TestIdempotencyKeyReplaysLocationHeader(t *testing.T) {
+	env := setupHandlerTest(t)
+	user, err := env.authService.RegisterUser(context.Background(), "location@example.com", "password123", "usd")
+	require.NoError(t, err)
+
+	calls := 0
+	engine := idempotentEngine(env, user.ID, func(c *gin.Context) {
+		calls++
+		c.Header("ETag", `"1"`)
+		c.Header("Location", "/work/1")
+		c.JSON(http.StatusCreated, gin.H{"calls": calls})
+	})
+
+	first := postWork(engine, context.Background(), "location-1")
+	require.Equal(t, http.StatusCreated, first.Code)
+
+	retry := postWork(engine, context.Background(), "location-1")
+	require.Equal(t, http.StatusCreated, retry.Code)
+	require.Equal(t, "true", retry.Header().Get(middleware.IdempotentReplayedHeader))
+	require.Equal(t, `"1"`, retry.Header().Get("ETag"))
+	require.Equal(t, "/work/1", retry.Header().Get("Location"))
+	require.Equal(t, "application/json; charset=utf-8", retry.Header().Get("Content-Type"))
+	require.Equal(t, 1, calls)
+}
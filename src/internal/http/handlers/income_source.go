@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"bckndlab3/src/internal/http/middleware"
+	"bckndlab3/src/internal/http/requests"
+	"bckndlab3/src/internal/http/responses"
+	"bckndlab3/src/internal/storage"
+)
+
+// IncomeSourceHandler manages the payers the user receives income from.
+type IncomeSourceHandler struct {
+	Service *storage.IncomeSourceService
+}
+
+func NewIncomeSourceHandler(service *storage.IncomeSourceService) *IncomeSourceHandler {
+	return &IncomeSourceHandler{Service: service}
+}
+
+func (h *IncomeSourceHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("", h.ListIncomeSources)
+	router.POST("", h.CreateIncomeSource)
+	router.GET("/:id", h.GetIncomeSource)
+	router.PATCH("/:id", h.UpdateIncomeSource)
+	router.DELETE("/:id", h.DeleteIncomeSource)
+}
+
+func (h *IncomeSourceHandler) ListIncomeSources(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": gin.H{"code": "unauthorized", "message": "user not authenticated"},
+		})
+		return
+	}
+
+	sources, err := h.Service.ListIncomeSources(c.Request.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewIncomeSourceListResponse(sources))
+}
+
+func (h *IncomeSourceHandler) CreateIncomeSource(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": gin.H{"code": "unauthorized", "message": "user not authenticated"},
+		})
+		return
+	}
+
+	var req requests.IncomeSourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	source, err := h.Service.CreateIncomeSource(c.Request.Context(), userID, req.ToModel())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("ETag", versionETag(source.Version))
+	c.JSON(http.StatusCreated, responses.NewIncomeSourceResponse(source))
+}
+
+func (h *IncomeSourceHandler) GetIncomeSource(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": gin.H{"code": "unauthorized", "message": "user not authenticated"},
+		})
+		return
+	}
+
+	sourceID, err := requests.ParseUintParam(c, "id")
+	if err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	source, err := h.Service.GetIncomeSource(c.Request.Context(), userID, sourceID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if notModified(c, versionETag(source.Version)) {
+		return
+	}
+	c.JSON(http.StatusOK, responses.NewIncomeSourceResponse(source))
+}
+
+func (h *IncomeSourceHandler) UpdateIncomeSource(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": gin.H{"code": "unauthorized", "message": "user not authenticated"},
+		})
+		return
+	}
+
+	sourceID, err := requests.ParseUintParam(c, "id")
+	if err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	var req requests.IncomeSourceUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	ifVersion, err := ifMatchVersion(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	source, err := h.Service.UpdateIncomeSource(c.Request.Context(), userID, sourceID, ifVersion, storage.IncomeSourceUpdate{
+		Name:              req.Name,
+		Type:              req.Type,
+		TaxID:             req.TaxID,
+		DefaultCategoryID: req.DefaultCategoryID,
+	})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("ETag", versionETag(source.Version))
+	c.JSON(http.StatusOK, responses.NewIncomeSourceResponse(source))
+}
+
+func (h *IncomeSourceHandler) DeleteIncomeSource(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": gin.H{"code": "unauthorized", "message": "user not authenticated"},
+		})
+		return
+	}
+
+	sourceID, err := requests.ParseUintParam(c, "id")
+	if err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	ifVersion, err := ifMatchVersion(c)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if err := h.Service.DeleteIncomeSource(c.Request.Context(), userID, sourceID, ifVersion); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,101 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type incomeSourceItem struct {
+	ID                uint   `json:"id"`
+	Name              string `json:"name"`
+	Type              string `json:"type"`
+	TaxID             string `json:"tax_id"`
+	DefaultCategoryID *uint  `json:"default_category_id"`
+	Version           uint   `json:"version"`
+}
+
+func TestIncomeSourceHandlerCRUD(t *testing.T) {
+	env := setupHandlerTest(t)
+	ctx := context.Background()
+
+	user, err := env.authService.RegisterUser(ctx, "payers@example.com", "password123", "usd")
+	require.NoError(t, err)
+	authHeader := env.authHeader(user.ID, user.Email)
+
+	res := authorizedRequest(env, http.MethodGet, "/api/v1/categories?type=income", authHeader)
+	require.Equal(t, http.StatusOK, res.Code)
+	var categories []categoryItem
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &categories))
+	var salary categoryItem
+	for _, category := range categories {
+		if category.Name == "Salary" {
+			salary = category
+		}
+	}
+	require.NotZero(t, salary.ID)
+
+	res = jsonRequest(t, env, http.MethodPost, "/api/v1/income-sources", authHeader, map[string]any{"name": "Acme", "type": "lottery"})
+	require.Equal(t, http.StatusBadRequest, res.Code)
+
+	res = jsonRequest(t, env, http.MethodPost, "/api/v1/income-sources", authHeader, map[string]any{
+		"name": "Acme", "type": "salary", "tax_id": "DE123456789", "default_category_id": salary.ID,
+	})
+	require.Equal(t, http.StatusCreated, res.Code, res.Body.String())
+	var acme incomeSourceItem
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &acme))
+	require.Equal(t, "salary", acme.Type)
+	require.Equal(t, "DE123456789", acme.TaxID)
+	require.Equal(t, salary.ID, *acme.DefaultCategoryID)
+
+	res = jsonRequest(t, env, http.MethodPost, "/api/v1/income-sources", authHeader, map[string]any{"name": "acme"})
+	require.Equal(t, http.StatusConflict, res.Code)
+
+	res = jsonRequest(t, env, http.MethodPost, "/api/v1/accounts/incomes", authHeader, map[string]any{"amount": "1200", "source_id": acme.ID})
+	require.Equal(t, http.StatusCreated, res.Code, res.Body.String())
+	var income struct {
+		ID         uint   `json:"id"`
+		SourceID   *uint  `json:"source_id"`
+		Source     string `json:"source"`
+		CategoryID *uint  `json:"category_id"`
+	}
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &income))
+	require.Equal(t, acme.ID, *income.SourceID)
+	require.Equal(t, "Acme", income.Source)
+	require.Equal(t, salary.ID, *income.CategoryID, "the source's default category applies")
+
+	res = jsonRequest(t, env, http.MethodPost, "/api/v1/accounts/incomes", authHeader, map[string]any{"amount": "10"})
+	require.Equal(t, http.StatusBadRequest, res.Code, "a source id or name is required")
+
+	path := fmt.Sprintf("/api/v1/income-sources/%d", acme.ID)
+	res = jsonRequest(t, env, http.MethodPatch, path, authHeader, map[string]any{"name": "Acme GmbH", "default_category_id": 0})
+	require.Equal(t, http.StatusOK, res.Code, res.Body.String())
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &acme))
+	require.Equal(t, "Acme GmbH", acme.Name)
+	require.Nil(t, acme.DefaultCategoryID)
+
+	res = authorizedRequest(env, http.MethodGet, fmt.Sprintf("/api/v1/accounts/incomes/%d", income.ID), authHeader)
+	require.Equal(t, http.StatusOK, res.Code)
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &income))
+	require.Equal(t, "Acme GmbH", income.Source)
+
+	res = authorizedRequest(env, http.MethodGet, "/api/v1/income-sources", authHeader)
+	require.Equal(t, http.StatusOK, res.Code)
+	var sources []incomeSourceItem
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &sources))
+	require.Len(t, sources, 1)
+
+	res = authorizedRequest(env, http.MethodDelete, path, authHeader)
+	require.Equal(t, http.StatusBadRequest, res.Code, "the source is still in use")
+
+	res = authorizedRequest(env, http.MethodDelete, fmt.Sprintf("/api/v1/accounts/incomes/%d", income.ID), authHeader)
+	require.Equal(t, http.StatusNoContent, res.Code)
+	res = authorizedRequest(env, http.MethodDelete, path, authHeader)
+	require.Equal(t, http.StatusNoContent, res.Code)
+	res = authorizedRequest(env, http.MethodGet, path, authHeader)
+	require.Equal(t, http.StatusNotFound, res.Code)
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"bckndlab3/src/internal/http/middleware"
+	"bckndlab3/src/internal/http/requests"
+	"bckndlab3/src/internal/http/responses"
+	"bckndlab3/src/internal/services"
+	"bckndlab3/src/internal/storage"
+)
+
+// ReportHandler exposes aggregate reports over the user's incomes and expenses.
+type ReportHandler struct {
+	Reports *storage.ReportService
+	Time    services.TimeProvider
+}
+
+func NewReportHandler(reports *storage.ReportService, timeProvider services.TimeProvider) *ReportHandler {
+	return &ReportHandler{Reports: reports, Time: timeProvider}
+}
+
+// RegisterRoutes registers report endpoints.
+func (h *ReportHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/summary", h.GetSummary)
+}
+
+// GetSummary returns the income, expense and category totals of a month, quarter or year,
+// the current month by default.
+func (h *ReportHandler) GetSummary(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": gin.H{"code": "unauthorized", "message": "user not authenticated"},
+		})
+		return
+	}
+
+	var query requests.ReportQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	period, from, to := query.Range(h.Time.Now())
+	report, err := h.Reports.Summary(c.Request.Context(), userID, from, to)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewSummaryReportResponse(period, report))
+}
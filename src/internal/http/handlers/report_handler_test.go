@@ -0,0 +1,111 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"bckndlab3/src/internal/models"
+)
+
+type summaryReport struct {
+	Period     string `json:"period"`
+	From       string `json:"from"`
+	To         string `json:"to"`
+	Months     int    `json:"months"`
+	Currencies []struct {
+		CurrencyISOCode      string `json:"currency_iso_code"`
+		IncomeDecimal        string `json:"income_decimal"`
+		ExpenseDecimal       string `json:"expense_decimal"`
+		NetDecimal           string `json:"net_decimal"`
+		AverageIncomeDecimal string `json:"average_income_decimal"`
+		Months               []struct {
+			Month             string `json:"month"`
+			NetDecimal        string `json:"net_decimal"`
+			NetChangeDecimal  string `json:"net_change_decimal"`
+			IncomeChangeCents int64  `json:"income_change_cents"`
+		} `json:"months"`
+		Categories []struct {
+			Type          string `json:"type"`
+			Name          string `json:"name"`
+			AmountDecimal string `json:"amount_decimal"`
+		} `json:"categories"`
+		Sources []struct {
+			Name          string `json:"name"`
+			AmountDecimal string `json:"amount_decimal"`
+		} `json:"sources"`
+	} `json:"currencies"`
+}
+
+func TestReportHandlerSummary(t *testing.T) {
+	env := setupHandlerTest(t)
+
+	ctx := context.Background()
+	user, err := env.authService.RegisterUser(ctx, "reports@example.com", "password123", "usd")
+	require.NoError(t, err)
+	authHeader := env.authHeader(user.ID, user.Email)
+	accountID := env.defaultAccountID(t, user.ID)
+
+	_, _, err = env.accountService.CreditIncome(ctx, user.ID, accountID, &models.Income{AmountCents: 200000, Source: "Employer", ReceivedAt: env.frozen.AddDate(0, -1, 0)})
+	require.NoError(t, err)
+	_, _, err = env.accountService.CreditIncome(ctx, user.ID, accountID, &models.Income{AmountCents: 250000, Source: "Employer", ReceivedAt: env.frozen})
+	require.NoError(t, err)
+	res := jsonRequest(t, env, http.MethodPost, "/api/v1/accounts/expenses", authHeader, map[string]any{
+		"amount": "80",
+		"splits": []map[string]any{
+			{"amount": "50", "category": "Groceries"},
+			{"amount": "30", "category": "Pharmacy"},
+		},
+	})
+	require.Equal(t, http.StatusCreated, res.Code, res.Body.String())
+
+	summary := func(query string) summaryReport {
+		t.Helper()
+		res := authorizedRequest(env, http.MethodGet, "/api/v1/reports/summary"+query, authHeader)
+		require.Equal(t, http.StatusOK, res.Code, res.Body.String())
+		var report summaryReport
+		require.NoError(t, json.Unmarshal(res.Body.Bytes(), &report))
+		return report
+	}
+
+	report := summary("")
+	require.Equal(t, "2025-11", report.Period, "the current month is the default period")
+	require.Equal(t, "2025-11-01", report.From)
+	require.Equal(t, "2025-11-30", report.To)
+	require.Len(t, report.Currencies, 1)
+	usd := report.Currencies[0]
+	require.Equal(t, "2500.00", usd.IncomeDecimal)
+	require.Equal(t, "80.00", usd.ExpenseDecimal)
+	require.Equal(t, "2420.00", usd.NetDecimal)
+	require.Len(t, usd.Months, 1)
+	require.Equal(t, "420.00", usd.Months[0].NetChangeDecimal)
+	require.Equal(t, int64(50000), usd.Months[0].IncomeChangeCents)
+	require.Len(t, usd.Categories, 3)
+	require.Equal(t, "Groceries", usd.Categories[1].Name)
+	require.Equal(t, "50.00", usd.Categories[1].AmountDecimal)
+	require.Equal(t, "Pharmacy", usd.Categories[2].Name)
+	require.Len(t, usd.Sources, 1)
+	require.Equal(t, "Employer", usd.Sources[0].Name)
+
+	report = summary("?period=2025-q4")
+	require.Equal(t, "2025-Q4", report.Period)
+	require.Equal(t, "2025-12-31", report.To)
+	require.Equal(t, 3, report.Months)
+	require.Equal(t, "4500.00", report.Currencies[0].IncomeDecimal)
+	require.Equal(t, "1500.00", report.Currencies[0].AverageIncomeDecimal)
+	require.Equal(t, "-2420.00", report.Currencies[0].Months[2].NetChangeDecimal)
+
+	report = summary("?period=2025")
+	require.Equal(t, 12, report.Months)
+	require.Equal(t, "2025-01", report.Currencies[0].Months[0].Month)
+
+	require.Empty(t, summary("?period=2024-02").Currencies)
+
+	for _, query := range []string{"?period=2025-13", "?period=2025-Q5", "?period=last-month", "?period=25"} {
+		res := authorizedRequest(env, http.MethodGet, "/api/v1/reports/summary"+query, authHeader)
+		require.Equal(t, http.StatusBadRequest, res.Code, query)
+	}
+}
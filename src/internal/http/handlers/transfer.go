@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"bckndlab3/src/internal/http/middleware"
+	"bckndlab3/src/internal/http/requests"
+	"bckndlab3/src/internal/http/responses"
+	"bckndlab3/src/internal/services"
+	"bckndlab3/src/internal/storage"
+)
+
+// TransferHandler manages transfers between a user's accounts.
+type TransferHandler struct {
+	Service *storage.AccountService
+	Time    services.TimeProvider
+}
+
+func NewTransferHandler(service *storage.AccountService, timeProvider services.TimeProvider) *TransferHandler {
+	return &TransferHandler{Service: service, Time: timeProvider}
+}
+
+func (h *TransferHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.POST("", h.CreateTransfer)
+	router.GET("", h.ListTransfers)
+}
+
+func (h *TransferHandler) CreateTransfer(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": gin.H{"code": "unauthorized", "message": "user not authenticated"},
+		})
+		return
+	}
+
+	var req requests.TransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	from, err := h.Service.GetAccount(c.Request.Context(), userID, req.FromAccountID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	transferModel, err := req.ToModel(h.Time.Now(), from.CurrencyISOCode)
+	if err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	transfer, fromBalance, toBalance, err := h.Service.Transfer(c.Request.Context(), userID, transferModel)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, responses.NewTransferResponse(transfer, fromBalance, toBalance))
+}
+
+func (h *TransferHandler) ListTransfers(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": gin.H{"code": "unauthorized", "message": "user not authenticated"},
+		})
+		return
+	}
+	limit := requests.ParseLimitQuery(c, "limit", 50)
+
+	accountID, err := requests.ParseUintQuery(c, "account_id")
+	if err != nil {
+		c.Error(responses.NewValidationError(err))
+		return
+	}
+
+	transfers, err := h.Service.ListTransfers(c.Request.Context(), userID, accountID, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, responses.NewTransferListResponse(transfers))
+}
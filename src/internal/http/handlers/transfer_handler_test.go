@@ -0,0 +1,96 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"bckndlab3/src/internal/models"
+)
+
+func TestTransferHandlerCreateAndList(t *testing.T) {
+	env := setupHandlerTest(t)
+
+	ctx := context.Background()
+	user, err := env.authService.RegisterUser(ctx, "transfer-handler@example.com", "password123", "uah")
+	require.NoError(t, err)
+	authHeader := env.authHeader(user.ID, user.Email)
+
+	mainID := env.defaultAccountID(t, user.ID)
+	savings, err := env.accountService.CreateAccount(ctx, user.ID, &models.Account{Name: "Savings", Type: models.AccountTypeSavings})
+	require.NoError(t, err)
+
+	_, _, err = env.accountService.CreditIncome(ctx, user.ID, mainID, &models.Income{AmountCents: 50000, Source: "seed", ReceivedAt: env.frozen})
+	require.NoError(t, err)
+
+	body, err := json.Marshal(map[string]any{
+		"from_account_id": mainID,
+		"to_account_id":   savings.ID,
+		"amount":          125.5,
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/transfers", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", authHeader)
+	res := httptest.NewRecorder()
+	env.engine.ServeHTTP(res, req)
+	require.Equal(t, http.StatusCreated, res.Code)
+
+	var created struct {
+		ID               uint  `json:"id"`
+		FromBalanceCents int64 `json:"from_balance_cents"`
+		ToBalanceCents   int64 `json:"to_balance_cents"`
+	}
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &created))
+	require.Equal(t, int64(37450), created.FromBalanceCents)
+	require.Equal(t, int64(12550), created.ToBalanceCents)
+
+	req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/transfers?account_id=%d", savings.ID), nil)
+	req.Header.Set("Authorization", authHeader)
+	res = httptest.NewRecorder()
+	env.engine.ServeHTTP(res, req)
+	require.Equal(t, http.StatusOK, res.Code)
+
+	var list []struct {
+		ID     uint    `json:"id"`
+		Amount float64 `json:"amount"`
+	}
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &list))
+	require.Len(t, list, 1)
+	require.Equal(t, created.ID, list[0].ID)
+	require.InDelta(t, 125.5, list[0].Amount, 0.001)
+}
+
+func TestTransferHandlerRejectsSameAccount(t *testing.T) {
+	env := setupHandlerTest(t)
+
+	ctx := context.Background()
+	user, err := env.authService.RegisterUser(ctx, "transfer-same@example.com", "password123", "uah")
+	require.NoError(t, err)
+	mainID := env.defaultAccountID(t, user.ID)
+
+	body, err := json.Marshal(map[string]any{
+		"from_account_id": mainID,
+		"to_account_id":   mainID,
+		"amount":          1,
+	})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/transfers", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", env.authHeader(user.ID, user.Email))
+	res := httptest.NewRecorder()
+	env.engine.ServeHTTP(res, req)
+	require.Equal(t, http.StatusBadRequest, res.Code)
+
+	var payload errorEnvelope
+	require.NoError(t, json.Unmarshal(res.Body.Bytes(), &payload))
+	require.Equal(t, "validation_error", payload.Error.Code)
+}
@@ -48,9 +48,24 @@ func mapError(err error) (int, gin.H) {
 	case errors.Is(err, storage.ErrConflict):
 		status = http.StatusConflict
 		code = "conflict"
+	case errors.Is(err, storage.ErrUnauthorized):
+		status = http.StatusUnauthorized
+		code = "unauthorized"
 	case errors.Is(err, storage.ErrInsufficientFunds):
 		status = http.StatusBadRequest
 		code = "insufficient_funds"
+	case errors.Is(err, storage.ErrVersionMismatch):
+		status = http.StatusPreconditionFailed
+		code = "version_mismatch"
+	case errors.Is(err, storage.ErrRateUnavailable):
+		status = http.StatusUnprocessableEntity
+		code = "exchange_rate_unavailable"
+	case errors.Is(err, storage.ErrIdempotencyKeyInUse):
+		status = http.StatusConflict
+		code = "idempotency_key_in_use"
+	case errors.Is(err, storage.ErrIdempotencyKeyMismatch):
+		status = http.StatusUnprocessableEntity
+		code = "idempotency_key_mismatch"
 	case errors.Is(err, storage.ErrPreconditionFailed):
 		status = http.StatusBadRequest
 		code = "precondition_failed"
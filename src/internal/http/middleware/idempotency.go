@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"bckndlab3/src/internal/storage"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+	completeAttempts         = 3
+)
+
+// Idempotency makes mutating requests carrying an Idempotency-Key header safe to retry.
+// The first request with a key runs normally and its successful response is stored;
+// retries with the same key and body replay that response, with its Content-Type, ETag
+// and Location headers, without running the handler again. Requests that fail or panic
+// release the key, so they can be retried. It must run after JWTAuth because keys are
+// scoped to the authenticated user.
+func Idempotency(store *storage.IdempotencyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" || !isMutatingMethod(c.Request.Method) {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": gin.H{
+					"code":    "validation_error",
+					"message": "Idempotency-Key must be at most 255 characters",
+				},
+			})
+			return
+		}
+
+		userID, ok := GetUserID(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": gin.H{"code": "unauthorized", "message": "user not authenticated"},
+			})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		fingerprint := storage.Fingerprint(c.Request.Method, c.Request.URL.RequestURI(), body)
+		record, created, err := store.Reserve(c.Request.Context(), userID, key, fingerprint)
+		if err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+
+		if !created {
+			c.Header(IdempotentReplayedHeader, "true")
+			for header, value := range map[string]string{
+				"Content-Type": record.ContentType,
+				"ETag":         record.ETag,
+				"Location":     record.Location,
+			} {
+				if value != "" {
+					c.Header(header, value)
+				}
+			}
+			c.Status(record.StatusCode)
+			_, _ = c.Writer.Write(record.ResponseBody)
+			c.Abort()
+			return
+		}
+
+		// The reservation outlives the request context, so it is released even when the
+		// client has gone away.
+		release := func() {
+			if err := store.Release(context.WithoutCancel(c.Request.Context()), record.ID); err != nil {
+				log.Printf("release idempotency key %d: %v", record.ID, err)
+			}
+		}
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				release()
+				panic(recovered)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// Errors are rendered later by ErrorHandler and nothing was committed, so the
+		// reservation is dropped and the client may retry with the same key.
+		if len(c.Errors) > 0 || c.Writer.Status() >= http.StatusInternalServerError {
+			release()
+			return
+		}
+
+		// The mutation is committed at this point, so the reservation must never be
+		// released: a retry would run it a second time. Storing the response outlives
+		// the request context and is retried; if it still fails the key stays reserved
+		// and retries are refused as in progress until it expires.
+		ctx := context.WithoutCancel(c.Request.Context())
+		header := c.Writer.Header()
+		response := storage.IdempotentResponse{
+			StatusCode:  c.Writer.Status(),
+			ContentType: header.Get("Content-Type"),
+			ETag:        header.Get("ETag"),
+			Location:    header.Get("Location"),
+			Body:        recorder.body.Bytes(),
+		}
+		for attempt := 1; attempt <= completeAttempts; attempt++ {
+			err = store.Complete(ctx, record.ID, response)
+			if err == nil {
+				return
+			}
+			log.Printf("store idempotent response %d (attempt %d): %v", record.ID, attempt, err)
+		}
+	}
+}
+
+func isMutatingMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// responseRecorder copies everything written to the response so it can be stored.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(data string) (int, error) {
+	w.body.WriteString(data)
+	return w.ResponseWriter.WriteString(data)
+}
@@ -1,8 +1,10 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -14,10 +16,13 @@ const (
 	BearerPrefix        = "Bearer "
 	ContextUserID       = "userID"
 	ContextEmail        = "email"
+	ContextTokenID      = "tokenID"
+	ContextTokenExpiry  = "tokenExpiresAt"
 )
 
-// JWTAuth creates middleware that validates JWT tokens from Authorization header.
-func JWTAuth(jwtService *storage.JWTService) gin.HandlerFunc {
+// JWTAuth creates middleware that validates JWT tokens from Authorization header
+// and rejects tokens that have been revoked or whose user no longer exists.
+func JWTAuth(jwtService *storage.JWTService, revocations *storage.TokenRevocationStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		header := c.GetHeader(AuthorizationHeader)
 		if header == "" {
@@ -52,8 +57,27 @@ func JWTAuth(jwtService *storage.JWTService) gin.HandlerFunc {
 			return
 		}
 
+		if err := revocations.Check(c.Request.Context(), claims); err != nil {
+			if !errors.Is(err, storage.ErrUnauthorized) {
+				c.Error(err)
+				c.Abort()
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": gin.H{
+					"code":    "unauthorized",
+					"message": err.Error(),
+				},
+			})
+			return
+		}
+
 		c.Set(ContextUserID, claims.UserID)
 		c.Set(ContextEmail, claims.Email)
+		c.Set(ContextTokenID, claims.ID)
+		if claims.ExpiresAt != nil {
+			c.Set(ContextTokenExpiry, claims.ExpiresAt.Time)
+		}
 		c.Next()
 	}
 }
@@ -77,3 +101,23 @@ func GetEmail(c *gin.Context) (string, bool) {
 	e, ok := email.(string)
 	return e, ok
 }
+
+// GetTokenID extracts the jti of the presented access token from context.
+func GetTokenID(c *gin.Context) (string, bool) {
+	id, exists := c.Get(ContextTokenID)
+	if !exists {
+		return "", false
+	}
+	tokenID, ok := id.(string)
+	return tokenID, ok && tokenID != ""
+}
+
+// GetTokenExpiry extracts the expiry of the presented access token from context.
+func GetTokenExpiry(c *gin.Context) (time.Time, bool) {
+	value, exists := c.Get(ContextTokenExpiry)
+	if !exists {
+		return time.Time{}, false
+	}
+	expiresAt, ok := value.(time.Time)
+	return expiresAt, ok
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"bckndlab3/src/internal/storage"
+)
+
+// RequireAdmin restricts a route group to authenticated users holding the admin role on
+// their user record, which is looked up on every request so revocations apply at once.
+// It must run after JWTAuth.
+func RequireAdmin(users *storage.AuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, ok := GetUserID(c)
+		if ok {
+			admin, err := users.IsAdmin(c.Request.Context(), userID)
+			if err != nil {
+				c.Error(err)
+				c.Abort()
+				return
+			}
+			ok = admin
+		}
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": gin.H{
+					"code":    "forbidden",
+					"message": "administrator access required",
+				},
+			})
+			return
+		}
+		c.Next()
+	}
+}
@@ -1,26 +1,72 @@
 package requests
 
 import (
+	"errors"
 	"fmt"
-	"math"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"bckndlab3/src/internal/currency"
 	"bckndlab3/src/internal/models"
 )
 
+// AccountRequest represents payload for opening an additional account.
+type AccountRequest struct {
+	Name      string `json:"name" binding:"required,max=120"`
+	Type      string `json:"type" binding:"omitempty,oneof=wallet card cash savings"`
+	Currency  string `json:"currency" binding:"omitempty,currency"`
+	IsDefault bool   `json:"is_default"`
+}
+
+// ToModel converts request to models.Account.
+func (r AccountRequest) ToModel() *models.Account {
+	return &models.Account{
+		Name:            r.Name,
+		Type:            r.Type,
+		CurrencyISOCode: r.Currency,
+		IsDefault:       r.IsDefault,
+	}
+}
+
+// AccountUpdateRequest represents a partial update of account settings.
+type AccountUpdateRequest struct {
+	Name      *string `json:"name" binding:"omitempty,min=1,max=120"`
+	Type      *string `json:"type" binding:"omitempty,oneof=wallet card cash savings"`
+	IsDefault *bool   `json:"is_default"`
+}
+
 // IncomeRequest represents payload for creating an income record.
+// AccountID selects the credited account; the user's default account is used when omitted.
+// Currency names the currency the amount was received in when it differs from the
+// account's; Rate optionally fixes the conversion (account units per unit of Currency).
+// The payer is given by SourceID or by name in Source; an unknown name is added to the
+// user's income sources. CategoryID optionally files the income under an income category,
+// falling back to the source's default category. Tags label the income across categories,
+// and Splits divide it across several income categories.
 type IncomeRequest struct {
-	Amount     float64 `json:"amount" binding:"required,gt=0"`
-	Source     string  `json:"source" binding:"required"`
-	ReceivedAt string  `json:"received_at" binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
-	Notes      string  `json:"notes" binding:"omitempty,max=512"`
+	AccountID  uint                 `json:"account_id"`
+	Amount     Amount               `json:"amount" binding:"required"`
+	Currency   string               `json:"currency" binding:"omitempty,currency"`
+	Rate       string               `json:"rate" binding:"omitempty,max=32"`
+	CategoryID uint                 `json:"category_id"`
+	SourceID   uint                 `json:"source_id"`
+	Source     string               `json:"source" binding:"required_without=SourceID,max=255"`
+	ReceivedAt string               `json:"received_at" binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Notes      string               `json:"notes" binding:"omitempty,max=512"`
+	Tags       []string             `json:"tags" binding:"omitempty,max=20,dive,min=1,max=64"`
+	Splits     []IncomeSplitRequest `json:"splits" binding:"omitempty,max=50,dive"`
 }
 
-// ToModel converts request to models.Income.
-func (r IncomeRequest) ToModel(defaultTime time.Time) *models.Income {
+// ToModel converts request to models.Income, reading the amount in the request currency or,
+// when none is given, in the currency of the credited account.
+func (r IncomeRequest) ToModel(defaultTime time.Time, currencyCode string) (*models.Income, error) {
+	entered, err := newEnteredAmount(r.Amount, r.Currency, r.Rate, currencyCode)
+	if err != nil {
+		return nil, err
+	}
+
 	ts := defaultTime
 	if r.ReceivedAt != "" {
 		if parsed, err := time.Parse(time.RFC3339, r.ReceivedAt); err == nil {
@@ -28,24 +74,53 @@ func (r IncomeRequest) ToModel(defaultTime time.Time) *models.Income {
 		}
 	}
 
-	return &models.Income{
-		AmountCents: int64(math.Round(r.Amount * 100)),
-		Source:      r.Source,
-		ReceivedAt:  ts,
-		Notes:       r.Notes,
+	splits, err := incomeSplitModels(r.Splits, entered.currency(currencyCode), entered.OriginalCurrency != "")
+	if err != nil {
+		return nil, err
 	}
+
+	return &models.Income{
+		AmountCents:         entered.AmountCents,
+		OriginalAmountCents: entered.OriginalAmountCents,
+		OriginalCurrency:    entered.OriginalCurrency,
+		ExchangeRate:        entered.ExchangeRate,
+		CategoryID:          optionalID(r.CategoryID),
+		SourceID:            optionalID(r.SourceID),
+		Source:              r.Source,
+		ReceivedAt:          ts,
+		Notes:               r.Notes,
+		Tags:                tagModels(r.Tags),
+		Splits:              splits,
+	}, nil
 }
 
 // ExpenseRequest represents payload for creating an expense record.
+// AccountID selects the debited account; the user's default account is used when omitted.
+// Currency and Rate describe a payment in another currency, as in IncomeRequest.
+// The category is given by CategoryID or by name; an unknown name is added to the catalogue.
+// Tags label the expense across categories, and Splits divide it across several expense
+// categories; a split expense may omit its own category.
 type ExpenseRequest struct {
-	Amount      float64 `json:"amount" binding:"required,gt=0"`
-	Category    string  `json:"category" binding:"required"`
-	IncurredAt  string  `json:"incurred_at" binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
-	Description string  `json:"description" binding:"omitempty,max=512"`
+	AccountID   uint                  `json:"account_id"`
+	Amount      Amount                `json:"amount" binding:"required"`
+	Currency    string                `json:"currency" binding:"omitempty,currency"`
+	Rate        string                `json:"rate" binding:"omitempty,max=32"`
+	CategoryID  uint                  `json:"category_id"`
+	Category    string                `json:"category" binding:"required_without_all=CategoryID Splits,max=120"`
+	IncurredAt  string                `json:"incurred_at" binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Description string                `json:"description" binding:"omitempty,max=512"`
+	Tags        []string              `json:"tags" binding:"omitempty,max=20,dive,min=1,max=64"`
+	Splits      []ExpenseSplitRequest `json:"splits" binding:"omitempty,max=50,dive"`
 }
 
-// ToModel converts request to models.Expense.
-func (r ExpenseRequest) ToModel(defaultTime time.Time) *models.Expense {
+// ToModel converts request to models.Expense, reading the amount in the request currency or,
+// when none is given, in the currency of the debited account.
+func (r ExpenseRequest) ToModel(defaultTime time.Time, currencyCode string) (*models.Expense, error) {
+	entered, err := newEnteredAmount(r.Amount, r.Currency, r.Rate, currencyCode)
+	if err != nil {
+		return nil, err
+	}
+
 	ts := defaultTime
 	if r.IncurredAt != "" {
 		if parsed, err := time.Parse(time.RFC3339, r.IncurredAt); err == nil {
@@ -53,12 +128,277 @@ func (r ExpenseRequest) ToModel(defaultTime time.Time) *models.Expense {
 		}
 	}
 
+	splits, err := expenseSplitModels(r.Splits, entered.currency(currencyCode), entered.OriginalCurrency != "")
+	if err != nil {
+		return nil, err
+	}
+
 	return &models.Expense{
-		AmountCents: int64(math.Round(r.Amount * 100)),
-		Category:    r.Category,
-		IncurredAt:  ts,
-		Description: r.Description,
+		AmountCents:         entered.AmountCents,
+		OriginalAmountCents: entered.OriginalAmountCents,
+		OriginalCurrency:    entered.OriginalCurrency,
+		ExchangeRate:        entered.ExchangeRate,
+		CategoryID:          optionalID(r.CategoryID),
+		Category:            r.Category,
+		IncurredAt:          ts,
+		Description:         r.Description,
+		Tags:                tagModels(r.Tags),
+		Splits:              splits,
+	}, nil
+}
+
+// enteredAmount is an amount in minor units, either of the account currency (AmountCents)
+// or of a foreign currency that the service converts (the Original fields and ExchangeRate).
+type enteredAmount struct {
+	AmountCents         int64
+	OriginalAmountCents int64
+	OriginalCurrency    string
+	ExchangeRate        string
+}
+
+// currency returns the currency the amount was entered in.
+func (a enteredAmount) currency(accountCurrency string) string {
+	if a.OriginalCurrency != "" {
+		return a.OriginalCurrency
+	}
+	return accountCurrency
+}
+
+func newEnteredAmount(amount Amount, code, rate, accountCurrency string) (enteredAmount, error) {
+	code = currency.Normalize(code)
+	if code == "" || code == accountCurrency {
+		if rate != "" && rate != "1" {
+			return enteredAmount{}, errors.New("rate is only allowed with a currency other than the account's")
+		}
+		units, err := amount.MinorUnitsOf(accountCurrency)
+		return enteredAmount{AmountCents: units}, err
+	}
+
+	units, err := amount.MinorUnitsOf(code)
+	if err != nil {
+		return enteredAmount{}, err
+	}
+	return enteredAmount{OriginalAmountCents: units, OriginalCurrency: code, ExchangeRate: rate}, nil
+}
+
+// IncomeUpdateRequest represents a partial update of an income record. A category_id of 0
+// removes the income from its category; tags and splits replace the income's tags and
+// split lines, and an empty list removes them.
+type IncomeUpdateRequest struct {
+	Amount     *Amount               `json:"amount"`
+	CategoryID *uint                 `json:"category_id"`
+	SourceID   *uint                 `json:"source_id" binding:"omitempty,min=1"`
+	Source     *string               `json:"source" binding:"omitempty,min=1,max=255"`
+	ReceivedAt *string               `json:"received_at" binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Notes      *string               `json:"notes" binding:"omitempty,max=512"`
+	Tags       *[]string             `json:"tags" binding:"omitempty,max=20,dive,min=1,max=64"`
+	Splits     *[]IncomeSplitRequest `json:"splits" binding:"omitempty,max=50,dive"`
+}
+
+// AmountCents returns the new amount in minor units of the currency the income was entered
+// in, or nil when it is unchanged.
+func (r IncomeUpdateRequest) AmountCents(currencyCode string) (*int64, error) {
+	return optionalMinorUnits(r.Amount, currencyCode)
+}
+
+// SplitModels returns the new split lines with amounts in minor units of the currency the
+// income was entered in, or nil when they are unchanged.
+func (r IncomeUpdateRequest) SplitModels(currencyCode string) (*[]models.IncomeSplit, error) {
+	if r.Splits == nil {
+		return nil, nil
+	}
+	splits, err := incomeSplitModels(*r.Splits, currencyCode, false)
+	if err != nil {
+		return nil, err
+	}
+	if splits == nil {
+		splits = []models.IncomeSplit{}
+	}
+	return &splits, nil
+}
+
+// ReceivedAtTime returns the new receipt time, or nil when it is unchanged.
+func (r IncomeUpdateRequest) ReceivedAtTime() *time.Time { return optionalTime(r.ReceivedAt) }
+
+// ExpenseUpdateRequest represents a partial update of an expense record. Tags and splits
+// replace the expense's tags and split lines, and an empty list removes them.
+type ExpenseUpdateRequest struct {
+	Amount      *Amount                `json:"amount"`
+	CategoryID  *uint                  `json:"category_id" binding:"omitempty,min=1"`
+	Category    *string                `json:"category" binding:"omitempty,min=1,max=120"`
+	IncurredAt  *string                `json:"incurred_at" binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Description *string                `json:"description" binding:"omitempty,max=512"`
+	Tags        *[]string              `json:"tags" binding:"omitempty,max=20,dive,min=1,max=64"`
+	Splits      *[]ExpenseSplitRequest `json:"splits" binding:"omitempty,max=50,dive"`
+}
+
+// AmountCents returns the new amount in minor units of the currency the expense was entered
+// in, or nil when it is unchanged.
+func (r ExpenseUpdateRequest) AmountCents(currencyCode string) (*int64, error) {
+	return optionalMinorUnits(r.Amount, currencyCode)
+}
+
+// SplitModels returns the new split lines with amounts in minor units of the currency the
+// expense was entered in, or nil when they are unchanged.
+func (r ExpenseUpdateRequest) SplitModels(currencyCode string) (*[]models.ExpenseSplit, error) {
+	if r.Splits == nil {
+		return nil, nil
+	}
+	splits, err := expenseSplitModels(*r.Splits, currencyCode, false)
+	if err != nil {
+		return nil, err
+	}
+	if splits == nil {
+		splits = []models.ExpenseSplit{}
+	}
+	return &splits, nil
+}
+
+// IncurredAtTime returns the new expense time, or nil when it is unchanged.
+func (r ExpenseUpdateRequest) IncurredAtTime() *time.Time { return optionalTime(r.IncurredAt) }
+
+// TransactionListQuery filters, sorts and pages income and expense listings. Repeated tag
+// parameters match rows carrying any of the tags, or all of them with tag_match=all. From
+// and To are inclusive days; MinAmount and MaxAmount are inclusive decimal amounts, each row
+// compared in the currency it was booked in; q searches notes, descriptions, sources and category
+// names. Rows are sorted by date or amount, newest or largest first unless order=asc, and cursor
+// continues the listing after a previous page's next_cursor.
+type TransactionListQuery struct {
+	Tags       []string `form:"tag" binding:"omitempty,max=20,dive,min=1,max=64"`
+	TagMatch   string   `form:"tag_match" binding:"omitempty,oneof=any all"`
+	CategoryID uint     `form:"category_id" binding:"omitempty,min=1"`
+	SourceID   uint     `form:"source_id" binding:"omitempty,min=1"`
+	From       string   `form:"from" binding:"omitempty,datetime=2006-01-02"`
+	To         string   `form:"to" binding:"omitempty,datetime=2006-01-02"`
+	MinAmount  *Amount  `form:"min_amount"`
+	MaxAmount  *Amount  `form:"max_amount"`
+	Search     string   `form:"q" binding:"omitempty,max=100"`
+	Sort       string   `form:"sort" binding:"omitempty,oneof=date amount"`
+	Order      string   `form:"order" binding:"omitempty,oneof=asc desc"`
+	Cursor     string   `form:"cursor" binding:"omitempty,max=512"`
+}
+
+// MatchAllTags reports whether rows must carry every requested tag.
+func (q TransactionListQuery) MatchAllTags() bool { return q.TagMatch == "all" }
+
+// Period returns the listed time range: from the start of From up to, but excluding, the
+// day after To. Missing bounds are zero.
+func (q TransactionListQuery) Period() (from, to time.Time) {
+	from = parseDay(q.From)
+	if q.To != "" {
+		to = parseDay(q.To).AddDate(0, 0, 1)
+	}
+	return from, to
+}
+
+// Ascending reports whether rows are listed oldest or smallest first.
+func (q TransactionListQuery) Ascending() bool { return q.Order == "asc" }
+
+// AmountRange returns the decimal amount bounds, empty for a missing bound. It rejects a
+// minimum above the maximum.
+func (q TransactionListQuery) AmountRange() (minAmount, maxAmount string, err error) {
+	if q.MinAmount != nil {
+		minAmount = string(*q.MinAmount)
+	}
+	if q.MaxAmount != nil {
+		maxAmount = string(*q.MaxAmount)
+	}
+	if q.MinAmount != nil && q.MaxAmount != nil && q.MinAmount.Cmp(*q.MaxAmount) > 0 {
+		return "", "", errors.New("min_amount must not exceed max_amount")
+	}
+	return minAmount, maxAmount, nil
+}
+
+// TransactionFeedQuery filters and pages the merged transaction feed. Repeated type
+// parameters select entry types; From and To are inclusive days. Entries are listed newest
+// first unless order=asc, and cursor continues after a previous page's next_cursor.
+type TransactionFeedQuery struct {
+	Types  []string `form:"type" binding:"omitempty,max=4,dive,oneof=income expense transfer conversion"`
+	From   string   `form:"from" binding:"omitempty,datetime=2006-01-02"`
+	To     string   `form:"to" binding:"omitempty,datetime=2006-01-02"`
+	Order  string   `form:"order" binding:"omitempty,oneof=asc desc"`
+	Cursor string   `form:"cursor" binding:"omitempty,max=512"`
+}
+
+// Period returns the listed time range as for TransactionListQuery.
+func (q TransactionFeedQuery) Period() (from, to time.Time) {
+	return TransactionListQuery{From: q.From, To: q.To}.Period()
+}
+
+// Ascending reports whether entries are listed oldest first.
+func (q TransactionFeedQuery) Ascending() bool { return q.Order == "asc" }
+
+// BalanceQuery selects the instant of a balance lookup: an RFC 3339 timestamp, or a day
+// standing for the end of that day (UTC). Without At the current balance is returned.
+type BalanceQuery struct {
+	At string `form:"at" binding:"omitempty,moment"`
+}
+
+// Time returns the requested instant, or the zero time for the current balance.
+func (q BalanceQuery) Time() time.Time {
+	at, _ := parseMoment(q.At)
+	return at
+}
+
+// BalanceHistoryQuery selects the days covered by a balance history and the interval of
+// its points, daily by default.
+type BalanceHistoryQuery struct {
+	From     string `form:"from" binding:"required,datetime=2006-01-02"`
+	To       string `form:"to" binding:"required,datetime=2006-01-02"`
+	Interval string `form:"interval" binding:"omitempty,oneof=day week month"`
+}
+
+// Period returns the first and last requested days.
+func (q BalanceHistoryQuery) Period() (from, to time.Time) {
+	return parseDay(q.From), parseDay(q.To)
+}
+
+// IntervalOrDefault returns the requested interval, defaulting to daily points.
+func (q BalanceHistoryQuery) IntervalOrDefault() string {
+	if q.Interval == "" {
+		return "day"
 	}
+	return q.Interval
+}
+
+func tagModels(names []string) []models.Tag {
+	if len(names) == 0 {
+		return nil
+	}
+	tags := make([]models.Tag, 0, len(names))
+	for _, name := range names {
+		tags = append(tags, models.Tag{Name: name})
+	}
+	return tags
+}
+
+func optionalID(id uint) *uint {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
+
+func optionalMinorUnits(amount *Amount, currencyCode string) (*int64, error) {
+	if amount == nil {
+		return nil, nil
+	}
+	units, err := amount.MinorUnitsOf(currencyCode)
+	if err != nil {
+		return nil, err
+	}
+	return &units, nil
+}
+
+func optionalTime(value *string) *time.Time {
+	if value == nil {
+		return nil
+	}
+	parsed, err := time.Parse(time.RFC3339, *value)
+	if err != nil {
+		return nil
+	}
+	return &parsed
 }
 
 // ParseUintParam parses uint from path parameter.
@@ -88,3 +428,19 @@ func ParseLimitQuery(c *gin.Context, key string, fallback int) int {
 	}
 	return parsed
 }
+
+// ParseUintQuery parses an optional unsigned integer query parameter. It returns zero
+// when the parameter is absent.
+func ParseUintQuery(c *gin.Context, key string) (uint, error) {
+	value := c.Query(key)
+	if value == "" {
+		return 0, nil
+	}
+
+	parsed, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || parsed == 0 {
+		return 0, fmt.Errorf("%s must be a positive integer", key)
+	}
+
+	return uint(parsed), nil
+}
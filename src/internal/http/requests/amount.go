@@ -0,0 +1,97 @@
+package requests
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"bckndlab3/src/internal/currency"
+)
+
+// Amount is a positive decimal money amount such as "12.50". It accepts JSON strings and,
+// for compatibility, JSON numbers, and keeps the literal exactly as sent so the value never
+// passes through float64.
+type Amount string
+
+// UnmarshalJSON accepts "12.50" as well as 12.50 and rejects anything that is not a plain
+// positive decimal number.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	raw := strings.TrimSpace(string(data))
+	if raw == "null" {
+		return nil
+	}
+	if strings.HasPrefix(raw, `"`) {
+		var value string
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+		raw = value
+	}
+	return a.UnmarshalParam(raw)
+}
+
+// UnmarshalParam parses an amount from a query or form parameter such as
+// min_amount=12.50.
+func (a *Amount) UnmarshalParam(param string) error {
+	raw := strings.TrimSpace(param)
+	whole, frac, _ := strings.Cut(raw, ".")
+	if !isDigits(whole) || (strings.Contains(raw, ".") && !isDigits(frac)) {
+		return fmt.Errorf("amount %q must be a plain decimal number such as \"12.50\"", raw)
+	}
+	if strings.Trim(whole+frac, "0") == "" {
+		return errors.New("amount must be greater than zero")
+	}
+
+	*a = Amount(raw)
+	return nil
+}
+
+// MinorUnits converts the amount into minor units of a currency with the given number of
+// decimal places. Amounts with more significant decimal places than that are rejected
+// instead of being rounded.
+func (a Amount) MinorUnits(exponent int) (int64, error) {
+	whole, frac, _ := strings.Cut(string(a), ".")
+	frac = strings.TrimRight(frac, "0")
+	if len(frac) > exponent {
+		return 0, fmt.Errorf("amount %s has more than %d decimal places", a, exponent)
+	}
+
+	digits := whole + frac + strings.Repeat("0", exponent-len(frac))
+	units, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("amount %s is too large", a)
+	}
+	return units, nil
+}
+
+// Cmp compares the amount with other, returning -1, 0 or +1 like strings.Compare.
+func (a Amount) Cmp(other Amount) int {
+	x, _ := new(big.Rat).SetString(string(a))
+	y, _ := new(big.Rat).SetString(string(other))
+	return x.Cmp(y)
+}
+
+// MinorUnitsOf converts the amount into minor units of the given ISO 4217 currency, e.g.
+// cents for USD and whole yen for JPY.
+func (a Amount) MinorUnitsOf(code string) (int64, error) {
+	units, err := a.MinorUnits(currency.Exponent(code))
+	if err != nil {
+		return 0, fmt.Errorf("%w for %s", err, currency.Normalize(code))
+	}
+	return units, nil
+}
+
+func isDigits(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
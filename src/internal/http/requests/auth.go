@@ -4,11 +4,31 @@ package requests
 type RegisterRequest struct {
 	Email           string `json:"email" binding:"required,email"`
 	Password        string `json:"password" binding:"required,min=8"`
-	DefaultCurrency string `json:"default_currency" binding:"omitempty,len=3"`
+	DefaultCurrency string `json:"default_currency" binding:"omitempty,currency"`
 }
 
 // LoginRequest represents credentials for authentication.
 type LoginRequest struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=8"`
+	Email      string `json:"email" binding:"required,email"`
+	Password   string `json:"password" binding:"required,min=8"`
+	DeviceName string `json:"device_name" binding:"omitempty,max=255"`
+}
+
+// RefreshRequest carries a refresh token to be exchanged for a new token pair.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// LogoutRequest optionally names the refresh token to revoke alongside the access token.
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// ProfileUpdateRequest changes the authenticated user's settings. Changing the default
+// currency of an account with a non-zero balance requires ConvertBalance; Rate optionally
+// fixes the conversion rate as new currency units per old currency unit, e.g. "0.024".
+type ProfileUpdateRequest struct {
+	DefaultCurrency string `json:"default_currency" binding:"required,currency"`
+	ConvertBalance  bool   `json:"convert_balance"`
+	Rate            string `json:"rate" binding:"omitempty,max=32"`
 }
@@ -0,0 +1,41 @@
+package requests
+
+import "bckndlab3/src/internal/models"
+
+// CategoryRequest represents payload for adding a category to the catalogue.
+type CategoryRequest struct {
+	Name     string `json:"name" binding:"required,max=120"`
+	Type     string `json:"type" binding:"required,oneof=income expense"`
+	ParentID uint   `json:"parent_id"`
+	Icon     string `json:"icon" binding:"omitempty,max=64"`
+	Color    string `json:"color" binding:"omitempty,hexcolor,len=7"`
+}
+
+// ToModel converts request to models.Category.
+func (r CategoryRequest) ToModel() *models.Category {
+	category := &models.Category{
+		Name:  r.Name,
+		Type:  r.Type,
+		Icon:  r.Icon,
+		Color: r.Color,
+	}
+	if r.ParentID != 0 {
+		parentID := r.ParentID
+		category.ParentID = &parentID
+	}
+	return category
+}
+
+// CategoryUpdateRequest represents a partial update of a category. A parent_id of 0 moves
+// the category to the top level.
+type CategoryUpdateRequest struct {
+	Name     *string `json:"name" binding:"omitempty,min=1,max=120"`
+	ParentID *uint   `json:"parent_id"`
+	Icon     *string `json:"icon" binding:"omitempty,max=64"`
+	Color    *string `json:"color" binding:"omitempty,hexcolor,len=7"`
+}
+
+// CategoryListQuery filters the catalogue by type.
+type CategoryListQuery struct {
+	Type string `form:"type" binding:"omitempty,oneof=income expense"`
+}
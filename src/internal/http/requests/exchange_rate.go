@@ -0,0 +1,73 @@
+package requests
+
+import (
+	"time"
+
+	"bckndlab3/src/internal/models"
+)
+
+// ExchangeRateRequest represents one daily rate: one unit of Base buys Rate units of Quote.
+type ExchangeRateRequest struct {
+	Base   string `json:"base" binding:"required,currency"`
+	Quote  string `json:"quote" binding:"required,currency,nefield=Base"`
+	Rate   string `json:"rate" binding:"required,max=32"`
+	Date   string `json:"date" binding:"required,datetime=2006-01-02"`
+	Source string `json:"source" binding:"omitempty,max=16"`
+}
+
+// ExchangeRatesUpsertRequest represents a batch of rates to create or replace.
+type ExchangeRatesUpsertRequest struct {
+	Rates []ExchangeRateRequest `json:"rates" binding:"required,min=1,max=1000,dive"`
+}
+
+// ToModels converts request to models.ExchangeRate values.
+func (r ExchangeRatesUpsertRequest) ToModels() []models.ExchangeRate {
+	rates := make([]models.ExchangeRate, 0, len(r.Rates))
+	for _, item := range r.Rates {
+		rates = append(rates, models.ExchangeRate{
+			BaseCurrency:  item.Base,
+			QuoteCurrency: item.Quote,
+			EffectiveOn:   parseDay(item.Date),
+			Rate:          item.Rate,
+			Source:        item.Source,
+		})
+	}
+	return rates
+}
+
+// ExchangeRateQuery represents a historical rate lookup. Date defaults to today.
+type ExchangeRateQuery struct {
+	From string `form:"from" binding:"required,currency"`
+	To   string `form:"to" binding:"required,currency"`
+	Date string `form:"date" binding:"omitempty,datetime=2006-01-02"`
+}
+
+// ExchangeRateListQuery filters the stored rates. Empty fields match everything.
+type ExchangeRateListQuery struct {
+	Base  string `form:"base" binding:"omitempty,currency"`
+	Quote string `form:"quote" binding:"omitempty,currency"`
+	From  string `form:"from" binding:"omitempty,datetime=2006-01-02"`
+	To    string `form:"to" binding:"omitempty,datetime=2006-01-02"`
+}
+
+// Day returns the requested day, or fallback when the query has none.
+func (q ExchangeRateQuery) Day(fallback time.Time) time.Time {
+	if q.Date == "" {
+		return fallback
+	}
+	return parseDay(q.Date)
+}
+
+// Range returns the requested days; a zero time leaves that end of the range open.
+func (q ExchangeRateListQuery) Range() (time.Time, time.Time) {
+	return parseDay(q.From), parseDay(q.To)
+}
+
+// parseDay parses a YYYY-MM-DD value already checked by the datetime binding tag.
+func parseDay(value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	day, _ := time.Parse(time.DateOnly, value)
+	return day
+}
@@ -0,0 +1,30 @@
+package requests
+
+import "bckndlab3/src/internal/models"
+
+// IncomeSourceRequest represents payload for adding a payer to the user's income sources.
+type IncomeSourceRequest struct {
+	Name              string `json:"name" binding:"required,max=255"`
+	Type              string `json:"type" binding:"omitempty,oneof=salary freelance dividend interest rental other"`
+	TaxID             string `json:"tax_id" binding:"omitempty,max=32"`
+	DefaultCategoryID uint   `json:"default_category_id"`
+}
+
+// ToModel converts request to models.IncomeSource.
+func (r IncomeSourceRequest) ToModel() *models.IncomeSource {
+	return &models.IncomeSource{
+		Name:              r.Name,
+		Type:              r.Type,
+		TaxID:             r.TaxID,
+		DefaultCategoryID: optionalID(r.DefaultCategoryID),
+	}
+}
+
+// IncomeSourceUpdateRequest represents a partial update of an income source. A
+// default_category_id of 0 removes the default category.
+type IncomeSourceUpdateRequest struct {
+	Name              *string `json:"name" binding:"omitempty,min=1,max=255"`
+	Type              *string `json:"type" binding:"omitempty,oneof=salary freelance dividend interest rental other"`
+	TaxID             *string `json:"tax_id" binding:"omitempty,max=32"`
+	DefaultCategoryID *uint   `json:"default_category_id"`
+}
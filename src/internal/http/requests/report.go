@@ -0,0 +1,52 @@
+package requests
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReportQuery selects the period of a summary report: a month (2025-11), a quarter
+// (2025-Q4) or a year (2025). It defaults to the current month.
+type ReportQuery struct {
+	Period string `form:"period" binding:"omitempty,period"`
+}
+
+// Range returns the requested period, or the month of now when the query has none, with
+// its first month and the month after its last one.
+func (q ReportQuery) Range(now time.Time) (period string, from, to time.Time) {
+	period = q.Period
+	if period == "" {
+		period = now.UTC().Format("2006-01")
+	}
+	from, to, _ = parsePeriod(period)
+	return strings.ToUpper(period), from, to
+}
+
+// parsePeriod reads a month, quarter or year, returning its first month and the month
+// after its last one.
+func parsePeriod(value string) (from, to time.Time, ok bool) {
+	switch {
+	case len(value) == len("2006"):
+		year, err := strconv.Atoi(value)
+		if err != nil || year < 1 {
+			return time.Time{}, time.Time{}, false
+		}
+		from = time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		return from, from.AddDate(1, 0, 0), true
+	case len(value) == len("2006-Q1") && strings.EqualFold(value[5:6], "q"):
+		year, err := strconv.Atoi(value[:4])
+		quarter := int(value[6] - '0')
+		if err != nil || year < 1 || value[4] != '-' || quarter < 1 || quarter > 4 {
+			return time.Time{}, time.Time{}, false
+		}
+		from = time.Date(year, time.Month(3*quarter-2), 1, 0, 0, 0, 0, time.UTC)
+		return from, from.AddDate(0, 3, 0), true
+	default:
+		month, err := time.Parse("2006-01", value)
+		if err != nil {
+			return time.Time{}, time.Time{}, false
+		}
+		return month, month.AddDate(0, 1, 0), true
+	}
+}
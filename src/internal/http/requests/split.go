@@ -0,0 +1,65 @@
+package requests
+
+import "bckndlab3/src/internal/models"
+
+// ExpenseSplitRequest is a line of a split expense. Its category is given by CategoryID or
+// by name, as for the expense itself, and its amount is in the currency of the expense.
+type ExpenseSplitRequest struct {
+	CategoryID uint   `json:"category_id"`
+	Category   string `json:"category" binding:"required_without=CategoryID,max=120"`
+	Amount     Amount `json:"amount" binding:"required"`
+	Note       string `json:"note" binding:"omitempty,max=255"`
+}
+
+// IncomeSplitRequest is a line of a split income with an optional income category. Its
+// amount is in the currency of the income.
+type IncomeSplitRequest struct {
+	CategoryID uint   `json:"category_id"`
+	Amount     Amount `json:"amount" binding:"required"`
+	Note       string `json:"note" binding:"omitempty,max=255"`
+}
+
+// expenseSplitModels converts split lines with amounts in currencyCode. The amounts of a
+// foreign-currency expense are original amounts, matching the expense's own.
+func expenseSplitModels(lines []ExpenseSplitRequest, currencyCode string, foreign bool) ([]models.ExpenseSplit, error) {
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	splits := make([]models.ExpenseSplit, 0, len(lines))
+	for _, line := range lines {
+		units, err := line.Amount.MinorUnitsOf(currencyCode)
+		if err != nil {
+			return nil, err
+		}
+		split := models.ExpenseSplit{CategoryID: optionalID(line.CategoryID), Category: line.Category, Note: line.Note}
+		if foreign {
+			split.OriginalAmountCents = units
+		} else {
+			split.AmountCents = units
+		}
+		splits = append(splits, split)
+	}
+	return splits, nil
+}
+
+// incomeSplitModels converts split lines with amounts in currencyCode, as for expenses.
+func incomeSplitModels(lines []IncomeSplitRequest, currencyCode string, foreign bool) ([]models.IncomeSplit, error) {
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	splits := make([]models.IncomeSplit, 0, len(lines))
+	for _, line := range lines {
+		units, err := line.Amount.MinorUnitsOf(currencyCode)
+		if err != nil {
+			return nil, err
+		}
+		split := models.IncomeSplit{CategoryID: optionalID(line.CategoryID), Note: line.Note}
+		if foreign {
+			split.OriginalAmountCents = units
+		} else {
+			split.AmountCents = units
+		}
+		splits = append(splits, split)
+	}
+	return splits, nil
+}
@@ -0,0 +1,44 @@
+package requests
+
+import (
+	"time"
+
+	"bckndlab3/src/internal/models"
+)
+
+// TransferRequest represents payload for moving money between two of the user's accounts.
+// Rate is required when the accounts use different currencies and gives destination
+// currency units per source currency unit, e.g. "41.25".
+type TransferRequest struct {
+	FromAccountID uint   `json:"from_account_id" binding:"required"`
+	ToAccountID   uint   `json:"to_account_id" binding:"required,nefield=FromAccountID"`
+	Amount        Amount `json:"amount" binding:"required"`
+	Rate          string `json:"rate" binding:"omitempty,max=32"`
+	TransferredAt string `json:"transferred_at" binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Notes         string `json:"notes" binding:"omitempty,max=512"`
+}
+
+// ToModel converts request to models.Transfer, reading the amount in the currency of the
+// source account.
+func (r TransferRequest) ToModel(defaultTime time.Time, currencyCode string) (*models.Transfer, error) {
+	cents, err := r.Amount.MinorUnitsOf(currencyCode)
+	if err != nil {
+		return nil, err
+	}
+
+	ts := defaultTime
+	if r.TransferredAt != "" {
+		if parsed, err := time.Parse(time.RFC3339, r.TransferredAt); err == nil {
+			ts = parsed
+		}
+	}
+
+	return &models.Transfer{
+		FromAccountID: r.FromAccountID,
+		ToAccountID:   r.ToAccountID,
+		AmountCents:   cents,
+		ExchangeRate:  r.Rate,
+		TransferredAt: ts,
+		Notes:         r.Notes,
+	}, nil
+}
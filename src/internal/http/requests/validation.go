@@ -0,0 +1,49 @@
+package requests
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+
+	"bckndlab3/src/internal/currency"
+)
+
+// RegisterValidations installs the custom binding tags used by request payloads:
+//
+//	currency  an ISO 4217 currency code, matched case-insensitively
+//	moment    an RFC 3339 timestamp or a YYYY-MM-DD day
+//	period    a month (2025-11), a quarter (2025-Q4) or a year (2025)
+func RegisterValidations() error {
+	engine, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return nil
+	}
+	if err := engine.RegisterValidation("currency", func(fl validator.FieldLevel) bool {
+		return currency.IsSupported(fl.Field().String())
+	}); err != nil {
+		return err
+	}
+	if err := engine.RegisterValidation("moment", func(fl validator.FieldLevel) bool {
+		_, ok := parseMoment(fl.Field().String())
+		return ok
+	}); err != nil {
+		return err
+	}
+	return engine.RegisterValidation("period", func(fl validator.FieldLevel) bool {
+		_, _, ok := parsePeriod(fl.Field().String())
+		return ok
+	})
+}
+
+// parseMoment reads an RFC 3339 timestamp, or a day standing for its last instant in UTC.
+func parseMoment(value string) (time.Time, bool) {
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		return at, true
+	}
+	day, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return day.AddDate(0, 0, 1).Add(-time.Microsecond), true
+}
@@ -8,101 +8,239 @@ import (
 
 // IncomeResponse payload for created income that returns current balance context.
 type IncomeResponse struct {
-	ID           uint    `json:"id"`
-	Amount       float64 `json:"amount"`
-	Source       string  `json:"source"`
-	ReceivedAt   string  `json:"received_at"`
-	Notes        string  `json:"notes,omitempty"`
-	BalanceCents int64   `json:"balance_cents"`
+	ID              uint                  `json:"id"`
+	AccountID       uint                  `json:"account_id"`
+	Amount          float64               `json:"amount"`
+	AmountCents     int64                 `json:"amount_cents"`
+	AmountDecimal   string                `json:"amount_decimal"`
+	CurrencyISOCode string                `json:"currency_iso_code"`
+	CategoryID      *uint                 `json:"category_id"`
+	SourceID        *uint                 `json:"source_id"`
+	Source          string                `json:"source"`
+	ReceivedAt      string                `json:"received_at"`
+	Notes           string                `json:"notes,omitempty"`
+	Tags            []string              `json:"tags"`
+	Splits          []IncomeSplitResponse `json:"splits,omitempty"`
+	Version         uint                  `json:"version"`
+	BalanceCents    int64                 `json:"balance_cents"`
+	BalanceDecimal  string                `json:"balance_decimal"`
+	OriginalAmount
 }
 
 // NewIncomeResponse builds an IncomeResponse.
 func NewIncomeResponse(income *models.Income, balance int64) IncomeResponse {
+	code := income.CurrencyISOCode
 	return IncomeResponse{
-		ID:           income.ID,
-		Amount:       centsToFloat(income.AmountCents),
-		Source:       income.Source,
-		ReceivedAt:   income.ReceivedAt.Format(time.RFC3339),
-		Notes:        income.Notes,
-		BalanceCents: balance,
+		ID:              income.ID,
+		AccountID:       income.AccountID,
+		Amount:          amountToFloat(income.AmountCents, code),
+		AmountCents:     income.AmountCents,
+		AmountDecimal:   formatAmount(income.AmountCents, code),
+		CurrencyISOCode: code,
+		CategoryID:      income.CategoryID,
+		SourceID:        income.SourceID,
+		Source:          income.Source,
+		ReceivedAt:      income.ReceivedAt.Format(time.RFC3339),
+		Notes:           income.Notes,
+		Tags:            models.TagNames(income.Tags),
+		Splits:          newIncomeSplits(income, code),
+		Version:         income.Version,
+		OriginalAmount:  newOriginalAmount(income.AmountCents, code, income.OriginalAmountCents, income.OriginalCurrency, income.ExchangeRate),
+		BalanceCents:    balance,
+		BalanceDecimal:  formatAmount(balance, accountCurrency(income.Account)),
+	}
+}
+
+// OriginalAmount describes an amount as it was entered. For transactions paid in another
+// currency than the account's it differs from the account-currency amount and
+// ExchangeRate is the rate that converted it; otherwise it repeats that amount at rate 1.
+type OriginalAmount struct {
+	OriginalAmountCents     int64  `json:"original_amount_cents"`
+	OriginalAmountDecimal   string `json:"original_amount_decimal"`
+	OriginalCurrencyISOCode string `json:"original_currency_iso_code"`
+	ExchangeRate            string `json:"exchange_rate"`
+}
+
+func newOriginalAmount(amountCents int64, accountCurrency string, originalCents int64, originalCurrency, rate string) OriginalAmount {
+	if originalCurrency == "" {
+		originalCents, originalCurrency, rate = amountCents, accountCurrency, "1"
+	}
+	return OriginalAmount{
+		OriginalAmountCents:     originalCents,
+		OriginalAmountDecimal:   formatAmount(originalCents, originalCurrency),
+		OriginalCurrencyISOCode: originalCurrency,
+		ExchangeRate:            rate,
 	}
 }
 
 // IncomeListItem represents income data without balance context.
 type IncomeListItem struct {
-	ID         uint    `json:"id"`
-	Amount     float64 `json:"amount"`
-	Source     string  `json:"source"`
-	ReceivedAt string  `json:"received_at"`
-	Notes      string  `json:"notes,omitempty"`
-}
-
-// NewIncomeListResponse builds a list of incomes for listing endpoints.
-func NewIncomeListResponse(incomes []models.Income) []IncomeListItem {
-	items := make([]IncomeListItem, 0, len(incomes))
-	for i := range incomes {
-		items = append(items, IncomeListItem{
-			ID:         incomes[i].ID,
-			Amount:     centsToFloat(incomes[i].AmountCents),
-			Source:     incomes[i].Source,
-			ReceivedAt: incomes[i].ReceivedAt.Format(time.RFC3339),
-			Notes:      incomes[i].Notes,
-		})
+	ID              uint                  `json:"id"`
+	AccountID       uint                  `json:"account_id"`
+	Amount          float64               `json:"amount"`
+	AmountCents     int64                 `json:"amount_cents"`
+	AmountDecimal   string                `json:"amount_decimal"`
+	CurrencyISOCode string                `json:"currency_iso_code"`
+	CategoryID      *uint                 `json:"category_id"`
+	SourceID        *uint                 `json:"source_id"`
+	Source          string                `json:"source"`
+	ReceivedAt      string                `json:"received_at"`
+	Notes           string                `json:"notes,omitempty"`
+	Tags            []string              `json:"tags"`
+	Splits          []IncomeSplitResponse `json:"splits,omitempty"`
+	Version         uint                  `json:"version"`
+	OriginalAmount
+}
+
+// NewIncomeListItem builds an IncomeListItem for a single income.
+func NewIncomeListItem(income *models.Income) IncomeListItem {
+	code := income.CurrencyISOCode
+	return IncomeListItem{
+		ID:              income.ID,
+		AccountID:       income.AccountID,
+		Amount:          amountToFloat(income.AmountCents, code),
+		AmountCents:     income.AmountCents,
+		AmountDecimal:   formatAmount(income.AmountCents, code),
+		CurrencyISOCode: code,
+		CategoryID:      income.CategoryID,
+		SourceID:        income.SourceID,
+		Source:          income.Source,
+		ReceivedAt:      income.ReceivedAt.Format(time.RFC3339),
+		Notes:           income.Notes,
+		Tags:            models.TagNames(income.Tags),
+		Splits:          newIncomeSplits(income, code),
+		Version:         income.Version,
+		OriginalAmount:  newOriginalAmount(income.AmountCents, code, income.OriginalAmountCents, income.OriginalCurrency, income.ExchangeRate),
 	}
-	return items
 }
 
 // ExpenseResponse payload for created expense.
 type ExpenseResponse struct {
-	ID           uint    `json:"id"`
-	Amount       float64 `json:"amount"`
-	Category     string  `json:"category"`
-	IncurredAt   string  `json:"incurred_at"`
-	Description  string  `json:"description,omitempty"`
-	BalanceCents int64   `json:"balance_cents"`
+	ID              uint                   `json:"id"`
+	AccountID       uint                   `json:"account_id"`
+	Amount          float64                `json:"amount"`
+	AmountCents     int64                  `json:"amount_cents"`
+	AmountDecimal   string                 `json:"amount_decimal"`
+	CurrencyISOCode string                 `json:"currency_iso_code"`
+	CategoryID      *uint                  `json:"category_id"`
+	Category        string                 `json:"category"`
+	IncurredAt      string                 `json:"incurred_at"`
+	Description     string                 `json:"description,omitempty"`
+	Tags            []string               `json:"tags"`
+	Splits          []ExpenseSplitResponse `json:"splits,omitempty"`
+	Version         uint                   `json:"version"`
+	BalanceCents    int64                  `json:"balance_cents"`
+	BalanceDecimal  string                 `json:"balance_decimal"`
+	OriginalAmount
 }
 
 // NewExpenseResponse builds an ExpenseResponse.
 func NewExpenseResponse(expense *models.Expense, balance int64) ExpenseResponse {
+	code := expense.CurrencyISOCode
 	return ExpenseResponse{
-		ID:           expense.ID,
-		Amount:       centsToFloat(expense.AmountCents),
-		Category:     expense.Category,
-		IncurredAt:   expense.IncurredAt.Format(time.RFC3339),
-		Description:  expense.Description,
-		BalanceCents: balance,
+		ID:              expense.ID,
+		AccountID:       expense.AccountID,
+		Amount:          amountToFloat(expense.AmountCents, code),
+		AmountCents:     expense.AmountCents,
+		AmountDecimal:   formatAmount(expense.AmountCents, code),
+		CurrencyISOCode: code,
+		CategoryID:      expense.CategoryID,
+		Category:        expense.Category,
+		IncurredAt:      expense.IncurredAt.Format(time.RFC3339),
+		Description:     expense.Description,
+		Tags:            models.TagNames(expense.Tags),
+		Splits:          newExpenseSplits(expense, code),
+		Version:         expense.Version,
+		OriginalAmount:  newOriginalAmount(expense.AmountCents, code, expense.OriginalAmountCents, expense.OriginalCurrency, expense.ExchangeRate),
+		BalanceCents:    balance,
+		BalanceDecimal:  formatAmount(balance, accountCurrency(expense.Account)),
 	}
 }
 
 // ExpenseListItem represents expense data without balance context.
 type ExpenseListItem struct {
-	ID          uint    `json:"id"`
-	Amount      float64 `json:"amount"`
-	Category    string  `json:"category"`
-	IncurredAt  string  `json:"incurred_at"`
-	Description string  `json:"description,omitempty"`
-}
-
-// NewExpenseListResponse builds a list of expenses for listing endpoints.
-func NewExpenseListResponse(expenses []models.Expense) []ExpenseListItem {
-	items := make([]ExpenseListItem, 0, len(expenses))
-	for i := range expenses {
-		items = append(items, ExpenseListItem{
-			ID:          expenses[i].ID,
-			Amount:      centsToFloat(expenses[i].AmountCents),
-			Category:    expenses[i].Category,
-			IncurredAt:  expenses[i].IncurredAt.Format(time.RFC3339),
-			Description: expenses[i].Description,
-		})
+	ID              uint                   `json:"id"`
+	AccountID       uint                   `json:"account_id"`
+	Amount          float64                `json:"amount"`
+	AmountCents     int64                  `json:"amount_cents"`
+	AmountDecimal   string                 `json:"amount_decimal"`
+	CurrencyISOCode string                 `json:"currency_iso_code"`
+	CategoryID      *uint                  `json:"category_id"`
+	Category        string                 `json:"category"`
+	IncurredAt      string                 `json:"incurred_at"`
+	Description     string                 `json:"description,omitempty"`
+	Tags            []string               `json:"tags"`
+	Splits          []ExpenseSplitResponse `json:"splits,omitempty"`
+	Version         uint                   `json:"version"`
+	OriginalAmount
+}
+
+// NewExpenseListItem builds an ExpenseListItem for a single expense.
+func NewExpenseListItem(expense *models.Expense) ExpenseListItem {
+	code := expense.CurrencyISOCode
+	return ExpenseListItem{
+		ID:              expense.ID,
+		AccountID:       expense.AccountID,
+		Amount:          amountToFloat(expense.AmountCents, code),
+		AmountCents:     expense.AmountCents,
+		AmountDecimal:   formatAmount(expense.AmountCents, code),
+		CurrencyISOCode: code,
+		CategoryID:      expense.CategoryID,
+		Category:        expense.Category,
+		IncurredAt:      expense.IncurredAt.Format(time.RFC3339),
+		Description:     expense.Description,
+		Tags:            models.TagNames(expense.Tags),
+		Splits:          newExpenseSplits(expense, code),
+		Version:         expense.Version,
+		OriginalAmount:  newOriginalAmount(expense.AmountCents, code, expense.OriginalAmountCents, expense.OriginalCurrency, expense.ExchangeRate),
+	}
+}
+
+// AccountResponse describes one of the user's accounts.
+type AccountResponse struct {
+	ID              uint   `json:"id"`
+	Name            string `json:"name"`
+	Type            string `json:"type"`
+	IsDefault       bool   `json:"is_default"`
+	BalanceCents    int64  `json:"balance_cents"`
+	BalanceDecimal  string `json:"balance_decimal"`
+	CurrencyISOCode string `json:"currency_iso_code"`
+	Version         uint   `json:"version"`
+	CreatedAt       string `json:"created_at"`
+}
+
+// NewAccountResponse builds an account payload.
+func NewAccountResponse(account *models.Account) AccountResponse {
+	return AccountResponse{
+		ID:              account.ID,
+		Name:            account.Name,
+		Type:            account.Type,
+		IsDefault:       account.IsDefault,
+		BalanceCents:    account.BalanceCents,
+		BalanceDecimal:  formatAmount(account.BalanceCents, account.CurrencyISOCode),
+		CurrencyISOCode: account.CurrencyISOCode,
+		Version:         account.Version,
+		CreatedAt:       account.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// NewAccountListResponse builds a list of account payloads.
+func NewAccountListResponse(accounts []models.Account) []AccountResponse {
+	items := make([]AccountResponse, 0, len(accounts))
+	for i := range accounts {
+		items = append(items, NewAccountResponse(&accounts[i]))
 	}
 	return items
 }
 
-// BalanceResponse returns account balance detail.
+// BalanceResponse returns account balance detail. At is set for a balance looked up at a
+// past or future time.
 type BalanceResponse struct {
 	AccountID       uint   `json:"account_id"`
 	BalanceCents    int64  `json:"balance_cents"`
+	BalanceDecimal  string `json:"balance_decimal"`
 	CurrencyISOCode string `json:"currency_iso_code"`
+	At              string `json:"at,omitempty"`
 }
 
 // NewBalanceResponse builds a balance response payload.
@@ -110,10 +248,19 @@ func NewBalanceResponse(account *models.Account) BalanceResponse {
 	return BalanceResponse{
 		AccountID:       account.ID,
 		BalanceCents:    account.BalanceCents,
+		BalanceDecimal:  formatAmount(account.BalanceCents, account.CurrencyISOCode),
 		CurrencyISOCode: account.CurrencyISOCode,
 	}
 }
 
-func centsToFloat(cents int64) float64 {
-	return float64(cents) / 100
+// NewHistoricalBalanceResponse builds the payload of an account balance at a given time,
+// in the currency the account held then.
+func NewHistoricalBalanceResponse(account *models.Account, balance int64, code string, at time.Time) BalanceResponse {
+	return BalanceResponse{
+		AccountID:       account.ID,
+		BalanceCents:    balance,
+		BalanceDecimal:  formatAmount(balance, code),
+		CurrencyISOCode: code,
+		At:              at.UTC().Format(time.RFC3339Nano),
+	}
 }
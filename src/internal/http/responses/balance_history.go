@@ -0,0 +1,57 @@
+package responses
+
+import (
+	"bckndlab3/src/internal/storage"
+)
+
+// BalanceHistoryResponse is the balance of an account over consecutive days, weeks or
+// months. The opening balance is the one before the first point. CurrencyISOCode is the
+// account's current currency; the opening balance and every point name the currency the
+// account held at that time.
+type BalanceHistoryResponse struct {
+	AccountID              uint                   `json:"account_id"`
+	CurrencyISOCode        string                 `json:"currency_iso_code"`
+	Interval               string                 `json:"interval"`
+	OpeningBalanceCents    int64                  `json:"opening_balance_cents"`
+	OpeningBalanceDecimal  string                 `json:"opening_balance_decimal"`
+	OpeningCurrencyISOCode string                 `json:"opening_currency_iso_code"`
+	Points                 []BalancePointResponse `json:"points"`
+}
+
+// BalancePointResponse is the balance at the end of one interval, which covers the days
+// from start through end, and the net change over the interval, both in the currency the
+// account held at the end of the interval.
+type BalancePointResponse struct {
+	Start           string `json:"start"`
+	End             string `json:"end"`
+	CurrencyISOCode string `json:"currency_iso_code"`
+	BalanceCents    int64  `json:"balance_cents"`
+	BalanceDecimal  string `json:"balance_decimal"`
+	ChangeCents     int64  `json:"change_cents"`
+	ChangeDecimal   string `json:"change_decimal"`
+}
+
+// NewBalanceHistoryResponse builds a BalanceHistoryResponse.
+func NewBalanceHistoryResponse(history *storage.BalanceHistory) BalanceHistoryResponse {
+	points := make([]BalancePointResponse, 0, len(history.Points))
+	for _, point := range history.Points {
+		points = append(points, BalancePointResponse{
+			Start:           point.Start.Format(storage.RateDateLayout),
+			End:             point.End.AddDate(0, 0, -1).Format(storage.RateDateLayout),
+			CurrencyISOCode: point.CurrencyISOCode,
+			BalanceCents:    point.BalanceCents,
+			BalanceDecimal:  formatAmount(point.BalanceCents, point.CurrencyISOCode),
+			ChangeCents:     point.ChangeCents,
+			ChangeDecimal:   formatAmount(point.ChangeCents, point.CurrencyISOCode),
+		})
+	}
+	return BalanceHistoryResponse{
+		AccountID:              history.Account.ID,
+		CurrencyISOCode:        history.Account.CurrencyISOCode,
+		Interval:               history.Interval,
+		OpeningBalanceCents:    history.OpeningCents,
+		OpeningBalanceDecimal:  formatAmount(history.OpeningCents, history.OpeningCurrencyISOCode),
+		OpeningCurrencyISOCode: history.OpeningCurrencyISOCode,
+		Points:                 points,
+	}
+}
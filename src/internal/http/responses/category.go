@@ -0,0 +1,42 @@
+package responses
+
+import (
+	"time"
+
+	"bckndlab3/src/internal/models"
+)
+
+// CategoryResponse describes an entry of the category catalogue.
+type CategoryResponse struct {
+	ID        uint   `json:"id"`
+	ParentID  *uint  `json:"parent_id"`
+	Type      string `json:"type"`
+	Name      string `json:"name"`
+	Icon      string `json:"icon,omitempty"`
+	Color     string `json:"color,omitempty"`
+	Version   uint   `json:"version"`
+	CreatedAt string `json:"created_at"`
+}
+
+// NewCategoryResponse builds a category payload.
+func NewCategoryResponse(category *models.Category) CategoryResponse {
+	return CategoryResponse{
+		ID:        category.ID,
+		ParentID:  category.ParentID,
+		Type:      category.Type,
+		Name:      category.Name,
+		Icon:      category.Icon,
+		Color:     category.Color,
+		Version:   category.Version,
+		CreatedAt: category.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// NewCategoryListResponse builds a list of category payloads.
+func NewCategoryListResponse(categories []models.Category) []CategoryResponse {
+	items := make([]CategoryResponse, 0, len(categories))
+	for i := range categories {
+		items = append(items, NewCategoryResponse(&categories[i]))
+	}
+	return items
+}
@@ -0,0 +1,65 @@
+package responses
+
+import (
+	"math/big"
+	"time"
+
+	"bckndlab3/src/internal/models"
+	"bckndlab3/src/internal/storage"
+)
+
+// ExchangeRateResponse payload for a stored daily rate.
+type ExchangeRateResponse struct {
+	ID        uint   `json:"id"`
+	Base      string `json:"base"`
+	Quote     string `json:"quote"`
+	Rate      string `json:"rate"`
+	Date      string `json:"date"`
+	Source    string `json:"source"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// ExchangeRateQuoteResponse payload for a historical rate lookup.
+type ExchangeRateQuoteResponse struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Date string `json:"date"`
+	Rate string `json:"rate"`
+}
+
+// ExchangeRateImportResponse reports how many rates an import stored.
+type ExchangeRateImportResponse struct {
+	Imported int `json:"imported"`
+}
+
+// NewExchangeRateResponse builds an ExchangeRateResponse.
+func NewExchangeRateResponse(rate models.ExchangeRate) ExchangeRateResponse {
+	return ExchangeRateResponse{
+		ID:        rate.ID,
+		Base:      rate.BaseCurrency,
+		Quote:     rate.QuoteCurrency,
+		Rate:      rate.Rate,
+		Date:      rate.EffectiveOn.UTC().Format(storage.RateDateLayout),
+		Source:    rate.Source,
+		UpdatedAt: rate.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// NewExchangeRateResponses builds a slice of ExchangeRateResponse.
+func NewExchangeRateResponses(rates []models.ExchangeRate) []ExchangeRateResponse {
+	result := make([]ExchangeRateResponse, 0, len(rates))
+	for _, rate := range rates {
+		result = append(result, NewExchangeRateResponse(rate))
+	}
+	return result
+}
+
+// NewExchangeRateQuoteResponse builds an ExchangeRateQuoteResponse.
+func NewExchangeRateQuoteResponse(from, to string, day time.Time, rate *big.Rat) ExchangeRateQuoteResponse {
+	return ExchangeRateQuoteResponse{
+		From: from,
+		To:   to,
+		Date: storage.RateDay(day).Format(storage.RateDateLayout),
+		Rate: storage.FormatRate(rate),
+	}
+}
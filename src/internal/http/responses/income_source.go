@@ -0,0 +1,40 @@
+package responses
+
+import (
+	"time"
+
+	"bckndlab3/src/internal/models"
+)
+
+// IncomeSourceResponse describes a payer the user receives income from.
+type IncomeSourceResponse struct {
+	ID                uint   `json:"id"`
+	Name              string `json:"name"`
+	Type              string `json:"type"`
+	TaxID             string `json:"tax_id,omitempty"`
+	DefaultCategoryID *uint  `json:"default_category_id"`
+	Version           uint   `json:"version"`
+	CreatedAt         string `json:"created_at"`
+}
+
+// NewIncomeSourceResponse builds an income source payload.
+func NewIncomeSourceResponse(source *models.IncomeSource) IncomeSourceResponse {
+	return IncomeSourceResponse{
+		ID:                source.ID,
+		Name:              source.Name,
+		Type:              source.Type,
+		TaxID:             source.TaxID,
+		DefaultCategoryID: source.DefaultCategoryID,
+		Version:           source.Version,
+		CreatedAt:         source.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// NewIncomeSourceListResponse builds a list of income source payloads.
+func NewIncomeSourceListResponse(sources []models.IncomeSource) []IncomeSourceResponse {
+	items := make([]IncomeSourceResponse, 0, len(sources))
+	for i := range sources {
+		items = append(items, NewIncomeSourceResponse(&sources[i]))
+	}
+	return items
+}
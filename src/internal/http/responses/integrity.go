@@ -0,0 +1,47 @@
+package responses
+
+import (
+	"time"
+
+	"bckndlab3/src/internal/storage"
+)
+
+// IntegrityReportResponse payload for a balance integrity check.
+type IntegrityReportResponse struct {
+	CheckedAt       string                       `json:"checked_at"`
+	AccountsChecked int                          `json:"accounts_checked"`
+	Repaired        bool                         `json:"repaired"`
+	Discrepancies   []BalanceDiscrepancyResponse `json:"discrepancies"`
+}
+
+// BalanceDiscrepancyResponse describes an account whose balance does not reconcile.
+type BalanceDiscrepancyResponse struct {
+	AccountID         uint   `json:"account_id"`
+	UserID            uint   `json:"user_id"`
+	Currency          string `json:"currency"`
+	StoredCents       int64  `json:"stored_cents"`
+	LedgerCents       int64  `json:"ledger_cents"`
+	TransactionsCents int64  `json:"transactions_cents"`
+}
+
+// NewIntegrityReportResponse builds an IntegrityReportResponse.
+func NewIntegrityReportResponse(report *storage.IntegrityReport) IntegrityReportResponse {
+	discrepancies := make([]BalanceDiscrepancyResponse, 0, len(report.Discrepancies))
+	for _, discrepancy := range report.Discrepancies {
+		discrepancies = append(discrepancies, BalanceDiscrepancyResponse{
+			AccountID:         discrepancy.AccountID,
+			UserID:            discrepancy.UserID,
+			Currency:          discrepancy.CurrencyISOCode,
+			StoredCents:       discrepancy.StoredCents,
+			LedgerCents:       discrepancy.LedgerCents,
+			TransactionsCents: discrepancy.TransactionsCents,
+		})
+	}
+
+	return IntegrityReportResponse{
+		CheckedAt:       report.CheckedAt.Format(time.RFC3339),
+		AccountsChecked: report.AccountsChecked,
+		Repaired:        report.Repaired,
+		Discrepancies:   discrepancies,
+	}
+}
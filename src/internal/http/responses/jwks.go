@@ -0,0 +1,57 @@
+package responses
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+)
+
+// JWK is a single public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+
+	// RSA parameters.
+	Modulus  string `json:"n,omitempty"`
+	Exponent string `json:"e,omitempty"`
+
+	// OKP (Ed25519) parameters.
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+// JWKSResponse is the document served at /.well-known/jwks.json.
+type JWKSResponse struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewJWK converts a public key into its JWK form. It reports false for key types
+// that cannot be published.
+func NewJWK(keyID, algorithm string, key crypto.PublicKey) (JWK, bool) {
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			KeyType:   "RSA",
+			KeyID:     keyID,
+			Use:       "sig",
+			Algorithm: algorithm,
+			Modulus:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			Exponent:  base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}, true
+	case ed25519.PublicKey:
+		return JWK{
+			KeyType:   "OKP",
+			KeyID:     keyID,
+			Use:       "sig",
+			Algorithm: algorithm,
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(pub),
+		}, true
+	default:
+		return JWK{}, false
+	}
+}
@@ -0,0 +1,46 @@
+package responses
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"bckndlab3/src/internal/currency"
+	"bckndlab3/src/internal/models"
+)
+
+// formatAmount renders an amount in minor units of the currency as an exact decimal string,
+// e.g. 1050 USD becomes "10.50" and 1050 JPY stays "1050".
+func formatAmount(units int64, code string) string {
+	return formatMinorUnits(units, currency.Exponent(code))
+}
+
+func formatMinorUnits(units int64, exponent int) string {
+	digits := strconv.FormatInt(units, 10)
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+	if exponent <= 0 {
+		return sign + digits
+	}
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+	split := len(digits) - exponent
+	return sign + digits[:split] + "." + digits[split:]
+}
+
+// amountToFloat renders an amount for the legacy numeric fields.
+func amountToFloat(units int64, code string) float64 {
+	return float64(units) / math.Pow10(currency.Exponent(code))
+}
+
+// accountCurrency returns the currency of a loaded account association, or an empty code
+// when the association was not loaded.
+func accountCurrency(account *models.Account) string {
+	if account == nil {
+		return ""
+	}
+	return account.CurrencyISOCode
+}
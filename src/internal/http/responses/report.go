@@ -0,0 +1,154 @@
+package responses
+
+import (
+	"bckndlab3/src/internal/storage"
+)
+
+// SummaryReportResponse aggregates the incomes and expenses of a period per currency.
+// From and to are the first and last day of the period.
+type SummaryReportResponse struct {
+	Period     string                    `json:"period"`
+	From       string                    `json:"from"`
+	To         string                    `json:"to"`
+	Months     int                       `json:"months"`
+	Currencies []CurrencySummaryResponse `json:"currencies"`
+}
+
+// CurrencySummaryResponse totals the transactions of the accounts in one currency.
+// Averages are per month of the period.
+type CurrencySummaryResponse struct {
+	CurrencyISOCode       string                  `json:"currency_iso_code"`
+	IncomeCents           int64                   `json:"income_cents"`
+	IncomeDecimal         string                  `json:"income_decimal"`
+	IncomeCount           int64                   `json:"income_count"`
+	ExpenseCents          int64                   `json:"expense_cents"`
+	ExpenseDecimal        string                  `json:"expense_decimal"`
+	ExpenseCount          int64                   `json:"expense_count"`
+	NetCents              int64                   `json:"net_cents"`
+	NetDecimal            string                  `json:"net_decimal"`
+	AverageIncomeCents    int64                   `json:"average_income_cents"`
+	AverageIncomeDecimal  string                  `json:"average_income_decimal"`
+	AverageExpenseCents   int64                   `json:"average_expense_cents"`
+	AverageExpenseDecimal string                  `json:"average_expense_decimal"`
+	AverageNetCents       int64                   `json:"average_net_cents"`
+	AverageNetDecimal     string                  `json:"average_net_decimal"`
+	Months                []MonthSummaryResponse  `json:"months"`
+	Categories            []CategoryTotalResponse `json:"categories"`
+	Sources               []SourceTotalResponse   `json:"sources"`
+}
+
+// MonthSummaryResponse totals one month; the change fields compare it with the month before.
+type MonthSummaryResponse struct {
+	Month                string `json:"month"`
+	IncomeCents          int64  `json:"income_cents"`
+	IncomeDecimal        string `json:"income_decimal"`
+	ExpenseCents         int64  `json:"expense_cents"`
+	ExpenseDecimal       string `json:"expense_decimal"`
+	NetCents             int64  `json:"net_cents"`
+	NetDecimal           string `json:"net_decimal"`
+	IncomeChangeCents    int64  `json:"income_change_cents"`
+	IncomeChangeDecimal  string `json:"income_change_decimal"`
+	ExpenseChangeCents   int64  `json:"expense_change_cents"`
+	ExpenseChangeDecimal string `json:"expense_change_decimal"`
+	NetChangeCents       int64  `json:"net_change_cents"`
+	NetChangeDecimal     string `json:"net_change_decimal"`
+}
+
+// CategoryTotalResponse is the amount filed under one category, split lines included.
+type CategoryTotalResponse struct {
+	Type          string `json:"type"`
+	CategoryID    *uint  `json:"category_id"`
+	ParentID      *uint  `json:"parent_id"`
+	Name          string `json:"name"`
+	Count         int64  `json:"count"`
+	AmountCents   int64  `json:"amount_cents"`
+	AmountDecimal string `json:"amount_decimal"`
+}
+
+// SourceTotalResponse is the income received from one payer.
+type SourceTotalResponse struct {
+	SourceID      *uint  `json:"source_id"`
+	Name          string `json:"name"`
+	Count         int64  `json:"count"`
+	AmountCents   int64  `json:"amount_cents"`
+	AmountDecimal string `json:"amount_decimal"`
+}
+
+// NewSummaryReportResponse builds a SummaryReportResponse for the named period.
+func NewSummaryReportResponse(period string, report *storage.SummaryReport) SummaryReportResponse {
+	currencies := make([]CurrencySummaryResponse, 0, len(report.Currencies))
+	for _, summary := range report.Currencies {
+		currencies = append(currencies, newCurrencySummaryResponse(summary))
+	}
+	return SummaryReportResponse{
+		Period:     period,
+		From:       report.From.Format(storage.RateDateLayout),
+		To:         report.To.AddDate(0, 0, -1).Format(storage.RateDateLayout),
+		Months:     report.Months,
+		Currencies: currencies,
+	}
+}
+
+func newCurrencySummaryResponse(summary storage.CurrencySummary) CurrencySummaryResponse {
+	code := summary.CurrencyISOCode
+	months := make([]MonthSummaryResponse, 0, len(summary.Months))
+	for _, month := range summary.Months {
+		months = append(months, MonthSummaryResponse{
+			Month:                month.Start.Format("2006-01"),
+			IncomeCents:          month.IncomeCents,
+			IncomeDecimal:        formatAmount(month.IncomeCents, code),
+			ExpenseCents:         month.ExpenseCents,
+			ExpenseDecimal:       formatAmount(month.ExpenseCents, code),
+			NetCents:             month.NetCents,
+			NetDecimal:           formatAmount(month.NetCents, code),
+			IncomeChangeCents:    month.IncomeChangeCents,
+			IncomeChangeDecimal:  formatAmount(month.IncomeChangeCents, code),
+			ExpenseChangeCents:   month.ExpenseChangeCents,
+			ExpenseChangeDecimal: formatAmount(month.ExpenseChangeCents, code),
+			NetChangeCents:       month.NetChangeCents,
+			NetChangeDecimal:     formatAmount(month.NetChangeCents, code),
+		})
+	}
+	categories := make([]CategoryTotalResponse, 0, len(summary.Categories))
+	for _, category := range summary.Categories {
+		categories = append(categories, CategoryTotalResponse{
+			Type:          category.Type,
+			CategoryID:    category.CategoryID,
+			ParentID:      category.ParentID,
+			Name:          category.Name,
+			Count:         category.Count,
+			AmountCents:   category.AmountCents,
+			AmountDecimal: formatAmount(category.AmountCents, code),
+		})
+	}
+	sources := make([]SourceTotalResponse, 0, len(summary.Sources))
+	for _, source := range summary.Sources {
+		sources = append(sources, SourceTotalResponse{
+			SourceID:      source.SourceID,
+			Name:          source.Name,
+			Count:         source.Count,
+			AmountCents:   source.AmountCents,
+			AmountDecimal: formatAmount(source.AmountCents, code),
+		})
+	}
+	return CurrencySummaryResponse{
+		CurrencyISOCode:       code,
+		IncomeCents:           summary.IncomeCents,
+		IncomeDecimal:         formatAmount(summary.IncomeCents, code),
+		IncomeCount:           summary.IncomeCount,
+		ExpenseCents:          summary.ExpenseCents,
+		ExpenseDecimal:        formatAmount(summary.ExpenseCents, code),
+		ExpenseCount:          summary.ExpenseCount,
+		NetCents:              summary.NetCents,
+		NetDecimal:            formatAmount(summary.NetCents, code),
+		AverageIncomeCents:    summary.AverageIncomeCents,
+		AverageIncomeDecimal:  formatAmount(summary.AverageIncomeCents, code),
+		AverageExpenseCents:   summary.AverageExpenseCents,
+		AverageExpenseDecimal: formatAmount(summary.AverageExpenseCents, code),
+		AverageNetCents:       summary.AverageNetCents,
+		AverageNetDecimal:     formatAmount(summary.AverageNetCents, code),
+		Months:                months,
+		Categories:            categories,
+		Sources:               sources,
+	}
+}
@@ -0,0 +1,74 @@
+package responses
+
+import "bckndlab3/src/internal/models"
+
+// SplitAmount describes a split line amount in the account currency and as entered, in
+// the currency of the transaction's original amount.
+type SplitAmount struct {
+	AmountCents           int64  `json:"amount_cents"`
+	AmountDecimal         string `json:"amount_decimal"`
+	OriginalAmountCents   int64  `json:"original_amount_cents"`
+	OriginalAmountDecimal string `json:"original_amount_decimal"`
+}
+
+// ExpenseSplitResponse describes a line of a split expense.
+type ExpenseSplitResponse struct {
+	ID         uint   `json:"id"`
+	CategoryID *uint  `json:"category_id"`
+	Category   string `json:"category"`
+	Note       string `json:"note,omitempty"`
+	SplitAmount
+}
+
+// IncomeSplitResponse describes a line of a split income.
+type IncomeSplitResponse struct {
+	ID         uint   `json:"id"`
+	CategoryID *uint  `json:"category_id"`
+	Note       string `json:"note,omitempty"`
+	SplitAmount
+}
+
+func newSplitAmount(amountCents int64, accountCurrency string, originalCents int64, originalCurrency string) SplitAmount {
+	if originalCurrency == "" {
+		originalCents, originalCurrency = amountCents, accountCurrency
+	}
+	return SplitAmount{
+		AmountCents:           amountCents,
+		AmountDecimal:         formatAmount(amountCents, accountCurrency),
+		OriginalAmountCents:   originalCents,
+		OriginalAmountDecimal: formatAmount(originalCents, originalCurrency),
+	}
+}
+
+func newExpenseSplits(expense *models.Expense, code string) []ExpenseSplitResponse {
+	if len(expense.Splits) == 0 {
+		return nil
+	}
+	items := make([]ExpenseSplitResponse, 0, len(expense.Splits))
+	for _, split := range expense.Splits {
+		items = append(items, ExpenseSplitResponse{
+			ID:          split.ID,
+			CategoryID:  split.CategoryID,
+			Category:    split.Category,
+			Note:        split.Note,
+			SplitAmount: newSplitAmount(split.AmountCents, code, split.OriginalAmountCents, expense.OriginalCurrency),
+		})
+	}
+	return items
+}
+
+func newIncomeSplits(income *models.Income, code string) []IncomeSplitResponse {
+	if len(income.Splits) == 0 {
+		return nil
+	}
+	items := make([]IncomeSplitResponse, 0, len(income.Splits))
+	for _, split := range income.Splits {
+		items = append(items, IncomeSplitResponse{
+			ID:          split.ID,
+			CategoryID:  split.CategoryID,
+			Note:        split.Note,
+			SplitAmount: newSplitAmount(split.AmountCents, code, split.OriginalAmountCents, income.OriginalCurrency),
+		})
+	}
+	return items
+}
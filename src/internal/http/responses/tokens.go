@@ -0,0 +1,27 @@
+package responses
+
+import "time"
+
+// TokenResponse carries an access/refresh token pair issued on login or refresh.
+type TokenResponse struct {
+	// Token duplicates AccessToken for clients written against the single-token API.
+	Token            string        `json:"token"`
+	AccessToken      string        `json:"access_token"`
+	RefreshToken     string        `json:"refresh_token"`
+	TokenType        string        `json:"token_type"`
+	ExpiresIn        int64         `json:"expires_in"`
+	RefreshExpiresIn int64         `json:"refresh_expires_in"`
+	User             *UserResponse `json:"user,omitempty"`
+}
+
+// NewTokenResponse builds a token pair payload.
+func NewTokenResponse(accessToken string, accessTTL time.Duration, refreshToken string, refreshTTL time.Duration) TokenResponse {
+	return TokenResponse{
+		Token:            accessToken,
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		TokenType:        "Bearer",
+		ExpiresIn:        int64(accessTTL.Seconds()),
+		RefreshExpiresIn: int64(refreshTTL.Seconds()),
+	}
+}
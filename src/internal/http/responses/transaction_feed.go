@@ -0,0 +1,69 @@
+package responses
+
+import (
+	"time"
+
+	"bckndlab3/src/internal/storage"
+)
+
+// TransactionFeedResponse is one page of the merged transaction feed. NextCursor is null
+// on the last page.
+type TransactionFeedResponse struct {
+	Items      []TransactionFeedItem `json:"items"`
+	NextCursor *string               `json:"next_cursor"`
+}
+
+// TransactionFeedItem is one account movement of the feed. Type tells which resource ID
+// refers to; amounts are signed, negative amounts leaving the account, and are in the
+// currency the movement was booked in. Transfers appear once per account they touch,
+// naming the other one in counterparty_account_id. A conversion credits the new currency
+// and reports the converted balance in the from_* fields. The balance after the movement
+// is in balance_currency_iso_code, the account currency at that point.
+type TransactionFeedItem struct {
+	Type                   string `json:"type"`
+	ID                     uint   `json:"id"`
+	AccountID              uint   `json:"account_id"`
+	CounterpartyAccountID  *uint  `json:"counterparty_account_id,omitempty"`
+	AmountCents            int64  `json:"amount_cents"`
+	AmountDecimal          string `json:"amount_decimal"`
+	CurrencyISOCode        string `json:"currency_iso_code"`
+	FromAmountCents        *int64 `json:"from_amount_cents,omitempty"`
+	FromAmountDecimal      string `json:"from_amount_decimal,omitempty"`
+	FromCurrencyISOCode    string `json:"from_currency_iso_code,omitempty"`
+	OccurredAt             string `json:"occurred_at"`
+	Title                  string `json:"title,omitempty"`
+	Notes                  string `json:"notes,omitempty"`
+	BalanceAfterCents      int64  `json:"balance_after_cents"`
+	BalanceAfterDecimal    string `json:"balance_after_decimal"`
+	BalanceCurrencyISOCode string `json:"balance_currency_iso_code"`
+}
+
+// NewTransactionFeedResponse builds a page of the transaction feed.
+func NewTransactionFeedResponse(page *storage.FeedPage) TransactionFeedResponse {
+	items := make([]TransactionFeedItem, 0, len(page.Entries))
+	for _, entry := range page.Entries {
+		item := TransactionFeedItem{
+			Type:                   entry.Type,
+			ID:                     entry.ID,
+			AccountID:              entry.AccountID,
+			CounterpartyAccountID:  entry.CounterpartyAccountID,
+			AmountCents:            entry.AmountCents,
+			AmountDecimal:          formatAmount(entry.AmountCents, entry.CurrencyISOCode),
+			CurrencyISOCode:        entry.CurrencyISOCode,
+			OccurredAt:             entry.OccurredAt.Format(time.RFC3339),
+			Title:                  entry.Title,
+			Notes:                  entry.Notes,
+			BalanceAfterCents:      entry.BalanceAfterCents,
+			BalanceAfterDecimal:    formatAmount(entry.BalanceAfterCents, entry.BalanceCurrencyISOCode),
+			BalanceCurrencyISOCode: entry.BalanceCurrencyISOCode,
+		}
+		if entry.FromCurrencyISOCode != "" {
+			fromAmount := entry.FromAmountCents
+			item.FromAmountCents = &fromAmount
+			item.FromAmountDecimal = formatAmount(fromAmount, entry.FromCurrencyISOCode)
+			item.FromCurrencyISOCode = entry.FromCurrencyISOCode
+		}
+		items = append(items, item)
+	}
+	return TransactionFeedResponse{Items: items, NextCursor: optionalCursor(page.NextCursor)}
+}
@@ -0,0 +1,81 @@
+package responses
+
+import (
+	"bckndlab3/src/internal/storage"
+)
+
+// IncomeListResponse is one page of incomes. NextCursor is null on the last page.
+type IncomeListResponse struct {
+	Items      []IncomeListItem  `json:"items"`
+	NextCursor *string           `json:"next_cursor"`
+	Totals     TransactionTotals `json:"totals"`
+}
+
+// ExpenseListResponse is one page of expenses. NextCursor is null on the last page.
+type ExpenseListResponse struct {
+	Items      []ExpenseListItem `json:"items"`
+	NextCursor *string           `json:"next_cursor"`
+	Totals     TransactionTotals `json:"totals"`
+}
+
+// TransactionTotals counts all transactions matching a listing's filter, across pages, and
+// sums their amounts per account currency.
+type TransactionTotals struct {
+	Count   int64               `json:"count"`
+	Amounts []TransactionAmount `json:"amounts"`
+}
+
+// TransactionAmount is the sum of listed transactions held in one currency.
+type TransactionAmount struct {
+	CurrencyISOCode string `json:"currency_iso_code"`
+	Count           int64  `json:"count"`
+	AmountCents     int64  `json:"amount_cents"`
+	AmountDecimal   string `json:"amount_decimal"`
+}
+
+// NewIncomeListResponse builds a page of incomes for listing endpoints.
+func NewIncomeListResponse(page *storage.IncomePage) IncomeListResponse {
+	items := make([]IncomeListItem, 0, len(page.Incomes))
+	for i := range page.Incomes {
+		items = append(items, NewIncomeListItem(&page.Incomes[i]))
+	}
+	return IncomeListResponse{
+		Items:      items,
+		NextCursor: optionalCursor(page.NextCursor),
+		Totals:     newTransactionTotals(page.Totals),
+	}
+}
+
+// NewExpenseListResponse builds a page of expenses for listing endpoints.
+func NewExpenseListResponse(page *storage.ExpensePage) ExpenseListResponse {
+	items := make([]ExpenseListItem, 0, len(page.Expenses))
+	for i := range page.Expenses {
+		items = append(items, NewExpenseListItem(&page.Expenses[i]))
+	}
+	return ExpenseListResponse{
+		Items:      items,
+		NextCursor: optionalCursor(page.NextCursor),
+		Totals:     newTransactionTotals(page.Totals),
+	}
+}
+
+func newTransactionTotals(totals []storage.TransactionTotal) TransactionTotals {
+	result := TransactionTotals{Amounts: make([]TransactionAmount, 0, len(totals))}
+	for _, total := range totals {
+		result.Count += total.Count
+		result.Amounts = append(result.Amounts, TransactionAmount{
+			CurrencyISOCode: total.CurrencyISOCode,
+			Count:           total.Count,
+			AmountCents:     total.AmountCents,
+			AmountDecimal:   formatAmount(total.AmountCents, total.CurrencyISOCode),
+		})
+	}
+	return result
+}
+
+func optionalCursor(cursor string) *string {
+	if cursor == "" {
+		return nil
+	}
+	return &cursor
+}
@@ -0,0 +1,75 @@
+package responses
+
+import (
+	"time"
+
+	"bckndlab3/src/internal/models"
+)
+
+// TransferResponse payload for a created transfer with resulting balances.
+type TransferResponse struct {
+	TransferListItem
+	FromBalanceCents   int64  `json:"from_balance_cents"`
+	FromBalanceDecimal string `json:"from_balance_decimal"`
+	ToBalanceCents     int64  `json:"to_balance_cents"`
+	ToBalanceDecimal   string `json:"to_balance_decimal"`
+}
+
+// NewTransferResponse builds a TransferResponse.
+func NewTransferResponse(transfer *models.Transfer, fromBalance, toBalance int64) TransferResponse {
+	item := newTransferListItem(transfer)
+	return TransferResponse{
+		TransferListItem:   item,
+		FromBalanceCents:   fromBalance,
+		FromBalanceDecimal: formatAmount(fromBalance, item.CurrencyISOCode),
+		ToBalanceCents:     toBalance,
+		ToBalanceDecimal:   formatAmount(toBalance, item.ToCurrencyISOCode),
+	}
+}
+
+// TransferListItem represents transfer data without balance context.
+type TransferListItem struct {
+	ID                uint    `json:"id"`
+	FromAccountID     uint    `json:"from_account_id"`
+	ToAccountID       uint    `json:"to_account_id"`
+	Amount            float64 `json:"amount"`
+	AmountCents       int64   `json:"amount_cents"`
+	AmountDecimal     string  `json:"amount_decimal"`
+	CurrencyISOCode   string  `json:"currency_iso_code"`
+	ToAmount          float64 `json:"to_amount"`
+	ToAmountCents     int64   `json:"to_amount_cents"`
+	ToAmountDecimal   string  `json:"to_amount_decimal"`
+	ToCurrencyISOCode string  `json:"to_currency_iso_code"`
+	Rate              string  `json:"rate"`
+	TransferredAt     string  `json:"transferred_at"`
+	Notes             string  `json:"notes,omitempty"`
+}
+
+// NewTransferListResponse builds a list of transfers for listing endpoints.
+func NewTransferListResponse(transfers []models.Transfer) []TransferListItem {
+	items := make([]TransferListItem, 0, len(transfers))
+	for i := range transfers {
+		items = append(items, newTransferListItem(&transfers[i]))
+	}
+	return items
+}
+
+func newTransferListItem(transfer *models.Transfer) TransferListItem {
+	from, to := transfer.FromCurrencyISOCode, transfer.ToCurrencyISOCode
+	return TransferListItem{
+		ID:                transfer.ID,
+		FromAccountID:     transfer.FromAccountID,
+		ToAccountID:       transfer.ToAccountID,
+		Amount:            amountToFloat(transfer.AmountCents, from),
+		AmountCents:       transfer.AmountCents,
+		AmountDecimal:     formatAmount(transfer.AmountCents, from),
+		CurrencyISOCode:   from,
+		ToAmount:          amountToFloat(transfer.ToAmountCents, to),
+		ToAmountCents:     transfer.ToAmountCents,
+		ToAmountDecimal:   formatAmount(transfer.ToAmountCents, to),
+		ToCurrencyISOCode: to,
+		Rate:              transfer.ExchangeRate,
+		TransferredAt:     transfer.TransferredAt.Format(time.RFC3339),
+		Notes:             transfer.Notes,
+	}
+}
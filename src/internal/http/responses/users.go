@@ -17,3 +17,17 @@ func NewUserResponse(user *models.User) UserResponse {
 		DefaultCurrency: user.DefaultCurrency,
 	}
 }
+
+// ProfileResponse describes the user together with their default account.
+type ProfileResponse struct {
+	UserResponse
+	DefaultAccount AccountResponse `json:"default_account"`
+}
+
+// NewProfileResponse builds a profile payload.
+func NewProfileResponse(user *models.User, account *models.Account) ProfileResponse {
+	return ProfileResponse{
+		UserResponse:   NewUserResponse(user),
+		DefaultAccount: NewAccountResponse(account),
+	}
+}
@@ -7,18 +7,32 @@ import (
 
 	"bckndlab3/src/internal/http/handlers"
 	"bckndlab3/src/internal/http/middleware"
+	"bckndlab3/src/internal/http/requests"
 	"bckndlab3/src/internal/storage"
 )
 
 // Dependencies groups handler dependencies for router setup.
 type Dependencies struct {
-	Auth       *handlers.AuthHandler
-	Account    *handlers.AccountHandler
-	JWTService *storage.JWTService
+	Auth          *handlers.AuthHandler
+	Account       *handlers.AccountHandler
+	Transfer      *handlers.TransferHandler
+	Categories    *handlers.CategoryHandler
+	IncomeSources *handlers.IncomeSourceHandler
+	Admin         *handlers.AdminHandler
+	ExchangeRates *handlers.ExchangeRateHandler
+	Reports       *handlers.ReportHandler
+	JWTService    *storage.JWTService
+	Revocations   *storage.TokenRevocationStore
+	Idempotency   *storage.IdempotencyStore
+	Users         *storage.AuthService
 }
 
 // New creates and configures the HTTP router.
 func New(deps Dependencies) *gin.Engine {
+	if err := requests.RegisterValidations(); err != nil {
+		panic(err) // the tags are static, so this only fails on a programming error
+	}
+
 	engine := gin.New()
 	engine.Use(gin.Logger(), gin.Recovery(), middleware.ErrorHandler())
 
@@ -26,13 +40,15 @@ func New(deps Dependencies) *gin.Engine {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
 
+	deps.Auth.RegisterWellKnownRoutes(engine)
+
 	api := engine.Group("/api/v1")
 
 	auth := api.Group("/auth")
 	deps.Auth.RegisterPublicRoutes(auth)
 
 	protected := api.Group("")
-	protected.Use(middleware.JWTAuth(deps.JWTService))
+	protected.Use(middleware.JWTAuth(deps.JWTService, deps.Revocations), middleware.Idempotency(deps.Idempotency))
 
 	protectedAuth := protected.Group("/auth")
 	deps.Auth.RegisterProtectedRoutes(protectedAuth)
@@ -40,5 +56,25 @@ func New(deps Dependencies) *gin.Engine {
 	accounts := protected.Group("/accounts")
 	deps.Account.RegisterRoutes(accounts)
 
+	transfers := protected.Group("/transfers")
+	deps.Transfer.RegisterRoutes(transfers)
+
+	categories := protected.Group("/categories")
+	deps.Categories.RegisterRoutes(categories)
+
+	incomeSources := protected.Group("/income-sources")
+	deps.IncomeSources.RegisterRoutes(incomeSources)
+
+	exchangeRates := protected.Group("/exchange-rates")
+	deps.ExchangeRates.RegisterRoutes(exchangeRates)
+
+	reports := protected.Group("/reports")
+	deps.Reports.RegisterRoutes(reports)
+
+	admin := protected.Group("/admin")
+	admin.Use(middleware.RequireAdmin(deps.Users))
+	deps.Admin.RegisterRoutes(admin)
+	deps.ExchangeRates.RegisterAdminRoutes(admin.Group("/exchange-rates"))
+
 	return engine
 }
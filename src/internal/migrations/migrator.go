@@ -1,7 +1,9 @@
 package migrations
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 
 	"gorm.io/gorm"
 
@@ -10,14 +12,324 @@ import (
 
 // Run ensures all database schema migrations are applied.
 func Run(db *gorm.DB) error {
+	if err := dropUniqueAccountUserIndex(db); err != nil {
+		return err
+	}
+
+	if err := dropBalanceSnapshotMonthIndex(db); err != nil {
+		return err
+	}
+
 	if err := db.AutoMigrate(
 		&models.User{},
 		&models.Account{},
 		&models.Income{},
 		&models.Expense{},
+		&models.Transfer{},
+		&models.RefreshToken{},
+		&models.RevokedToken{},
+		&models.LedgerJournal{},
+		&models.LedgerEntry{},
+		&models.BalanceAudit{},
+		&models.IdempotencyKey{},
+		&models.CurrencyConversion{},
+		&models.ExchangeRate{},
+		&models.Category{},
+		&models.IncomeSource{},
+		&models.Tag{},
+		&models.IncomeSplit{},
+		&models.ExpenseSplit{},
+		&models.BalanceSnapshot{},
 	); err != nil {
 		return fmt.Errorf("auto migrate: %w", err)
 	}
 
+	if err := backfillDefaultAccounts(db); err != nil {
+		return err
+	}
+
+	if err := backfillLedger(db); err != nil {
+		return err
+	}
+
+	if err := backfillExpenseCategories(db); err != nil {
+		return err
+	}
+
+	if err := backfillIncomeSources(db); err != nil {
+		return err
+	}
+
+	if err := backfillBookedCurrencies(db); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// dropUniqueAccountUserIndex removes the one-account-per-user constraint from schemas
+// created before users could own several accounts. AutoMigrate then recreates the index
+// under the same name as a plain, non-unique index.
+func dropUniqueAccountUserIndex(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&models.Account{}) {
+		return nil
+	}
+
+	indexes, err := migrator.GetIndexes(&models.Account{})
+	if err != nil {
+		return fmt.Errorf("inspect account indexes: %w", err)
+	}
+	for _, index := range indexes {
+		if index.Name() != "idx_accounts_user_id" {
+			continue
+		}
+		if unique, ok := index.Unique(); ok && unique {
+			if err := migrator.DropIndex(&models.Account{}, index.Name()); err != nil {
+				return fmt.Errorf("drop unique account user index: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// dropBalanceSnapshotMonthIndex removes the one-snapshot-per-month constraint from schemas
+// created before snapshots carried an epoch. AutoMigrate then adds the index on account,
+// month and epoch.
+func dropBalanceSnapshotMonthIndex(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasIndex(&models.BalanceSnapshot{}, "idx_balance_snapshots_account_month") {
+		return nil
+	}
+	if err := migrator.DropIndex(&models.BalanceSnapshot{}, "idx_balance_snapshots_account_month"); err != nil {
+		return fmt.Errorf("drop balance snapshot month index: %w", err)
+	}
+	return nil
+}
+
+// backfillDefaultAccounts marks the oldest account of every user without a default
+// account as the default one.
+func backfillDefaultAccounts(db *gorm.DB) error {
+	err := db.Exec(`
+		UPDATE accounts SET is_default = ?
+		WHERE id IN (
+			SELECT MIN(id) FROM accounts
+			GROUP BY user_id
+			HAVING SUM(CASE WHEN is_default THEN 1 ELSE 0 END) = 0
+		)`, true).Error
+	if err != nil {
+		return fmt.Errorf("backfill default accounts: %w", err)
+	}
+	return nil
+}
+
+// backfillLedger records journals for incomes, expenses and transfers created before the
+// ledger existed. Account balances already include these amounts, so only the journals and
+// their entries are written.
+func backfillLedger(db *gorm.DB) error {
+	journals := []string{
+		`INSERT INTO ledger_journals (created_at, user_id, kind, reference_id, posted_at, description)
+		SELECT CURRENT_TIMESTAMP, i.user_id, 'income', i.id, i.received_at, i.source FROM incomes i
+		WHERE NOT EXISTS (SELECT 1 FROM ledger_journals j WHERE j.kind = 'income' AND j.reference_id = i.id)`,
+		`INSERT INTO ledger_journals (created_at, user_id, kind, reference_id, posted_at, description)
+		SELECT CURRENT_TIMESTAMP, e.user_id, 'expense', e.id, e.incurred_at, e.category FROM expenses e
+		WHERE NOT EXISTS (SELECT 1 FROM ledger_journals j WHERE j.kind = 'expense' AND j.reference_id = e.id)`,
+		`INSERT INTO ledger_journals (created_at, user_id, kind, reference_id, posted_at, description)
+		SELECT CURRENT_TIMESTAMP, t.user_id, 'transfer', t.id, t.transferred_at, t.notes FROM transfers t
+		WHERE NOT EXISTS (SELECT 1 FROM ledger_journals j WHERE j.kind = 'transfer' AND j.reference_id = t.id)`,
+	}
+	for _, statement := range journals {
+		if err := db.Exec(statement).Error; err != nil {
+			return fmt.Errorf("backfill ledger journals: %w", err)
+		}
+	}
+
+	// Entries are only written for journals that have none yet, so the backfill is idempotent.
+	err := db.Exec(`
+		INSERT INTO ledger_entries (created_at, journal_id, account_id, ledger_account, currency_iso_code, amount_cents)
+		SELECT CURRENT_TIMESTAMP, j.id, p.account_id, p.ledger_account, p.currency_iso_code, p.amount_cents
+		FROM ledger_journals j
+		JOIN (
+			SELECT 'income' AS kind, i.id AS reference_id, a.id AS account_id, 'assets' AS ledger_account,
+				a.currency_iso_code, i.amount_cents
+			FROM incomes i JOIN accounts a ON a.id = i.account_id
+			UNION ALL
+			SELECT 'income', i.id, NULL, 'income', a.currency_iso_code, -i.amount_cents
+			FROM incomes i JOIN accounts a ON a.id = i.account_id
+			UNION ALL
+			SELECT 'expense', e.id, NULL, 'expense', a.currency_iso_code, e.amount_cents
+			FROM expenses e JOIN accounts a ON a.id = e.account_id
+			UNION ALL
+			SELECT 'expense', e.id, a.id, 'assets', a.currency_iso_code, -e.amount_cents
+			FROM expenses e JOIN accounts a ON a.id = e.account_id
+			UNION ALL
+			SELECT 'transfer', t.id, f.id, 'assets', f.currency_iso_code, -t.amount_cents
+			FROM transfers t JOIN accounts f ON f.id = t.from_account_id
+			UNION ALL
+			SELECT 'transfer', t.id, d.id, 'assets', d.currency_iso_code, t.to_amount_cents
+			FROM transfers t JOIN accounts d ON d.id = t.to_account_id
+			UNION ALL
+			SELECT 'transfer', t.id, NULL, 'fx_clearing', f.currency_iso_code, t.amount_cents
+			FROM transfers t
+			JOIN accounts f ON f.id = t.from_account_id
+			JOIN accounts d ON d.id = t.to_account_id
+			WHERE f.currency_iso_code <> d.currency_iso_code
+			UNION ALL
+			SELECT 'transfer', t.id, NULL, 'fx_clearing', d.currency_iso_code, -t.to_amount_cents
+			FROM transfers t
+			JOIN accounts f ON f.id = t.from_account_id
+			JOIN accounts d ON d.id = t.to_account_id
+			WHERE f.currency_iso_code <> d.currency_iso_code
+		) p ON p.kind = j.kind AND p.reference_id = j.reference_id
+		WHERE NOT EXISTS (SELECT 1 FROM ledger_entries e WHERE e.journal_id = j.id)`).Error
+	if err != nil {
+		return fmt.Errorf("backfill ledger entries: %w", err)
+	}
+	return nil
+}
+
+// backfillExpenseCategories files expenses recorded before the category catalogue existed.
+// Their free-text categories are grouped per user by normalized name, so "Food", "food"
+// and "Food " end up in one catalogue entry, and the expenses take its canonical name.
+// Only expenses without a category_id are touched, so the backfill is idempotent.
+func backfillExpenseCategories(db *gorm.DB) error {
+	var rows []legacyName
+	err := db.Model(&models.Expense{}).
+		Select("DISTINCT user_id, category AS name").
+		Where("category_id IS NULL").
+		Order("user_id ASC, name ASC").
+		Find(&rows).Error
+	if err != nil {
+		return fmt.Errorf("load uncategorized expenses: %w", err)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, group := range groupLegacyNames(rows, "Other") {
+			var category models.Category
+			err := tx.Where("user_id = ? AND type = ? AND normalized_name = ?", group.userID, models.CategoryTypeExpense, group.normalized).
+				First(&category).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				category = models.Category{UserID: group.userID, Type: models.CategoryTypeExpense, Name: group.name, NormalizedName: group.normalized}
+				err = tx.Create(&category).Error
+			}
+			if err != nil {
+				return fmt.Errorf("backfill expense category %q: %w", group.normalized, err)
+			}
+
+			err = tx.Model(&models.Expense{}).
+				Where("user_id = ? AND category_id IS NULL AND category IN ?", group.userID, group.variants).
+				Updates(map[string]any{"category_id": category.ID, "category": category.Name}).Error
+			if err != nil {
+				return fmt.Errorf("backfill expense category %q: %w", group.normalized, err)
+			}
+		}
+		return nil
+	})
+}
+
+// backfillIncomeSources links incomes recorded before income sources existed to sources
+// created from their free-text payer names, grouped like expense categories. Only incomes
+// without a source_id are touched, so the backfill is idempotent.
+func backfillIncomeSources(db *gorm.DB) error {
+	var rows []legacyName
+	err := db.Model(&models.Income{}).
+		Select("DISTINCT user_id, source AS name").
+		Where("source_id IS NULL").
+		Order("user_id ASC, name ASC").
+		Find(&rows).Error
+	if err != nil {
+		return fmt.Errorf("load incomes without source: %w", err)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, group := range groupLegacyNames(rows, "Unknown") {
+			var source models.IncomeSource
+			err := tx.Where("user_id = ? AND normalized_name = ?", group.userID, group.normalized).
+				First(&source).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				source = models.IncomeSource{UserID: group.userID, Name: group.name, NormalizedName: group.normalized, Type: models.IncomeSourceTypeOther}
+				err = tx.Create(&source).Error
+			}
+			if err != nil {
+				return fmt.Errorf("backfill income source %q: %w", group.normalized, err)
+			}
+
+			err = tx.Model(&models.Income{}).
+				Where("user_id = ? AND source_id IS NULL AND source IN ?", group.userID, group.variants).
+				Updates(map[string]any{"source_id": source.ID, "source": source.Name}).Error
+			if err != nil {
+				return fmt.Errorf("backfill income source %q: %w", group.normalized, err)
+			}
+		}
+		return nil
+	})
+}
+
+// backfillBookedCurrencies records the currency of incomes, expenses and transfer legs
+// stored before rows carried it. A row created before a conversion of its account was
+// booked in the currency the first later conversion converted from; other rows are in the
+// account's currency. Only rows without a currency are touched, so the backfill is
+// idempotent.
+func backfillBookedCurrencies(db *gorm.DB) error {
+	columns := []struct{ table, currency, account string }{
+		{"incomes", "currency_iso_code", "account_id"},
+		{"expenses", "currency_iso_code", "account_id"},
+		{"transfers", "from_currency_iso_code", "from_account_id"},
+		{"transfers", "to_currency_iso_code", "to_account_id"},
+	}
+	for _, column := range columns {
+		err := db.Exec(fmt.Sprintf(`
+			UPDATE %[1]s SET %[2]s = COALESCE(
+				(SELECT c.from_currency FROM currency_conversions c
+				WHERE c.account_id = %[1]s.%[3]s AND c.created_at > %[1]s.created_at
+				ORDER BY c.created_at, c.id LIMIT 1),
+				(SELECT a.currency_iso_code FROM accounts a WHERE a.id = %[1]s.%[3]s))
+			WHERE %[2]s IS NULL OR %[2]s = ''`, column.table, column.currency, column.account)).Error
+		if err != nil {
+			return fmt.Errorf("backfill %s %s: %w", column.table, column.currency, err)
+		}
+	}
 	return nil
 }
+
+// legacyName is a distinct free-text name a user has entered.
+type legacyName struct {
+	UserID uint
+	Name   string
+}
+
+// legacyNameGroup collects the spellings of one name: variants are the stored values and
+// name is the canonical spelling of the catalogue entry.
+type legacyNameGroup struct {
+	userID     uint
+	normalized string
+	name       string
+	variants   []string
+}
+
+// groupLegacyNames groups names per user by their normalized form, keeping the order of
+// rows. The first spelling of a group becomes its canonical name; with rows sorted by name
+// that prefers capitalized spellings. Blank names are grouped under fallback.
+func groupLegacyNames(rows []legacyName, fallback string) []*legacyNameGroup {
+	type key struct {
+		userID     uint
+		normalized string
+	}
+	index := make(map[key]*legacyNameGroup)
+	var groups []*legacyNameGroup
+	for _, row := range rows {
+		name := strings.Join(strings.Fields(row.Name), " ")
+		if name == "" {
+			name = fallback
+		}
+		k := key{userID: row.UserID, normalized: models.NormalizeCategoryName(name)}
+		group, ok := index[k]
+		if !ok {
+			group = &legacyNameGroup{userID: row.UserID, normalized: k.normalized, name: name}
+			index[k] = group
+			groups = append(groups, group)
+		}
+		group.variants = append(group.variants, row.Name)
+	}
+	return groups
+}
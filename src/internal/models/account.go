@@ -1,16 +1,44 @@
 package models
 
-// Account keeps aggregated balance for a specific user.
+// Account types supported for user balances.
+const (
+	AccountTypeWallet  = "wallet"
+	AccountTypeCard    = "card"
+	AccountTypeCash    = "cash"
+	AccountTypeSavings = "savings"
+)
+
+// DefaultAccountName is used for the account created on registration.
+const DefaultAccountName = "Main"
+
+// Account keeps aggregated balance for one of a user's named accounts.
 type Account struct {
 	BaseModel
 
-	UserID uint `gorm:"uniqueIndex;not null"`
+	UserID uint `gorm:"not null;index;uniqueIndex:idx_accounts_user_name"`
+
+	Name      string `gorm:"size:120;not null;default:'Main';uniqueIndex:idx_accounts_user_name"`
+	Type      string `gorm:"size:20;not null;default:'wallet'"`
+	IsDefault bool   `gorm:"not null;default:false"`
 
 	BalanceCents    int64  `gorm:"not null;default:0"`
 	CurrencyISOCode string `gorm:"size:3;not null;default:'UAH'"`
 
+	// SnapshotEpoch advances whenever a backdated change invalidates balance snapshots.
+	SnapshotEpoch uint `gorm:"not null;default:0"`
+
 	User *User `gorm:"constraint:OnDelete:CASCADE"`
 
 	Expenses []Expense `gorm:"constraint:OnDelete:CASCADE"`
 	Incomes  []Income  `gorm:"constraint:OnDelete:CASCADE"`
 }
+
+// IsValidAccountType reports whether t is one of the supported account types.
+func IsValidAccountType(t string) bool {
+	switch t {
+	case AccountTypeWallet, AccountTypeCard, AccountTypeCash, AccountTypeSavings:
+		return true
+	default:
+		return false
+	}
+}
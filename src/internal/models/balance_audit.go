@@ -0,0 +1,16 @@
+package models
+
+// BalanceAudit records a balance repaired by the integrity check: the cached balance and
+// the ledger total found before the repair, and the transaction total they were set to.
+type BalanceAudit struct {
+	BaseModel
+
+	AccountID uint `gorm:"not null;index"`
+	UserID    uint `gorm:"not null;index"`
+
+	StoredCents       int64 `gorm:"not null"`
+	LedgerCents       int64 `gorm:"not null"`
+	TransactionsCents int64 `gorm:"not null"`
+
+	Actor string `gorm:"size:255;not null"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// BalanceSnapshot caches the balance of an account at the start of a month (UTC): the sum
+// of its incomes, expenses, transfers and currency conversions dated before MonthStart.
+// Snapshots are derived data; they are built on demand by balance queries and removed
+// when a transaction dated before MonthStart is recorded, edited or deleted. Epoch is the
+// account's SnapshotEpoch the snapshot was built under; only snapshots of the current
+// epoch are used.
+type BalanceSnapshot struct {
+	ID        uint      `gorm:"primaryKey"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+
+	AccountID    uint      `gorm:"not null;uniqueIndex:idx_balance_snapshots_account_month_epoch"`
+	MonthStart   time.Time `gorm:"not null;uniqueIndex:idx_balance_snapshots_account_month_epoch"`
+	Epoch        uint      `gorm:"not null;default:0;uniqueIndex:idx_balance_snapshots_account_month_epoch"`
+	BalanceCents int64     `gorm:"not null"`
+
+	Account *Account `gorm:"constraint:OnDelete:CASCADE"`
+}
@@ -2,9 +2,12 @@ package models
 
 import "time"
 
-// BaseModel captures shared timestamp fields for persisted entities.
+// BaseModel captures shared identity, timestamp and version fields for persisted entities.
+// Version starts at 1 and is incremented by every update of a resource exposed through the
+// API, so it can be used as an optimistic concurrency token.
 type BaseModel struct {
 	ID        uint      `gorm:"primaryKey"`
 	CreatedAt time.Time `gorm:"autoCreateTime"`
 	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+	Version   uint      `gorm:"not null;default:1"`
 }
@@ -0,0 +1,63 @@
+package models
+
+import "strings"
+
+// Category types: a category classifies either incomes or expenses.
+const (
+	CategoryTypeIncome  = "income"
+	CategoryTypeExpense = "expense"
+)
+
+// Category is an entry of a user's income or expense catalogue. Categories form a tree
+// through ParentID; a child always has the type of its parent. Names are unique per user
+// and type regardless of case and surrounding whitespace, which NormalizedName enforces.
+type Category struct {
+	BaseModel
+
+	UserID   uint  `gorm:"not null;index;uniqueIndex:idx_categories_user_type_name"`
+	ParentID *uint `gorm:"index"`
+
+	Type           string `gorm:"size:16;not null;uniqueIndex:idx_categories_user_type_name"`
+	Name           string `gorm:"size:120;not null"`
+	NormalizedName string `gorm:"size:120;not null;uniqueIndex:idx_categories_user_type_name"`
+	Icon           string `gorm:"size:64"`
+	Color          string `gorm:"size:7"`
+
+	Parent *Category `gorm:"constraint:OnDelete:SET NULL"`
+	User   *User     `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+// IsValidCategoryType reports whether t is one of the supported category types.
+func IsValidCategoryType(t string) bool {
+	return t == CategoryTypeIncome || t == CategoryTypeExpense
+}
+
+// NormalizeCategoryName returns the key under which category names are compared:
+// lower case with runs of whitespace collapsed, so "Food", "food" and " Food " match.
+func NormalizeCategoryName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// CategorySeed describes a catalogue entry created for new users.
+type CategorySeed struct {
+	Type     string
+	Name     string
+	Icon     string
+	Color    string
+	Children []string
+}
+
+// DefaultCategories is the catalogue every user starts with.
+var DefaultCategories = []CategorySeed{
+	{Type: CategoryTypeExpense, Name: "Food", Icon: "utensils", Color: "#E67E22", Children: []string{"Groceries", "Restaurants"}},
+	{Type: CategoryTypeExpense, Name: "Transport", Icon: "bus", Color: "#3498DB", Children: []string{"Public transport", "Fuel", "Taxi"}},
+	{Type: CategoryTypeExpense, Name: "Housing", Icon: "home", Color: "#8E44AD", Children: []string{"Rent", "Utilities"}},
+	{Type: CategoryTypeExpense, Name: "Health", Icon: "heart", Color: "#E74C3C"},
+	{Type: CategoryTypeExpense, Name: "Entertainment", Icon: "film", Color: "#F1C40F"},
+	{Type: CategoryTypeExpense, Name: "Shopping", Icon: "shopping-bag", Color: "#1ABC9C"},
+	{Type: CategoryTypeExpense, Name: "Other", Icon: "tag", Color: "#95A5A6"},
+	{Type: CategoryTypeIncome, Name: "Salary", Icon: "briefcase", Color: "#27AE60"},
+	{Type: CategoryTypeIncome, Name: "Gifts", Icon: "gift", Color: "#D35400"},
+	{Type: CategoryTypeIncome, Name: "Investments", Icon: "trending-up", Color: "#2980B9"},
+	{Type: CategoryTypeIncome, Name: "Other", Icon: "tag", Color: "#95A5A6"},
+}
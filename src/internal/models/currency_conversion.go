@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// CurrencyConversion records an account balance converted into another currency when the
+// user changed their default currency. Rate gives ToCurrency units per FromCurrency unit.
+type CurrencyConversion struct {
+	BaseModel
+
+	UserID    uint `gorm:"not null;index"`
+	AccountID uint `gorm:"not null;index"`
+
+	FromCurrency    string    `gorm:"size:3;not null"`
+	ToCurrency      string    `gorm:"size:3;not null"`
+	Rate            string    `gorm:"size:32;not null"`
+	FromAmountCents int64     `gorm:"not null"`
+	ToAmountCents   int64     `gorm:"not null"`
+	ConvertedAt     time.Time `gorm:"not null"`
+
+	Account *Account `gorm:"constraint:OnDelete:CASCADE"`
+	User    *User    `gorm:"constraint:OnDelete:CASCADE"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Exchange rate sources.
+const (
+	ExchangeRateSourceManual = "manual"
+	ExchangeRateSourceCSV    = "csv"
+	ExchangeRateSourceECB    = "ecb"
+	ExchangeRateSourceNBU    = "nbu"
+)
+
+// ExchangeRate is the official or manually entered rate of a currency pair for one day:
+// one unit of BaseCurrency buys Rate units of QuoteCurrency. EffectiveOn is midnight UTC
+// of that day.
+type ExchangeRate struct {
+	BaseModel
+
+	BaseCurrency  string    `gorm:"size:3;not null;uniqueIndex:idx_exchange_rates_pair_day"`
+	QuoteCurrency string    `gorm:"size:3;not null;uniqueIndex:idx_exchange_rates_pair_day"`
+	EffectiveOn   time.Time `gorm:"not null;uniqueIndex:idx_exchange_rates_pair_day"`
+
+	Rate   string `gorm:"size:32;not null"`
+	Source string `gorm:"size:16;not null;default:'manual'"`
+}
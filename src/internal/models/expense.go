@@ -13,8 +13,39 @@ type Expense struct {
 	Category    string    `gorm:"size:120;not null"`
 	IncurredAt  time.Time `gorm:"not null"`
 
+	// CategoryID references the catalogue entry; Category keeps its name for display.
+	CategoryID *uint `gorm:"index"`
+
+	// CurrencyISOCode is the currency of AmountCents: the account currency when the expense
+	// was recorded. It stays behind when the account is later converted to another currency.
+	CurrencyISOCode string `gorm:"size:3"`
+
+	// OriginalAmountCents and OriginalCurrency hold the amount as entered when it was paid
+	// in another currency than the account's, and ExchangeRate the rate that converted it
+	// into AmountCents (account currency units per original unit). All three are empty
+	// for amounts entered in the account currency.
+	OriginalAmountCents int64  `gorm:"not null;default:0"`
+	OriginalCurrency    string `gorm:"size:3"`
+	ExchangeRate        string `gorm:"size:32"`
+
 	Description string `gorm:"size:512"`
 
-	Account *Account `gorm:"constraint:OnDelete:CASCADE"`
-	User    *User    `gorm:"constraint:OnDelete:CASCADE"`
+	Account       *Account  `gorm:"constraint:OnDelete:CASCADE"`
+	User          *User     `gorm:"constraint:OnDelete:CASCADE"`
+	CategoryEntry *Category `gorm:"foreignKey:CategoryID;constraint:OnDelete:SET NULL"`
+
+	// Tags label the expense across categories; they are linked through expense_tags.
+	Tags []Tag `gorm:"many2many:expense_tags;constraint:OnDelete:CASCADE"`
+	// Splits divide the expense across several categories; they are empty for expenses
+	// filed under a single category.
+	Splits []ExpenseSplit `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+// EntryCurrency returns the currency the amount was entered in: OriginalCurrency for
+// foreign-currency expenses, otherwise the currency it was recorded in.
+func (e *Expense) EntryCurrency() string {
+	if e.OriginalCurrency != "" {
+		return e.OriginalCurrency
+	}
+	return e.CurrencyISOCode
 }
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// IdempotencyKey remembers the outcome of a mutating request sent with an
+// Idempotency-Key header so that retries replay the original response instead of
+// repeating the operation. A zero StatusCode marks a request that is still in progress.
+// ContentType, ETag and Location keep the response headers replayed with the body.
+type IdempotencyKey struct {
+	BaseModel
+
+	UserID uint   `gorm:"not null;uniqueIndex:idx_idempotency_keys_user_key"`
+	Key    string `gorm:"column:idempotency_key;size:255;not null;uniqueIndex:idx_idempotency_keys_user_key"`
+
+	Fingerprint string `gorm:"size:64;not null"`
+
+	StatusCode   int
+	ContentType  string `gorm:"size:255"`
+	ETag         string `gorm:"column:etag;size:255"`
+	Location     string `gorm:"size:2048"`
+	ResponseBody []byte
+
+	ExpiresAt time.Time `gorm:"not null;index"`
+
+	User *User `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+// Completed reports whether the original request has finished and its response is stored.
+func (k *IdempotencyKey) Completed() bool {
+	return k.StatusCode != 0
+}
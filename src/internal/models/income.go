@@ -13,8 +13,42 @@ type Income struct {
 	Source      string    `gorm:"size:255;not null"`
 	ReceivedAt  time.Time `gorm:"not null"`
 
+	// SourceID references the payer; Source keeps its name for display.
+	SourceID *uint `gorm:"index"`
+	// CategoryID optionally classifies the income with an entry of the income catalogue.
+	CategoryID *uint `gorm:"index"`
+
+	// CurrencyISOCode is the currency of AmountCents: the account currency when the income
+	// was recorded. It stays behind when the account is later converted to another currency.
+	CurrencyISOCode string `gorm:"size:3"`
+
+	// OriginalAmountCents and OriginalCurrency hold the amount as entered when it was paid
+	// in another currency than the account's, and ExchangeRate the rate that converted it
+	// into AmountCents (account currency units per original unit). All three are empty
+	// for amounts entered in the account currency.
+	OriginalAmountCents int64  `gorm:"not null;default:0"`
+	OriginalCurrency    string `gorm:"size:3"`
+	ExchangeRate        string `gorm:"size:32"`
+
 	Notes string `gorm:"size:512"`
 
-	Account *Account `gorm:"constraint:OnDelete:CASCADE"`
-	User    *User    `gorm:"constraint:OnDelete:CASCADE"`
+	Account     *Account      `gorm:"constraint:OnDelete:CASCADE"`
+	User        *User         `gorm:"constraint:OnDelete:CASCADE"`
+	Category    *Category     `gorm:"constraint:OnDelete:SET NULL"`
+	SourceEntry *IncomeSource `gorm:"foreignKey:SourceID;constraint:OnDelete:SET NULL"`
+
+	// Tags label the income across categories; they are linked through income_tags.
+	Tags []Tag `gorm:"many2many:income_tags;constraint:OnDelete:CASCADE"`
+	// Splits divide the income across several categories; they are empty for incomes
+	// filed under a single category.
+	Splits []IncomeSplit `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+// EntryCurrency returns the currency the amount was entered in: OriginalCurrency for
+// foreign-currency incomes, otherwise the currency it was recorded in.
+func (i *Income) EntryCurrency() string {
+	if i.OriginalCurrency != "" {
+		return i.OriginalCurrency
+	}
+	return i.CurrencyISOCode
 }
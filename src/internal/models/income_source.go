@@ -0,0 +1,48 @@
+package models
+
+// Income source types describing how a payer pays the user.
+const (
+	IncomeSourceTypeSalary    = "salary"
+	IncomeSourceTypeFreelance = "freelance"
+	IncomeSourceTypeDividend  = "dividend"
+	IncomeSourceTypeInterest  = "interest"
+	IncomeSourceTypeRental    = "rental"
+	IncomeSourceTypeOther     = "other"
+)
+
+// IncomeSource is a payer the user receives income from, such as an employer or a client.
+// Names are unique per user regardless of case and surrounding whitespace, which
+// NormalizedName enforces. Incomes from the source are filed under DefaultCategoryID
+// unless they name a category themselves.
+type IncomeSource struct {
+	BaseModel
+
+	UserID uint `gorm:"not null;index;uniqueIndex:idx_income_sources_user_name"`
+
+	Name           string `gorm:"size:255;not null"`
+	NormalizedName string `gorm:"size:255;not null;uniqueIndex:idx_income_sources_user_name"`
+	Type           string `gorm:"size:16;not null;default:'other'"`
+	TaxID          string `gorm:"size:32"`
+
+	DefaultCategoryID *uint `gorm:"index"`
+
+	DefaultCategory *Category `gorm:"constraint:OnDelete:SET NULL"`
+	User            *User     `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+// IsValidIncomeSourceType reports whether t is one of the supported income source types.
+func IsValidIncomeSourceType(t string) bool {
+	switch t {
+	case IncomeSourceTypeSalary, IncomeSourceTypeFreelance, IncomeSourceTypeDividend,
+		IncomeSourceTypeInterest, IncomeSourceTypeRental, IncomeSourceTypeOther:
+		return true
+	default:
+		return false
+	}
+}
+
+// NormalizeIncomeSourceName returns the key under which income source names are compared,
+// following the same rules as category names.
+func NormalizeIncomeSourceName(name string) string {
+	return NormalizeCategoryName(name)
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Journal kinds identify the business event a journal was recorded for.
+const (
+	JournalKindIncome     = "income"
+	JournalKindExpense    = "expense"
+	JournalKindTransfer   = "transfer"
+	JournalKindAdjustment = "adjustment"
+	JournalKindConversion = "conversion"
+)
+
+// Ledger accounts. User accounts are posted to LedgerAccountAssets together with their
+// AccountID; the remaining ledger accounts are the counterparties outside the user's books.
+const (
+	LedgerAccountAssets     = "assets"
+	LedgerAccountIncome     = "income"
+	LedgerAccountExpense    = "expense"
+	LedgerAccountFXClearing = "fx_clearing"
+	// LedgerAccountReconciliation absorbs corrections made by the balance integrity repair.
+	LedgerAccountReconciliation = "reconciliation"
+)
+
+// ErrLedgerImmutable is returned when a persisted journal or posting would be modified.
+var ErrLedgerImmutable = errors.New("ledger records are immutable")
+
+// LedgerJournal groups the balanced postings recorded for a single business event.
+type LedgerJournal struct {
+	ID        uint      `gorm:"primaryKey"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+
+	UserID      uint      `gorm:"not null;index"`
+	Kind        string    `gorm:"size:32;not null;index:idx_ledger_journals_reference"`
+	ReferenceID uint      `gorm:"not null;index:idx_ledger_journals_reference"`
+	PostedAt    time.Time `gorm:"not null"`
+
+	Description string `gorm:"size:255"`
+
+	Entries []LedgerEntry `gorm:"foreignKey:JournalID;constraint:OnDelete:CASCADE"`
+	User    *User         `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+// LedgerEntry is a single posting of a journal. Positive amounts are debits and negative
+// amounts are credits, so the entries of a journal sum to zero in every currency and an
+// asset account's balance is the sum of its entries.
+type LedgerEntry struct {
+	ID        uint      `gorm:"primaryKey"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+
+	JournalID     uint   `gorm:"not null;index"`
+	AccountID     *uint  `gorm:"index"`
+	LedgerAccount string `gorm:"size:32;not null"`
+
+	CurrencyISOCode string `gorm:"size:3;not null"`
+	AmountCents     int64  `gorm:"not null"`
+
+	Account *Account `gorm:"constraint:OnDelete:SET NULL"`
+}
+
+func (LedgerJournal) BeforeUpdate(*gorm.DB) error { return ErrLedgerImmutable }
+func (LedgerJournal) BeforeDelete(*gorm.DB) error { return ErrLedgerImmutable }
+func (LedgerEntry) BeforeUpdate(*gorm.DB) error   { return ErrLedgerImmutable }
+func (LedgerEntry) BeforeDelete(*gorm.DB) error   { return ErrLedgerImmutable }
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// RefreshToken stores a hashed, single-use refresh token. Tokens issued for the same
+// login on the same device share a FamilyID so the whole chain can be revoked at once.
+type RefreshToken struct {
+	BaseModel
+
+	UserID   uint   `gorm:"not null;index"`
+	FamilyID string `gorm:"size:64;not null;index"`
+
+	TokenHash  string `gorm:"size:64;uniqueIndex;not null"`
+	DeviceName string `gorm:"size:255"`
+
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+
+	User *User `gorm:"constraint:OnDelete:CASCADE"`
+}
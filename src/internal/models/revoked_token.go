@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// RevokedToken records an access token, identified by its jti claim, that must no
+// longer be accepted even though its signature and expiry are still valid.
+type RevokedToken struct {
+	BaseModel
+
+	JTI    string `gorm:"column:jti;size:64;uniqueIndex;not null"`
+	UserID uint   `gorm:"not null;index"`
+
+	ExpiresAt time.Time `gorm:"not null;index"`
+
+	User *User `gorm:"constraint:OnDelete:CASCADE"`
+}
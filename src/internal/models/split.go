@@ -0,0 +1,56 @@
+package models
+
+// ExpenseSplit is a line of an expense divided across several categories, such as the
+// groceries, household and pharmacy parts of one supermarket receipt. The lines of an
+// expense add up to its amount, both in the account currency (AmountCents) and, for an
+// expense paid in another currency, in the currency it was entered in
+// (OriginalAmountCents).
+type ExpenseSplit struct {
+	ID uint `gorm:"primaryKey"`
+
+	ExpenseID uint `gorm:"not null;index"`
+	Position  int  `gorm:"not null"`
+
+	CategoryID *uint  `gorm:"index"`
+	Category   string `gorm:"size:120;not null"`
+
+	AmountCents         int64  `gorm:"not null"`
+	OriginalAmountCents int64  `gorm:"not null;default:0"`
+	Note                string `gorm:"size:255"`
+
+	CategoryEntry *Category `gorm:"foreignKey:CategoryID;constraint:OnDelete:SET NULL"`
+}
+
+// IncomeSplit is a line of an income divided across several income categories, such as
+// the base pay and bonus parts of one salary payment. The lines of an income add up to
+// its amount in the same way as expense splits.
+type IncomeSplit struct {
+	ID uint `gorm:"primaryKey"`
+
+	IncomeID uint `gorm:"not null;index"`
+	Position int  `gorm:"not null"`
+
+	CategoryID *uint `gorm:"index"`
+
+	AmountCents         int64  `gorm:"not null"`
+	OriginalAmountCents int64  `gorm:"not null;default:0"`
+	Note                string `gorm:"size:255"`
+
+	Category *Category `gorm:"constraint:OnDelete:SET NULL"`
+}
+
+// EnteredAmountCents returns the line amount in the currency it was entered in.
+func (s *ExpenseSplit) EnteredAmountCents() int64 {
+	if s.OriginalAmountCents != 0 {
+		return s.OriginalAmountCents
+	}
+	return s.AmountCents
+}
+
+// EnteredAmountCents returns the line amount in the currency it was entered in.
+func (s *IncomeSplit) EnteredAmountCents() int64 {
+	if s.OriginalAmountCents != 0 {
+		return s.OriginalAmountCents
+	}
+	return s.AmountCents
+}
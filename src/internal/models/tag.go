@@ -0,0 +1,30 @@
+package models
+
+import "strings"
+
+// Tag is a label that cuts across categories, such as "trip-2026" or "reimbursable".
+// Tag names are stored normalized, so they are unique per user regardless of case and
+// surrounding whitespace.
+type Tag struct {
+	BaseModel
+
+	UserID uint   `gorm:"not null;uniqueIndex:idx_tags_user_name"`
+	Name   string `gorm:"size:64;not null;uniqueIndex:idx_tags_user_name"`
+
+	User *User `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+// NormalizeTagName returns the stored form of a tag name: lower case with inner runs of
+// whitespace collapsed to one space.
+func NormalizeTagName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// TagNames returns the names of tags in order.
+func TagNames(tags []Tag) []string {
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Transfer moves money between two accounts of the same user. AmountCents is debited
+// from the source account in its currency; ToAmountCents is credited to the destination
+// account in its currency, converted with ExchangeRate (destination units per source unit).
+type Transfer struct {
+	BaseModel
+
+	UserID        uint `gorm:"not null;index"`
+	FromAccountID uint `gorm:"not null;index"`
+	ToAccountID   uint `gorm:"not null;index"`
+
+	AmountCents   int64     `gorm:"not null"`
+	ToAmountCents int64     `gorm:"not null"`
+	ExchangeRate  string    `gorm:"size:32;not null;default:'1'"`
+	TransferredAt time.Time `gorm:"not null"`
+
+	// FromCurrencyISOCode and ToCurrencyISOCode are the currencies of AmountCents and
+	// ToAmountCents: the account currencies when the transfer was made.
+	FromCurrencyISOCode string `gorm:"size:3"`
+	ToCurrencyISOCode   string `gorm:"size:3"`
+
+	Notes string `gorm:"size:512"`
+
+	FromAccount *Account `gorm:"foreignKey:FromAccountID;constraint:OnDelete:CASCADE"`
+	ToAccount   *Account `gorm:"foreignKey:ToAccountID;constraint:OnDelete:CASCADE"`
+	User        *User    `gorm:"constraint:OnDelete:CASCADE"`
+}
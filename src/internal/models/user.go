@@ -1,8 +1,12 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
 
-// User represents an application user owning a financial account.
+	"gorm.io/gorm"
+)
+
+// User represents an application user owning one or more financial accounts.
 type User struct {
 	BaseModel
 
@@ -11,7 +15,14 @@ type User struct {
 
 	DefaultCurrency string `gorm:"size:3;not null"`
 
-	Account Account `gorm:"constraint:OnDelete:CASCADE"`
+	// IsAdmin grants access to the admin endpoints. It is only set from the command line,
+	// never through the API.
+	IsAdmin bool `gorm:"not null;default:false"`
+
+	// TokensRevokedAt invalidates every access token issued before this instant.
+	TokensRevokedAt *time.Time
+
+	Accounts []Account `gorm:"constraint:OnDelete:CASCADE"`
 
 	Expenses []Expense `gorm:"constraint:OnDelete:CASCADE"`
 	Incomes  []Income  `gorm:"constraint:OnDelete:CASCADE"`
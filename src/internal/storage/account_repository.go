@@ -2,8 +2,11 @@ package storage
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"bckndlab3/src/internal/models"
 )
@@ -17,21 +20,158 @@ func NewAccountRepository(db *gorm.DB) *AccountRepository {
 	return &AccountRepository{db: db}
 }
 
-// GetByUserID returns the account belonging to a specific user.
+// GetByUserID returns the default account belonging to a specific user.
 func (r *AccountRepository) GetByUserID(ctx context.Context, userID uint) (*models.Account, error) {
 	var account models.Account
-	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&account).Error; err != nil {
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("is_default DESC, id ASC").
+		First(&account).Error
+	if err != nil {
 		return nil, translateError(err)
 	}
 	return &account, nil
 }
 
-// UpdateBalance sets the account balance to the provided amount.
-func (r *AccountRepository) UpdateBalance(ctx context.Context, accountID uint, balanceCents int64) error {
-	result := r.db.WithContext(ctx).Model(&models.Account{}).
-		Where("id = ?", accountID).
-		Update("balance_cents", balanceCents)
+// GetByIDForUser returns an account only if it belongs to the given user.
+func (r *AccountRepository) GetByIDForUser(ctx context.Context, accountID, userID uint) (*models.Account, error) {
+	var account models.Account
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", accountID, userID).
+		First(&account).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &account, nil
+}
+
+// LockByIDForUser loads an account of the given user inside tx and locks its row until
+// the transaction ends.
+func (r *AccountRepository) LockByIDForUser(ctx context.Context, tx *gorm.DB, accountID, userID uint) (*models.Account, error) {
+	var account models.Account
+	if err := lockOwnedRow(ctx, tx, "accounts", &account, accountID, userID); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// lockOwnedRow loads a row owned by the user into dest with SELECT ... FOR UPDATE. SQLite
+// has no row locks, so there the row is touched first, which takes the database write
+// lock and serializes writers.
+func lockOwnedRow(ctx context.Context, tx *gorm.DB, table string, dest any, id, userID uint) error {
+	tx = tx.WithContext(ctx)
+	if tx.Dialector.Name() == "sqlite" {
+		err := tx.Exec("UPDATE "+table+" SET id = id WHERE id = ? AND user_id = ?", id, userID).Error
+		if err != nil {
+			return translateError(err)
+		}
+	}
+
+	err := tx.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate}).
+		Where("id = ? AND user_id = ?", id, userID).
+		First(dest).Error
+	if err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+// ListByUserID returns all accounts of a user, default account first.
+func (r *AccountRepository) ListByUserID(ctx context.Context, userID uint) ([]models.Account, error) {
+	var accounts []models.Account
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("is_default DESC, id ASC").
+		Find(&accounts).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return accounts, nil
+}
+
+// CountByUserID returns how many accounts the user has, reading inside tx.
+func (r *AccountRepository) CountByUserID(ctx context.Context, tx *gorm.DB, userID uint) (int64, error) {
+	var count int64
+	if err := tx.WithContext(ctx).Model(&models.Account{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return 0, translateError(err)
+	}
+	return count, nil
+}
+
+// Create persists a new account.
+func (r *AccountRepository) Create(ctx context.Context, tx *gorm.DB, account *models.Account) error {
+	if err := tx.WithContext(ctx).Create(account).Error; err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+// UpdateFields applies column updates to an account of the given user.
+func (r *AccountRepository) UpdateFields(ctx context.Context, tx *gorm.DB, accountID, userID uint, fields map[string]any) error {
+	fields["version"] = nextVersion
+	result := tx.WithContext(ctx).Model(&models.Account{}).
+		Where("id = ? AND user_id = ?", accountID, userID).
+		Updates(fields)
+	if err := result.Error; err != nil {
+		return translateError(err)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ClearDefault unmarks every default account of a user.
+func (r *AccountRepository) ClearDefault(ctx context.Context, tx *gorm.DB, userID uint) error {
+	err := tx.WithContext(ctx).Model(&models.Account{}).
+		Where("user_id = ? AND is_default = ?", userID, true).
+		Updates(map[string]any{"is_default": false, "version": nextVersion}).Error
+	if err != nil {
+		return translateError(err)
+	}
+	return nil
+}
 
+// Delete removes an account of the given user.
+func (r *AccountRepository) Delete(ctx context.Context, tx *gorm.DB, accountID, userID uint) error {
+	result := tx.WithContext(ctx).
+		Where("id = ? AND user_id = ?", accountID, userID).
+		Delete(&models.Account{})
+	if err := result.Error; err != nil {
+		return translateError(err)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// HasTransactions reports whether any income, expense, transfer or currency conversion
+// references the account.
+func (r *AccountRepository) HasTransactions(ctx context.Context, tx *gorm.DB, accountID uint) (bool, error) {
+	const query = `
+SELECT 1 FROM incomes WHERE account_id = @account
+UNION ALL
+SELECT 1 FROM expenses WHERE account_id = @account
+UNION ALL
+SELECT 1 FROM transfers WHERE from_account_id = @account OR to_account_id = @account
+UNION ALL
+SELECT 1 FROM currency_conversions WHERE account_id = @account
+LIMIT 1`
+
+	var found []int
+	if err := tx.WithContext(ctx).Raw(query, map[string]any{"account": accountID}).Scan(&found).Error; err != nil {
+		return false, translateError(err)
+	}
+	return len(found) > 0, nil
+}
+
+// SetBalance overwrites the cached balance of an account. It is reserved for the
+// integrity repair, which resets the projection before posting corrections.
+func (r *AccountRepository) SetBalance(ctx context.Context, tx *gorm.DB, accountID uint, balanceCents int64) error {
+	result := tx.WithContext(ctx).Model(&models.Account{}).
+		Where("id = ?", accountID).
+		Updates(map[string]any{"balance_cents": balanceCents, "version": nextVersion})
 	if err := result.Error; err != nil {
 		return translateError(err)
 	}
@@ -42,19 +182,48 @@ func (r *AccountRepository) UpdateBalance(ctx context.Context, accountID uint, b
 }
 
 // AdjustBalance increments the account balance by delta and returns the updated value.
+// Balances are a projection of the ledger, so only Ledger.Post should call this.
 func (r *AccountRepository) AdjustBalance(ctx context.Context, tx *gorm.DB, accountID uint, delta int64) (int64, error) {
-	type balanceRow struct {
-		BalanceCents int64
+	tx = tx.WithContext(ctx)
+
+	result := tx.Exec("UPDATE accounts SET balance_cents = balance_cents + ?, version = version + 1 WHERE id = ?", delta, accountID)
+	if err := result.Error; err != nil {
+		return 0, translateError(err)
+	}
+	if result.RowsAffected == 0 {
+		return 0, ErrNotFound
 	}
+	return r.readBalance(tx, accountID)
+}
 
+// AdjustBalanceAboveFloor is AdjustBalance, but the update only applies when the
+// resulting balance stays at or above floor. The check and the update are a single
+// statement, so concurrent debits cannot overdraw the account together. It returns
+// ErrInsufficientFunds when the floor would be crossed.
+func (r *AccountRepository) AdjustBalanceAboveFloor(ctx context.Context, tx *gorm.DB, accountID uint, delta, floor int64) (int64, error) {
 	tx = tx.WithContext(ctx)
 
-	result := tx.Exec("UPDATE accounts SET balance_cents = balance_cents + ? WHERE id = ?", delta, accountID)
+	result := tx.Exec("UPDATE accounts SET balance_cents = balance_cents + ?, version = version + 1 WHERE id = ? AND balance_cents + ? >= ?",
+		delta, accountID, delta, floor)
 	if err := result.Error; err != nil {
 		return 0, translateError(err)
 	}
 	if result.RowsAffected == 0 {
-		return 0, ErrNotFound
+		var count int64
+		if err := tx.Model(&models.Account{}).Where("id = ?", accountID).Count(&count).Error; err != nil {
+			return 0, translateError(err)
+		}
+		if count == 0 {
+			return 0, ErrNotFound
+		}
+		return 0, ErrInsufficientFunds
+	}
+	return r.readBalance(tx, accountID)
+}
+
+func (r *AccountRepository) readBalance(tx *gorm.DB, accountID uint) (int64, error) {
+	type balanceRow struct {
+		BalanceCents int64
 	}
 
 	var row balanceRow
@@ -67,7 +236,7 @@ func (r *AccountRepository) AdjustBalance(ctx context.Context, tx *gorm.DB, acco
 
 // CreateIncome records a new income entry tied to the account.
 func (r *AccountRepository) CreateIncome(ctx context.Context, tx *gorm.DB, income *models.Income) error {
-	if err := tx.WithContext(ctx).Create(income).Error; err != nil {
+	if err := tx.WithContext(ctx).Omit("Tags", "Splits").Create(income).Error; err != nil {
 		return translateError(err)
 	}
 	return nil
@@ -75,38 +244,293 @@ func (r *AccountRepository) CreateIncome(ctx context.Context, tx *gorm.DB, incom
 
 // CreateExpense records a new expense entry tied to the account.
 func (r *AccountRepository) CreateExpense(ctx context.Context, tx *gorm.DB, expense *models.Expense) error {
-	if err := tx.WithContext(ctx).Create(expense).Error; err != nil {
+	if err := tx.WithContext(ctx).Omit("Tags", "Splits").Create(expense).Error; err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+// GetIncomeForUser returns an income only if it belongs to the given user.
+func (r *AccountRepository) GetIncomeForUser(ctx context.Context, incomeID, userID uint) (*models.Income, error) {
+	var income models.Income
+	err := r.db.WithContext(ctx).
+		Preload("Account").
+		Preload("Tags", preloadTags).
+		Preload("Splits", preloadSplits).
+		Where("id = ? AND user_id = ?", incomeID, userID).
+		First(&income).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &income, nil
+}
+
+// GetExpenseForUser returns an expense only if it belongs to the given user.
+func (r *AccountRepository) GetExpenseForUser(ctx context.Context, expenseID, userID uint) (*models.Expense, error) {
+	var expense models.Expense
+	err := r.db.WithContext(ctx).
+		Preload("Account").
+		Preload("Tags", preloadTags).
+		Preload("Splits", preloadSplits).
+		Where("id = ? AND user_id = ?", expenseID, userID).
+		First(&expense).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &expense, nil
+}
+
+// LockIncomeForUser loads an income of the given user inside tx and locks its row.
+func (r *AccountRepository) LockIncomeForUser(ctx context.Context, tx *gorm.DB, incomeID, userID uint) (*models.Income, error) {
+	var income models.Income
+	if err := lockOwnedRow(ctx, tx, "incomes", &income, incomeID, userID); err != nil {
+		return nil, err
+	}
+	return &income, nil
+}
+
+// LockExpenseForUser loads an expense of the given user inside tx and locks its row.
+func (r *AccountRepository) LockExpenseForUser(ctx context.Context, tx *gorm.DB, expenseID, userID uint) (*models.Expense, error) {
+	var expense models.Expense
+	if err := lockOwnedRow(ctx, tx, "expenses", &expense, expenseID, userID); err != nil {
+		return nil, err
+	}
+	return &expense, nil
+}
+
+// UpdateIncomeFields applies column updates to an income of the given user.
+func (r *AccountRepository) UpdateIncomeFields(ctx context.Context, tx *gorm.DB, incomeID, userID uint, fields map[string]any) error {
+	return updateOwnedFields(ctx, tx, &models.Income{}, incomeID, userID, fields)
+}
+
+// UpdateExpenseFields applies column updates to an expense of the given user.
+func (r *AccountRepository) UpdateExpenseFields(ctx context.Context, tx *gorm.DB, expenseID, userID uint, fields map[string]any) error {
+	return updateOwnedFields(ctx, tx, &models.Expense{}, expenseID, userID, fields)
+}
+
+// DeleteIncome removes an income of the given user.
+func (r *AccountRepository) DeleteIncome(ctx context.Context, tx *gorm.DB, incomeID, userID uint) error {
+	return deleteOwned(ctx, tx, &models.Income{}, incomeID, userID)
+}
+
+// DeleteExpense removes an expense of the given user.
+func (r *AccountRepository) DeleteExpense(ctx context.Context, tx *gorm.DB, expenseID, userID uint) error {
+	return deleteOwned(ctx, tx, &models.Expense{}, expenseID, userID)
+}
+
+// SetIncomeSplits replaces the split lines of an income.
+func (r *AccountRepository) SetIncomeSplits(ctx context.Context, tx *gorm.DB, incomeID uint, splits []models.IncomeSplit) error {
+	if err := tx.WithContext(ctx).Where("income_id = ?", incomeID).Delete(&models.IncomeSplit{}).Error; err != nil {
+		return translateError(err)
+	}
+	if len(splits) == 0 {
+		return nil
+	}
+	for i := range splits {
+		splits[i].ID, splits[i].IncomeID = 0, incomeID
+	}
+	if err := tx.WithContext(ctx).Create(&splits).Error; err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+// SetExpenseSplits replaces the split lines of an expense.
+func (r *AccountRepository) SetExpenseSplits(ctx context.Context, tx *gorm.DB, expenseID uint, splits []models.ExpenseSplit) error {
+	if err := tx.WithContext(ctx).Where("expense_id = ?", expenseID).Delete(&models.ExpenseSplit{}).Error; err != nil {
+		return translateError(err)
+	}
+	if len(splits) == 0 {
+		return nil
+	}
+	for i := range splits {
+		splits[i].ID, splits[i].ExpenseID = 0, expenseID
+	}
+	if err := tx.WithContext(ctx).Create(&splits).Error; err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+// CountIncomeSplits returns how many split lines an income has.
+func (r *AccountRepository) CountIncomeSplits(ctx context.Context, tx *gorm.DB, incomeID uint) (int64, error) {
+	var count int64
+	if err := tx.WithContext(ctx).Model(&models.IncomeSplit{}).Where("income_id = ?", incomeID).Count(&count).Error; err != nil {
+		return 0, translateError(err)
+	}
+	return count, nil
+}
+
+// CountExpenseSplits returns how many split lines an expense has.
+func (r *AccountRepository) CountExpenseSplits(ctx context.Context, tx *gorm.DB, expenseID uint) (int64, error) {
+	var count int64
+	if err := tx.WithContext(ctx).Model(&models.ExpenseSplit{}).Where("expense_id = ?", expenseID).Count(&count).Error; err != nil {
+		return 0, translateError(err)
+	}
+	return count, nil
+}
+
+// preloadSplits loads the split lines of incomes or expenses in their entered order.
+func preloadSplits(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC")
+}
+
+func updateOwnedFields(ctx context.Context, tx *gorm.DB, model any, id, userID uint, fields map[string]any) error {
+	fields["version"] = nextVersion
+	result := tx.WithContext(ctx).Model(model).
+		Where("id = ? AND user_id = ?", id, userID).
+		Updates(fields)
+	if err := result.Error; err != nil {
+		return translateError(err)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func deleteOwned(ctx context.Context, tx *gorm.DB, model any, id, userID uint) error {
+	result := tx.WithContext(ctx).
+		Where("id = ? AND user_id = ?", id, userID).
+		Delete(model)
+	if err := result.Error; err != nil {
 		return translateError(err)
 	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
 	return nil
 }
 
-// ListIncomes retrieves incomes for an account ordered by most recent.
-func (r *AccountRepository) ListIncomes(ctx context.Context, accountID uint, limit int) ([]models.Income, error) {
+// ListIncomes retrieves the user's incomes matching filter that follow cursor in the
+// filter's sort order, one more than the page size.
+func (r *AccountRepository) ListIncomes(ctx context.Context, userID uint, filter TransactionFilter, cursor *pageCursor) ([]models.Income, error) {
 	var incomes []models.Income
 	query := r.db.WithContext(ctx).
-		Where("account_id = ?", accountID).
-		Order("received_at DESC")
-	if limit > 0 {
-		query = query.Limit(limit)
-	}
+		Preload("Account").
+		Preload("Tags", preloadTags).
+		Preload("Splits", preloadSplits)
+	query = filter.page(filter.where(query, incomeTable, userID), incomeTable, cursor)
 	if err := query.Find(&incomes).Error; err != nil {
 		return nil, translateError(err)
 	}
 	return incomes, nil
 }
 
-// ListExpenses retrieves expenses for an account ordered by most recent.
-func (r *AccountRepository) ListExpenses(ctx context.Context, accountID uint, limit int) ([]models.Expense, error) {
+// ListExpenses retrieves the user's expenses matching filter that follow cursor in the
+// filter's sort order, one more than the page size.
+func (r *AccountRepository) ListExpenses(ctx context.Context, userID uint, filter TransactionFilter, cursor *pageCursor) ([]models.Expense, error) {
 	var expenses []models.Expense
 	query := r.db.WithContext(ctx).
-		Where("account_id = ?", accountID).
-		Order("incurred_at DESC")
+		Preload("Account").
+		Preload("Tags", preloadTags).
+		Preload("Splits", preloadSplits)
+	query = filter.page(filter.where(query, expenseTable, userID), expenseTable, cursor)
+	if err := query.Find(&expenses).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return expenses, nil
+}
+
+// SumIncomes counts and totals the user's incomes matching filter per currency they
+// were booked in, ignoring its cursor and limit.
+func (r *AccountRepository) SumIncomes(ctx context.Context, userID uint, filter TransactionFilter) ([]TransactionTotal, error) {
+	return sumTransactions(r.db.WithContext(ctx).Model(&models.Income{}), incomeTable, userID, filter)
+}
+
+// SumExpenses counts and totals the user's expenses matching filter per currency they
+// were booked in, ignoring its cursor and limit.
+func (r *AccountRepository) SumExpenses(ctx context.Context, userID uint, filter TransactionFilter) ([]TransactionTotal, error) {
+	return sumTransactions(r.db.WithContext(ctx).Model(&models.Expense{}), expenseTable, userID, filter)
+}
+
+func sumTransactions(query *gorm.DB, table transactionTable, userID uint, filter TransactionFilter) ([]TransactionTotal, error) {
+	var totals []TransactionTotal
+	err := filter.where(query, table, userID).
+		Select(fmt.Sprintf("%s AS currency_iso_code, COUNT(*) AS count, COALESCE(SUM(%s), 0) AS amount_cents", table.column("currency_iso_code"), table.column("amount_cents"))).
+		Group(table.column("currency_iso_code")).
+		Order(table.column("currency_iso_code") + " ASC").
+		Scan(&totals).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return totals, nil
+}
+
+// ListFeed retrieves the user's feed entries matching filter that follow cursor in feed
+// order, one more than the page size.
+func (r *AccountRepository) ListFeed(ctx context.Context, userID uint, filter FeedFilter, cursor *feedCursor) ([]FeedEntry, error) {
+	args := []any{userID, userID, userID, userID, userID}
+	var conditions []string
+	if filter.AccountID != 0 {
+		conditions = append(conditions, "entries.account_id = ?")
+		args = append(args, filter.AccountID)
+	}
+	if len(filter.Types) > 0 {
+		conditions = append(conditions, "entries.entry_type IN ?")
+		args = append(args, filter.Types)
+	}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "entries.occurred_at >= ?")
+		args = append(args, filter.From)
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "entries.occurred_at < ?")
+		args = append(args, filter.To)
+	}
+
+	direction, comparison := "DESC", "<"
+	if filter.Ascending {
+		direction, comparison = "ASC", ">"
+	}
+	if cursor != nil {
+		conditions = append(conditions, fmt.Sprintf("(entries.occurred_at, entries.entry_order, entries.id, entries.leg) %s (?, ?, ?, ?)", comparison))
+		args = append(args, cursor.Time, feedEntryOrder[cursor.Type], cursor.ID, cursor.Leg)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query := fmt.Sprintf(
+		"SELECT entries.*, %s AS balance_currency_iso_code FROM (%s) entries JOIN accounts ON accounts.id = entries.account_id%s"+
+			" ORDER BY entries.occurred_at %[4]s, entries.entry_order %[4]s, entries.id %[4]s, entries.leg %[4]s LIMIT ?",
+		feedBalanceCurrencySQL, feedEntriesSQL, where, direction,
+	)
+	args = append(args, filter.Limit+1)
+
+	var entries []FeedEntry
+	if err := r.db.WithContext(ctx).Raw(query, args...).Scan(&entries).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return entries, nil
+}
+
+// CreateTransfer records a transfer between two accounts.
+func (r *AccountRepository) CreateTransfer(ctx context.Context, tx *gorm.DB, transfer *models.Transfer) error {
+	if err := tx.WithContext(ctx).Create(transfer).Error; err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+// ListTransfers retrieves a user's transfers ordered by most recent. A non-zero
+// accountID restricts the list to transfers into or out of that account.
+func (r *AccountRepository) ListTransfers(ctx context.Context, userID, accountID uint, limit int) ([]models.Transfer, error) {
+	var transfers []models.Transfer
+	query := r.db.WithContext(ctx).
+		Preload("FromAccount").
+		Preload("ToAccount").
+		Where("user_id = ?", userID).
+		Order("transferred_at DESC")
+	if accountID != 0 {
+		query = query.Where("from_account_id = ? OR to_account_id = ?", accountID, accountID)
+	}
 	if limit > 0 {
 		query = query.Limit(limit)
 	}
-	if err := query.Find(&expenses).Error; err != nil {
+	if err := query.Find(&transfers).Error; err != nil {
 		return nil, translateError(err)
 	}
-	return expenses, nil
+	return transfers, nil
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math/big"
 	"strings"
 	"time"
 
@@ -16,118 +17,963 @@ import (
 type AccountService struct {
 	db                   *gorm.DB
 	accounts             *AccountRepository
+	categories           *CategoryRepository
+	sources              *IncomeSourceRepository
+	tags                 *TagRepository
+	snapshots            *BalanceSnapshotRepository
 	users                *UserRepository
+	ledger               *Ledger
+	rates                ExchangeRateProvider
 	allowNegativeBalance bool
 }
 
+// IncomeUpdate lists the income attributes that may be changed; nil fields are kept.
+// AmountCents is in the currency the income was entered in; a foreign-currency amount is
+// converted again at the rate captured when the income was recorded.
+// The payer is given by SourceID or by name in Source, as in CreditIncome. A CategoryID of
+// zero removes the income from its category. Tags and Splits, when set, replace the
+// income's tags and split lines; an empty list removes them. The amount of a split income
+// can only be changed together with its splits.
+type IncomeUpdate struct {
+	AmountCents *int64
+	Source      *string
+	SourceID    *uint
+	ReceivedAt  *time.Time
+	Notes       *string
+	CategoryID  *uint
+	Tags        *[]string
+	Splits      *[]models.IncomeSplit
+}
+
+// ExpenseUpdate lists the expense attributes that may be changed; nil fields are kept.
+// AmountCents is in the currency the expense was entered in; a foreign-currency amount is
+// converted again at the rate captured when the expense was recorded.
+// The category is given either by CategoryID or, for a category that may not exist yet,
+// by name in Category; CategoryID wins when both are set. Tags and Splits, when set,
+// replace the expense's tags and split lines as in IncomeUpdate.
+type ExpenseUpdate struct {
+	AmountCents *int64
+	Category    *string
+	CategoryID  *uint
+	IncurredAt  *time.Time
+	Description *string
+	Tags        *[]string
+	Splits      *[]models.ExpenseSplit
+}
+
+// AccountUpdate lists the account attributes that may be changed; nil fields are kept.
+type AccountUpdate struct {
+	Name      *string
+	Type      *string
+	IsDefault *bool
+}
+
 func NewAccountService(db *gorm.DB, allowNegativeBalance bool) *AccountService {
 	return &AccountService{
 		db:                   db,
 		accounts:             NewAccountRepository(db),
+		categories:           NewCategoryRepository(db),
+		sources:              NewIncomeSourceRepository(db),
+		tags:                 NewTagRepository(db),
+		snapshots:            NewBalanceSnapshotRepository(db),
 		users:                NewUserRepository(db),
+		ledger:               NewLedger(db),
 		allowNegativeBalance: allowNegativeBalance,
 	}
 }
 
-// CreditIncome credits an income amount to a user's account.
-func (s *AccountService) CreditIncome(ctx context.Context, userID uint, income *models.Income) (*models.Income, int64, error) {
-	if income.AmountCents <= 0 {
-		return nil, 0, fmt.Errorf("%w: income amount must be positive", ErrPreconditionFailed)
+// CreditIncome credits an income amount to one of the user's accounts. The payer is given
+// by SourceID or by name in Source, which is added to the user's income sources on first
+// use. The income is filed under the income category given by CategoryID, or else under
+// the source's default category. An income paid in
+// another currency carries OriginalAmountCents and OriginalCurrency instead of AmountCents
+// and is converted at its ExchangeRate or, when that is empty, at the provider's rate for
+// the receipt date. Splits, when given, divide the income across income categories; their
+// amounts are in the same currency as the income's and must add up to it.
+func (s *AccountService) CreditIncome(ctx context.Context, userID, accountID uint, income *models.Income) (*models.Income, int64, error) {
+	if err := checkEnteredAmount("income", income.AmountCents, income.OriginalAmountCents, &income.OriginalCurrency, income.ExchangeRate); err != nil {
+		return nil, 0, err
+	}
+	lines := income.Splits
+	if err := checkSplits("income", incomeSplitAmounts(lines), enteredTotal(income.AmountCents, income.OriginalAmountCents, income.OriginalCurrency)); err != nil {
+		return nil, 0, err
 	}
 	if income.ReceivedAt.IsZero() {
 		income.ReceivedAt = time.Now().UTC()
 	}
 	income.UserID = userID
+	tags, err := tagNames(models.TagNames(income.Tags))
+	if err != nil {
+		return nil, 0, err
+	}
+	sourceID, sourceName, categoryID := derefID(income.SourceID), income.Source, income.CategoryID
 
-	var updatedBalance int64
+	var (
+		account        *models.Account
+		updatedBalance int64
+	)
 
-	err := WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
-		account, err := s.accounts.GetByUserID(ctx, userID)
+	err = WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		income.ID = 0 // the transaction may be retried
+
+		locked, err := s.accounts.LockByIDForUser(ctx, tx, accountID, userID)
 		if err != nil {
 			return err
 		}
+		account = locked
 		income.AccountID = account.ID
+		income.CurrencyISOCode = account.CurrencyISOCode
+
+		err = s.convertEnteredAmount(ctx, account, income.ReceivedAt, &income.AmountCents, &income.OriginalAmountCents, &income.OriginalCurrency, &income.ExchangeRate)
+		if err != nil {
+			return err
+		}
+		source, err := resolveIncomeSource(ctx, tx, s.sources, userID, sourceID, sourceName)
+		if err != nil {
+			return err
+		}
+		income.SourceID, income.Source = &source.ID, source.Name
+		income.CategoryID = categoryID
+		if income.CategoryID == nil {
+			income.CategoryID = source.DefaultCategoryID
+		} else if _, err := resolveCategory(ctx, tx, s.categories, userID, models.CategoryTypeIncome, *income.CategoryID, ""); err != nil {
+			return err
+		}
+		if income.Splits, err = s.resolveIncomeSplits(ctx, tx, userID, income, lines); err != nil {
+			return err
+		}
 
 		if err := s.accounts.CreateIncome(ctx, tx, income); err != nil {
 			return err
 		}
+		if err := s.accounts.SetIncomeSplits(ctx, tx, income.ID, income.Splits); err != nil {
+			return err
+		}
+		if income.Tags, err = s.tags.FindOrCreate(ctx, tx, userID, tags); err != nil {
+			return err
+		}
+		if err := s.tags.SetIncomeTags(ctx, tx, income.ID, income.Tags); err != nil {
+			return err
+		}
 
-		updatedBalance, err = s.accounts.AdjustBalance(ctx, tx, account.ID, income.AmountCents)
+		balances, err := s.post(ctx, tx, incomeJournal(account, income))
 		if err != nil {
 			return err
 		}
-		return nil
+		updatedBalance = balances[account.ID]
+		return s.invalidateSnapshots(ctx, tx, account.ID, income.ReceivedAt)
 	})
 	if err != nil {
 		return nil, 0, err
 	}
 
+	income.Account = account
 	return income, updatedBalance, nil
 }
 
-// DebitExpense debits an expense amount from a user's account, respecting overdraft policy.
-func (s *AccountService) DebitExpense(ctx context.Context, userID uint, expense *models.Expense) (*models.Expense, int64, error) {
-	if expense.AmountCents <= 0 {
-		return nil, 0, fmt.Errorf("%w: expense amount must be positive", ErrPreconditionFailed)
+// DebitExpense debits an expense amount from one of the user's accounts, respecting overdraft policy.
+// The expense is filed under the expense category given by CategoryID or, when that is nil,
+// the one named by Category, which is added to the catalogue on first use.
+// Foreign-currency expenses are converted like incomes in CreditIncome. Splits, when given,
+// divide the expense across expense categories as in CreditIncome; a split expense without
+// a category of its own is filed under the category of its largest line.
+func (s *AccountService) DebitExpense(ctx context.Context, userID, accountID uint, expense *models.Expense) (*models.Expense, int64, error) {
+	if err := checkEnteredAmount("expense", expense.AmountCents, expense.OriginalAmountCents, &expense.OriginalCurrency, expense.ExchangeRate); err != nil {
+		return nil, 0, err
+	}
+	lines := expense.Splits
+	if err := checkSplits("expense", expenseSplitAmounts(lines), enteredTotal(expense.AmountCents, expense.OriginalAmountCents, expense.OriginalCurrency)); err != nil {
+		return nil, 0, err
 	}
 	if expense.IncurredAt.IsZero() {
 		expense.IncurredAt = time.Now().UTC()
 	}
 	expense.UserID = userID
-	var updatedBalance int64
+	tags, err := tagNames(models.TagNames(expense.Tags))
+	if err != nil {
+		return nil, 0, err
+	}
+	categoryID, categoryName := derefID(expense.CategoryID), expense.Category
 
-	err := WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
-		account, err := s.accounts.GetByUserID(ctx, userID)
+	var (
+		account        *models.Account
+		updatedBalance int64
+	)
+
+	err = WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		expense.ID = 0 // the transaction may be retried
+
+		locked, err := s.accounts.LockByIDForUser(ctx, tx, accountID, userID)
 		if err != nil {
 			return err
 		}
+		account = locked
 		expense.AccountID = account.ID
+		expense.CurrencyISOCode = account.CurrencyISOCode
 
-		delta := -expense.AmountCents
-
-		newBalance, err := s.accounts.AdjustBalance(ctx, tx, account.ID, delta)
+		err = s.convertEnteredAmount(ctx, account, expense.IncurredAt, &expense.AmountCents, &expense.OriginalAmountCents, &expense.OriginalCurrency, &expense.ExchangeRate)
 		if err != nil {
 			return err
 		}
-
-		if !s.allowNegativeBalance && newBalance < 0 {
-			return ErrInsufficientFunds
+		if expense.Splits, err = s.resolveExpenseSplits(ctx, tx, userID, expense, lines); err != nil {
+			return err
+		}
+		if categoryID == 0 && categoryName == "" && len(expense.Splits) > 0 {
+			largest := expense.Splits[largestSplit(expense.Splits)]
+			expense.CategoryID, expense.Category = largest.CategoryID, largest.Category
+		} else {
+			category, err := resolveCategory(ctx, tx, s.categories, userID, models.CategoryTypeExpense, categoryID, categoryName)
+			if err != nil {
+				return err
+			}
+			expense.CategoryID, expense.Category = &category.ID, category.Name
 		}
 
 		if err := s.accounts.CreateExpense(ctx, tx, expense); err != nil {
 			return err
 		}
+		if err := s.accounts.SetExpenseSplits(ctx, tx, expense.ID, expense.Splits); err != nil {
+			return err
+		}
+		if expense.Tags, err = s.tags.FindOrCreate(ctx, tx, userID, tags); err != nil {
+			return err
+		}
+		if err := s.tags.SetExpenseTags(ctx, tx, expense.ID, expense.Tags); err != nil {
+			return err
+		}
 
-		updatedBalance = newBalance
-		return nil
+		balances, err := s.post(ctx, tx, expenseJournal(account, expense))
+		if err != nil {
+			return err
+		}
+		updatedBalance = balances[account.ID]
+		return s.invalidateSnapshots(ctx, tx, account.ID, expense.IncurredAt)
 	})
 	if err != nil {
 		return nil, 0, err
 	}
 
+	expense.Account = account
 	return expense, updatedBalance, nil
 }
 
-// SetDefaultCurrency updates a user's default currency.
-func (s *AccountService) SetDefaultCurrency(ctx context.Context, userID uint, currency string) error {
-	currency = strings.ToUpper(currency)
-	err := s.db.WithContext(ctx).Model(&models.User{}).
-		Where("id = ?", userID).
-		Update("default_currency", currency).Error
+// GetIncome fetches an income, ensuring it belongs to the user.
+func (s *AccountService) GetIncome(ctx context.Context, userID, incomeID uint) (*models.Income, error) {
+	return s.accounts.GetIncomeForUser(ctx, incomeID, userID)
+}
+
+// GetExpense fetches an expense, ensuring it belongs to the user.
+func (s *AccountService) GetExpense(ctx context.Context, userID, expenseID uint) (*models.Expense, error) {
+	return s.accounts.GetExpenseForUser(ctx, expenseID, userID)
+}
+
+// UpdateIncome edits an income. An amount change is posted to the ledger as a correction
+// and applied to the account balance in the same transaction; lowering the amount is
+// subject to the overdraft policy. The amount, date and splits of an income recorded
+// before its account was converted to another currency can no longer be changed. A
+// non-zero ifVersion must match the income's version. It returns the income and the
+// resulting balance.
+func (s *AccountService) UpdateIncome(ctx context.Context, userID, incomeID, ifVersion uint, update IncomeUpdate) (*models.Income, int64, error) {
+	fields := make(map[string]any)
+	if update.AmountCents != nil && *update.AmountCents <= 0 {
+		return nil, 0, fmt.Errorf("%w: income amount must be positive", ErrPreconditionFailed)
+	}
+	if update.ReceivedAt != nil {
+		fields["received_at"] = *update.ReceivedAt
+	}
+	if update.Notes != nil {
+		fields["notes"] = *update.Notes
+	}
+	var tags []string
+	if update.Tags != nil {
+		var err error
+		if tags, err = tagNames(*update.Tags); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	var balance int64
+	err := WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		income, err := s.accounts.LockIncomeForUser(ctx, tx, incomeID, userID)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(ifVersion, income.Version); err != nil {
+			return err
+		}
+		account, err := s.accounts.LockByIDForUser(ctx, tx, income.AccountID, userID)
+		if err != nil {
+			return err
+		}
+		balance = account.BalanceCents
+		if update.AmountCents != nil || update.ReceivedAt != nil || update.Splits != nil {
+			if err := checkBookedCurrency("income", income.CurrencyISOCode, account); err != nil {
+				return err
+			}
+		}
+
+		if update.SourceID != nil || update.Source != nil {
+			var name string
+			if update.Source != nil {
+				name = *update.Source
+			}
+			source, err := resolveIncomeSource(ctx, tx, s.sources, userID, derefID(update.SourceID), name)
+			if err != nil {
+				return err
+			}
+			fields["source_id"], fields["source"] = source.ID, source.Name
+		}
+		if update.CategoryID != nil {
+			if *update.CategoryID == 0 {
+				fields["category_id"] = nil
+			} else {
+				if _, err := resolveCategory(ctx, tx, s.categories, userID, models.CategoryTypeIncome, *update.CategoryID, ""); err != nil {
+					return err
+				}
+				fields["category_id"] = *update.CategoryID
+			}
+		}
+
+		amountCents := income.AmountCents
+		if update.AmountCents != nil {
+			if amountCents, err = reconvertAmount(account, *update.AmountCents, income.OriginalCurrency, income.ExchangeRate); err != nil {
+				return err
+			}
+			if income.OriginalCurrency != "" {
+				fields["original_amount_cents"] = *update.AmountCents
+			}
+		}
+
+		if update.Splits != nil {
+			converted := *income
+			converted.AmountCents = amountCents
+			if update.AmountCents != nil && income.OriginalCurrency != "" {
+				converted.OriginalAmountCents = *update.AmountCents
+			}
+			total := enteredTotal(converted.AmountCents, converted.OriginalAmountCents, converted.OriginalCurrency)
+			if err := checkSplits("income", incomeSplitAmounts(*update.Splits), total); err != nil {
+				return err
+			}
+			splits, err := s.resolveIncomeSplits(ctx, tx, userID, &converted, *update.Splits)
+			if err != nil {
+				return err
+			}
+			if err := s.accounts.SetIncomeSplits(ctx, tx, incomeID, splits); err != nil {
+				return err
+			}
+		} else if update.AmountCents != nil {
+			lines, err := s.accounts.CountIncomeSplits(ctx, tx, incomeID)
+			if err != nil {
+				return err
+			}
+			if lines > 0 {
+				return fmt.Errorf("%w: the amount of a split income must be changed together with its splits", ErrPreconditionFailed)
+			}
+		}
+
+		if amountCents != income.AmountCents {
+			delta := amountCents - income.AmountCents
+			fields["amount_cents"] = amountCents
+
+			balances, err := s.post(ctx, tx, incomeCorrectionJournal(account, income, delta, time.Now().UTC(), "income amount corrected"))
+			if err != nil {
+				return err
+			}
+			balance = balances[account.ID]
+		}
+
+		if amountCents != income.AmountCents || update.ReceivedAt != nil {
+			dates := []time.Time{income.ReceivedAt}
+			if update.ReceivedAt != nil {
+				dates = append(dates, *update.ReceivedAt)
+			}
+			if err := s.invalidateSnapshots(ctx, tx, account.ID, dates...); err != nil {
+				return err
+			}
+		}
+
+		if update.Tags != nil {
+			linked, err := s.tags.FindOrCreate(ctx, tx, userID, tags)
+			if err != nil {
+				return err
+			}
+			if err := s.tags.SetIncomeTags(ctx, tx, incomeID, linked); err != nil {
+				return err
+			}
+		}
+
+		if len(fields) == 0 && update.Tags == nil && update.Splits == nil {
+			return nil
+		}
+		return s.accounts.UpdateIncomeFields(ctx, tx, incomeID, userID, fields)
+	})
 	if err != nil {
-		return translateError(err)
+		return nil, 0, err
 	}
 
-	if err := s.db.WithContext(ctx).Model(&models.Account{}).
-		Where("user_id = ?", userID).
-		Update("currency_iso_code", currency).Error; err != nil {
-		return translateError(err)
+	income, err := s.accounts.GetIncomeForUser(ctx, incomeID, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return income, balance, nil
+}
+
+// DeleteIncome removes an income and reverses its amount from the account balance,
+// subject to the overdraft policy. Incomes recorded before their account was converted to
+// another currency cannot be deleted. A non-zero ifVersion must match the income's
+// version. It returns the resulting balance.
+func (s *AccountService) DeleteIncome(ctx context.Context, userID, incomeID, ifVersion uint) (int64, error) {
+	var balance int64
+	err := WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		income, err := s.accounts.LockIncomeForUser(ctx, tx, incomeID, userID)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(ifVersion, income.Version); err != nil {
+			return err
+		}
+		account, err := s.accounts.LockByIDForUser(ctx, tx, income.AccountID, userID)
+		if err != nil {
+			return err
+		}
+		if err := checkBookedCurrency("income", income.CurrencyISOCode, account); err != nil {
+			return err
+		}
+
+		balances, err := s.post(ctx, tx, incomeCorrectionJournal(account, income, -income.AmountCents, time.Now().UTC(), "income deleted"))
+		if err != nil {
+			return err
+		}
+		balance = balances[account.ID]
+
+		if err := s.invalidateSnapshots(ctx, tx, account.ID, income.ReceivedAt); err != nil {
+			return err
+		}
+		return s.accounts.DeleteIncome(ctx, tx, incomeID, userID)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return balance, nil
+}
+
+// UpdateExpense edits an expense. An amount change is posted to the ledger as a correction
+// and applied to the account balance in the same transaction; raising the amount is
+// subject to the overdraft policy. As in UpdateIncome, only the description, category and
+// tags of an expense recorded before a currency conversion can be changed. A non-zero
+// ifVersion must match the expense's version. It returns the expense and the resulting
+// balance.
+func (s *AccountService) UpdateExpense(ctx context.Context, userID, expenseID, ifVersion uint, update ExpenseUpdate) (*models.Expense, int64, error) {
+	fields := make(map[string]any)
+	if update.AmountCents != nil && *update.AmountCents <= 0 {
+		return nil, 0, fmt.Errorf("%w: expense amount must be positive", ErrPreconditionFailed)
+	}
+	if update.IncurredAt != nil {
+		fields["incurred_at"] = *update.IncurredAt
+	}
+	if update.Description != nil {
+		fields["description"] = *update.Description
+	}
+	var tags []string
+	if update.Tags != nil {
+		var err error
+		if tags, err = tagNames(*update.Tags); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	var balance int64
+	err := WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		expense, err := s.accounts.LockExpenseForUser(ctx, tx, expenseID, userID)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(ifVersion, expense.Version); err != nil {
+			return err
+		}
+		account, err := s.accounts.LockByIDForUser(ctx, tx, expense.AccountID, userID)
+		if err != nil {
+			return err
+		}
+		balance = account.BalanceCents
+		if update.AmountCents != nil || update.IncurredAt != nil || update.Splits != nil {
+			if err := checkBookedCurrency("expense", expense.CurrencyISOCode, account); err != nil {
+				return err
+			}
+		}
+
+		if update.CategoryID != nil || update.Category != nil {
+			var name string
+			if update.Category != nil {
+				name = *update.Category
+			}
+			category, err := resolveCategory(ctx, tx, s.categories, userID, models.CategoryTypeExpense, derefID(update.CategoryID), name)
+			if err != nil {
+				return err
+			}
+			fields["category_id"], fields["category"] = category.ID, category.Name
+		}
+
+		amountCents := expense.AmountCents
+		if update.AmountCents != nil {
+			if amountCents, err = reconvertAmount(account, *update.AmountCents, expense.OriginalCurrency, expense.ExchangeRate); err != nil {
+				return err
+			}
+			if expense.OriginalCurrency != "" {
+				fields["original_amount_cents"] = *update.AmountCents
+			}
+		}
+
+		if update.Splits != nil {
+			converted := *expense
+			converted.AmountCents = amountCents
+			if update.AmountCents != nil && expense.OriginalCurrency != "" {
+				converted.OriginalAmountCents = *update.AmountCents
+			}
+			total := enteredTotal(converted.AmountCents, converted.OriginalAmountCents, converted.OriginalCurrency)
+			if err := checkSplits("expense", expenseSplitAmounts(*update.Splits), total); err != nil {
+				return err
+			}
+			splits, err := s.resolveExpenseSplits(ctx, tx, userID, &converted, *update.Splits)
+			if err != nil {
+				return err
+			}
+			if err := s.accounts.SetExpenseSplits(ctx, tx, expenseID, splits); err != nil {
+				return err
+			}
+		} else if update.AmountCents != nil {
+			lines, err := s.accounts.CountExpenseSplits(ctx, tx, expenseID)
+			if err != nil {
+				return err
+			}
+			if lines > 0 {
+				return fmt.Errorf("%w: the amount of a split expense must be changed together with its splits", ErrPreconditionFailed)
+			}
+		}
+
+		if amountCents != expense.AmountCents {
+			delta := amountCents - expense.AmountCents
+			fields["amount_cents"] = amountCents
+
+			balances, err := s.post(ctx, tx, expenseCorrectionJournal(account, expense, delta, time.Now().UTC(), "expense amount corrected"))
+			if err != nil {
+				return err
+			}
+			balance = balances[account.ID]
+		}
+
+		if amountCents != expense.AmountCents || update.IncurredAt != nil {
+			dates := []time.Time{expense.IncurredAt}
+			if update.IncurredAt != nil {
+				dates = append(dates, *update.IncurredAt)
+			}
+			if err := s.invalidateSnapshots(ctx, tx, account.ID, dates...); err != nil {
+				return err
+			}
+		}
+
+		if update.Tags != nil {
+			linked, err := s.tags.FindOrCreate(ctx, tx, userID, tags)
+			if err != nil {
+				return err
+			}
+			if err := s.tags.SetExpenseTags(ctx, tx, expenseID, linked); err != nil {
+				return err
+			}
+		}
+
+		if len(fields) == 0 && update.Tags == nil && update.Splits == nil {
+			return nil
+		}
+		return s.accounts.UpdateExpenseFields(ctx, tx, expenseID, userID, fields)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	expense, err := s.accounts.GetExpenseForUser(ctx, expenseID, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return expense, balance, nil
+}
+
+// DeleteExpense removes an expense and refunds its amount to the account balance.
+// Expenses recorded before their account was converted to another currency cannot be
+// deleted. A non-zero ifVersion must match the expense's version. It returns the
+// resulting balance.
+func (s *AccountService) DeleteExpense(ctx context.Context, userID, expenseID, ifVersion uint) (int64, error) {
+	var balance int64
+	err := WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		expense, err := s.accounts.LockExpenseForUser(ctx, tx, expenseID, userID)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(ifVersion, expense.Version); err != nil {
+			return err
+		}
+		account, err := s.accounts.LockByIDForUser(ctx, tx, expense.AccountID, userID)
+		if err != nil {
+			return err
+		}
+		if err := checkBookedCurrency("expense", expense.CurrencyISOCode, account); err != nil {
+			return err
+		}
+
+		balances, err := s.post(ctx, tx, expenseCorrectionJournal(account, expense, -expense.AmountCents, time.Now().UTC(), "expense deleted"))
+		if err != nil {
+			return err
+		}
+		balance = balances[account.ID]
+
+		if err := s.invalidateSnapshots(ctx, tx, account.ID, expense.IncurredAt); err != nil {
+			return err
+		}
+		return s.accounts.DeleteExpense(ctx, tx, expenseID, userID)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return balance, nil
+}
+
+// Transfer moves money between two of the user's accounts in a single transaction.
+// Transfers between accounts in different currencies use transfer.ExchangeRate, expressed
+// as destination currency units per source currency unit; when it is empty the rate in
+// effect on the transfer date is taken from the configured ExchangeRateProvider.
+// It returns the transfer along with the resulting source and destination balances.
+func (s *AccountService) Transfer(ctx context.Context, userID uint, transfer *models.Transfer) (*models.Transfer, int64, int64, error) {
+	if transfer.AmountCents <= 0 {
+		return nil, 0, 0, fmt.Errorf("%w: transfer amount must be positive", ErrPreconditionFailed)
+	}
+	if transfer.FromAccountID == transfer.ToAccountID {
+		return nil, 0, 0, fmt.Errorf("%w: source and destination accounts must differ", ErrPreconditionFailed)
+	}
+	if transfer.TransferredAt.IsZero() {
+		transfer.TransferredAt = time.Now().UTC()
+	}
+	transfer.UserID = userID
+
+	var (
+		fromAccount, toAccount *models.Account
+		fromBalance, toBalance int64
+	)
+
+	err := WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		transfer.ID = 0 // the transaction may be retried
+
+		from, to, err := s.lockAccountPair(ctx, tx, userID, transfer.FromAccountID, transfer.ToAccountID)
+		if err != nil {
+			return err
+		}
+		fromAccount, toAccount = from, to
+		transfer.FromCurrencyISOCode, transfer.ToCurrencyISOCode = from.CurrencyISOCode, to.CurrencyISOCode
+
+		if from.CurrencyISOCode == to.CurrencyISOCode {
+			if transfer.ExchangeRate != "" && transfer.ExchangeRate != "1" {
+				return fmt.Errorf("%w: exchange rate is only allowed between different currencies", ErrPreconditionFailed)
+			}
+			transfer.ExchangeRate = "1"
+			transfer.ToAmountCents = transfer.AmountCents
+		} else {
+			var rate *big.Rat
+			if transfer.ExchangeRate == "" {
+				if rate, err = s.exchangeRate(ctx, from.CurrencyISOCode, to.CurrencyISOCode, transfer.TransferredAt); err != nil {
+					return err
+				}
+				transfer.ExchangeRate = FormatRate(rate)
+			} else if rate, err = ParseExchangeRate(transfer.ExchangeRate); err != nil {
+				return fmt.Errorf("%w (%s to %s)", err, from.CurrencyISOCode, to.CurrencyISOCode)
+			}
+			transfer.ToAmountCents = ConvertMinorUnits(transfer.AmountCents, rate, from.CurrencyISOCode, to.CurrencyISOCode)
+			if transfer.ToAmountCents <= 0 {
+				return fmt.Errorf("%w: converted amount rounds to zero", ErrPreconditionFailed)
+			}
+		}
+
+		if err := s.accounts.CreateTransfer(ctx, tx, transfer); err != nil {
+			return err
+		}
+
+		balances, err := s.post(ctx, tx, transferJournal(from, to, transfer))
+		if err != nil {
+			return err
+		}
+		fromBalance, toBalance = balances[from.ID], balances[to.ID]
+
+		if err := s.invalidateSnapshots(ctx, tx, from.ID, transfer.TransferredAt); err != nil {
+			return err
+		}
+		return s.invalidateSnapshots(ctx, tx, to.ID, transfer.TransferredAt)
+	})
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	transfer.FromAccount, transfer.ToAccount = fromAccount, toAccount
+	return transfer, fromBalance, toBalance, nil
+}
+
+// lockAccountPair locks two accounts of the user in ascending ID order, so concurrent
+// transfers in opposite directions cannot deadlock.
+func (s *AccountService) lockAccountPair(ctx context.Context, tx *gorm.DB, userID, fromID, toID uint) (*models.Account, *models.Account, error) {
+	firstID, secondID := fromID, toID
+	if secondID < firstID {
+		firstID, secondID = secondID, firstID
+	}
+
+	first, err := s.accounts.LockByIDForUser(ctx, tx, firstID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	second, err := s.accounts.LockByIDForUser(ctx, tx, secondID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if first.ID == fromID {
+		return first, second, nil
+	}
+	return second, first, nil
+}
+
+// checkBookedCurrency refuses balance corrections for a transaction recorded in another
+// currency than the account's, which happens when the account was converted after the
+// transaction was recorded: its amount can no longer be applied to the balance, and its
+// place in the balance history is fixed relative to the conversion.
+func checkBookedCurrency(kind, booked string, account *models.Account) error {
+	if booked == "" || booked == account.CurrencyISOCode {
+		return nil
+	}
+	return fmt.Errorf("%w: the %s was recorded in %s before the account was converted to %s; only its description, category and tags can still be changed",
+		ErrPreconditionFailed, kind, booked, account.CurrencyISOCode)
+}
+
+// post records a journal, enforcing the overdraft policy on every entry that lowers an
+// account balance.
+func (s *AccountService) post(ctx context.Context, tx *gorm.DB, journal *models.LedgerJournal) (map[uint]int64, error) {
+	if s.allowNegativeBalance {
+		return s.ledger.Post(ctx, tx, journal)
+	}
+	return s.ledger.PostAboveFloor(ctx, tx, journal, 0)
+}
+
+// CurrencyChange describes a change of the user's default currency. Without Convert the
+// change is refused while the default account holds a non-zero balance. With Convert the
+// balance is converted at Rate (new currency units per old unit) or, when Rate is empty,
+// at the rate reported by the configured ExchangeRateProvider.
+type CurrencyChange struct {
+	Currency string
+	Convert  bool
+	Rate     string
+}
+
+// UseExchangeRates sets the source consulted for currency conversions without an explicit rate.
+func (s *AccountService) UseExchangeRates(rates ExchangeRateProvider) {
+	s.rates = rates
+}
+
+// SetDefaultCurrency updates a user's default currency and that of the default account.
+// It is refused while the default account holds a non-zero balance.
+func (s *AccountService) SetDefaultCurrency(ctx context.Context, userID uint, code string) error {
+	_, _, err := s.ChangeDefaultCurrency(ctx, userID, CurrencyChange{Currency: code})
+	return err
+}
+
+// ChangeDefaultCurrency updates a user's default currency and relabels the default account.
+// A non-zero balance is converted into the new currency and recorded as a
+// CurrencyConversion with a matching conversion journal, so the ledger and the balance
+// integrity check stay consistent. It returns the updated user and default account.
+func (s *AccountService) ChangeDefaultCurrency(ctx context.Context, userID uint, change CurrencyChange) (*models.User, *models.Account, error) {
+	code, err := normalizeCurrency(change.Currency)
+	if err != nil {
+		return nil, nil, err
+	}
+	var explicitRate *big.Rat
+	if change.Rate != "" {
+		if !change.Convert {
+			return nil, nil, fmt.Errorf("%w: a rate is only used when converting the balance", ErrPreconditionFailed)
+		}
+		if explicitRate, err = ParseExchangeRate(change.Rate); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	current, err := s.accounts.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		account, err := s.accounts.LockByIDForUser(ctx, tx, current.ID, userID)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.WithContext(ctx).Model(&models.User{}).
+			Where("id = ?", userID).
+			Update("default_currency", code).Error; err != nil {
+			return translateError(err)
+		}
+		if account.CurrencyISOCode == code {
+			return nil
+		}
+
+		if account.BalanceCents != 0 {
+			if !change.Convert {
+				return fmt.Errorf("%w: the default account balance must be zero to change its currency without conversion", ErrPreconditionFailed)
+			}
+			if err := s.convertBalance(ctx, tx, account, code, explicitRate); err != nil {
+				return err
+			}
+		}
+
+		return s.accounts.UpdateFields(ctx, tx, account.ID, userID, map[string]any{"currency_iso_code": code})
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	account, err := s.accounts.GetByIDForUser(ctx, current.ID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, account, nil
+}
+
+// checkEnteredAmount validates the amount of a new income or expense before it is
+// converted: a foreign-currency amount needs a supported currency, and a rate is only
+// meaningful together with one.
+func checkEnteredAmount(kind string, amountCents, originalCents int64, originalCurrency *string, rate string) error {
+	if *originalCurrency == "" {
+		if rate != "" {
+			return fmt.Errorf("%w: an exchange rate needs the currency the %s was paid in", ErrPreconditionFailed, kind)
+		}
+		if amountCents <= 0 {
+			return fmt.Errorf("%w: %s amount must be positive", ErrPreconditionFailed, kind)
+		}
+		return nil
+	}
+
+	code, err := normalizeCurrency(*originalCurrency)
+	if err != nil {
+		return err
+	}
+	*originalCurrency = code
+	if originalCents <= 0 {
+		return fmt.Errorf("%w: %s amount must be positive", ErrPreconditionFailed, kind)
 	}
 	return nil
 }
 
-// EnsureAccount ensures an account exists for the given user.
-func (s *AccountService) EnsureAccount(ctx context.Context, userID uint, currency string) (*models.Account, error) {
+// convertEnteredAmount sets amountCents from an amount entered in another currency than
+// the account's, recording the applied rate. An amount entered in the account currency
+// itself is moved to amountCents and the original fields are cleared.
+func (s *AccountService) convertEnteredAmount(ctx context.Context, account *models.Account, at time.Time, amountCents, originalCents *int64, originalCurrency, rate *string) error {
+	if *originalCurrency == "" {
+		return nil
+	}
+	if *originalCurrency == account.CurrencyISOCode {
+		if *rate != "" && *rate != "1" {
+			return fmt.Errorf("%w: exchange rate is only allowed for amounts in another currency than the account's", ErrPreconditionFailed)
+		}
+		*amountCents, *originalCents, *originalCurrency, *rate = *originalCents, 0, "", ""
+		return nil
+	}
+
+	var (
+		parsed *big.Rat
+		err    error
+	)
+	if *rate == "" {
+		if parsed, err = s.exchangeRate(ctx, *originalCurrency, account.CurrencyISOCode, at); err != nil {
+			return err
+		}
+		*rate = FormatRate(parsed)
+	} else if parsed, err = ParseExchangeRate(*rate); err != nil {
+		return fmt.Errorf("%w (%s to %s)", err, *originalCurrency, account.CurrencyISOCode)
+	}
+
+	*amountCents = ConvertMinorUnits(*originalCents, parsed, *originalCurrency, account.CurrencyISOCode)
+	if *amountCents <= 0 {
+		return fmt.Errorf("%w: converted amount rounds to zero", ErrPreconditionFailed)
+	}
+	return nil
+}
+
+// reconvertAmount converts an edited amount, given in the currency it was entered in, into
+// the account currency at the rate captured when the transaction was recorded.
+func reconvertAmount(account *models.Account, units int64, originalCurrency, rate string) (int64, error) {
+	if originalCurrency == "" {
+		return units, nil
+	}
+	parsed, err := ParseExchangeRate(rate)
+	if err != nil {
+		return 0, err
+	}
+	converted := ConvertMinorUnits(units, parsed, originalCurrency, account.CurrencyISOCode)
+	if converted <= 0 {
+		return 0, fmt.Errorf("%w: converted amount rounds to zero", ErrPreconditionFailed)
+	}
+	return converted, nil
+}
+
+// derefID returns the referenced ID, or zero for nil.
+func derefID(id *uint) uint {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
+
+// exchangeRate asks the configured ExchangeRateProvider for a rate.
+func (s *AccountService) exchangeRate(ctx context.Context, from, to string, at time.Time) (*big.Rat, error) {
+	if s.rates == nil {
+		return nil, fmt.Errorf("%w: no rate from %s to %s is configured, pass one explicitly", ErrRateUnavailable, from, to)
+	}
+	return s.rates.Rate(ctx, from, to, at)
+}
+
+// convertBalance converts the balance of a locked account into the given currency and
+// posts the conversion to the ledger.
+func (s *AccountService) convertBalance(ctx context.Context, tx *gorm.DB, account *models.Account, code string, rate *big.Rat) error {
+	now := time.Now().UTC()
+	if rate == nil {
+		var err error
+		if rate, err = s.exchangeRate(ctx, account.CurrencyISOCode, code, now); err != nil {
+			return err
+		}
+	}
+
+	conversion := &models.CurrencyConversion{
+		UserID:          account.UserID,
+		AccountID:       account.ID,
+		FromCurrency:    account.CurrencyISOCode,
+		ToCurrency:      code,
+		Rate:            FormatRate(rate),
+		FromAmountCents: account.BalanceCents,
+		ToAmountCents:   ConvertMinorUnits(account.BalanceCents, rate, account.CurrencyISOCode, code),
+		ConvertedAt:     now,
+	}
+	if conversion.ToAmountCents == 0 {
+		return fmt.Errorf("%w: converted balance rounds to zero", ErrPreconditionFailed)
+	}
+	if err := tx.WithContext(ctx).Create(conversion).Error; err != nil {
+		return translateError(err)
+	}
+
+	if _, err := s.post(ctx, tx, conversionJournal(account, conversion)); err != nil {
+		return err
+	}
+	return s.invalidateSnapshots(ctx, tx, account.ID, conversion.ConvertedAt)
+}
+
+// EnsureAccount ensures a default account exists for the given user.
+func (s *AccountService) EnsureAccount(ctx context.Context, userID uint, code string) (*models.Account, error) {
 	account, err := s.accounts.GetByUserID(ctx, userID)
 	if err == nil {
 		return account, nil
@@ -135,29 +981,269 @@ func (s *AccountService) EnsureAccount(ctx context.Context, userID uint, currenc
 	if !errors.Is(err, ErrNotFound) {
 		return nil, err
 	}
+	code, err = normalizeCurrency(code)
+	if err != nil {
+		return nil, err
+	}
 
 	account = &models.Account{
 		UserID:          userID,
-		CurrencyISOCode: strings.ToUpper(currency),
+		Name:            models.DefaultAccountName,
+		Type:            models.AccountTypeWallet,
+		IsDefault:       true,
+		CurrencyISOCode: code,
 	}
 
-	if err := s.db.WithContext(ctx).Create(account).Error; err != nil {
-		return nil, translateError(err)
+	if err := s.accounts.Create(ctx, s.db, account); err != nil {
+		return nil, err
 	}
 	return account, nil
 }
 
-// GetAccountByUserID fetches an account by user identifier.
+// GetAccountByUserID fetches the default account of a user.
 func (s *AccountService) GetAccountByUserID(ctx context.Context, userID uint) (*models.Account, error) {
 	return s.accounts.GetByUserID(ctx, userID)
 }
 
-// ListIncomes retrieves a slice of income records for the account.
-func (s *AccountService) ListIncomes(ctx context.Context, accountID uint, limit int) ([]models.Income, error) {
-	return s.accounts.ListIncomes(ctx, accountID, limit)
+// GetAccount fetches an account, ensuring it belongs to the user.
+func (s *AccountService) GetAccount(ctx context.Context, userID, accountID uint) (*models.Account, error) {
+	return s.accounts.GetByIDForUser(ctx, accountID, userID)
+}
+
+// ListAccounts returns all accounts owned by the user.
+func (s *AccountService) ListAccounts(ctx context.Context, userID uint) ([]models.Account, error) {
+	return s.accounts.ListByUserID(ctx, userID)
 }
 
-// ListExpenses retrieves a slice of expense records for the account.
-func (s *AccountService) ListExpenses(ctx context.Context, accountID uint, limit int) ([]models.Expense, error) {
-	return s.accounts.ListExpenses(ctx, accountID, limit)
+// CreateAccount opens an additional account for the user. The currency defaults to the
+// user's default currency; the first account of a user always becomes the default one.
+func (s *AccountService) CreateAccount(ctx context.Context, userID uint, account *models.Account) (*models.Account, error) {
+	account.Name = strings.TrimSpace(account.Name)
+	if account.Name == "" {
+		return nil, fmt.Errorf("%w: account name must not be empty", ErrPreconditionFailed)
+	}
+	if account.Type == "" {
+		account.Type = models.AccountTypeWallet
+	}
+	if !models.IsValidAccountType(account.Type) {
+		return nil, fmt.Errorf("%w: unknown account type %q", ErrPreconditionFailed, account.Type)
+	}
+
+	account.UserID = userID
+	account.BalanceCents = 0
+
+	err := WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		// Locking the user serializes account creation, so two concurrent first accounts
+		// cannot both become the default.
+		user, err := s.users.LockByID(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+		if account.CurrencyISOCode == "" {
+			account.CurrencyISOCode = user.DefaultCurrency
+		}
+		if account.CurrencyISOCode, err = normalizeCurrency(account.CurrencyISOCode); err != nil {
+			return err
+		}
+
+		existing, err := s.accounts.CountByUserID(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+		if existing == 0 {
+			account.IsDefault = true
+		}
+		if account.IsDefault {
+			if err := s.accounts.ClearDefault(ctx, tx, userID); err != nil {
+				return err
+			}
+		}
+		return s.accounts.Create(ctx, tx, account)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// UpdateAccount renames, retypes, or promotes an account to be the user's default.
+// A non-zero ifVersion must match the account's version.
+func (s *AccountService) UpdateAccount(ctx context.Context, userID, accountID, ifVersion uint, update AccountUpdate) (*models.Account, error) {
+	fields := make(map[string]any)
+	if update.Name != nil {
+		name := strings.TrimSpace(*update.Name)
+		if name == "" {
+			return nil, fmt.Errorf("%w: account name must not be empty", ErrPreconditionFailed)
+		}
+		fields["name"] = name
+	}
+	if update.Type != nil {
+		if !models.IsValidAccountType(*update.Type) {
+			return nil, fmt.Errorf("%w: unknown account type %q", ErrPreconditionFailed, *update.Type)
+		}
+		fields["type"] = *update.Type
+	}
+
+	err := WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		account, err := s.accounts.LockByIDForUser(ctx, tx, accountID, userID)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(ifVersion, account.Version); err != nil {
+			return err
+		}
+
+		if update.IsDefault != nil {
+			switch {
+			case *update.IsDefault && !account.IsDefault:
+				if err := s.accounts.ClearDefault(ctx, tx, userID); err != nil {
+					return err
+				}
+				fields["is_default"] = true
+			case !*update.IsDefault && account.IsDefault:
+				return fmt.Errorf("%w: promote another account to default instead", ErrPreconditionFailed)
+			}
+		}
+
+		if len(fields) == 0 {
+			return nil
+		}
+		return s.accounts.UpdateFields(ctx, tx, accountID, userID, fields)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.accounts.GetByIDForUser(ctx, accountID, userID)
+}
+
+// DeleteAccount closes a non-default account that was never used: it must have a zero
+// balance and no transactions. A non-zero ifVersion must match the account's version.
+func (s *AccountService) DeleteAccount(ctx context.Context, userID, accountID, ifVersion uint) error {
+	return WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		account, err := s.accounts.LockByIDForUser(ctx, tx, accountID, userID)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(ifVersion, account.Version); err != nil {
+			return err
+		}
+		if account.IsDefault {
+			return fmt.Errorf("%w: the default account cannot be deleted", ErrPreconditionFailed)
+		}
+		if account.BalanceCents != 0 {
+			return fmt.Errorf("%w: only accounts with a zero balance can be deleted", ErrPreconditionFailed)
+		}
+		// Deleting an account would cascade to its transactions, taking the other side of
+		// its transfers with it, so only accounts that were never used can go.
+		used, err := s.accounts.HasTransactions(ctx, tx, accountID)
+		if err != nil {
+			return err
+		}
+		if used {
+			return fmt.Errorf("%w: accounts with transactions cannot be deleted", ErrPreconditionFailed)
+		}
+		return s.accounts.Delete(ctx, tx, accountID, userID)
+	})
+}
+
+// ListIncomes retrieves a page of the user's incomes matching filter, along with the totals
+// of all matching incomes.
+func (s *AccountService) ListIncomes(ctx context.Context, userID uint, filter TransactionFilter) (*IncomePage, error) {
+	cursor, err := s.prepareFilter(ctx, userID, models.CategoryTypeIncome, &filter)
+	if err != nil {
+		return nil, err
+	}
+	incomes, err := s.accounts.ListIncomes(ctx, userID, filter, cursor)
+	if err != nil {
+		return nil, err
+	}
+	totals, err := s.accounts.SumIncomes(ctx, userID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &IncomePage{Incomes: incomes, Totals: totals}
+	if len(incomes) > filter.Limit {
+		page.Incomes = incomes[:filter.Limit]
+		last := page.Incomes[filter.Limit-1]
+		page.NextCursor = filter.encodeCursor(last.ID, last.ReceivedAt, last.AmountCents)
+	}
+	return page, nil
+}
+
+// ListTransfers retrieves the user's transfers, optionally those touching one account.
+func (s *AccountService) ListTransfers(ctx context.Context, userID, accountID uint, limit int) ([]models.Transfer, error) {
+	return s.accounts.ListTransfers(ctx, userID, accountID, limit)
+}
+
+// ListExpenses retrieves a page of the user's expenses matching filter, along with the
+// totals of all matching expenses.
+func (s *AccountService) ListExpenses(ctx context.Context, userID uint, filter TransactionFilter) (*ExpensePage, error) {
+	cursor, err := s.prepareFilter(ctx, userID, models.CategoryTypeExpense, &filter)
+	if err != nil {
+		return nil, err
+	}
+	expenses, err := s.accounts.ListExpenses(ctx, userID, filter, cursor)
+	if err != nil {
+		return nil, err
+	}
+	totals, err := s.accounts.SumExpenses(ctx, userID, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &ExpensePage{Expenses: expenses, Totals: totals}
+	if len(expenses) > filter.Limit {
+		page.Expenses = expenses[:filter.Limit]
+		last := page.Expenses[filter.Limit-1]
+		page.NextCursor = filter.encodeCursor(last.ID, last.IncurredAt, last.AmountCents)
+	}
+	return page, nil
+}
+
+// ListTransactions retrieves a page of the user's incomes, expenses, transfers and
+// currency conversions merged into a single feed with running account balances.
+func (s *AccountService) ListTransactions(ctx context.Context, userID uint, filter FeedFilter) (*FeedPage, error) {
+	if err := filter.normalize(); err != nil {
+		return nil, err
+	}
+	cursor, err := filter.decodeCursor()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.accounts.ListFeed(ctx, userID, filter, cursor)
+	if err != nil {
+		return nil, err
+	}
+	page := &FeedPage{Entries: entries}
+	if len(entries) > filter.Limit {
+		page.Entries = entries[:filter.Limit]
+		last := page.Entries[filter.Limit-1]
+		page.NextCursor = encodeToken(feedCursor{
+			Ascending: filter.Ascending,
+			Time:      last.OccurredAt,
+			Type:      last.Type,
+			ID:        last.ID,
+			Leg:       last.Leg,
+		})
+	}
+	return page, nil
+}
+
+// prepareFilter normalizes a listing filter, expands its category to the category's
+// subtree of the given kind and decodes its cursor.
+func (s *AccountService) prepareFilter(ctx context.Context, userID uint, kind string, filter *TransactionFilter) (*pageCursor, error) {
+	if err := filter.normalize(); err != nil {
+		return nil, err
+	}
+	if filter.CategoryID != 0 {
+		categories, err := s.categories.List(ctx, userID, kind)
+		if err != nil {
+			return nil, err
+		}
+		filter.categoryIDs = categorySubtree(categories, filter.CategoryID)
+	}
+	return filter.decodeCursor()
 }
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"math/big"
 	"testing"
 	"time"
 
@@ -31,18 +32,26 @@ func setupTestDB(t *testing.T) *gorm.DB {
 	return db
 }
 
+func defaultAccountID(t *testing.T, svc *AccountService, userID uint) uint {
+	t.Helper()
+
+	account, err := svc.GetAccountByUserID(context.Background(), userID)
+	require.NoError(t, err)
+	return account.ID
+}
+
 func TestAccountServiceCreditIncome(t *testing.T) {
 	db := setupTestDB(t)
 	ctx := context.Background()
 
-	auth := NewAuthService(db)
+	auth := NewAuthService(db, newTestPasswordHasher())
 	user, err := auth.RegisterUser(ctx, "credit@example.com", "strongpass", "uah")
 	require.NoError(t, err)
 
 	svc := NewAccountService(db, false)
 
 	income := &models.Income{AmountCents: 12500, Source: "Salary", Notes: "October"}
-	saved, balance, err := svc.CreditIncome(ctx, user.ID, income)
+	saved, balance, err := svc.CreditIncome(ctx, user.ID, defaultAccountID(t, svc, user.ID), income)
 	require.NoError(t, err)
 	require.NotZero(t, saved.ID)
 	require.False(t, saved.ReceivedAt.IsZero())
@@ -57,14 +66,14 @@ func TestAccountServiceDebitExpenseInsufficientFunds(t *testing.T) {
 	db := setupTestDB(t)
 	ctx := context.Background()
 
-	auth := NewAuthService(db)
+	auth := NewAuthService(db, newTestPasswordHasher())
 	user, err := auth.RegisterUser(ctx, "debit@example.com", "strongpass", "usd")
 	require.NoError(t, err)
 
 	svc := NewAccountService(db, false)
 
 	expense := &models.Expense{AmountCents: 5000, Category: "Groceries"}
-	_, _, err = svc.DebitExpense(ctx, user.ID, expense)
+	_, _, err = svc.DebitExpense(ctx, user.ID, defaultAccountID(t, svc, user.ID), expense)
 	require.ErrorIs(t, err, ErrInsufficientFunds)
 
 	account, err := svc.GetAccountByUserID(ctx, user.ID)
@@ -76,13 +85,13 @@ func TestAccountServiceDebitExpenseAllowsNegativeBalance(t *testing.T) {
 	db := setupTestDB(t)
 	ctx := context.Background()
 
-	auth := NewAuthService(db)
+	auth := NewAuthService(db, newTestPasswordHasher())
 	user, err := auth.RegisterUser(ctx, "allow@example.com", "strongpass", "eur")
 	require.NoError(t, err)
 
 	svc := NewAccountService(db, true)
 
-	_, balance, err := svc.CreditIncome(ctx, user.ID, &models.Income{
+	_, balance, err := svc.CreditIncome(ctx, user.ID, defaultAccountID(t, svc, user.ID), &models.Income{
 		AmountCents: 10000,
 		Source:      "Adhoc",
 		ReceivedAt:  time.Now().UTC(),
@@ -91,7 +100,7 @@ func TestAccountServiceDebitExpenseAllowsNegativeBalance(t *testing.T) {
 	require.Equal(t, int64(10000), balance)
 
 	expense := &models.Expense{AmountCents: 15000, Category: "Equipment"}
-	_, balance, err = svc.DebitExpense(ctx, user.ID, expense)
+	_, balance, err = svc.DebitExpense(ctx, user.ID, defaultAccountID(t, svc, user.ID), expense)
 	require.NoError(t, err)
 	require.Equal(t, int64(-5000), balance)
 }
@@ -100,20 +109,19 @@ func TestAccountServiceCreditIncomeRejectsNonPositiveAmount(t *testing.T) {
 	db := setupTestDB(t)
 	ctx := context.Background()
 
-	auth := NewAuthService(db)
+	auth := NewAuthService(db, newTestPasswordHasher())
 	user, err := auth.RegisterUser(ctx, "reject-income@example.com", "strongpass", "usd")
 	require.NoError(t, err)
 
 	svc := NewAccountService(db, false)
 
-	_, _, err = svc.CreditIncome(ctx, user.ID, &models.Income{AmountCents: 0, Source: "Gift"})
+	_, _, err = svc.CreditIncome(ctx, user.ID, defaultAccountID(t, svc, user.ID), &models.Income{AmountCents: 0, Source: "Gift"})
 	require.ErrorIs(t, err, ErrPreconditionFailed)
 
 	account, err := svc.GetAccountByUserID(ctx, user.ID)
 	require.NoError(t, err)
 
-	incomes, err := svc.ListIncomes(ctx, account.ID, 10)
-	require.NoError(t, err)
+	incomes := mustListIncomes(t, svc, user.ID, TransactionFilter{AccountID: account.ID, Limit: 10})
 	require.Empty(t, incomes)
 }
 
@@ -121,21 +129,20 @@ func TestAccountServiceDebitExpenseRejectsNonPositiveAmount(t *testing.T) {
 	db := setupTestDB(t)
 	ctx := context.Background()
 
-	auth := NewAuthService(db)
+	auth := NewAuthService(db, newTestPasswordHasher())
 	user, err := auth.RegisterUser(ctx, "reject-expense@example.com", "strongpass", "usd")
 	require.NoError(t, err)
 
 	svc := NewAccountService(db, false)
 
-	_, _, err = svc.DebitExpense(ctx, user.ID, &models.Expense{AmountCents: 0, Category: "Misc"})
+	_, _, err = svc.DebitExpense(ctx, user.ID, defaultAccountID(t, svc, user.ID), &models.Expense{AmountCents: 0, Category: "Misc"})
 	require.ErrorIs(t, err, ErrPreconditionFailed)
 
 	account, err := svc.GetAccountByUserID(ctx, user.ID)
 	require.NoError(t, err)
 	require.Equal(t, int64(0), account.BalanceCents)
 
-	expenses, err := svc.ListExpenses(ctx, account.ID, 10)
-	require.NoError(t, err)
+	expenses := mustListExpenses(t, svc, user.ID, TransactionFilter{AccountID: account.ID, Limit: 10})
 	require.Empty(t, expenses)
 }
 
@@ -143,7 +150,7 @@ func TestAccountServiceSetDefaultCurrencyUpdatesAccount(t *testing.T) {
 	db := setupTestDB(t)
 	ctx := context.Background()
 
-	auth := NewAuthService(db)
+	auth := NewAuthService(db, newTestPasswordHasher())
 	user, err := auth.RegisterUser(ctx, "currency@example.com", "strongpass", "usd")
 	require.NoError(t, err)
 
@@ -158,4 +165,1160 @@ func TestAccountServiceSetDefaultCurrencyUpdatesAccount(t *testing.T) {
 	account, err := svc.GetAccountByUserID(ctx, user.ID)
 	require.NoError(t, err)
 	require.Equal(t, "PLN", account.CurrencyISOCode)
+
+	require.ErrorIs(t, svc.SetDefaultCurrency(ctx, user.ID, "xyz"), ErrPreconditionFailed)
+	_, err = auth.RegisterUser(ctx, "unknown-currency@example.com", "strongpass", "xyz")
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+}
+
+func TestAccountServiceMultipleAccounts(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "multi@example.com", "strongpass", "uah")
+	require.NoError(t, err)
+
+	svc := NewAccountService(db, false)
+
+	main, err := svc.GetAccountByUserID(ctx, user.ID)
+	require.NoError(t, err)
+	require.True(t, main.IsDefault)
+	require.Equal(t, models.DefaultAccountName, main.Name)
+
+	savings, err := svc.CreateAccount(ctx, user.ID, &models.Account{Name: "Savings pot", Type: models.AccountTypeSavings, CurrencyISOCode: "usd"})
+	require.NoError(t, err)
+	require.False(t, savings.IsDefault)
+	require.Equal(t, "USD", savings.CurrencyISOCode)
+
+	_, err = svc.CreateAccount(ctx, user.ID, &models.Account{Name: "Savings pot", Type: models.AccountTypeCash})
+	require.ErrorIs(t, err, ErrConflict)
+
+	_, balance, err := svc.CreditIncome(ctx, user.ID, savings.ID, &models.Income{AmountCents: 5000, Source: "Interest"})
+	require.NoError(t, err)
+	require.Equal(t, int64(5000), balance)
+
+	refreshedMain, err := svc.GetAccount(ctx, user.ID, main.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), refreshedMain.BalanceCents)
+
+	incomes := mustListIncomes(t, svc, user.ID, TransactionFilter{AccountID: main.ID, Limit: 10})
+	require.Empty(t, incomes)
+
+	incomes = mustListIncomes(t, svc, user.ID, TransactionFilter{Limit: 10})
+	require.Len(t, incomes, 1)
+	require.Equal(t, savings.ID, incomes[0].AccountID)
+
+	accounts, err := svc.ListAccounts(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, accounts, 2)
+	require.Equal(t, main.ID, accounts[0].ID)
+}
+
+func TestAccountServiceRejectsForeignAccount(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	owner, err := auth.RegisterUser(ctx, "owner@example.com", "strongpass", "uah")
+	require.NoError(t, err)
+	intruder, err := auth.RegisterUser(ctx, "intruder@example.com", "strongpass", "uah")
+	require.NoError(t, err)
+
+	svc := NewAccountService(db, true)
+	ownerAccountID := defaultAccountID(t, svc, owner.ID)
+
+	_, _, err = svc.DebitExpense(ctx, intruder.ID, ownerAccountID, &models.Expense{AmountCents: 100, Category: "Theft"})
+	require.ErrorIs(t, err, ErrNotFound)
+
+	_, err = svc.GetAccount(ctx, intruder.ID, ownerAccountID)
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestAccountServiceUpdateAndDeleteAccount(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "update-account@example.com", "strongpass", "uah")
+	require.NoError(t, err)
+
+	svc := NewAccountService(db, false)
+	mainID := defaultAccountID(t, svc, user.ID)
+
+	card, err := svc.CreateAccount(ctx, user.ID, &models.Account{Name: "Card", Type: models.AccountTypeCard})
+	require.NoError(t, err)
+
+	isDefault := true
+	name := "Debit card"
+	updated, err := svc.UpdateAccount(ctx, user.ID, card.ID, 0, AccountUpdate{Name: &name, IsDefault: &isDefault})
+	require.NoError(t, err)
+	require.Equal(t, "Debit card", updated.Name)
+	require.True(t, updated.IsDefault)
+
+	main, err := svc.GetAccount(ctx, user.ID, mainID)
+	require.NoError(t, err)
+	require.False(t, main.IsDefault)
+
+	err = svc.DeleteAccount(ctx, user.ID, card.ID, 0)
+	require.ErrorIs(t, err, ErrPreconditionFailed, "default account cannot be deleted")
+
+	income, _, err := svc.CreditIncome(ctx, user.ID, mainID, &models.Income{AmountCents: 100, Source: "Gift"})
+	require.NoError(t, err)
+	err = svc.DeleteAccount(ctx, user.ID, mainID, 0)
+	require.ErrorIs(t, err, ErrPreconditionFailed, "non-empty account cannot be deleted")
+
+	expense, _, err := svc.DebitExpense(ctx, user.ID, mainID, &models.Expense{AmountCents: 100, Category: "Misc"})
+	require.NoError(t, err)
+	err = svc.DeleteAccount(ctx, user.ID, mainID, 0)
+	require.ErrorIs(t, err, ErrPreconditionFailed, "an emptied account keeps its transactions")
+
+	_, err = svc.DeleteExpense(ctx, user.ID, expense.ID, 0)
+	require.NoError(t, err)
+	_, err = svc.DeleteIncome(ctx, user.ID, income.ID, 0)
+	require.NoError(t, err)
+	require.NoError(t, svc.DeleteAccount(ctx, user.ID, mainID, 0))
+
+	_, err = svc.GetAccount(ctx, user.ID, mainID)
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestAccountServiceTransferSameCurrency(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "transfer@example.com", "strongpass", "uah")
+	require.NoError(t, err)
+
+	svc := NewAccountService(db, false)
+	mainID := defaultAccountID(t, svc, user.ID)
+	savings, err := svc.CreateAccount(ctx, user.ID, &models.Account{Name: "Savings", Type: models.AccountTypeSavings})
+	require.NoError(t, err)
+
+	_, _, err = svc.CreditIncome(ctx, user.ID, mainID, &models.Income{AmountCents: 10000, Source: "Salary"})
+	require.NoError(t, err)
+
+	transfer, fromBalance, toBalance, err := svc.Transfer(ctx, user.ID, &models.Transfer{
+		FromAccountID: mainID,
+		ToAccountID:   savings.ID,
+		AmountCents:   4000,
+	})
+	require.NoError(t, err)
+	require.NotZero(t, transfer.ID)
+	require.Equal(t, "1", transfer.ExchangeRate)
+	require.Equal(t, int64(4000), transfer.ToAmountCents)
+	require.Equal(t, int64(6000), fromBalance)
+	require.Equal(t, int64(4000), toBalance)
+
+	_, _, _, err = svc.Transfer(ctx, user.ID, &models.Transfer{
+		FromAccountID: mainID,
+		ToAccountID:   savings.ID,
+		AmountCents:   7000,
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	main, err := svc.GetAccount(ctx, user.ID, mainID)
+	require.NoError(t, err)
+	require.Equal(t, int64(6000), main.BalanceCents, "failed transfer must not move money")
+
+	transfers, err := svc.ListTransfers(ctx, user.ID, savings.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+
+	// Transfers never show up as incomes or expenses.
+	expenses := mustListExpenses(t, svc, user.ID, TransactionFilter{Limit: 10})
+	require.Empty(t, expenses)
+}
+
+func TestAccountServiceDeleteAccountAfterTransfer(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "delete-after-transfer@example.com", "strongpass", "uah")
+	require.NoError(t, err)
+
+	svc := NewAccountService(db, false)
+	mainID := defaultAccountID(t, svc, user.ID)
+	cash, err := svc.CreateAccount(ctx, user.ID, &models.Account{Name: "Cash", Type: models.AccountTypeCash})
+	require.NoError(t, err)
+
+	_, _, err = svc.CreditIncome(ctx, user.ID, cash.ID, &models.Income{AmountCents: 5000, Source: "Tips"})
+	require.NoError(t, err)
+	_, _, _, err = svc.Transfer(ctx, user.ID, &models.Transfer{FromAccountID: cash.ID, ToAccountID: mainID, AmountCents: 5000})
+	require.NoError(t, err)
+
+	// The emptied account keeps the transfer that funded the main account.
+	err = svc.DeleteAccount(ctx, user.ID, cash.ID, 0)
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+
+	transfers, err := svc.ListTransfers(ctx, user.ID, mainID, 10)
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+
+	report, err := NewIntegrityService(db).Check(ctx)
+	require.NoError(t, err)
+	require.Empty(t, report.Discrepancies)
+
+	// An account that never moved money can still be deleted.
+	unused, err := svc.CreateAccount(ctx, user.ID, &models.Account{Name: "Unused", Type: models.AccountTypeCard})
+	require.NoError(t, err)
+	require.NoError(t, svc.DeleteAccount(ctx, user.ID, unused.ID, 0))
+}
+
+func TestAccountServiceTransferCrossCurrency(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "fx-transfer@example.com", "strongpass", "usd")
+	require.NoError(t, err)
+
+	svc := NewAccountService(db, false)
+	usdID := defaultAccountID(t, svc, user.ID)
+	uah, err := svc.CreateAccount(ctx, user.ID, &models.Account{Name: "Hryvnia card", Type: models.AccountTypeCard, CurrencyISOCode: "UAH"})
+	require.NoError(t, err)
+
+	_, _, err = svc.CreditIncome(ctx, user.ID, usdID, &models.Income{AmountCents: 10000, Source: "Salary"})
+	require.NoError(t, err)
+
+	_, _, _, err = svc.Transfer(ctx, user.ID, &models.Transfer{FromAccountID: usdID, ToAccountID: uah.ID, AmountCents: 1000})
+	require.ErrorIs(t, err, ErrRateUnavailable, "cross-currency transfers need a rate or an exchange rate provider")
+
+	transfer, fromBalance, toBalance, err := svc.Transfer(ctx, user.ID, &models.Transfer{
+		FromAccountID: usdID,
+		ToAccountID:   uah.ID,
+		AmountCents:   1005,
+		ExchangeRate:  "41.255",
+	})
+	require.NoError(t, err)
+	// 10.05 USD * 41.255 = 414.61275 UAH, rounded to 414.61.
+	require.Equal(t, int64(41461), transfer.ToAmountCents)
+	require.Equal(t, int64(8995), fromBalance)
+	require.Equal(t, int64(41461), toBalance)
+}
+
+func TestConvertAmountRoundsHalfAwayFromZero(t *testing.T) {
+	rate, err := ParseExchangeRate("0.5")
+	require.NoError(t, err)
+	require.Equal(t, int64(2), ConvertAmount(3, rate))
+	require.Equal(t, int64(-2), ConvertAmount(-3, rate))
+
+	// Rates are quoted in major units, so JPY (no decimals) to USD (cents) scales by 100.
+	rate, err = ParseExchangeRate("0.0067")
+	require.NoError(t, err)
+	require.Equal(t, int64(1005), ConvertMinorUnits(1500, rate, "JPY", "USD"))
+	rate, err = ParseExchangeRate("149.25")
+	require.NoError(t, err)
+	require.Equal(t, int64(1500), ConvertMinorUnits(1005, rate, "USD", "JPY"))
+
+	_, err = ParseExchangeRate("1e3")
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+	_, err = ParseExchangeRate("-1")
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+}
+
+func TestAccountServiceEditAndDeleteTransactions(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "edit@example.com", "strongpass", "usd")
+	require.NoError(t, err)
+
+	svc := NewAccountService(db, false)
+	accountID := defaultAccountID(t, svc, user.ID)
+
+	income, _, err := svc.CreditIncome(ctx, user.ID, accountID, &models.Income{AmountCents: 10000, Source: "Salary"})
+	require.NoError(t, err)
+	expense, _, err := svc.DebitExpense(ctx, user.ID, accountID, &models.Expense{AmountCents: 4000, Category: "Rent"})
+	require.NoError(t, err)
+
+	amount := int64(12000)
+	notes := "corrected"
+	updated, balance, err := svc.UpdateIncome(ctx, user.ID, income.ID, 0, IncomeUpdate{AmountCents: &amount, Notes: &notes})
+	require.NoError(t, err)
+	require.Equal(t, int64(12000), updated.AmountCents)
+	require.Equal(t, "corrected", updated.Notes)
+	require.Equal(t, int64(8000), balance)
+
+	tooMuch := int64(20001)
+	_, _, err = svc.UpdateExpense(ctx, user.ID, expense.ID, 0, ExpenseUpdate{AmountCents: &tooMuch})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	tooLittle := int64(1000)
+	_, _, err = svc.UpdateIncome(ctx, user.ID, income.ID, 0, IncomeUpdate{AmountCents: &tooLittle})
+	require.ErrorIs(t, err, ErrInsufficientFunds, "lowering an income must not overdraw the account")
+
+	other, err := auth.RegisterUser(ctx, "edit-other@example.com", "strongpass", "usd")
+	require.NoError(t, err)
+	_, err = svc.DeleteExpense(ctx, other.ID, expense.ID, 0)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	balance, err = svc.DeleteExpense(ctx, user.ID, expense.ID, 0)
+	require.NoError(t, err)
+	require.Equal(t, int64(12000), balance)
+	_, err = svc.GetExpense(ctx, user.ID, expense.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	balance, err = svc.DeleteIncome(ctx, user.ID, income.ID, 0)
+	require.NoError(t, err)
+	require.Zero(t, balance)
+
+	journals, err := NewLedger(db).Journals(ctx, models.JournalKindIncome, income.ID)
+	require.NoError(t, err)
+	require.Len(t, journals, 3, "original posting, correction and reversal")
+
+	report, err := NewIntegrityService(db).Check(ctx)
+	require.NoError(t, err)
+	require.Empty(t, report.Discrepancies)
+}
+
+func TestAccountServiceVersionPreconditions(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "versions@example.com", "strongpass", "usd")
+	require.NoError(t, err)
+
+	svc := NewAccountService(db, false)
+	account, err := svc.GetAccountByUserID(ctx, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, uint(1), account.Version)
+
+	income, _, err := svc.CreditIncome(ctx, user.ID, account.ID, &models.Income{AmountCents: 500, Source: "Gift"})
+	require.NoError(t, err)
+	require.Equal(t, uint(1), income.Version)
+
+	account, err = svc.GetAccount(ctx, user.ID, account.ID)
+	require.NoError(t, err)
+	require.Equal(t, uint(2), account.Version, "balance changes bump the account version")
+
+	name := "Renamed"
+	_, err = svc.UpdateAccount(ctx, user.ID, account.ID, 1, AccountUpdate{Name: &name})
+	require.ErrorIs(t, err, ErrVersionMismatch)
+	updated, err := svc.UpdateAccount(ctx, user.ID, account.ID, 2, AccountUpdate{Name: &name})
+	require.NoError(t, err)
+	require.Equal(t, uint(3), updated.Version)
+
+	notes := "edited"
+	edited, _, err := svc.UpdateIncome(ctx, user.ID, income.ID, 1, IncomeUpdate{Notes: &notes})
+	require.NoError(t, err)
+	require.Equal(t, uint(2), edited.Version)
+
+	_, err = svc.DeleteIncome(ctx, user.ID, income.ID, 1)
+	require.ErrorIs(t, err, ErrVersionMismatch)
+	_, err = svc.DeleteIncome(ctx, user.ID, income.ID, 2)
+	require.NoError(t, err)
+}
+
+type staticRates map[string]string
+
+func (r staticRates) Rate(_ context.Context, from, to string, _ time.Time) (*big.Rat, error) {
+	value, ok := r[from+to]
+	if !ok {
+		return nil, ErrRateUnavailable
+	}
+	return ParseExchangeRate(value)
+}
+
+func TestAccountServiceChangeDefaultCurrencyConvertsBalance(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "convert@example.com", "strongpass", "uah")
+	require.NoError(t, err)
+
+	svc := NewAccountService(db, false)
+	accountID := defaultAccountID(t, svc, user.ID)
+	_, _, err = svc.CreditIncome(ctx, user.ID, accountID, &models.Income{AmountCents: 100000, Source: "Salary"})
+	require.NoError(t, err)
+
+	require.ErrorIs(t, svc.SetDefaultCurrency(ctx, user.ID, "usd"), ErrPreconditionFailed)
+	_, _, err = svc.ChangeDefaultCurrency(ctx, user.ID, CurrencyChange{Currency: "usd", Convert: true})
+	require.ErrorIs(t, err, ErrRateUnavailable, "no rate and no exchange rate provider")
+
+	updatedUser, account, err := svc.ChangeDefaultCurrency(ctx, user.ID, CurrencyChange{Currency: "usd", Convert: true, Rate: "0.0243"})
+	require.NoError(t, err)
+	require.Equal(t, "USD", updatedUser.DefaultCurrency)
+	require.Equal(t, "USD", account.CurrencyISOCode)
+	require.Equal(t, int64(2430), account.BalanceCents)
+
+	var conversion models.CurrencyConversion
+	require.NoError(t, db.Where("account_id = ?", accountID).First(&conversion).Error)
+	require.Equal(t, "UAH", conversion.FromCurrency)
+	require.Equal(t, "0.0243", conversion.Rate)
+	require.Equal(t, int64(100000), conversion.FromAmountCents)
+
+	journals, err := NewLedger(db).Journals(ctx, models.JournalKindConversion, conversion.ID)
+	require.NoError(t, err)
+	require.Len(t, journals, 1)
+	requireBalancedJournal(t, journals[0])
+
+	ledgerBalance, err := NewLedger(db).AccountBalance(ctx, accountID)
+	require.NoError(t, err)
+	require.Equal(t, int64(2430), ledgerBalance)
+
+	report, err := NewIntegrityService(db).Check(ctx)
+	require.NoError(t, err)
+	require.Empty(t, report.Discrepancies)
+
+	feed, err := svc.ListTransactions(ctx, user.ID, FeedFilter{})
+	require.NoError(t, err)
+	require.Len(t, feed.Entries, 2)
+	require.Equal(t, FeedEntryConversion, feed.Entries[0].Type)
+	require.Equal(t, int64(2430), feed.Entries[0].AmountCents)
+	require.Equal(t, "USD", feed.Entries[0].CurrencyISOCode)
+	require.Equal(t, int64(-100000), feed.Entries[0].FromAmountCents)
+	require.Equal(t, "UAH", feed.Entries[0].FromCurrencyISOCode)
+	require.Equal(t, int64(2430), feed.Entries[0].BalanceAfterCents)
+	require.Equal(t, "USD", feed.Entries[0].BalanceCurrencyISOCode)
+	require.Equal(t, FeedEntryIncome, feed.Entries[1].Type)
+	require.Equal(t, "UAH", feed.Entries[1].CurrencyISOCode, "entries keep the currency they were booked in")
+	require.Equal(t, int64(100000), feed.Entries[1].BalanceAfterCents)
+	require.Equal(t, "UAH", feed.Entries[1].BalanceCurrencyISOCode)
+
+	svc.UseExchangeRates(staticRates{"USDEUR": "0.9"})
+	_, account, err = svc.ChangeDefaultCurrency(ctx, user.ID, CurrencyChange{Currency: "eur", Convert: true})
+	require.NoError(t, err)
+	require.Equal(t, int64(2187), account.BalanceCents)
+}
+
+func TestAccountServiceKeepsTransactionsRecordedBeforeConversion(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "pre-conversion@example.com", "strongpass", "uah")
+	require.NoError(t, err)
+
+	svc := NewAccountService(db, false)
+	accountID := defaultAccountID(t, svc, user.ID)
+	salary, _, err := svc.CreditIncome(ctx, user.ID, accountID, &models.Income{AmountCents: 100000, Source: "Salary"})
+	require.NoError(t, err)
+	require.Equal(t, "UAH", salary.CurrencyISOCode)
+	rent, _, err := svc.DebitExpense(ctx, user.ID, accountID, &models.Expense{AmountCents: 20000, Category: "Rent"})
+	require.NoError(t, err)
+
+	_, account, err := svc.ChangeDefaultCurrency(ctx, user.ID, CurrencyChange{Currency: "usd", Convert: true, Rate: "0.025"})
+	require.NoError(t, err)
+	require.Equal(t, int64(2000), account.BalanceCents)
+
+	// The UAH amounts cannot be taken off a USD balance.
+	_, err = svc.DeleteIncome(ctx, user.ID, salary.ID, 0)
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+	_, err = svc.DeleteExpense(ctx, user.ID, rent.ID, 0)
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+	amount := int64(50000)
+	_, _, err = svc.UpdateIncome(ctx, user.ID, salary.ID, 0, IncomeUpdate{AmountCents: &amount})
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+	receivedAt := time.Now().UTC()
+	_, _, err = svc.UpdateIncome(ctx, user.ID, salary.ID, 0, IncomeUpdate{ReceivedAt: &receivedAt})
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+
+	notes := "March salary"
+	updated, balance, err := svc.UpdateIncome(ctx, user.ID, salary.ID, 0, IncomeUpdate{Notes: &notes})
+	require.NoError(t, err)
+	require.Equal(t, notes, updated.Notes)
+	require.Equal(t, "UAH", updated.CurrencyISOCode)
+	require.Equal(t, int64(2000), balance)
+
+	// Transactions recorded after the conversion are in USD and can be deleted as usual.
+	coffee, _, err := svc.DebitExpense(ctx, user.ID, accountID, &models.Expense{AmountCents: 500, Category: "Coffee"})
+	require.NoError(t, err)
+	require.Equal(t, "USD", coffee.CurrencyISOCode)
+	balance, err = svc.DeleteExpense(ctx, user.ID, coffee.ID, 0)
+	require.NoError(t, err)
+	require.Equal(t, int64(2000), balance)
+
+	account, err = svc.GetAccount(ctx, user.ID, accountID)
+	require.NoError(t, err)
+	require.Equal(t, int64(2000), account.BalanceCents)
+	page, err := svc.ListIncomes(ctx, user.ID, TransactionFilter{})
+	require.NoError(t, err)
+	require.Equal(t, []TransactionTotal{{CurrencyISOCode: "UAH", Count: 1, AmountCents: 100000}}, page.Totals, "totals keep the pre-conversion currency")
+	report, err := NewIntegrityService(db).Check(ctx)
+	require.NoError(t, err)
+	require.Empty(t, report.Discrepancies)
+}
+
+func TestAccountServiceFeedKeepsCurrenciesAcrossConversion(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "feed-conversion@example.com", "strongpass", "uah")
+	require.NoError(t, err)
+
+	svc := NewAccountService(db, false)
+	mainID := defaultAccountID(t, svc, user.ID)
+	savings, err := svc.CreateAccount(ctx, user.ID, &models.Account{Name: "Savings", Type: models.AccountTypeSavings, CurrencyISOCode: "USD"})
+	require.NoError(t, err)
+
+	base := time.Now().UTC().Add(-time.Hour)
+	_, _, err = svc.CreditIncome(ctx, user.ID, mainID, &models.Income{AmountCents: 100000, Source: "Salary", ReceivedAt: base})
+	require.NoError(t, err)
+	transfer, _, _, err := svc.Transfer(ctx, user.ID, &models.Transfer{
+		FromAccountID: mainID,
+		ToAccountID:   savings.ID,
+		AmountCents:   40000,
+		ExchangeRate:  "0.025",
+		TransferredAt: base.Add(time.Minute),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "UAH", transfer.FromCurrencyISOCode)
+	require.Equal(t, "USD", transfer.ToCurrencyISOCode)
+
+	_, _, err = svc.ChangeDefaultCurrency(ctx, user.ID, CurrencyChange{Currency: "eur", Convert: true, Rate: "0.02"})
+	require.NoError(t, err)
+
+	feed, err := svc.ListTransactions(ctx, user.ID, FeedFilter{AccountID: mainID, Ascending: true})
+	require.NoError(t, err)
+	require.Len(t, feed.Entries, 3)
+
+	income, leg, conversion := feed.Entries[0], feed.Entries[1], feed.Entries[2]
+	require.Equal(t, "UAH", income.CurrencyISOCode)
+	require.Equal(t, "UAH", income.BalanceCurrencyISOCode)
+	require.Equal(t, "UAH", leg.CurrencyISOCode, "the transfer left the account in its currency at the time")
+	require.Equal(t, int64(60000), leg.BalanceAfterCents)
+	require.Equal(t, "UAH", leg.BalanceCurrencyISOCode)
+	require.Equal(t, FeedEntryConversion, conversion.Type)
+	require.Equal(t, int64(-60000), conversion.FromAmountCents)
+	require.Equal(t, "UAH", conversion.FromCurrencyISOCode)
+	require.Equal(t, int64(1200), conversion.AmountCents)
+	require.Equal(t, "EUR", conversion.CurrencyISOCode)
+	require.Equal(t, int64(1200), conversion.BalanceAfterCents)
+	require.Equal(t, "EUR", conversion.BalanceCurrencyISOCode)
+
+	feed, err = svc.ListTransactions(ctx, user.ID, FeedFilter{AccountID: savings.ID})
+	require.NoError(t, err)
+	require.Len(t, feed.Entries, 1)
+	require.Equal(t, "USD", feed.Entries[0].CurrencyISOCode)
+	require.Equal(t, "USD", feed.Entries[0].BalanceCurrencyISOCode)
+}
+
+func TestAccountServiceForeignCurrencyTransactions(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "fx-expense@example.com", "strongpass", "uah")
+	require.NoError(t, err)
+
+	svc := NewAccountService(db, false)
+	accountID := defaultAccountID(t, svc, user.ID)
+	receivedAt := time.Date(2025, time.March, 3, 10, 0, 0, 0, time.UTC)
+
+	_, _, err = svc.CreditIncome(ctx, user.ID, accountID, &models.Income{OriginalAmountCents: 10000, OriginalCurrency: "usd", Source: "Invoice", ReceivedAt: receivedAt})
+	require.ErrorIs(t, err, ErrRateUnavailable, "no explicit rate and no provider")
+
+	income, balance, err := svc.CreditIncome(ctx, user.ID, accountID, &models.Income{
+		OriginalAmountCents: 10000,
+		OriginalCurrency:    "usd",
+		ExchangeRate:        "41.5",
+		Source:              "Invoice",
+		ReceivedAt:          receivedAt,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "USD", income.OriginalCurrency)
+	require.Equal(t, int64(415000), income.AmountCents)
+	require.Equal(t, int64(415000), balance)
+
+	svc.UseExchangeRates(staticRates{"EURUAH": "45.125"})
+	expense, balance, err := svc.DebitExpense(ctx, user.ID, accountID, &models.Expense{
+		OriginalAmountCents: 1999,
+		OriginalCurrency:    "EUR",
+		Category:            "Travel",
+		IncurredAt:          receivedAt,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "45.125", expense.ExchangeRate, "the provider rate is captured")
+	// 19.99 EUR * 45.125 = 902.04875 UAH, rounded to 902.05.
+	require.Equal(t, int64(90205), expense.AmountCents)
+	require.Equal(t, int64(415000-90205), balance)
+
+	// An amount in the account currency is stored as a plain expense.
+	plain, _, err := svc.DebitExpense(ctx, user.ID, accountID, &models.Expense{OriginalAmountCents: 500, OriginalCurrency: "UAH", Category: "Coffee"})
+	require.NoError(t, err)
+	require.Equal(t, int64(500), plain.AmountCents)
+	require.Empty(t, plain.OriginalCurrency)
+
+	_, _, err = svc.DebitExpense(ctx, user.ID, accountID, &models.Expense{AmountCents: 500, ExchangeRate: "2", Category: "Coffee"})
+	require.ErrorIs(t, err, ErrPreconditionFailed, "a rate needs a currency")
+
+	// Editing the amount re-converts it at the captured rate, even after rates change.
+	svc.UseExchangeRates(staticRates{"EURUAH": "50"})
+	newAmount := int64(2000)
+	expense, balance, err = svc.UpdateExpense(ctx, user.ID, expense.ID, 0, ExpenseUpdate{AmountCents: &newAmount})
+	require.NoError(t, err)
+	require.Equal(t, int64(2000), expense.OriginalAmountCents)
+	require.Equal(t, int64(90250), expense.AmountCents)
+	require.Equal(t, int64(415000-90250-500), balance)
+
+	integrity := NewIntegrityService(db)
+	report, err := integrity.Check(ctx)
+	require.NoError(t, err)
+	require.Empty(t, report.Discrepancies)
+}
+
+func TestAccountServiceTags(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "tags@example.com", "strongpass", "usd")
+	require.NoError(t, err)
+
+	svc := NewAccountService(db, true)
+	accountID := defaultAccountID(t, svc, user.ID)
+
+	tagged := func(names ...string) []models.Tag {
+		tags := make([]models.Tag, 0, len(names))
+		for _, name := range names {
+			tags = append(tags, models.Tag{Name: name})
+		}
+		return tags
+	}
+
+	flight, _, err := svc.DebitExpense(ctx, user.ID, accountID, &models.Expense{AmountCents: 30000, Category: "Travel", Tags: tagged("Trip-2026", " reimbursable ", "trip-2026")})
+	require.NoError(t, err)
+	require.Equal(t, []string{"reimbursable", "trip-2026"}, models.TagNames(flight.Tags), "tags are normalized and deduplicated")
+	hotel, _, err := svc.DebitExpense(ctx, user.ID, accountID, &models.Expense{AmountCents: 50000, Category: "Travel", Tags: tagged("trip-2026")})
+	require.NoError(t, err)
+	_, _, err = svc.DebitExpense(ctx, user.ID, accountID, &models.Expense{AmountCents: 700, Category: "Coffee"})
+	require.NoError(t, err)
+
+	_, _, err = svc.DebitExpense(ctx, user.ID, accountID, &models.Expense{AmountCents: 100, Category: "Coffee", Tags: tagged("  ")})
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+
+	expenses := mustListExpenses(t, svc, user.ID, TransactionFilter{Tags: []string{"Trip-2026", "reimbursable"}})
+	require.Len(t, expenses, 2, "any tag matches by default")
+	expenses = mustListExpenses(t, svc, user.ID, TransactionFilter{Tags: []string{"trip-2026", "reimbursable"}, MatchAllTags: true})
+	require.Len(t, expenses, 1)
+	require.Equal(t, flight.ID, expenses[0].ID)
+	require.Equal(t, []string{"reimbursable", "trip-2026"}, models.TagNames(expenses[0].Tags))
+	expenses = mustListExpenses(t, svc, user.ID, TransactionFilter{Tags: []string{"unknown"}})
+	require.Empty(t, expenses)
+
+	// Replacing tags bumps the version; an empty list removes them.
+	tags := []string{"reimbursable"}
+	hotel, _, err = svc.UpdateExpense(ctx, user.ID, hotel.ID, hotel.Version, ExpenseUpdate{Tags: &tags})
+	require.NoError(t, err)
+	require.Equal(t, []string{"reimbursable"}, models.TagNames(hotel.Tags))
+	require.Equal(t, uint(2), hotel.Version)
+	expenses = mustListExpenses(t, svc, user.ID, TransactionFilter{Tags: []string{"trip-2026"}})
+	require.Len(t, expenses, 1)
+
+	income, _, err := svc.CreditIncome(ctx, user.ID, accountID, &models.Income{AmountCents: 30000, Source: "Employer", Tags: tagged("reimbursable")})
+	require.NoError(t, err)
+	incomes := mustListIncomes(t, svc, user.ID, TransactionFilter{Tags: []string{"reimbursable"}})
+	require.Len(t, incomes, 1)
+	none := []string{}
+	income, _, err = svc.UpdateIncome(ctx, user.ID, income.ID, 0, IncomeUpdate{Tags: &none})
+	require.NoError(t, err)
+	require.Empty(t, income.Tags)
+
+	_, err = svc.DeleteExpense(ctx, user.ID, flight.ID, 0)
+	require.NoError(t, err)
+	var links int64
+	require.NoError(t, db.Table("expense_tags").Where("expense_id = ?", flight.ID).Count(&links).Error)
+	require.Zero(t, links, "links are removed with the expense")
+}
+
+func TestAccountServiceSplitTransactions(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "splits@example.com", "strongpass", "uah")
+	require.NoError(t, err)
+
+	svc := NewAccountService(db, true)
+	accountID := defaultAccountID(t, svc, user.ID)
+	categories := NewCategoryService(db)
+	catalogue, err := categories.ListCategories(ctx, user.ID, "")
+	require.NoError(t, err)
+	groceries := categoryByName(t, catalogue, models.CategoryTypeExpense, "Groceries")
+	salary := categoryByName(t, catalogue, models.CategoryTypeIncome, "Salary")
+
+	_, _, err = svc.DebitExpense(ctx, user.ID, accountID, &models.Expense{AmountCents: 10000, Splits: []models.ExpenseSplit{
+		{CategoryID: &groceries.ID, AmountCents: 6000},
+		{Category: "Household", AmountCents: 3000},
+	}})
+	require.ErrorIs(t, err, ErrPreconditionFailed, "splits must add up to the amount")
+	_, _, err = svc.DebitExpense(ctx, user.ID, accountID, &models.Expense{AmountCents: 10000, Splits: []models.ExpenseSplit{
+		{CategoryID: &groceries.ID, AmountCents: 10000},
+	}})
+	require.ErrorIs(t, err, ErrPreconditionFailed, "a split needs two lines")
+	_, _, err = svc.DebitExpense(ctx, user.ID, accountID, &models.Expense{AmountCents: 10000, Splits: []models.ExpenseSplit{
+		{CategoryID: &salary.ID, AmountCents: 5000},
+		{Category: "Household", AmountCents: 5000},
+	}})
+	require.ErrorIs(t, err, ErrPreconditionFailed, "an income category cannot file a split line")
+
+	receipt, balance, err := svc.DebitExpense(ctx, user.ID, accountID, &models.Expense{AmountCents: 10000, Splits: []models.ExpenseSplit{
+		{CategoryID: &groceries.ID, AmountCents: 6000, Note: "weekly shop"},
+		{Category: "Household", AmountCents: 2500},
+		{Category: "Pharmacy", AmountCents: 1500},
+	}})
+	require.NoError(t, err)
+	require.Equal(t, int64(-10000), balance)
+	require.Equal(t, groceries.ID, *receipt.CategoryID, "the largest line files the expense")
+	require.Len(t, receipt.Splits, 3)
+	require.Equal(t, "Household", receipt.Splits[1].Category)
+
+	stored, err := svc.GetExpense(ctx, user.ID, receipt.ID)
+	require.NoError(t, err)
+	require.Len(t, stored.Splits, 3)
+	require.Equal(t, "weekly shop", stored.Splits[0].Note)
+	require.Equal(t, int64(1500), stored.Splits[2].AmountCents)
+
+	// The amount of a split expense changes together with its lines.
+	amount := int64(12000)
+	_, _, err = svc.UpdateExpense(ctx, user.ID, receipt.ID, 0, ExpenseUpdate{AmountCents: &amount})
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+	lines := []models.ExpenseSplit{
+		{CategoryID: &groceries.ID, AmountCents: 8000},
+		{Category: "Household", AmountCents: 4000},
+	}
+	receipt, balance, err = svc.UpdateExpense(ctx, user.ID, receipt.ID, 0, ExpenseUpdate{AmountCents: &amount, Splits: &lines})
+	require.NoError(t, err)
+	require.Equal(t, int64(-12000), balance)
+	require.Len(t, receipt.Splits, 2)
+
+	// Category bookkeeping covers split lines.
+	household := categoryByName(t, mustListCategories(t, categories, user.ID), models.CategoryTypeExpense, "Household")
+	require.ErrorIs(t, categories.DeleteCategory(ctx, user.ID, household.ID, 0), ErrPreconditionFailed, "household files a split line")
+	renamed := "Home"
+	_, err = categories.UpdateCategory(ctx, user.ID, household.ID, 0, CategoryUpdate{Name: &renamed})
+	require.NoError(t, err)
+	renamedReceipt, err := svc.GetExpense(ctx, user.ID, receipt.ID)
+	require.NoError(t, err)
+	require.Equal(t, "Home", renamedReceipt.Splits[1].Category)
+	require.Greater(t, renamedReceipt.Version, receipt.Version)
+
+	none := []models.ExpenseSplit{}
+	receipt, _, err = svc.UpdateExpense(ctx, user.ID, receipt.ID, 0, ExpenseUpdate{Splits: &none})
+	require.NoError(t, err)
+	require.Empty(t, receipt.Splits)
+	require.NoError(t, categories.DeleteCategory(ctx, user.ID, household.ID, 0))
+
+	// Foreign-currency lines are converted in proportion, the last taking the remainder.
+	trip, _, err := svc.DebitExpense(ctx, user.ID, accountID, &models.Expense{
+		OriginalAmountCents: 1000,
+		OriginalCurrency:    "EUR",
+		ExchangeRate:        "45.125",
+		Splits: []models.ExpenseSplit{
+			{Category: "Food", OriginalAmountCents: 333},
+			{Category: "Transport", OriginalAmountCents: 667},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(45125), trip.AmountCents)
+	require.Equal(t, int64(15026), trip.Splits[0].AmountCents)
+	require.Equal(t, int64(30099), trip.Splits[1].AmountCents)
+	require.Equal(t, int64(667), trip.Splits[1].OriginalAmountCents)
+
+	payslip, _, err := svc.CreditIncome(ctx, user.ID, accountID, &models.Income{AmountCents: 50000, Source: "Employer", Splits: []models.IncomeSplit{
+		{CategoryID: &salary.ID, AmountCents: 40000, Note: "base"},
+		{AmountCents: 10000, Note: "bonus"},
+	}})
+	require.NoError(t, err)
+	require.Len(t, payslip.Splits, 2)
+	require.Nil(t, payslip.Splits[1].CategoryID)
+
+	integrity := NewIntegrityService(db)
+	report, err := integrity.Check(ctx)
+	require.NoError(t, err)
+	require.Empty(t, report.Discrepancies)
+}
+
+func TestAccountServiceListPagination(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "pages@example.com", "strongpass", "uah")
+	require.NoError(t, err)
+
+	svc := NewAccountService(db, true)
+	accountID := defaultAccountID(t, svc, user.ID)
+	categories := NewCategoryService(db)
+	groceries := categoryByName(t, mustListCategories(t, categories, user.ID), models.CategoryTypeExpense, "Groceries")
+	organic, err := categories.CreateCategory(ctx, user.ID, &models.Category{Name: "Organic", Type: models.CategoryTypeExpense, ParentID: &groceries.ID})
+	require.NoError(t, err)
+
+	base := time.Date(2026, time.March, 1, 9, 0, 0, 0, time.UTC)
+	for _, expense := range []*models.Expense{
+		{AmountCents: 1000, CategoryID: &groceries.ID, IncurredAt: base, Description: "Weekly shop"},
+		{AmountCents: 2000, Category: "Transport", IncurredAt: base.AddDate(0, 0, 1), Description: "Taxi"},
+		{AmountCents: 2000, CategoryID: &organic.ID, IncurredAt: base.AddDate(0, 0, 1), Description: "100% organic"},
+		{AmountCents: 3000, Category: "Transport", IncurredAt: base.AddDate(0, 0, 2), Description: "Train_ticket"},
+		{AmountCents: 4000, Category: "Household", IncurredAt: base.AddDate(0, 0, 3), Splits: []models.ExpenseSplit{
+			{Category: "Household", AmountCents: 3000},
+			{CategoryID: &organic.ID, AmountCents: 1000},
+		}},
+	} {
+		_, _, err := svc.DebitExpense(ctx, user.ID, accountID, expense)
+		require.NoError(t, err)
+	}
+
+	// Pages follow each other without gaps or repeats, even across equal dates.
+	walk := func(filter TransactionFilter) []int64 {
+		t.Helper()
+		var amounts []int64
+		seen := make(map[uint]bool)
+		for {
+			page, err := svc.ListExpenses(ctx, user.ID, filter)
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(page.Expenses), filter.Limit)
+			require.Len(t, page.Totals, 1)
+			require.Equal(t, int64(12000), page.Totals[0].AmountCents, "totals cover every page")
+			require.Equal(t, int64(5), page.Totals[0].Count)
+			for _, expense := range page.Expenses {
+				require.False(t, seen[expense.ID])
+				seen[expense.ID] = true
+				amounts = append(amounts, expense.AmountCents)
+			}
+			if page.NextCursor == "" {
+				return amounts
+			}
+			filter.Cursor = page.NextCursor
+		}
+	}
+	require.Equal(t, []int64{4000, 3000, 2000, 2000, 1000}, walk(TransactionFilter{Limit: 2}))
+	require.Equal(t, []int64{1000, 2000, 2000, 3000, 4000}, walk(TransactionFilter{Limit: 2, Sort: SortByAmount, Ascending: true}))
+
+	first, err := svc.ListExpenses(ctx, user.ID, TransactionFilter{Limit: 2})
+	require.NoError(t, err)
+	_, err = svc.ListExpenses(ctx, user.ID, TransactionFilter{Limit: 2, Sort: SortByAmount, Cursor: first.NextCursor})
+	require.ErrorIs(t, err, ErrPreconditionFailed, "a cursor only continues its own sort order")
+	_, err = svc.ListExpenses(ctx, user.ID, TransactionFilter{Cursor: "not-a-cursor"})
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+	_, err = svc.ListExpenses(ctx, user.ID, TransactionFilter{Sort: "category"})
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+
+	amounts := func(filter TransactionFilter) []int64 {
+		t.Helper()
+		var result []int64
+		for _, expense := range mustListExpenses(t, svc, user.ID, filter) {
+			result = append(result, expense.AmountCents)
+		}
+		return result
+	}
+	require.Equal(t, []int64{3000, 2000, 2000}, amounts(TransactionFilter{From: base.AddDate(0, 0, 1), To: base.AddDate(0, 0, 3)}))
+	require.Equal(t, []int64{3000, 2000, 2000}, amounts(TransactionFilter{MinAmount: "20", MaxAmount: "30.00"}))
+	require.Equal(t, []int64{3000}, amounts(TransactionFilter{MinAmount: "20.001", MaxAmount: "39.999"}), "a bound between two minor units rounds inwards")
+	_, err = svc.ListExpenses(ctx, user.ID, TransactionFilter{MinAmount: "30", MaxAmount: "20"})
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+	_, err = svc.ListExpenses(ctx, user.ID, TransactionFilter{MinAmount: "1e3"})
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+	require.Equal(t, []int64{2000}, amounts(TransactionFilter{Search: "100%"}), "wildcards are matched literally")
+	require.Equal(t, []int64{3000, 2000}, amounts(TransactionFilter{Search: "TRANSPORT"}))
+	require.Equal(t, []int64{3000}, amounts(TransactionFilter{Search: "n_t"}))
+	require.Equal(t, []int64{4000, 2000, 1000}, amounts(TransactionFilter{CategoryID: groceries.ID}), "subcategories and split lines match")
+	require.Equal(t, []int64{4000, 2000}, amounts(TransactionFilter{CategoryID: organic.ID}))
+	_, err = svc.ListExpenses(ctx, user.ID, TransactionFilter{From: base, To: base})
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+
+	// Totals are kept apart per account currency.
+	dollars, err := svc.CreateAccount(ctx, user.ID, &models.Account{Name: "Dollars", Type: models.AccountTypeCash, CurrencyISOCode: "usd"})
+	require.NoError(t, err)
+	employer, _, err := svc.CreditIncome(ctx, user.ID, dollars.ID, &models.Income{AmountCents: 50000, Source: "Employer", ReceivedAt: base})
+	require.NoError(t, err)
+	_, _, err = svc.CreditIncome(ctx, user.ID, accountID, &models.Income{AmountCents: 70000, Source: "Tenant", ReceivedAt: base})
+	require.NoError(t, err)
+	_, _, err = svc.CreditIncome(ctx, user.ID, accountID, &models.Income{AmountCents: 10000, Source: "Employer", ReceivedAt: base})
+	require.NoError(t, err)
+
+	incomes, err := svc.ListIncomes(ctx, user.ID, TransactionFilter{})
+	require.NoError(t, err)
+	require.Equal(t, []TransactionTotal{
+		{CurrencyISOCode: "UAH", Count: 2, AmountCents: 80000},
+		{CurrencyISOCode: "USD", Count: 1, AmountCents: 50000},
+	}, incomes.Totals)
+	require.Len(t, mustListIncomes(t, svc, user.ID, TransactionFilter{SourceID: *employer.SourceID}), 2)
+
+	// Amount bounds apply to every row in the currency it was booked in.
+	yen, err := svc.CreateAccount(ctx, user.ID, &models.Account{Name: "Yen", Type: models.AccountTypeCash, CurrencyISOCode: "jpy"})
+	require.NoError(t, err)
+	_, _, err = svc.CreditIncome(ctx, user.ID, yen.ID, &models.Income{AmountCents: 500, Source: "Gift", ReceivedAt: base})
+	require.NoError(t, err)
+	incomeAmounts := func(filter TransactionFilter) map[string]int64 {
+		t.Helper()
+		result := make(map[string]int64)
+		for _, income := range mustListIncomes(t, svc, user.ID, filter) {
+			result[income.Source+" "+income.CurrencyISOCode] = income.AmountCents
+		}
+		return result
+	}
+	require.Equal(t, map[string]int64{"Employer USD": 50000, "Tenant UAH": 70000, "Gift JPY": 500}, incomeAmounts(TransactionFilter{MinAmount: "500"}))
+	require.Equal(t, map[string]int64{"Employer UAH": 10000}, incomeAmounts(TransactionFilter{MinAmount: "100", MaxAmount: "499.99"}))
+	require.Empty(t, incomeAmounts(TransactionFilter{MaxAmount: "0.5"}))
+}
+
+func TestAccountServiceTransactionFeed(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "feed@example.com", "strongpass", "uah")
+	require.NoError(t, err)
+
+	svc := NewAccountService(db, false)
+	mainID := defaultAccountID(t, svc, user.ID)
+	savings, err := svc.CreateAccount(ctx, user.ID, &models.Account{Name: "Savings", Type: models.AccountTypeSavings})
+	require.NoError(t, err)
+
+	base := time.Date(2026, time.April, 1, 9, 0, 0, 0, time.UTC)
+	_, _, err = svc.CreditIncome(ctx, user.ID, mainID, &models.Income{AmountCents: 100000, Source: "Employer", ReceivedAt: base})
+	require.NoError(t, err)
+	_, _, err = svc.DebitExpense(ctx, user.ID, mainID, &models.Expense{AmountCents: 2500, Category: "Groceries", IncurredAt: base.Add(time.Hour)})
+	require.NoError(t, err)
+	transfer, _, _, err := svc.Transfer(ctx, user.ID, &models.Transfer{FromAccountID: mainID, ToAccountID: savings.ID, AmountCents: 30000, TransferredAt: base.AddDate(0, 0, 1)})
+	require.NoError(t, err)
+	// Recorded last but dated first: the running balance follows the entry dates.
+	_, _, err = svc.CreditIncome(ctx, user.ID, savings.ID, &models.Income{AmountCents: 5000, Source: "Interest", ReceivedAt: base.Add(-time.Hour)})
+	require.NoError(t, err)
+
+	type row struct {
+		Type    string
+		Account uint
+		Amount  int64
+		Balance int64
+	}
+	walk := func(filter FeedFilter) []row {
+		t.Helper()
+		var rows []row
+		for {
+			page, err := svc.ListTransactions(ctx, user.ID, filter)
+			require.NoError(t, err)
+			for _, entry := range page.Entries {
+				rows = append(rows, row{entry.Type, entry.AccountID, entry.AmountCents, entry.BalanceAfterCents})
+			}
+			if page.NextCursor == "" {
+				return rows
+			}
+			filter.Cursor = page.NextCursor
+		}
+	}
+
+	require.Equal(t, []row{
+		{FeedEntryIncome, savings.ID, 5000, 5000},
+		{FeedEntryIncome, mainID, 100000, 100000},
+		{FeedEntryExpense, mainID, -2500, 97500},
+		{FeedEntryTransfer, mainID, -30000, 67500},
+		{FeedEntryTransfer, savings.ID, 30000, 35000},
+	}, walk(FeedFilter{Ascending: true, Limit: 2}))
+
+	newest := walk(FeedFilter{Limit: 1})
+	require.Len(t, newest, 5)
+	require.Equal(t, row{FeedEntryTransfer, savings.ID, 30000, 35000}, newest[0])
+
+	// Filtering keeps the balances computed over the whole history.
+	require.Equal(t, []row{
+		{FeedEntryTransfer, savings.ID, 30000, 35000},
+		{FeedEntryIncome, savings.ID, 5000, 5000},
+	}, walk(FeedFilter{AccountID: savings.ID}))
+	require.Equal(t, []row{
+		{FeedEntryExpense, mainID, -2500, 97500},
+	}, walk(FeedFilter{Types: []string{FeedEntryExpense}}))
+	require.Len(t, walk(FeedFilter{From: base, To: base.AddDate(0, 0, 1)}), 2)
+
+	page, err := svc.ListTransactions(ctx, user.ID, FeedFilter{AccountID: mainID, Types: []string{FeedEntryTransfer}})
+	require.NoError(t, err)
+	require.Len(t, page.Entries, 1)
+	require.Equal(t, transfer.ID, page.Entries[0].ID)
+	require.Equal(t, savings.ID, *page.Entries[0].CounterpartyAccountID)
+	require.Equal(t, "UAH", page.Entries[0].CurrencyISOCode)
+
+	for _, account := range []uint{mainID, savings.ID} {
+		stored, err := svc.GetAccount(ctx, user.ID, account)
+		require.NoError(t, err)
+		latest, err := svc.ListTransactions(ctx, user.ID, FeedFilter{AccountID: account, Limit: 1})
+		require.NoError(t, err)
+		require.Equal(t, stored.BalanceCents, latest.Entries[0].BalanceAfterCents)
+	}
+
+	first, err := svc.ListTransactions(ctx, user.ID, FeedFilter{Limit: 1})
+	require.NoError(t, err)
+	_, err = svc.ListTransactions(ctx, user.ID, FeedFilter{Ascending: true, Cursor: first.NextCursor})
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+	_, err = svc.ListTransactions(ctx, user.ID, FeedFilter{Types: []string{"refund"}})
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+}
+
+func TestAccountServiceBalanceHistory(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "history@example.com", "strongpass", "uah")
+	require.NoError(t, err)
+
+	svc := NewAccountService(db, true)
+	mainID := defaultAccountID(t, svc, user.ID)
+	savings, err := svc.CreateAccount(ctx, user.ID, &models.Account{Name: "Savings", Type: models.AccountTypeSavings})
+	require.NoError(t, err)
+
+	day := func(month time.Month, d, hour int) time.Time {
+		return time.Date(2025, month, d, hour, 0, 0, 0, time.UTC)
+	}
+	_, _, err = svc.CreditIncome(ctx, user.ID, mainID, &models.Income{AmountCents: 100000, Source: "Employer", ReceivedAt: day(time.January, 30, 9)})
+	require.NoError(t, err)
+	_, _, err = svc.DebitExpense(ctx, user.ID, mainID, &models.Expense{AmountCents: 20000, Category: "Rent", IncurredAt: day(time.February, 3, 12)})
+	require.NoError(t, err)
+	_, _, _, err = svc.Transfer(ctx, user.ID, &models.Transfer{FromAccountID: mainID, ToAccountID: savings.ID, AmountCents: 30000, TransferredAt: day(time.March, 4, 8)})
+	require.NoError(t, err)
+
+	balanceAt := func(accountID uint, at time.Time) int64 {
+		t.Helper()
+		_, balance, _, err := svc.BalanceAt(ctx, user.ID, accountID, at)
+		require.NoError(t, err)
+		return balance
+	}
+	require.Equal(t, int64(0), balanceAt(mainID, day(time.January, 30, 8)))
+	require.Equal(t, int64(100000), balanceAt(mainID, day(time.January, 30, 9)), "transactions at the instant count")
+	require.Equal(t, int64(80000), balanceAt(mainID, day(time.March, 1, 0)))
+	require.Equal(t, int64(50000), balanceAt(mainID, day(time.March, 31, 0)))
+	require.Equal(t, int64(30000), balanceAt(savings.ID, day(time.April, 1, 0)))
+	_, _, _, err = svc.BalanceAt(ctx, user.ID+1, mainID, day(time.March, 1, 0))
+	require.ErrorIs(t, err, ErrNotFound)
+
+	// Month starts are answered from snapshots, which backdated changes invalidate.
+	var snapshot models.BalanceSnapshot
+	require.NoError(t, db.Where("account_id = ? AND month_start = ?", mainID, day(time.March, 1, 0)).First(&snapshot).Error)
+	require.Equal(t, int64(80000), snapshot.BalanceCents)
+	require.NoError(t, db.Model(&snapshot).Update("balance_cents", 1).Error)
+	require.Equal(t, int64(1-30000), balanceAt(mainID, day(time.March, 31, 0)), "the stored snapshot is used")
+
+	bonus, _, err := svc.CreditIncome(ctx, user.ID, mainID, &models.Income{AmountCents: 5000, Source: "Employer", ReceivedAt: day(time.February, 20, 9)})
+	require.NoError(t, err)
+	var stale int64
+	require.NoError(t, db.Model(&models.BalanceSnapshot{}).Where("account_id = ? AND month_start > ?", mainID, bonus.ReceivedAt).Count(&stale).Error)
+	require.Zero(t, stale)
+	require.Equal(t, int64(55000), balanceAt(mainID, day(time.March, 31, 0)))
+
+	// A snapshot built from an account read before a backdated change keeps the old epoch
+	// and is never used, as when a balance query races with the change.
+	before, err := svc.GetAccount(ctx, user.ID, mainID)
+	require.NoError(t, err)
+	refund, _, err := svc.CreditIncome(ctx, user.ID, mainID, &models.Income{AmountCents: 700, Source: "Shop", ReceivedAt: day(time.January, 31, 9)})
+	require.NoError(t, err)
+	_, err = svc.balanceAtMonthStart(ctx, before, day(time.May, 1, 0))
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&models.BalanceSnapshot{}).
+		Where("account_id = ? AND month_start = ? AND epoch = ?", mainID, day(time.May, 1, 0), before.SnapshotEpoch).
+		Update("balance_cents", 1).Error)
+	require.Equal(t, int64(55700), balanceAt(mainID, day(time.May, 1, 0)))
+	_, err = svc.DeleteIncome(ctx, user.ID, refund.ID, 0)
+	require.NoError(t, err)
+	after, err := svc.GetAccount(ctx, user.ID, mainID)
+	require.NoError(t, err)
+	require.Greater(t, after.SnapshotEpoch, before.SnapshotEpoch)
+	require.NoError(t, db.Where("account_id = ? AND month_start = ? AND epoch = ?", mainID, day(time.January, 1, 0), after.SnapshotEpoch).
+		First(&models.BalanceSnapshot{}).Error, "earlier snapshots carry over to the new epoch")
+
+	moved := day(time.April, 2, 9)
+	_, _, err = svc.UpdateIncome(ctx, user.ID, bonus.ID, 0, IncomeUpdate{ReceivedAt: &moved})
+	require.NoError(t, err)
+	require.Equal(t, int64(50000), balanceAt(mainID, day(time.March, 31, 0)))
+	_, err = svc.DeleteIncome(ctx, user.ID, bonus.ID, 0)
+	require.NoError(t, err)
+	require.Equal(t, int64(50000), balanceAt(mainID, day(time.April, 30, 0)))
+
+	history, err := svc.BalanceHistory(ctx, user.ID, mainID, day(time.February, 2, 0), day(time.February, 4, 0), IntervalDay)
+	require.NoError(t, err)
+	require.Equal(t, int64(100000), history.OpeningCents)
+	require.Equal(t, "UAH", history.OpeningCurrencyISOCode)
+	require.Equal(t, []BalancePoint{
+		{Start: day(time.February, 2, 0), End: day(time.February, 3, 0), CurrencyISOCode: "UAH", BalanceCents: 100000},
+		{Start: day(time.February, 3, 0), End: day(time.February, 4, 0), CurrencyISOCode: "UAH", BalanceCents: 80000, ChangeCents: -20000},
+		{Start: day(time.February, 4, 0), End: day(time.February, 5, 0), CurrencyISOCode: "UAH", BalanceCents: 80000},
+	}, history.Points)
+
+	history, err = svc.BalanceHistory(ctx, user.ID, mainID, day(time.January, 15, 0), day(time.March, 15, 0), IntervalMonth)
+	require.NoError(t, err)
+	require.Zero(t, history.OpeningCents)
+	require.Len(t, history.Points, 3)
+	require.Equal(t, []int64{100000, 80000, 50000}, []int64{history.Points[0].BalanceCents, history.Points[1].BalanceCents, history.Points[2].BalanceCents})
+
+	history, err = svc.BalanceHistory(ctx, user.ID, mainID, day(time.February, 5, 0), day(time.February, 5, 0), IntervalWeek)
+	require.NoError(t, err)
+	require.Len(t, history.Points, 1)
+	require.Equal(t, day(time.February, 3, 0), history.Points[0].Start, "weeks start on Monday")
+	require.Equal(t, int64(-20000), history.Points[0].ChangeCents)
+
+	_, err = svc.BalanceHistory(ctx, user.ID, mainID, day(time.February, 5, 0), day(time.February, 4, 0), IntervalDay)
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+	_, err = svc.BalanceHistory(ctx, user.ID, mainID, day(time.February, 5, 0), day(time.February, 6, 0), "hour")
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+	_, err = svc.BalanceHistory(ctx, user.ID, mainID, day(time.January, 1, 0), day(time.January, 1, 0).AddDate(3, 0, 0), IntervalDay)
+	require.ErrorIs(t, err, ErrPreconditionFailed, "too many points")
+}
+
+func TestAccountServiceBalanceHistoryAcrossConversion(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "history-conversion@example.com", "strongpass", "uah")
+	require.NoError(t, err)
+
+	svc := NewAccountService(db, false)
+	accountID := defaultAccountID(t, svc, user.ID)
+	earned := time.Now().UTC().AddDate(0, 0, -40)
+	_, _, err = svc.CreditIncome(ctx, user.ID, accountID, &models.Income{AmountCents: 100000, Source: "Salary", ReceivedAt: earned})
+	require.NoError(t, err)
+
+	_, _, err = svc.ChangeDefaultCurrency(ctx, user.ID, CurrencyChange{Currency: "usd", Convert: true, Rate: "0.025"})
+	require.NoError(t, err)
+	_, _, err = svc.DebitExpense(ctx, user.ID, accountID, &models.Expense{AmountCents: 500, Category: "Coffee"})
+	require.NoError(t, err)
+
+	_, balance, code, err := svc.BalanceAt(ctx, user.ID, accountID, earned.Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, int64(100000), balance)
+	require.Equal(t, "UAH", code, "balances before the conversion keep the old currency")
+	_, balance, code, err = svc.BalanceAt(ctx, user.ID, accountID, time.Now().UTC().Add(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, int64(2000), balance)
+	require.Equal(t, "USD", code)
+
+	history, err := svc.BalanceHistory(ctx, user.ID, accountID, earned, time.Now().UTC(), IntervalMonth)
+	require.NoError(t, err)
+	require.Equal(t, "UAH", history.OpeningCurrencyISOCode)
+	first, last := history.Points[0], history.Points[len(history.Points)-1]
+	require.Equal(t, "UAH", first.CurrencyISOCode)
+	require.Equal(t, int64(100000), first.BalanceCents)
+	require.Equal(t, "USD", last.CurrencyISOCode)
+	require.Equal(t, int64(2000), last.BalanceCents)
+	require.Equal(t, int64(-500), last.ChangeCents, "the change is measured from the converted opening balance")
+}
+
+func mustListCategories(t *testing.T, svc *CategoryService, userID uint) []models.Category {
+	t.Helper()
+
+	categories, err := svc.ListCategories(context.Background(), userID, "")
+	require.NoError(t, err)
+	return categories
+}
+
+func mustListIncomes(t *testing.T, svc *AccountService, userID uint, filter TransactionFilter) []models.Income {
+	t.Helper()
+
+	page, err := svc.ListIncomes(context.Background(), userID, filter)
+	require.NoError(t, err)
+	return page.Incomes
+}
+
+func mustListExpenses(t *testing.T, svc *AccountService, userID uint, filter TransactionFilter) []models.Expense {
+	t.Helper()
+
+	page, err := svc.ListExpenses(context.Background(), userID, filter)
+	require.NoError(t, err)
+	return page.Expenses
 }
@@ -2,55 +2,70 @@ package storage
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
+	"errors"
 	"fmt"
-	"strings"
 
 	"gorm.io/gorm"
 
+	"bckndlab3/src/internal/currency"
 	"bckndlab3/src/internal/models"
 )
 
 // AuthService handles user creation and credential verification.
 type AuthService struct {
-	db    *gorm.DB
-	users *UserRepository
+	db         *gorm.DB
+	users      *UserRepository
+	categories *CategoryRepository
+	hasher     PasswordHasher
 }
 
-func NewAuthService(db *gorm.DB) *AuthService {
+func NewAuthService(db *gorm.DB, hasher PasswordHasher) *AuthService {
 	return &AuthService{
-		db:    db,
-		users: NewUserRepository(db),
+		db:         db,
+		users:      NewUserRepository(db),
+		categories: NewCategoryRepository(db),
+		hasher:     hasher,
 	}
 }
 
-// RegisterUser creates a user, hashes the password, and ensures an account exists.
+// RegisterUser creates a user, hashes the password, opens the user's default account and
+// seeds the default category catalogue.
 func (s *AuthService) RegisterUser(ctx context.Context, email, password, defaultCurrency string) (*models.User, error) {
 	if defaultCurrency == "" {
-		defaultCurrency = "UAH"
-	} else {
-		defaultCurrency = strings.ToUpper(defaultCurrency)
+		defaultCurrency = currency.DefaultCode
+	}
+	defaultCurrency, err := normalizeCurrency(defaultCurrency)
+	if err != nil {
+		return nil, err
 	}
 
-	hashed := hashPassword(password)
+	if err := checkPasswordLength(s.hasher, password); err != nil {
+		return nil, err
+	}
+	hashed, err := s.hasher.Hash(password)
+	if err != nil {
+		return nil, fmt.Errorf("hash password: %w", err)
+	}
 	user := &models.User{
 		Email:           email,
 		PasswordHash:    hashed,
 		DefaultCurrency: defaultCurrency,
 	}
 
-	err := WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+	err = WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
 		if err := tx.WithContext(ctx).Create(user).Error; err != nil {
 			return translateError(err)
 		}
 		if err := tx.WithContext(ctx).Create(&models.Account{
 			UserID:          user.ID,
+			Name:            models.DefaultAccountName,
+			Type:            models.AccountTypeWallet,
+			IsDefault:       true,
 			CurrencyISOCode: user.DefaultCurrency,
 		}).Error; err != nil {
 			return translateError(err)
 		}
-		return nil
+		return s.categories.SeedDefaults(ctx, tx, user.ID)
 	})
 	if err != nil {
 		return nil, err
@@ -59,25 +74,53 @@ func (s *AuthService) RegisterUser(ctx context.Context, email, password, default
 	return user, nil
 }
 
-// Authenticate validates user credentials.
+// Authenticate validates user credentials. Hashes produced by an outdated scheme or with
+// outdated parameters are transparently replaced after a successful verification.
 func (s *AuthService) Authenticate(ctx context.Context, email, password string) (*models.User, error) {
 	user, err := s.users.GetByEmail(ctx, email)
 	if err != nil {
 		return nil, err
 	}
-	expected := hashPassword(password)
-	if user.PasswordHash != expected {
+
+	ok, err := s.hasher.Verify(password, user.PasswordHash)
+	if err != nil && !errors.Is(err, ErrUnsupportedHash) {
+		return nil, err
+	}
+	if !ok {
 		return nil, fmt.Errorf("%w: invalid credentials", ErrPreconditionFailed)
 	}
+
+	if s.hasher.NeedsRehash(user.PasswordHash) {
+		// The user is already authenticated at this point; failing to upgrade the
+		// stored hash must not fail the login, it will simply be retried next time.
+		if rehashed, err := s.hasher.Hash(password); err == nil {
+			if err := s.users.UpdatePasswordHash(ctx, user.ID, rehashed); err == nil {
+				user.PasswordHash = rehashed
+			}
+		}
+	}
+
 	return user, nil
 }
 
+// GetUser fetches a user by identifier.
+func (s *AuthService) GetUser(ctx context.Context, userID uint) (*models.User, error) {
+	return s.users.GetByID(ctx, userID)
+}
+
+// IsAdmin reports whether the user holds the admin role. Deleted users are not admins.
+func (s *AuthService) IsAdmin(ctx context.Context, userID uint) (bool, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return user.IsAdmin, nil
+}
+
 // DeleteUser removes a user and all owned data.
 func (s *AuthService) DeleteUser(ctx context.Context, userID uint) error {
 	return s.users.DeleteByID(ctx, userID)
 }
-
-func hashPassword(password string) string {
-	sum := sha256.Sum256([]byte(password))
-	return hex.EncodeToString(sum[:])
-}
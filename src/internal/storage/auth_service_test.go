@@ -2,16 +2,22 @@ package storage
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"bckndlab3/src/internal/models"
 )
 
 func TestAuthServiceRegisterAndAuthenticate(t *testing.T) {
 	db := setupTestDB(t)
 	ctx := context.Background()
 
-	service := NewAuthService(db)
+	service := NewAuthService(db, newTestPasswordHasher())
 
 	user, err := service.RegisterUser(ctx, "auth@example.com", "topsecret", "gbp")
 	require.NoError(t, err)
@@ -35,7 +41,7 @@ func TestAuthServiceDeleteUser(t *testing.T) {
 	db := setupTestDB(t)
 	ctx := context.Background()
 
-	service := NewAuthService(db)
+	service := NewAuthService(db, newTestPasswordHasher())
 
 	user, err := service.RegisterUser(ctx, "delete@example.com", "strongpass", "usd")
 	require.NoError(t, err)
@@ -61,7 +67,7 @@ func TestAuthServiceRegisterDuplicateEmail(t *testing.T) {
 	db := setupTestDB(t)
 	ctx := context.Background()
 
-	service := NewAuthService(db)
+	service := NewAuthService(db, newTestPasswordHasher())
 
 	_, err := service.RegisterUser(ctx, "dup@example.com", "strongpass", "usd")
 	require.NoError(t, err)
@@ -69,3 +75,49 @@ func TestAuthServiceRegisterDuplicateEmail(t *testing.T) {
 	_, err = service.RegisterUser(ctx, "dup@example.com", "anotherpass", "usd")
 	require.ErrorIs(t, err, ErrConflict)
 }
+
+func TestAuthServiceAuthenticateUpgradesLegacyHash(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	service := NewAuthService(db, newTestPasswordHasher())
+
+	user, err := service.RegisterUser(ctx, "legacy@example.com", "topsecret", "usd")
+	require.NoError(t, err)
+
+	sum := sha256.Sum256([]byte("topsecret"))
+	legacy := hex.EncodeToString(sum[:])
+	require.NoError(t, db.Model(&models.User{}).Where("id = ?", user.ID).Update("password_hash", legacy).Error)
+
+	_, err = service.Authenticate(ctx, "legacy@example.com", "wrongsecret")
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+
+	authenticated, err := service.Authenticate(ctx, "legacy@example.com", "topsecret")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(authenticated.PasswordHash, "$argon2id$"))
+
+	var stored models.User
+	require.NoError(t, db.First(&stored, user.ID).Error)
+	require.Equal(t, authenticated.PasswordHash, stored.PasswordHash)
+
+	_, err = service.Authenticate(ctx, "legacy@example.com", "topsecret")
+	require.NoError(t, err)
+}
+
+func TestAuthServiceLimitsPasswordBytesOnlyForBcrypt(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	bcryptHasher := NewUpgradingHasher(NewBcryptHasher(bcrypt.MinCost), LegacySHA256Hasher{})
+	service := NewAuthService(db, bcryptHasher)
+
+	// 37 two-byte runes are 74 bytes, past what bcrypt hashes.
+	_, err := service.RegisterUser(ctx, "bcrypt-long@example.com", strings.Repeat("é", 37), "usd")
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+	_, err = service.RegisterUser(ctx, "bcrypt-max@example.com", strings.Repeat("é", 36), "usd")
+	require.NoError(t, err)
+
+	service = NewAuthService(db, newTestPasswordHasher())
+	_, err = service.RegisterUser(ctx, "argon-long@example.com", strings.Repeat("é", 37), "usd")
+	require.NoError(t, err)
+}
@@ -0,0 +1,393 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"bckndlab3/src/internal/models"
+)
+
+// Intervals of balance history points.
+const (
+	IntervalDay   = "day"
+	IntervalWeek  = "week"
+	IntervalMonth = "month"
+)
+
+// maxHistoryPoints caps the number of points a single balance history query may return.
+const maxHistoryPoints = 1000
+
+// snapshotGrace delays storing the snapshot of a month that has just started. A change
+// dated in the previous month and recorded just before the month started left the
+// snapshot epoch alone, so its transaction has this long to commit.
+const snapshotGrace = 5 * time.Minute
+
+// BalancePoint is the balance of an account at the end of one history interval, which
+// spans [Start, End), along with the net change over the interval. Both are in
+// CurrencyISOCode, the account currency at the end of the interval; when the account was
+// converted during the interval, the change is measured from the opening balance
+// converted at the conversion rate.
+type BalancePoint struct {
+	Start           time.Time
+	End             time.Time
+	CurrencyISOCode string
+	BalanceCents    int64
+	ChangeCents     int64
+}
+
+// BalanceHistory is the balance of an account over consecutive intervals. OpeningCents is
+// the balance at the start of the first interval, in OpeningCurrencyISOCode.
+type BalanceHistory struct {
+	Account                *models.Account
+	Interval               string
+	OpeningCents           int64
+	OpeningCurrencyISOCode string
+	Points                 []BalancePoint
+}
+
+// accountMovementsSQL lists the signed amounts by which incomes, expenses, transfers and
+// currency conversions moved the balance of the @account account, with their dates and,
+// for conversions, the conversion ID.
+const accountMovementsSQL = `
+SELECT amount_cents, occurred_at, conversion_id FROM (
+	SELECT amount_cents, received_at AS occurred_at, 0 AS conversion_id FROM incomes WHERE account_id = @account
+	UNION ALL
+	SELECT -amount_cents, incurred_at, 0 FROM expenses WHERE account_id = @account
+	UNION ALL
+	SELECT -amount_cents, transferred_at, 0 FROM transfers WHERE from_account_id = @account
+	UNION ALL
+	SELECT to_amount_cents, transferred_at, 0 FROM transfers WHERE to_account_id = @account
+	UNION ALL
+	SELECT to_amount_cents - from_amount_cents, converted_at, id FROM currency_conversions WHERE account_id = @account
+) movements`
+
+// accountMovement is a signed change of an account balance. ConversionID is set when the
+// change is a currency conversion.
+type accountMovement struct {
+	AmountCents  int64
+	OccurredAt   time.Time
+	ConversionID uint
+}
+
+// SumMovementsBefore returns the net amount of the account's transactions dated from
+// `from` up to but excluding `before`. A zero from covers all earlier transactions.
+func (r *AccountRepository) SumMovementsBefore(ctx context.Context, tx *gorm.DB, accountID uint, from, before time.Time) (int64, error) {
+	return sumMovements(ctx, tx, accountID, from, before, "<")
+}
+
+// SumMovementsThrough returns the net amount of the account's transactions dated from
+// `from` up to and including `through`. A zero from covers all earlier transactions.
+func (r *AccountRepository) SumMovementsThrough(ctx context.Context, tx *gorm.DB, accountID uint, from, through time.Time) (int64, error) {
+	return sumMovements(ctx, tx, accountID, from, through, "<=")
+}
+
+func sumMovements(ctx context.Context, tx *gorm.DB, accountID uint, from, to time.Time, comparison string) (int64, error) {
+	query := fmt.Sprintf("SELECT COALESCE(SUM(amount_cents), 0) FROM (%s) entries WHERE occurred_at %s @to", accountMovementsSQL, comparison)
+	args := map[string]any{"account": accountID, "to": to}
+	if !from.IsZero() {
+		query += " AND occurred_at >= @from"
+		args["from"] = from
+	}
+
+	var total int64
+	if err := tx.WithContext(ctx).Raw(query, args).Scan(&total).Error; err != nil {
+		return 0, translateError(err)
+	}
+	return total, nil
+}
+
+// listMovements returns the account's transactions dated in [from, before), ordered by date.
+func (r *AccountRepository) listMovements(ctx context.Context, accountID uint, from, before time.Time) ([]accountMovement, error) {
+	query := fmt.Sprintf("SELECT amount_cents, occurred_at, conversion_id FROM (%s) entries WHERE occurred_at >= @from AND occurred_at < @before ORDER BY occurred_at", accountMovementsSQL)
+
+	var movements []accountMovement
+	err := r.db.WithContext(ctx).
+		Raw(query, map[string]any{"account": accountID, "from": from, "before": before}).
+		Scan(&movements).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return movements, nil
+}
+
+// listConversions returns the account's currency conversions made after `since`, or at
+// `since` too with the ">=" comparison, ordered by time.
+func (r *AccountRepository) listConversions(ctx context.Context, accountID uint, since time.Time, comparison string) ([]models.CurrencyConversion, error) {
+	var conversions []models.CurrencyConversion
+	err := r.db.WithContext(ctx).
+		Where("account_id = ? AND converted_at "+comparison+" ?", accountID, since).
+		Order("converted_at ASC, id ASC").
+		Find(&conversions).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return conversions, nil
+}
+
+// currencyBefore returns the currency an account held before the first of conversions,
+// which are ordered by time, or its current currency when there is none.
+func currencyBefore(account *models.Account, conversions []models.CurrencyConversion) string {
+	if len(conversions) > 0 {
+		return conversions[0].FromCurrency
+	}
+	return account.CurrencyISOCode
+}
+
+// BalanceSnapshotRepository handles persistence for cached month-start balances.
+type BalanceSnapshotRepository struct {
+	db *gorm.DB
+}
+
+func NewBalanceSnapshotRepository(db *gorm.DB) *BalanceSnapshotRepository {
+	return &BalanceSnapshotRepository{db: db}
+}
+
+// Latest returns the account's most recent snapshot of the given epoch taken at or before at.
+func (r *BalanceSnapshotRepository) Latest(ctx context.Context, tx *gorm.DB, accountID, epoch uint, at time.Time) (*models.BalanceSnapshot, error) {
+	var snapshot models.BalanceSnapshot
+	err := tx.WithContext(ctx).
+		Where("account_id = ? AND epoch = ? AND month_start <= ?", accountID, epoch, at).
+		Order("month_start DESC").
+		First(&snapshot).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &snapshot, nil
+}
+
+// Create stores a snapshot unless one already exists for the same account, time and epoch.
+func (r *BalanceSnapshotRepository) Create(ctx context.Context, tx *gorm.DB, snapshot *models.BalanceSnapshot) error {
+	err := tx.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(snapshot).Error
+	if err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+// Invalidate removes the account's snapshots taken after since, which no longer reflect a
+// transaction recorded, edited or deleted at that time, and advances the account's
+// snapshot epoch. Snapshots taken at or before since carry over to the new epoch; any
+// other snapshot, including one a concurrent balance query builds from the ledger as it
+// was before the change, keeps the old epoch and is never used. Changes dated in the
+// current month cannot affect a stored snapshot, so they leave the epoch alone.
+func (r *BalanceSnapshotRepository) Invalidate(ctx context.Context, tx *gorm.DB, accountID uint, since time.Time) error {
+	if !since.Before(truncateToInterval(time.Now(), IntervalMonth)) {
+		return nil
+	}
+
+	db := tx.WithContext(ctx)
+	epoch := db.Model(&models.Account{}).Select("snapshot_epoch").Where("id = ?", accountID)
+	err := db.Model(&models.BalanceSnapshot{}).
+		Where("account_id = ? AND month_start <= ? AND epoch = (?)", accountID, since, epoch).
+		UpdateColumn("epoch", gorm.Expr("epoch + 1")).Error
+	if err != nil {
+		return translateError(err)
+	}
+	err = db.Model(&models.Account{}).
+		Where("id = ?", accountID).
+		UpdateColumn("snapshot_epoch", gorm.Expr("snapshot_epoch + 1")).Error
+	if err != nil {
+		return translateError(err)
+	}
+	err = db.Where("account_id = ? AND epoch <> (?)", accountID, epoch).
+		Delete(&models.BalanceSnapshot{}).Error
+	if err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+// BalanceAt returns one of the user's accounts and its balance at the given time,
+// counting the transactions dated up to and including it, along with the currency the
+// account held at that time.
+func (s *AccountService) BalanceAt(ctx context.Context, userID, accountID uint, at time.Time) (*models.Account, int64, string, error) {
+	account, err := s.accounts.GetByIDForUser(ctx, accountID, userID)
+	if err != nil {
+		return nil, 0, "", err
+	}
+
+	boundary := truncateToInterval(at, IntervalMonth)
+	opening, err := s.balanceAtMonthStart(ctx, account, boundary)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	movements, err := s.accounts.SumMovementsThrough(ctx, s.db, account.ID, boundary, at)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	later, err := s.accounts.listConversions(ctx, account.ID, at, ">")
+	if err != nil {
+		return nil, 0, "", err
+	}
+	return account, opening + movements, currencyBefore(account, later), nil
+}
+
+// BalanceHistory returns the balance of one of the user's accounts at the end of every
+// day, week (starting on Monday) or month from the interval containing from through the
+// one containing to. Intervals follow UTC calendar days.
+func (s *AccountService) BalanceHistory(ctx context.Context, userID, accountID uint, from, to time.Time, interval string) (*BalanceHistory, error) {
+	switch interval {
+	case IntervalDay, IntervalWeek, IntervalMonth:
+	default:
+		return nil, fmt.Errorf("%w: unknown interval %q", ErrPreconditionFailed, interval)
+	}
+	if to.Before(from) {
+		return nil, fmt.Errorf("%w: the history ends before it starts", ErrPreconditionFailed)
+	}
+
+	var points []BalancePoint
+	for start := truncateToInterval(from, interval); !start.After(to); start = nextInterval(start, interval) {
+		if len(points) == maxHistoryPoints {
+			return nil, fmt.Errorf("%w: a balance history has at most %d points", ErrPreconditionFailed, maxHistoryPoints)
+		}
+		points = append(points, BalancePoint{Start: start, End: nextInterval(start, interval)})
+	}
+
+	account, err := s.accounts.GetByIDForUser(ctx, accountID, userID)
+	if err != nil {
+		return nil, err
+	}
+	first, last := points[0].Start, points[len(points)-1].End
+
+	boundary := truncateToInterval(first, IntervalMonth)
+	opening, err := s.balanceAtMonthStart(ctx, account, boundary)
+	if err != nil {
+		return nil, err
+	}
+	before, err := s.accounts.SumMovementsBefore(ctx, s.db, account.ID, boundary, first)
+	if err != nil {
+		return nil, err
+	}
+	opening += before
+
+	movements, err := s.accounts.listMovements(ctx, account.ID, first, last)
+	if err != nil {
+		return nil, err
+	}
+	conversions, err := s.accounts.listConversions(ctx, account.ID, first, ">=")
+	if err != nil {
+		return nil, err
+	}
+	history := &BalanceHistory{
+		Account:                account,
+		Interval:               interval,
+		OpeningCents:           opening,
+		OpeningCurrencyISOCode: currencyBefore(account, conversions),
+		Points:                 points,
+	}
+
+	byID := make(map[uint]models.CurrencyConversion, len(conversions))
+	for _, conversion := range conversions {
+		byID[conversion.ID] = conversion
+	}
+	balance, next, pending := opening, 0, conversions
+	for i := range points {
+		start := balance
+		for ; next < len(movements) && movements[next].OccurredAt.Before(points[i].End); next++ {
+			balance += movements[next].AmountCents
+			if conversion, ok := byID[movements[next].ConversionID]; ok {
+				if start, err = convertAtConversion(start, conversion); err != nil {
+					return nil, err
+				}
+			}
+		}
+		for len(pending) > 0 && pending[0].ConvertedAt.Before(points[i].End) {
+			pending = pending[1:]
+		}
+		points[i].BalanceCents = balance
+		points[i].ChangeCents = balance - start
+		points[i].CurrencyISOCode = currencyBefore(account, pending)
+	}
+	return history, nil
+}
+
+// convertAtConversion converts an amount in minor units at the rate of a currency
+// conversion.
+func convertAtConversion(amount int64, conversion models.CurrencyConversion) (int64, error) {
+	rate, err := ParseExchangeRate(conversion.Rate)
+	if err != nil {
+		return 0, err
+	}
+	return ConvertMinorUnits(amount, rate, conversion.FromCurrency, conversion.ToCurrency), nil
+}
+
+// balanceAtMonthStart returns the balance of an account at the start of a month from its
+// snapshot, building the snapshot from the latest earlier one when it is missing. No lock
+// is taken: the snapshot is stored under the epoch the account had when it was read, so
+// if a backdated change commits meanwhile, the advanced epoch hides the snapshot. Months
+// that started less than snapshotGrace ago are computed without being stored.
+func (s *AccountService) balanceAtMonthStart(ctx context.Context, account *models.Account, at time.Time) (int64, error) {
+	var from time.Time
+	var balance int64
+	snapshot, err := s.snapshots.Latest(ctx, s.db, account.ID, account.SnapshotEpoch, at)
+	switch {
+	case err == nil:
+		if snapshot.MonthStart.Equal(at) {
+			return snapshot.BalanceCents, nil
+		}
+		from, balance = snapshot.MonthStart, snapshot.BalanceCents
+	case !errors.Is(err, ErrNotFound):
+		return 0, err
+	}
+
+	movements, err := s.accounts.SumMovementsBefore(ctx, s.db, account.ID, from, at)
+	if err != nil {
+		return 0, err
+	}
+	balance += movements
+
+	if time.Since(at) < snapshotGrace {
+		return balance, nil
+	}
+	snapshot = &models.BalanceSnapshot{AccountID: account.ID, MonthStart: at, Epoch: account.SnapshotEpoch, BalanceCents: balance}
+	if err := s.snapshots.Create(ctx, s.db, snapshot); err != nil {
+		return 0, err
+	}
+	return balance, nil
+}
+
+// invalidateSnapshots drops the snapshots of an account that a transaction dated at any of
+// the given times affects. It runs in the transaction that records the change, after the
+// account has been locked.
+func (s *AccountService) invalidateSnapshots(ctx context.Context, tx *gorm.DB, accountID uint, dates ...time.Time) error {
+	earliest := dates[0]
+	for _, date := range dates[1:] {
+		if date.Before(earliest) {
+			earliest = date
+		}
+	}
+	return s.snapshots.Invalidate(ctx, tx, accountID, earliest)
+}
+
+// truncateToInterval returns the start of the UTC day, week (Monday) or month containing t.
+func truncateToInterval(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	switch interval {
+	case IntervalMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case IntervalWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// nextInterval returns the start of the interval following the one starting at start.
+func nextInterval(start time.Time, interval string) time.Time {
+	switch interval {
+	case IntervalMonth:
+		return start.AddDate(0, 1, 0)
+	case IntervalWeek:
+		return start.AddDate(0, 0, 7)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
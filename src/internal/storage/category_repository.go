@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"bckndlab3/src/internal/models"
+)
+
+// CategoryRepository handles persistence for the per-user category catalogue.
+type CategoryRepository struct {
+	db *gorm.DB
+}
+
+func NewCategoryRepository(db *gorm.DB) *CategoryRepository {
+	return &CategoryRepository{db: db}
+}
+
+// List returns the categories of a user, optionally of one type, ordered by type and name.
+func (r *CategoryRepository) List(ctx context.Context, userID uint, kind string) ([]models.Category, error) {
+	query := r.db.WithContext(ctx).Where("user_id = ?", userID)
+	if kind != "" {
+		query = query.Where("type = ?", kind)
+	}
+
+	var categories []models.Category
+	if err := query.Order("type ASC, normalized_name ASC").Find(&categories).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return categories, nil
+}
+
+// GetForUser returns a category only if it belongs to the given user. It reads through tx,
+// which may be the repository's own handle outside of a transaction.
+func (r *CategoryRepository) GetForUser(ctx context.Context, tx *gorm.DB, categoryID, userID uint) (*models.Category, error) {
+	var category models.Category
+	err := tx.WithContext(ctx).
+		Where("id = ? AND user_id = ?", categoryID, userID).
+		First(&category).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &category, nil
+}
+
+// LockForUser loads a category of the given user inside tx and locks its row until the
+// transaction ends.
+func (r *CategoryRepository) LockForUser(ctx context.Context, tx *gorm.DB, categoryID, userID uint) (*models.Category, error) {
+	var category models.Category
+	if err := lockOwnedRow(ctx, tx, "categories", &category, categoryID, userID); err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// FindOrCreate returns the user's category of the given type whose normalized name matches
+// category.Name, creating category when there is none. Concurrent creations of the same
+// name resolve to a single row.
+func (r *CategoryRepository) FindOrCreate(ctx context.Context, tx *gorm.DB, category *models.Category) (*models.Category, error) {
+	category.NormalizedName = models.NormalizeCategoryName(category.Name)
+	err := tx.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(category).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	if category.ID != 0 {
+		return category, nil
+	}
+
+	var existing models.Category
+	err = tx.WithContext(ctx).
+		Where("user_id = ? AND type = ? AND normalized_name = ?", category.UserID, category.Type, category.NormalizedName).
+		First(&existing).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &existing, nil
+}
+
+// Create persists a new category.
+func (r *CategoryRepository) Create(ctx context.Context, tx *gorm.DB, category *models.Category) error {
+	category.NormalizedName = models.NormalizeCategoryName(category.Name)
+	if err := tx.WithContext(ctx).Create(category).Error; err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+// SeedDefaults creates models.DefaultCategories for a user.
+func (r *CategoryRepository) SeedDefaults(ctx context.Context, tx *gorm.DB, userID uint) error {
+	for _, seed := range models.DefaultCategories {
+		parent := &models.Category{UserID: userID, Type: seed.Type, Name: seed.Name, Icon: seed.Icon, Color: seed.Color}
+		if err := r.Create(ctx, tx, parent); err != nil {
+			return err
+		}
+		for _, name := range seed.Children {
+			child := &models.Category{UserID: userID, ParentID: &parent.ID, Type: seed.Type, Name: name, Icon: seed.Icon, Color: seed.Color}
+			if err := r.Create(ctx, tx, child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// UpdateFields applies column updates to a category of the given user.
+func (r *CategoryRepository) UpdateFields(ctx context.Context, tx *gorm.DB, categoryID, userID uint, fields map[string]any) error {
+	fields["version"] = nextVersion
+	result := tx.WithContext(ctx).Model(&models.Category{}).
+		Where("id = ? AND user_id = ?", categoryID, userID).
+		Updates(fields)
+	if err := result.Error; err != nil {
+		return translateError(err)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RenameExpenses copies a category's new name onto the expenses and expense split lines
+// that reference it; expenses whose lines are renamed get a new version.
+func (r *CategoryRepository) RenameExpenses(ctx context.Context, tx *gorm.DB, categoryID uint, name string) error {
+	err := tx.WithContext(ctx).Model(&models.Expense{}).
+		Where("category_id = ?", categoryID).
+		Updates(map[string]any{"category": name, "version": nextVersion}).Error
+	if err != nil {
+		return translateError(err)
+	}
+	err = tx.WithContext(ctx).Model(&models.ExpenseSplit{}).
+		Where("category_id = ?", categoryID).
+		Update("category", name).Error
+	if err != nil {
+		return translateError(err)
+	}
+	split := tx.Session(&gorm.Session{NewDB: true}).Model(&models.ExpenseSplit{}).Select("expense_id").Where("category_id = ?", categoryID)
+	err = tx.WithContext(ctx).Model(&models.Expense{}).
+		Where("id IN (?) AND (category_id IS NULL OR category_id <> ?)", split, categoryID).
+		Update("version", nextVersion).Error
+	if err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+// CountReferences returns how many subcategories and transactions, including split lines,
+// refer to a category.
+func (r *CategoryRepository) CountReferences(ctx context.Context, tx *gorm.DB, categoryID uint) (children, transactions int64, err error) {
+	db := tx.WithContext(ctx)
+	if err := db.Model(&models.Category{}).Where("parent_id = ?", categoryID).Count(&children).Error; err != nil {
+		return 0, 0, translateError(err)
+	}
+	var incomes, expenses int64
+	if err := db.Model(&models.Income{}).Where("category_id = ?", categoryID).Count(&incomes).Error; err != nil {
+		return 0, 0, translateError(err)
+	}
+	if err := db.Model(&models.Expense{}).Where("category_id = ?", categoryID).Count(&expenses).Error; err != nil {
+		return 0, 0, translateError(err)
+	}
+	var incomeSplits, expenseSplits int64
+	if err := db.Model(&models.IncomeSplit{}).Where("category_id = ?", categoryID).Count(&incomeSplits).Error; err != nil {
+		return 0, 0, translateError(err)
+	}
+	if err := db.Model(&models.ExpenseSplit{}).Where("category_id = ?", categoryID).Count(&expenseSplits).Error; err != nil {
+		return 0, 0, translateError(err)
+	}
+	return children, incomes + expenses + incomeSplits + expenseSplits, nil
+}
+
+// Delete removes a category of the given user.
+func (r *CategoryRepository) Delete(ctx context.Context, tx *gorm.DB, categoryID, userID uint) error {
+	result := tx.WithContext(ctx).
+		Where("id = ? AND user_id = ?", categoryID, userID).
+		Delete(&models.Category{})
+	if err := result.Error; err != nil {
+		return translateError(err)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
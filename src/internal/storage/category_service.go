@@ -0,0 +1,223 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"bckndlab3/src/internal/models"
+)
+
+// CategoryService manages the per-user income and expense category catalogue.
+type CategoryService struct {
+	db         *gorm.DB
+	categories *CategoryRepository
+}
+
+// CategoryUpdate lists the category attributes that may be changed; nil fields are kept.
+// A ParentID of zero moves the category to the top level.
+type CategoryUpdate struct {
+	Name     *string
+	ParentID *uint
+	Icon     *string
+	Color    *string
+}
+
+func NewCategoryService(db *gorm.DB) *CategoryService {
+	return &CategoryService{db: db, categories: NewCategoryRepository(db)}
+}
+
+// ListCategories returns the user's categories, optionally of one type.
+func (s *CategoryService) ListCategories(ctx context.Context, userID uint, kind string) ([]models.Category, error) {
+	if kind != "" && !models.IsValidCategoryType(kind) {
+		return nil, fmt.Errorf("%w: unknown category type %q", ErrPreconditionFailed, kind)
+	}
+	return s.categories.List(ctx, userID, kind)
+}
+
+// GetCategory fetches a category, ensuring it belongs to the user.
+func (s *CategoryService) GetCategory(ctx context.Context, userID, categoryID uint) (*models.Category, error) {
+	return s.categories.GetForUser(ctx, s.db, categoryID, userID)
+}
+
+// CreateCategory adds a category to the user's catalogue. A subcategory must have the
+// type of its parent. Names are unique per type, ignoring case and extra whitespace.
+func (s *CategoryService) CreateCategory(ctx context.Context, userID uint, category *models.Category) (*models.Category, error) {
+	if !models.IsValidCategoryType(category.Type) {
+		return nil, fmt.Errorf("%w: unknown category type %q", ErrPreconditionFailed, category.Type)
+	}
+	name, err := categoryName(category.Name)
+	if err != nil {
+		return nil, err
+	}
+	category.Name = name
+	category.UserID = userID
+
+	err = WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		category.ID = 0 // the transaction may be retried
+
+		if category.ParentID != nil {
+			parent, err := s.categories.GetForUser(ctx, tx, *category.ParentID, userID)
+			if err != nil {
+				return err
+			}
+			if parent.Type != category.Type {
+				return fmt.Errorf("%w: a subcategory must have the type of its parent", ErrPreconditionFailed)
+			}
+		}
+		return s.categories.Create(ctx, tx, category)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// UpdateCategory edits a category. Renaming it also renames the expenses filed under it.
+// A category cannot be moved under itself or one of its descendants. A non-zero ifVersion
+// must match the category's version.
+func (s *CategoryService) UpdateCategory(ctx context.Context, userID, categoryID, ifVersion uint, update CategoryUpdate) (*models.Category, error) {
+	fields := make(map[string]any)
+	if update.Name != nil {
+		name, err := categoryName(*update.Name)
+		if err != nil {
+			return nil, err
+		}
+		fields["name"] = name
+		fields["normalized_name"] = models.NormalizeCategoryName(name)
+	}
+	if update.Icon != nil {
+		fields["icon"] = *update.Icon
+	}
+	if update.Color != nil {
+		fields["color"] = *update.Color
+	}
+
+	err := WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		category, err := s.categories.LockForUser(ctx, tx, categoryID, userID)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(ifVersion, category.Version); err != nil {
+			return err
+		}
+
+		if update.ParentID != nil {
+			if *update.ParentID == 0 {
+				fields["parent_id"] = nil
+			} else {
+				if err := s.checkParent(ctx, tx, category, *update.ParentID); err != nil {
+					return err
+				}
+				fields["parent_id"] = *update.ParentID
+			}
+		}
+
+		if len(fields) == 0 {
+			return nil
+		}
+		if err := s.categories.UpdateFields(ctx, tx, categoryID, userID, fields); err != nil {
+			return err
+		}
+		if name, ok := fields["name"].(string); ok && name != category.Name {
+			return s.categories.RenameExpenses(ctx, tx, categoryID, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.categories.GetForUser(ctx, s.db, categoryID, userID)
+}
+
+// checkParent verifies that parentID may become the parent of category: it must be a
+// category of the same user and type that is neither the category nor a descendant of it.
+func (s *CategoryService) checkParent(ctx context.Context, tx *gorm.DB, category *models.Category, parentID uint) error {
+	parent, err := s.categories.GetForUser(ctx, tx, parentID, category.UserID)
+	if err != nil {
+		return err
+	}
+	if parent.Type != category.Type {
+		return fmt.Errorf("%w: a subcategory must have the type of its parent", ErrPreconditionFailed)
+	}
+	for ancestor := parent; ; {
+		if ancestor.ID == category.ID {
+			return fmt.Errorf("%w: a category cannot be moved under itself or its subcategories", ErrPreconditionFailed)
+		}
+		if ancestor.ParentID == nil {
+			return nil
+		}
+		if ancestor, err = s.categories.GetForUser(ctx, tx, *ancestor.ParentID, category.UserID); err != nil {
+			return err
+		}
+	}
+}
+
+// DeleteCategory removes a category that has no subcategories and is not used by any
+// income or expense. A non-zero ifVersion must match the category's version.
+func (s *CategoryService) DeleteCategory(ctx context.Context, userID, categoryID, ifVersion uint) error {
+	return WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		category, err := s.categories.LockForUser(ctx, tx, categoryID, userID)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(ifVersion, category.Version); err != nil {
+			return err
+		}
+
+		children, transactions, err := s.categories.CountReferences(ctx, tx, categoryID)
+		if err != nil {
+			return err
+		}
+		if children > 0 {
+			return fmt.Errorf("%w: delete or move the subcategories first", ErrPreconditionFailed)
+		}
+		if transactions > 0 {
+			return fmt.Errorf("%w: category is used by %d transactions", ErrPreconditionFailed, transactions)
+		}
+		return s.categories.Delete(ctx, tx, categoryID, userID)
+	})
+}
+
+// resolveCategory returns the category of the given type that a transaction is filed
+// under: the category with the given ID or, when the ID is zero, the one matching name,
+// which is created on first use.
+func resolveCategory(ctx context.Context, tx *gorm.DB, categories *CategoryRepository, userID uint, kind string, categoryID uint, name string) (*models.Category, error) {
+	if categoryID != 0 {
+		category, err := categories.GetForUser(ctx, tx, categoryID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if category.Type != kind {
+			return nil, fmt.Errorf("%w: category %d is not an %s category", ErrPreconditionFailed, categoryID, kind)
+		}
+		return category, nil
+	}
+
+	name, err := categoryName(name)
+	if err != nil {
+		return nil, err
+	}
+	return categories.FindOrCreate(ctx, tx, &models.Category{UserID: userID, Type: kind, Name: name})
+}
+
+// categoryName trims a category name and collapses inner runs of whitespace.
+func categoryName(name string) (string, error) {
+	return cleanName("category", name, 120)
+}
+
+// cleanName trims a catalogue entry name, collapses inner runs of whitespace and checks
+// its length in characters.
+func cleanName(kind, name string, maxLen int) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" {
+		return "", fmt.Errorf("%w: %s name must not be empty", ErrPreconditionFailed, kind)
+	}
+	if len([]rune(name)) > maxLen {
+		return "", fmt.Errorf("%w: %s name must be at most %d characters", ErrPreconditionFailed, kind, maxLen)
+	}
+	return name, nil
+}
@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"bckndlab3/src/internal/migrations"
+	"bckndlab3/src/internal/models"
+)
+
+func categoryByName(t *testing.T, categories []models.Category, kind, name string) models.Category {
+	t.Helper()
+
+	for _, category := range categories {
+		if category.Type == kind && category.Name == name {
+			return category
+		}
+	}
+	require.Failf(t, "category not found", "%s category %q", kind, name)
+	return models.Category{}
+}
+
+func TestCategoryServiceCatalogue(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "categories@example.com", "strongpass", "usd")
+	require.NoError(t, err)
+
+	svc := NewCategoryService(db)
+	categories, err := svc.ListCategories(ctx, user.ID, "")
+	require.NoError(t, err)
+	food := categoryByName(t, categories, models.CategoryTypeExpense, "Food")
+	groceries := categoryByName(t, categories, models.CategoryTypeExpense, "Groceries")
+	require.Equal(t, food.ID, *groceries.ParentID)
+	salary := categoryByName(t, categories, models.CategoryTypeIncome, "Salary")
+
+	incomeCategories, err := svc.ListCategories(ctx, user.ID, models.CategoryTypeIncome)
+	require.NoError(t, err)
+	for _, category := range incomeCategories {
+		require.Equal(t, models.CategoryTypeIncome, category.Type)
+	}
+	_, err = svc.ListCategories(ctx, user.ID, "transfer")
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+
+	_, err = svc.CreateCategory(ctx, user.ID, &models.Category{Type: models.CategoryTypeExpense, Name: "  FOOD "})
+	require.ErrorIs(t, err, ErrConflict, "names are unique regardless of case and whitespace")
+	_, err = svc.CreateCategory(ctx, user.ID, &models.Category{Type: models.CategoryTypeIncome, Name: "Bonus", ParentID: &food.ID})
+	require.ErrorIs(t, err, ErrPreconditionFailed, "a child has the type of its parent")
+
+	coffee, err := svc.CreateCategory(ctx, user.ID, &models.Category{Type: models.CategoryTypeExpense, Name: " Coffee   shops ", ParentID: &food.ID, Color: "#6F4E37"})
+	require.NoError(t, err)
+	require.Equal(t, "Coffee shops", coffee.Name)
+
+	_, err = svc.UpdateCategory(ctx, user.ID, food.ID, 0, CategoryUpdate{ParentID: &coffee.ID})
+	require.ErrorIs(t, err, ErrPreconditionFailed, "a category cannot move under its own subcategory")
+
+	// Expenses resolve free-text names against the catalogue.
+	accounts := NewAccountService(db, true)
+	accountID := defaultAccountID(t, accounts, user.ID)
+	expense, _, err := accounts.DebitExpense(ctx, user.ID, accountID, &models.Expense{AmountCents: 350, Category: "coffee SHOPS"})
+	require.NoError(t, err)
+	require.Equal(t, coffee.ID, *expense.CategoryID)
+	require.Equal(t, "Coffee shops", expense.Category)
+
+	books, _, err := accounts.DebitExpense(ctx, user.ID, accountID, &models.Expense{AmountCents: 900, Category: "Books"})
+	require.NoError(t, err)
+	require.NotNil(t, books.CategoryID, "unknown names are added to the catalogue")
+	again, _, err := accounts.DebitExpense(ctx, user.ID, accountID, &models.Expense{AmountCents: 100, Category: "books"})
+	require.NoError(t, err)
+	require.Equal(t, *books.CategoryID, *again.CategoryID)
+
+	_, _, err = accounts.DebitExpense(ctx, user.ID, accountID, &models.Expense{AmountCents: 100, CategoryID: &salary.ID})
+	require.ErrorIs(t, err, ErrPreconditionFailed, "an income category cannot file an expense")
+	income, _, err := accounts.CreditIncome(ctx, user.ID, accountID, &models.Income{AmountCents: 100, Source: "Employer", CategoryID: &salary.ID})
+	require.NoError(t, err)
+	require.Equal(t, salary.ID, *income.CategoryID)
+
+	// Renaming a category renames the expenses filed under it.
+	renamed := "Cafés"
+	coffee, err = svc.UpdateCategory(ctx, user.ID, coffee.ID, coffee.Version, CategoryUpdate{Name: &renamed})
+	require.NoError(t, err)
+	require.Equal(t, uint(2), coffee.Version)
+	expense, err = accounts.GetExpense(ctx, user.ID, expense.ID)
+	require.NoError(t, err)
+	require.Equal(t, "Cafés", expense.Category)
+
+	require.ErrorIs(t, svc.DeleteCategory(ctx, user.ID, food.ID, 0), ErrPreconditionFailed, "food has subcategories")
+	require.ErrorIs(t, svc.DeleteCategory(ctx, user.ID, coffee.ID, 0), ErrPreconditionFailed, "coffee is in use")
+	_, err = accounts.DeleteExpense(ctx, user.ID, expense.ID, 0)
+	require.NoError(t, err)
+	require.ErrorIs(t, svc.DeleteCategory(ctx, user.ID, coffee.ID, 1), ErrVersionMismatch)
+	require.NoError(t, svc.DeleteCategory(ctx, user.ID, coffee.ID, coffee.Version))
+	_, err = svc.GetCategory(ctx, user.ID, coffee.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	other, err := auth.RegisterUser(ctx, "other-categories@example.com", "strongpass", "usd")
+	require.NoError(t, err)
+	_, err = svc.GetCategory(ctx, other.ID, food.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestMigrationsBackfillExpenseCategories(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "legacy-categories@example.com", "strongpass", "usd")
+	require.NoError(t, err)
+	accounts := NewAccountService(db, true)
+	accountID := defaultAccountID(t, accounts, user.ID)
+
+	// Expenses written before the catalogue existed carry only free text.
+	for _, name := range []string{"Food", "food", "Food ", "Pet  supplies", "pet supplies"} {
+		require.NoError(t, db.Create(&models.Expense{
+			AccountID:   accountID,
+			UserID:      user.ID,
+			AmountCents: 100,
+			Category:    name,
+			IncurredAt:  time.Now().UTC(),
+		}).Error)
+	}
+
+	require.NoError(t, migrations.Run(db))
+	require.NoError(t, migrations.Run(db), "the backfill is idempotent")
+
+	var expenses []models.Expense
+	require.NoError(t, db.Where("user_id = ?", user.ID).Order("id").Find(&expenses).Error)
+	categories, err := NewCategoryService(db).ListCategories(ctx, user.ID, models.CategoryTypeExpense)
+	require.NoError(t, err)
+	food := categoryByName(t, categories, models.CategoryTypeExpense, "Food")
+	pets := categoryByName(t, categories, models.CategoryTypeExpense, "Pet supplies")
+
+	for _, expense := range expenses[:3] {
+		require.Equal(t, food.ID, *expense.CategoryID)
+		require.Equal(t, "Food", expense.Category)
+	}
+	for _, expense := range expenses[3:] {
+		require.Equal(t, pets.ID, *expense.CategoryID)
+		require.Equal(t, "Pet supplies", expense.Category)
+	}
+}
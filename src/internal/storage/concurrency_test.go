@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"bckndlab3/src/internal/migrations"
+	"bckndlab3/src/internal/models"
+)
+
+// setupFileTestDB opens a file-backed SQLite database, which unlike the shared in-memory
+// database lets several connections run transactions concurrently.
+func setupFileTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "stress.db") + "?_busy_timeout=5000&_foreign_keys=on"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, migrations.Run(db))
+
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(8)
+	t.Cleanup(func() {
+		_ = sqlDB.Close()
+	})
+
+	return db
+}
+
+func TestAccountServiceConcurrentDebitsNeverOverdraw(t *testing.T) {
+	db := setupFileTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "stress@example.com", "strongpass", "usd")
+	require.NoError(t, err)
+
+	svc := NewAccountService(db, false)
+	accountID := defaultAccountID(t, svc, user.ID)
+	_, _, err = svc.CreditIncome(ctx, user.ID, accountID, &models.Income{AmountCents: 1000, Source: "Salary"})
+	require.NoError(t, err)
+
+	const workers = 40
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+		rejected  int
+		failures  []error
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := svc.DebitExpense(ctx, user.ID, accountID, &models.Expense{AmountCents: 100, Category: "Coffee"})
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				succeeded++
+			case errors.Is(err, ErrInsufficientFunds):
+				rejected++
+			default:
+				failures = append(failures, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Empty(t, failures)
+	require.Equal(t, 10, succeeded)
+	require.Equal(t, workers-10, rejected)
+
+	account, err := svc.GetAccount(ctx, user.ID, accountID)
+	require.NoError(t, err)
+	require.Zero(t, account.BalanceCents)
+
+	report, err := NewIntegrityService(db).Check(ctx)
+	require.NoError(t, err)
+	require.Empty(t, report.Discrepancies)
+}
+
+func TestAccountServiceConcurrentFirstAccountsHaveOneDefault(t *testing.T) {
+	db := setupFileTestDB(t)
+	ctx := context.Background()
+
+	// A user without accounts, so every concurrent creation is a candidate first account.
+	user := &models.User{Email: "first-account@example.com", PasswordHash: "x", DefaultCurrency: "USD"}
+	require.NoError(t, db.Create(user).Error)
+
+	svc := NewAccountService(db, false)
+	const workers = 8
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		failures []error
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := svc.CreateAccount(ctx, user.ID, &models.Account{Name: fmt.Sprintf("Wallet %d", i)})
+			if err != nil {
+				mu.Lock()
+				failures = append(failures, err)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	require.Empty(t, failures)
+
+	var defaults int64
+	require.NoError(t, db.Model(&models.Account{}).Where("user_id = ? AND is_default = ?", user.ID, true).Count(&defaults).Error)
+	require.Equal(t, int64(1), defaults)
+}
@@ -9,6 +9,16 @@ var (
 	ErrConflict = errors.New("conflict")
 	// ErrPreconditionFailed indicates a business rule guard prevented an operation.
 	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrUnauthorized signals missing, invalid, or revoked credentials.
+	ErrUnauthorized = errors.New("unauthorized")
 	// ErrInsufficientFunds indicates an expense would drive balance below zero while forbidden.
 	ErrInsufficientFunds = errors.New("insufficient funds")
+	// ErrVersionMismatch signals that a resource changed since the version the client last saw.
+	ErrVersionMismatch = errors.New("resource version does not match")
+	// ErrRateUnavailable signals that no exchange rate is known for a currency pair and date.
+	ErrRateUnavailable = errors.New("exchange rate unavailable")
+	// ErrIdempotencyKeyInUse signals a retry while the original request is still being processed.
+	ErrIdempotencyKeyInUse = errors.New("idempotency key is in use by a request in progress")
+	// ErrIdempotencyKeyMismatch signals an idempotency key reused for a different request.
+	ErrIdempotencyKeyMismatch = errors.New("idempotency key was used for a different request")
 )
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"bckndlab3/src/internal/currency"
+)
+
+// ExchangeRateProvider supplies exchange rates as units of the to currency per unit of the
+// from currency, effective on the day of at. Providers return ErrRateUnavailable when they
+// know no rate for the pair.
+type ExchangeRateProvider interface {
+	Rate(ctx context.Context, from, to string, at time.Time) (*big.Rat, error)
+}
+
+// ParseExchangeRate parses a positive decimal exchange rate such as "41.2537" exactly.
+func ParseExchangeRate(value string) (*big.Rat, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, fmt.Errorf("%w: exchange rate is required", ErrPreconditionFailed)
+	}
+	if strings.ContainsAny(value, "/eE") {
+		return nil, fmt.Errorf("%w: exchange rate must be a plain decimal number", ErrPreconditionFailed)
+	}
+
+	rate, ok := new(big.Rat).SetString(value)
+	if !ok || rate.Sign() <= 0 {
+		return nil, fmt.Errorf("%w: exchange rate must be a positive decimal number", ErrPreconditionFailed)
+	}
+	return rate, nil
+}
+
+// ConvertAmount multiplies an amount in minor units by rate, rounding half away from zero.
+func ConvertAmount(amount int64, rate *big.Rat) int64 {
+	product := new(big.Rat).Mul(new(big.Rat).SetInt64(amount), rate)
+	return roundRat(product)
+}
+
+// ConvertMinorUnits converts an amount in minor units of one currency into minor units of
+// another. The rate is quoted in major units, so it is rescaled when the currencies have
+// different exponents, e.g. from JPY (no decimals) to USD (cents).
+func ConvertMinorUnits(amount int64, rate *big.Rat, from, to string) int64 {
+	shift := currency.Exponent(to) - currency.Exponent(from)
+	scaled := new(big.Rat).Set(rate)
+	if shift != 0 {
+		digits := shift
+		if digits < 0 {
+			digits = -digits
+		}
+		factor := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(digits)), nil))
+		if shift > 0 {
+			scaled.Mul(scaled, factor)
+		} else {
+			scaled.Quo(scaled, factor)
+		}
+	}
+	return ConvertAmount(amount, scaled)
+}
+
+// normalizeCurrency upper-cases a currency code and rejects codes outside ISO 4217.
+func normalizeCurrency(code string) (string, error) {
+	code = currency.Normalize(code)
+	if !currency.IsSupported(code) {
+		return "", fmt.Errorf("%w: unsupported currency %q", ErrPreconditionFailed, code)
+	}
+	return code, nil
+}
+
+// FormatRate renders a rate as a plain decimal string, as stored alongside converted amounts.
+func FormatRate(rate *big.Rat) string {
+	if rate.IsInt() {
+		return rate.Num().String()
+	}
+	formatted := strings.TrimRight(rate.FloatString(12), "0")
+	return strings.TrimSuffix(formatted, ".")
+}
+
+func roundRat(value *big.Rat) int64 {
+	num := new(big.Int).Set(value.Num())
+	den := value.Denom()
+
+	negative := num.Sign() < 0
+	num.Abs(num)
+
+	quotient, remainder := new(big.Int).QuoRem(num, den, new(big.Int))
+	if remainder.Lsh(remainder, 1).Cmp(den) >= 0 {
+		quotient.Add(quotient, big.NewInt(1))
+	}
+	if negative {
+		quotient.Neg(quotient)
+	}
+	return quotient.Int64()
+}
@@ -0,0 +1,212 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"bckndlab3/src/internal/currency"
+	"bckndlab3/src/internal/models"
+)
+
+// Exchange rate dump formats accepted by ParseExchangeRatesCSV.
+const (
+	// RateFormatCSV has the columns date (YYYY-MM-DD), base, quote and rate.
+	RateFormatCSV = "csv"
+	// RateFormatECB is the ECB reference rate history (eurofxref-hist.csv): a Date column
+	// followed by one column per currency holding its units per euro.
+	RateFormatECB = "ecb"
+	// RateFormatNBU is the NBU official rate export with the columns exchangedate
+	// (DD.MM.YYYY), cc and rate, the hryvnias per unit of cc.
+	RateFormatNBU = "nbu"
+)
+
+// ParseExchangeRatesCSV reads a daily rate dump. Columns are matched by header name, in
+// any order and case, and both comma and semicolon separators are accepted. The official
+// ECB and NBU dumps also list withdrawn currencies, precious metals and days without a
+// fixing; such cells are skipped, while a malformed generic CSV row is an error.
+func ParseExchangeRatesCSV(r io.Reader, format string) ([]models.ExchangeRate, error) {
+	reader, err := newRateCSVReader(r)
+	if err != nil {
+		return nil, err
+	}
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: rate file is empty", ErrPreconditionFailed)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: read rate file: %v", ErrPreconditionFailed, err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if name != "" {
+			columns[name] = i
+		}
+	}
+
+	switch format {
+	case RateFormatCSV, "":
+		return parseGenericRates(reader, columns)
+	case RateFormatECB:
+		return parseECBRates(reader, header, columns)
+	case RateFormatNBU:
+		return parseNBURates(reader, columns)
+	default:
+		return nil, fmt.Errorf("%w: unknown rate file format %q", ErrPreconditionFailed, format)
+	}
+}
+
+func newRateCSVReader(r io.Reader) (*csv.Reader, error) {
+	buffered := bufio.NewReader(r)
+	firstLine, err := buffered.Peek(4096)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, fmt.Errorf("%w: read rate file: %v", ErrPreconditionFailed, err)
+	}
+	if end := strings.IndexByte(string(firstLine), '\n'); end >= 0 {
+		firstLine = firstLine[:end]
+	}
+
+	reader := csv.NewReader(buffered)
+	if strings.Contains(string(firstLine), ";") && !strings.Contains(string(firstLine), ",") {
+		reader.Comma = ';'
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	return reader, nil
+}
+
+func requireColumns(columns map[string]int, names ...string) error {
+	for _, name := range names {
+		if _, ok := columns[name]; !ok {
+			return fmt.Errorf("%w: rate file has no %q column", ErrPreconditionFailed, name)
+		}
+	}
+	return nil
+}
+
+// eachRateRow calls fn for every data row with its 1-based line number.
+func eachRateRow(reader *csv.Reader, fn func(line int, record []string) error) error {
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: rate file line %d: %v", ErrPreconditionFailed, line, err)
+		}
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		if err := fn(line, record); err != nil {
+			return err
+		}
+	}
+}
+
+func cell(record []string, columns map[string]int, name string) string {
+	i, ok := columns[name]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+func parseGenericRates(reader *csv.Reader, columns map[string]int) ([]models.ExchangeRate, error) {
+	if err := requireColumns(columns, "date", "base", "quote", "rate"); err != nil {
+		return nil, err
+	}
+
+	var rates []models.ExchangeRate
+	err := eachRateRow(reader, func(line int, record []string) error {
+		day, err := time.Parse(RateDateLayout, cell(record, columns, "date"))
+		if err != nil {
+			return fmt.Errorf("%w: rate file line %d: date must look like 2025-01-31", ErrPreconditionFailed, line)
+		}
+		rate := models.ExchangeRate{
+			BaseCurrency:  currency.Normalize(cell(record, columns, "base")),
+			QuoteCurrency: currency.Normalize(cell(record, columns, "quote")),
+			EffectiveOn:   day,
+			Rate:          cell(record, columns, "rate"),
+			Source:        models.ExchangeRateSourceCSV,
+		}
+		if !currency.IsSupported(rate.BaseCurrency) || !currency.IsSupported(rate.QuoteCurrency) {
+			return fmt.Errorf("%w: rate file line %d: unsupported currency pair %s/%s", ErrPreconditionFailed, line, rate.BaseCurrency, rate.QuoteCurrency)
+		}
+		if _, err := ParseExchangeRate(rate.Rate); err != nil {
+			return fmt.Errorf("%w (rate file line %d)", err, line)
+		}
+		rates = append(rates, rate)
+		return nil
+	})
+	return rates, err
+}
+
+func parseECBRates(reader *csv.Reader, header []string, columns map[string]int) ([]models.ExchangeRate, error) {
+	if err := requireColumns(columns, "date"); err != nil {
+		return nil, err
+	}
+	dateColumn := columns["date"]
+
+	var rates []models.ExchangeRate
+	err := eachRateRow(reader, func(line int, record []string) error {
+		day, err := time.Parse(RateDateLayout, cell(record, columns, "date"))
+		if err != nil {
+			return fmt.Errorf("%w: rate file line %d: date must look like 2025-01-31", ErrPreconditionFailed, line)
+		}
+		for i, name := range header {
+			code := currency.Normalize(name)
+			if i == dateColumn || i >= len(record) || code == "EUR" || !currency.IsSupported(code) {
+				continue
+			}
+			value := strings.TrimSpace(record[i])
+			if _, err := ParseExchangeRate(value); err != nil {
+				continue // "N/A" on days without a fixing
+			}
+			rates = append(rates, models.ExchangeRate{
+				BaseCurrency:  "EUR",
+				QuoteCurrency: code,
+				EffectiveOn:   day,
+				Rate:          value,
+				Source:        models.ExchangeRateSourceECB,
+			})
+		}
+		return nil
+	})
+	return rates, err
+}
+
+func parseNBURates(reader *csv.Reader, columns map[string]int) ([]models.ExchangeRate, error) {
+	if err := requireColumns(columns, "exchangedate", "cc", "rate"); err != nil {
+		return nil, err
+	}
+
+	var rates []models.ExchangeRate
+	err := eachRateRow(reader, func(line int, record []string) error {
+		day, err := time.Parse("02.01.2006", cell(record, columns, "exchangedate"))
+		if err != nil {
+			return fmt.Errorf("%w: rate file line %d: exchangedate must look like 31.01.2025", ErrPreconditionFailed, line)
+		}
+		code := currency.Normalize(cell(record, columns, "cc"))
+		value := strings.ReplaceAll(cell(record, columns, "rate"), ",", ".")
+		if code == "UAH" || !currency.IsSupported(code) {
+			return nil // metals and SDR are published alongside currencies
+		}
+		if _, err := ParseExchangeRate(value); err != nil {
+			return fmt.Errorf("%w (rate file line %d)", err, line)
+		}
+		rates = append(rates, models.ExchangeRate{
+			BaseCurrency:  code,
+			QuoteCurrency: "UAH",
+			EffectiveOn:   day,
+			Rate:          value,
+			Source:        models.ExchangeRateSourceNBU,
+		})
+		return nil
+	})
+	return rates, err
+}
@@ -0,0 +1,293 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"bckndlab3/src/internal/currency"
+	"bckndlab3/src/internal/models"
+)
+
+// RateDateLayout is the calendar-day format exchange rates are published and queried with.
+const RateDateLayout = "2006-01-02"
+
+// ExchangeRateStore is the database-backed ExchangeRateProvider. Rates are stored per day
+// and a lookup uses the latest rate on or before the requested day, so weekends and bank
+// holidays fall back to the last published rate. A pair without stored rates is answered
+// from the reverse pair or crossed through a pivot currency that official dumps are quoted
+// against (UAH for the NBU, EUR for the ECB).
+//
+// Lookups are cached in memory: Upsert clears the cache of this instance, while rates
+// written by other instances become visible once the cached entry is older than the TTL.
+// Expired entries are dropped when read, and all of them when the cache is full.
+type ExchangeRateStore struct {
+	db        *gorm.DB
+	cacheTTL  time.Duration
+	cacheSize int
+	pivots    []string
+	now       func() time.Time
+
+	mu    sync.Mutex
+	cache map[rateCacheKey]rateCacheEntry
+	// generation counts the Upserts of this instance, so a lookup that started before
+	// one does not cache the rate it read.
+	generation uint64
+}
+
+type rateCacheKey struct {
+	from, to, day string
+}
+
+type rateCacheEntry struct {
+	rate      *big.Rat
+	fetchedAt time.Time
+}
+
+func NewExchangeRateStore(db *gorm.DB, cacheTTL time.Duration) *ExchangeRateStore {
+	return &ExchangeRateStore{
+		db:        db,
+		cacheTTL:  cacheTTL,
+		cacheSize: 10000,
+		pivots:    []string{"UAH", "EUR", "USD"},
+		now:       func() time.Time { return time.Now().UTC() },
+		cache:     make(map[rateCacheKey]rateCacheEntry),
+	}
+}
+
+// RateDay returns midnight UTC of the calendar day of t, the key rates are stored under.
+func RateDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Rate returns the units of to per unit of from in effect on the day of at.
+func (s *ExchangeRateStore) Rate(ctx context.Context, from, to string, at time.Time) (*big.Rat, error) {
+	from, to = currency.Normalize(from), currency.Normalize(to)
+	if from == to {
+		return big.NewRat(1, 1), nil
+	}
+	day := RateDay(at)
+	key := rateCacheKey{from: from, to: to, day: day.Format(RateDateLayout)}
+	now := s.now()
+
+	cached, generation := s.cached(key, now)
+	if cached != nil {
+		return new(big.Rat).Set(cached), nil
+	}
+
+	rate, err := s.lookup(ctx, from, to, day)
+	if err != nil {
+		return nil, err
+	}
+
+	s.remember(key, rate, generation, now)
+	return new(big.Rat).Set(rate), nil
+}
+
+// cached returns the cached rate of key unless it is missing or expired, dropping an
+// expired entry, along with the cache generation a lookup of key starts from.
+func (s *ExchangeRateStore) cached(key rateCacheKey, now time.Time) (*big.Rat, uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.cache[key]
+	if !ok {
+		return nil, s.generation
+	}
+	if now.Sub(entry.fetchedAt) >= s.cacheTTL {
+		delete(s.cache, key)
+		return nil, s.generation
+	}
+	return entry.rate, s.generation
+}
+
+// remember caches the rate of key looked up at now, unless an Upsert has run since the
+// lookup started. A full cache first drops its expired entries, or everything if none are.
+func (s *ExchangeRateStore) remember(key rateCacheKey, rate *big.Rat, generation uint64, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if generation != s.generation {
+		return
+	}
+	if len(s.cache) >= s.cacheSize {
+		for cachedKey, entry := range s.cache {
+			if now.Sub(entry.fetchedAt) >= s.cacheTTL {
+				delete(s.cache, cachedKey)
+			}
+		}
+		if len(s.cache) >= s.cacheSize {
+			s.cache = make(map[rateCacheKey]rateCacheEntry)
+		}
+	}
+	s.cache[key] = rateCacheEntry{rate: rate, fetchedAt: now}
+}
+
+func (s *ExchangeRateStore) lookup(ctx context.Context, from, to string, day time.Time) (*big.Rat, error) {
+	rate, err := s.pairRate(ctx, from, to, day)
+	if !errors.Is(err, ErrRateUnavailable) {
+		return rate, err
+	}
+
+	for _, pivot := range s.pivots {
+		if pivot == from || pivot == to {
+			continue
+		}
+		fromPivot, err := s.pairRate(ctx, from, pivot, day)
+		if errors.Is(err, ErrRateUnavailable) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		toPivot, err := s.pairRate(ctx, to, pivot, day)
+		if errors.Is(err, ErrRateUnavailable) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		return new(big.Rat).Quo(fromPivot, toPivot), nil
+	}
+
+	return nil, fmt.Errorf("%w: no rate from %s to %s on or before %s", ErrRateUnavailable, from, to, day.Format(RateDateLayout))
+}
+
+// pairRate returns the latest stored rate of the pair, inverting the reverse pair when it
+// was published more recently. On a tie the pair as requested wins.
+func (s *ExchangeRateStore) pairRate(ctx context.Context, from, to string, day time.Time) (*big.Rat, error) {
+	var rows []models.ExchangeRate
+	err := s.db.WithContext(ctx).
+		Where("((base_currency = ? AND quote_currency = ?) OR (base_currency = ? AND quote_currency = ?)) AND effective_on <= ?",
+			from, to, to, from, day).
+		Order("effective_on DESC").
+		Limit(2).
+		Find(&rows).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	if len(rows) == 0 {
+		return nil, ErrRateUnavailable
+	}
+
+	row := rows[0]
+	if len(rows) == 2 && rows[1].EffectiveOn.Equal(row.EffectiveOn) && rows[1].BaseCurrency == from {
+		row = rows[1]
+	}
+	rate, err := ParseExchangeRate(row.Rate)
+	if err != nil {
+		return nil, fmt.Errorf("stored rate %s/%s on %s: %w", row.BaseCurrency, row.QuoteCurrency, row.EffectiveOn.Format(RateDateLayout), err)
+	}
+	if row.BaseCurrency != from {
+		rate.Inv(rate)
+	}
+	return rate, nil
+}
+
+// Upsert stores rates, replacing the rate and source of pair-days that already exist.
+// Currency codes must be in the ISO 4217 registry and rates must be positive decimals.
+func (s *ExchangeRateStore) Upsert(ctx context.Context, rates []models.ExchangeRate) error {
+	byKey := make(map[rateCacheKey]int, len(rates))
+	unique := make([]models.ExchangeRate, 0, len(rates))
+	for _, rate := range rates {
+		base, err := normalizeCurrency(rate.BaseCurrency)
+		if err != nil {
+			return err
+		}
+		quote, err := normalizeCurrency(rate.QuoteCurrency)
+		if err != nil {
+			return err
+		}
+		if base == quote {
+			return fmt.Errorf("%w: exchange rate needs two different currencies, got %s", ErrPreconditionFailed, base)
+		}
+		if _, err := ParseExchangeRate(rate.Rate); err != nil {
+			return fmt.Errorf("%w (%s/%s)", err, base, quote)
+		}
+
+		rate.BaseCurrency, rate.QuoteCurrency = base, quote
+		rate.EffectiveOn = RateDay(rate.EffectiveOn)
+		if rate.Source == "" {
+			rate.Source = models.ExchangeRateSourceManual
+		}
+
+		// A batch may repeat a pair-day; the last value wins, as it would row by row.
+		key := rateCacheKey{from: base, to: quote, day: rate.EffectiveOn.Format(RateDateLayout)}
+		if i, ok := byKey[key]; ok {
+			unique[i] = rate
+			continue
+		}
+		byKey[key] = len(unique)
+		unique = append(unique, rate)
+	}
+	if len(unique) == 0 {
+		return nil
+	}
+
+	err := s.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "base_currency"}, {Name: "quote_currency"}, {Name: "effective_on"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"rate":       gorm.Expr("excluded.rate"),
+				"source":     gorm.Expr("excluded.source"),
+				"updated_at": gorm.Expr("excluded.updated_at"),
+				"version":    gorm.Expr("exchange_rates.version + 1"),
+			}),
+		}).
+		CreateInBatches(unique, 500).Error
+	if err != nil {
+		return translateError(err)
+	}
+
+	s.mu.Lock()
+	s.cache = make(map[rateCacheKey]rateCacheEntry)
+	s.generation++
+	s.mu.Unlock()
+	return nil
+}
+
+// Import parses a CSV dump in the given format and upserts its rates. It returns the
+// number of rates stored.
+func (s *ExchangeRateStore) Import(ctx context.Context, r io.Reader, format string) (int, error) {
+	rates, err := ParseExchangeRatesCSV(r, format)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.Upsert(ctx, rates); err != nil {
+		return 0, err
+	}
+	return len(rates), nil
+}
+
+// List returns the stored rates of a pair between two days inclusive, newest first. Empty
+// codes match any currency and zero days leave the range open.
+func (s *ExchangeRateStore) List(ctx context.Context, base, quote string, from, to time.Time, limit int) ([]models.ExchangeRate, error) {
+	query := s.db.WithContext(ctx).Order("effective_on DESC").Order("base_currency ASC").Order("quote_currency ASC")
+	if base != "" {
+		query = query.Where("base_currency = ?", currency.Normalize(base))
+	}
+	if quote != "" {
+		query = query.Where("quote_currency = ?", currency.Normalize(quote))
+	}
+	if !from.IsZero() {
+		query = query.Where("effective_on >= ?", RateDay(from))
+	}
+	if !to.IsZero() {
+		query = query.Where("effective_on <= ?", RateDay(to))
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var rates []models.ExchangeRate
+	if err := query.Find(&rates).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return rates, nil
+}
@@ -0,0 +1,212 @@
+package storage
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"bckndlab3/src/internal/models"
+)
+
+func mustDay(value string) time.Time {
+	parsed, err := time.Parse(RateDateLayout, value)
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+func requireRate(t *testing.T, store *ExchangeRateStore, from, to string, at time.Time, expected string) {
+	t.Helper()
+
+	rate, err := store.Rate(context.Background(), from, to, at)
+	require.NoError(t, err)
+	require.Equal(t, expected, FormatRate(rate), "%s/%s on %s", from, to, at.Format(RateDateLayout))
+}
+
+func TestExchangeRateStoreLookup(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+	store := NewExchangeRateStore(db, time.Hour)
+
+	require.NoError(t, store.Upsert(ctx, []models.ExchangeRate{
+		{BaseCurrency: "usd", QuoteCurrency: "uah", EffectiveOn: mustDay("2025-01-02"), Rate: "42"},
+		{BaseCurrency: "USD", QuoteCurrency: "UAH", EffectiveOn: mustDay("2025-01-06"), Rate: "42.5"},
+		{BaseCurrency: "EUR", QuoteCurrency: "UAH", EffectiveOn: mustDay("2025-01-06"), Rate: "44"},
+	}))
+
+	_, err := store.Rate(ctx, "USD", "UAH", mustDay("2025-01-01"))
+	require.ErrorIs(t, err, ErrRateUnavailable, "no rate before the first fixing")
+
+	requireRate(t, store, "USD", "UAH", mustDay("2025-01-02"), "42")
+	// Weekends and holidays fall back to the latest earlier fixing.
+	requireRate(t, store, "USD", "UAH", mustDay("2025-01-05").Add(18*time.Hour), "42")
+	requireRate(t, store, "USD", "UAH", mustDay("2025-01-10"), "42.5")
+	// The reverse pair is inverted.
+	requireRate(t, store, "UAH", "EUR", mustDay("2025-01-06"), "0.022727272727")
+	// Pairs without a direct quote are crossed through a pivot currency.
+	requireRate(t, store, "USD", "EUR", mustDay("2025-01-06"), "0.965909090909")
+	requireRate(t, store, "EUR", "EUR", mustDay("2025-01-06"), "1")
+
+	_, err = store.Rate(ctx, "USD", "JPY", mustDay("2025-01-06"))
+	require.ErrorIs(t, err, ErrRateUnavailable)
+
+	// Upserting replaces the pair-day and invalidates cached lookups.
+	require.NoError(t, store.Upsert(ctx, []models.ExchangeRate{
+		{BaseCurrency: "USD", QuoteCurrency: "UAH", EffectiveOn: mustDay("2025-01-06"), Rate: "43", Source: models.ExchangeRateSourceNBU},
+	}))
+	requireRate(t, store, "USD", "UAH", mustDay("2025-01-10"), "43")
+
+	rates, err := store.List(ctx, "USD", "UAH", time.Time{}, time.Time{}, 10)
+	require.NoError(t, err)
+	require.Len(t, rates, 2)
+	require.Equal(t, "43", rates[0].Rate)
+	require.Equal(t, models.ExchangeRateSourceNBU, rates[0].Source)
+	require.Equal(t, models.ExchangeRateSourceManual, rates[1].Source)
+
+	require.ErrorIs(t, store.Upsert(ctx, []models.ExchangeRate{
+		{BaseCurrency: "USD", QuoteCurrency: "USD", EffectiveOn: mustDay("2025-01-06"), Rate: "1"},
+	}), ErrPreconditionFailed)
+	require.ErrorIs(t, store.Upsert(ctx, []models.ExchangeRate{
+		{BaseCurrency: "USD", QuoteCurrency: "XYZ", EffectiveOn: mustDay("2025-01-06"), Rate: "1"},
+	}), ErrPreconditionFailed)
+	require.ErrorIs(t, store.Upsert(ctx, []models.ExchangeRate{
+		{BaseCurrency: "USD", QuoteCurrency: "UAH", EffectiveOn: mustDay("2025-01-06"), Rate: "-1"},
+	}), ErrPreconditionFailed)
+}
+
+func TestExchangeRateStoreCacheTTL(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	now := mustDay("2025-01-06")
+	store := NewExchangeRateStore(db, time.Minute)
+	store.now = func() time.Time { return now }
+	writer := NewExchangeRateStore(db, time.Minute)
+
+	require.NoError(t, writer.Upsert(ctx, []models.ExchangeRate{
+		{BaseCurrency: "USD", QuoteCurrency: "UAH", EffectiveOn: mustDay("2025-01-06"), Rate: "42"},
+	}))
+	requireRate(t, store, "USD", "UAH", now, "42")
+
+	// Another instance's write is only seen once the cached entry expires.
+	require.NoError(t, writer.Upsert(ctx, []models.ExchangeRate{
+		{BaseCurrency: "USD", QuoteCurrency: "UAH", EffectiveOn: mustDay("2025-01-06"), Rate: "43"},
+	}))
+	requireRate(t, store, "USD", "UAH", now, "42")
+
+	now = now.Add(2 * time.Minute)
+	requireRate(t, store, "USD", "UAH", mustDay("2025-01-06"), "43")
+}
+
+func TestParseExchangeRatesCSV(t *testing.T) {
+	generic := "date,base,quote,rate\n2025-01-06,usd,uah,42.1\n\n2025-01-07,EUR,UAH,43.9\n"
+	rates, err := ParseExchangeRatesCSV(strings.NewReader(generic), RateFormatCSV)
+	require.NoError(t, err)
+	require.Len(t, rates, 2)
+	require.Equal(t, "USD", rates[0].BaseCurrency)
+	require.Equal(t, "UAH", rates[0].QuoteCurrency)
+	require.Equal(t, "42.1", rates[0].Rate)
+	require.Equal(t, mustDay("2025-01-07"), rates[1].EffectiveOn)
+	require.Equal(t, models.ExchangeRateSourceCSV, rates[0].Source)
+
+	_, err = ParseExchangeRatesCSV(strings.NewReader("date,base,quote,rate\n2025-13-01,USD,UAH,42\n"), RateFormatCSV)
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+	_, err = ParseExchangeRatesCSV(strings.NewReader("date,base,rate\n"), RateFormatCSV)
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+	_, err = ParseExchangeRatesCSV(strings.NewReader(generic), "xml")
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+
+	ecb := "Date,USD,JPY,CYP,\n2025-01-06,1.0344,163.06,N/A,\n2025-01-03,1.0299,N/A,N/A,\n"
+	rates, err = ParseExchangeRatesCSV(strings.NewReader(ecb), RateFormatECB)
+	require.NoError(t, err)
+	require.Len(t, rates, 3, "withdrawn currencies and missing fixings are skipped")
+	for _, rate := range rates {
+		require.Equal(t, "EUR", rate.BaseCurrency)
+		require.Equal(t, models.ExchangeRateSourceECB, rate.Source)
+	}
+
+	nbu := "\ufeffr030;txt;rate;cc;exchangedate\n840;Долар США;42,0211;USD;06.01.2025\n959;Золото;110000;XAU;06.01.2025\n"
+	rates, err = ParseExchangeRatesCSV(strings.NewReader(nbu), RateFormatNBU)
+	require.NoError(t, err)
+	require.Len(t, rates, 1)
+	require.Equal(t, "USD", rates[0].BaseCurrency)
+	require.Equal(t, "UAH", rates[0].QuoteCurrency)
+	require.Equal(t, "42.0211", rates[0].Rate)
+	require.Equal(t, mustDay("2025-01-06"), rates[0].EffectiveOn)
+}
+
+func TestAccountServiceTransferUsesExchangeRateStore(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "fx-store@example.com", "strongpass", "usd")
+	require.NoError(t, err)
+
+	store := NewExchangeRateStore(db, time.Minute)
+	svc := NewAccountService(db, false)
+	svc.UseExchangeRates(store)
+	usdID := defaultAccountID(t, svc, user.ID)
+	uah, err := svc.CreateAccount(ctx, user.ID, &models.Account{Name: "Hryvnia card", Type: models.AccountTypeCard, CurrencyISOCode: "UAH"})
+	require.NoError(t, err)
+	_, _, err = svc.CreditIncome(ctx, user.ID, usdID, &models.Income{AmountCents: 10000, Source: "Salary"})
+	require.NoError(t, err)
+
+	imported, err := store.Import(ctx, strings.NewReader("date,base,quote,rate\n2025-01-06,USD,UAH,42.1\n2025-01-08,USD,UAH,42.3\n"), RateFormatCSV)
+	require.NoError(t, err)
+	require.Equal(t, 2, imported)
+
+	transfer, _, toBalance, err := svc.Transfer(ctx, user.ID, &models.Transfer{
+		FromAccountID: usdID,
+		ToAccountID:   uah.ID,
+		AmountCents:   1000,
+		TransferredAt: mustDay("2025-01-07").Add(15 * time.Hour),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "42.1", transfer.ExchangeRate, "the rate in effect on the transfer day is captured")
+	require.Equal(t, int64(42100), transfer.ToAmountCents)
+	require.Equal(t, int64(42100), toBalance)
+}
+
+func TestExchangeRateStoreCacheEviction(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	now := mustDay("2025-01-06")
+	store := NewExchangeRateStore(db, time.Minute)
+	store.now = func() time.Time { return now }
+	store.cacheSize = 3
+	require.NoError(t, store.Upsert(ctx, []models.ExchangeRate{
+		{BaseCurrency: "USD", QuoteCurrency: "UAH", EffectiveOn: mustDay("2025-01-01"), Rate: "42"},
+	}))
+
+	for _, day := range []string{"2025-01-02", "2025-01-03", "2025-01-04"} {
+		requireRate(t, store, "USD", "UAH", mustDay(day), "42")
+	}
+	require.Len(t, store.cache, 3)
+
+	// An expired entry is dropped when read, and a full cache drops its expired entries.
+	now = now.Add(2 * time.Minute)
+	requireRate(t, store, "USD", "UAH", mustDay("2025-01-02"), "42")
+	require.Len(t, store.cache, 3)
+	requireRate(t, store, "USD", "UAH", mustDay("2025-01-05"), "42")
+	require.Len(t, store.cache, 2)
+	requireRate(t, store, "USD", "UAH", mustDay("2025-01-06"), "42")
+	requireRate(t, store, "USD", "UAH", mustDay("2025-01-07"), "42")
+	require.Len(t, store.cache, 1, "a cache full of fresh entries starts over")
+
+	// A lookup that started before an Upsert does not cache the rate it read.
+	key := rateCacheKey{from: "USD", to: "UAH", day: "2025-01-08"}
+	_, generation := store.cached(key, now)
+	require.NoError(t, store.Upsert(ctx, []models.ExchangeRate{
+		{BaseCurrency: "USD", QuoteCurrency: "UAH", EffectiveOn: mustDay("2025-01-01"), Rate: "43"},
+	}))
+	store.remember(key, big.NewRat(42, 1), generation, now)
+	require.Empty(t, store.cache)
+	requireRate(t, store, "USD", "UAH", mustDay("2025-01-08"), "43")
+}
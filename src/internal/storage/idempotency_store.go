@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"bckndlab3/src/internal/models"
+)
+
+// IdempotencyStore records Idempotency-Key reservations and the responses they produced.
+type IdempotencyStore struct {
+	db  *gorm.DB
+	ttl time.Duration
+	now func() time.Time
+}
+
+func NewIdempotencyStore(db *gorm.DB, ttl time.Duration) *IdempotencyStore {
+	return &IdempotencyStore{
+		db:  db,
+		ttl: ttl,
+		now: func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Fingerprint identifies a request by method, path and body.
+func Fingerprint(method, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method))
+	hash.Write([]byte{0})
+	hash.Write([]byte(path))
+	hash.Write([]byte{0})
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Reserve claims key for a request of the user. When the key is new it returns the fresh
+// reservation and true. When the key was already used for the same request it returns the
+// stored record and false, so the caller can replay the response. It fails with
+// ErrIdempotencyKeyInUse while the original request is still running and with
+// ErrIdempotencyKeyMismatch when the key was used for a different request.
+func (s *IdempotencyStore) Reserve(ctx context.Context, userID uint, key, fingerprint string) (*models.IdempotencyKey, bool, error) {
+	now := s.now()
+
+	// Expired keys are forgotten so that they can be reused.
+	err := s.db.WithContext(ctx).
+		Where("user_id = ? AND expires_at <= ?", userID, now).
+		Delete(&models.IdempotencyKey{}).Error
+	if err != nil {
+		return nil, false, translateError(err)
+	}
+
+	record := &models.IdempotencyKey{
+		UserID:      userID,
+		Key:         key,
+		Fingerprint: fingerprint,
+		ExpiresAt:   now.Add(s.ttl),
+	}
+	err = s.db.WithContext(ctx).Create(record).Error
+	if err == nil {
+		return record, true, nil
+	}
+	if err = translateError(err); !errors.Is(err, ErrConflict) {
+		return nil, false, err
+	}
+
+	var existing models.IdempotencyKey
+	if err := s.db.WithContext(ctx).
+		Where("user_id = ? AND idempotency_key = ?", userID, key).
+		First(&existing).Error; err != nil {
+		return nil, false, translateError(err)
+	}
+	if existing.Fingerprint != fingerprint {
+		return nil, false, ErrIdempotencyKeyMismatch
+	}
+	if !existing.Completed() {
+		return nil, false, ErrIdempotencyKeyInUse
+	}
+	return &existing, false, nil
+}
+
+// IdempotentResponse is the response of a completed request, stored for replay.
+type IdempotentResponse struct {
+	StatusCode  int
+	ContentType string
+	ETag        string
+	Location    string
+	Body        []byte
+}
+
+// Complete stores the response produced for a reservation.
+func (s *IdempotencyStore) Complete(ctx context.Context, id uint, response IdempotentResponse) error {
+	err := s.db.WithContext(ctx).Model(&models.IdempotencyKey{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"status_code":   response.StatusCode,
+			"content_type":  response.ContentType,
+			"etag":          response.ETag,
+			"location":      response.Location,
+			"response_body": response.Body,
+		}).Error
+	if err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+// Release drops a reservation whose request failed, allowing the client to retry it.
+func (s *IdempotencyStore) Release(ctx context.Context, id uint) error {
+	if err := s.db.WithContext(ctx).Delete(&models.IdempotencyKey{}, id).Error; err != nil {
+		return translateError(err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyStoreLifecycle(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "idempotency@example.com", "strongpass", "usd")
+	require.NoError(t, err)
+
+	store := NewIdempotencyStore(db, time.Hour)
+	now := time.Now().UTC()
+	store.now = func() time.Time { return now }
+
+	fingerprint := Fingerprint("POST", "/api/v1/accounts/expenses", []byte(`{"amount":1}`))
+	record, created, err := store.Reserve(ctx, user.ID, "key-1", fingerprint)
+	require.NoError(t, err)
+	require.True(t, created)
+
+	_, _, err = store.Reserve(ctx, user.ID, "key-1", fingerprint)
+	require.ErrorIs(t, err, ErrIdempotencyKeyInUse)
+
+	require.NoError(t, store.Complete(ctx, record.ID, IdempotentResponse{
+		StatusCode:  201,
+		ContentType: "application/json",
+		ETag:        `"1"`,
+		Location:    "/api/v1/accounts/expenses/1",
+		Body:        []byte(`{"id":1}`),
+	}))
+
+	replay, created, err := store.Reserve(ctx, user.ID, "key-1", fingerprint)
+	require.NoError(t, err)
+	require.False(t, created)
+	require.Equal(t, 201, replay.StatusCode)
+	require.Equal(t, `"1"`, replay.ETag)
+	require.Equal(t, "/api/v1/accounts/expenses/1", replay.Location)
+	require.JSONEq(t, `{"id":1}`, string(replay.ResponseBody))
+
+	other := Fingerprint("POST", "/api/v1/accounts/expenses", []byte(`{"amount":2}`))
+	_, _, err = store.Reserve(ctx, user.ID, "key-1", other)
+	require.ErrorIs(t, err, ErrIdempotencyKeyMismatch)
+
+	// Keys are scoped per user.
+	stranger, err := auth.RegisterUser(ctx, "idempotency-other@example.com", "strongpass", "usd")
+	require.NoError(t, err)
+	_, created, err = store.Reserve(ctx, stranger.ID, "key-1", other)
+	require.NoError(t, err)
+	require.True(t, created)
+
+	// Released and expired keys can be reused.
+	failed, _, err := store.Reserve(ctx, user.ID, "key-2", fingerprint)
+	require.NoError(t, err)
+	require.NoError(t, store.Release(ctx, failed.ID))
+	_, created, err = store.Reserve(ctx, user.ID, "key-2", fingerprint)
+	require.NoError(t, err)
+	require.True(t, created)
+
+	now = now.Add(2 * time.Hour)
+	_, created, err = store.Reserve(ctx, user.ID, "key-1", other)
+	require.NoError(t, err)
+	require.True(t, created)
+}
@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"bckndlab3/src/internal/models"
+)
+
+// IncomeSourceRepository handles persistence for the payers users receive income from.
+type IncomeSourceRepository struct {
+	db *gorm.DB
+}
+
+func NewIncomeSourceRepository(db *gorm.DB) *IncomeSourceRepository {
+	return &IncomeSourceRepository{db: db}
+}
+
+// List returns the income sources of a user ordered by name.
+func (r *IncomeSourceRepository) List(ctx context.Context, userID uint) ([]models.IncomeSource, error) {
+	var sources []models.IncomeSource
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("normalized_name ASC").
+		Find(&sources).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return sources, nil
+}
+
+// GetForUser returns an income source only if it belongs to the given user. It reads
+// through tx, which may be the repository's own handle outside of a transaction.
+func (r *IncomeSourceRepository) GetForUser(ctx context.Context, tx *gorm.DB, sourceID, userID uint) (*models.IncomeSource, error) {
+	var source models.IncomeSource
+	err := tx.WithContext(ctx).
+		Where("id = ? AND user_id = ?", sourceID, userID).
+		First(&source).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &source, nil
+}
+
+// LockForUser loads an income source of the given user inside tx and locks its row until
+// the transaction ends.
+func (r *IncomeSourceRepository) LockForUser(ctx context.Context, tx *gorm.DB, sourceID, userID uint) (*models.IncomeSource, error) {
+	var source models.IncomeSource
+	if err := lockOwnedRow(ctx, tx, "income_sources", &source, sourceID, userID); err != nil {
+		return nil, err
+	}
+	return &source, nil
+}
+
+// FindOrCreate returns the user's income source whose normalized name matches source.Name,
+// creating source when there is none. Concurrent creations of the same name resolve to a
+// single row.
+func (r *IncomeSourceRepository) FindOrCreate(ctx context.Context, tx *gorm.DB, source *models.IncomeSource) (*models.IncomeSource, error) {
+	source.NormalizedName = models.NormalizeIncomeSourceName(source.Name)
+	err := tx.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(source).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	if source.ID != 0 {
+		return source, nil
+	}
+
+	var existing models.IncomeSource
+	err = tx.WithContext(ctx).
+		Where("user_id = ? AND normalized_name = ?", source.UserID, source.NormalizedName).
+		First(&existing).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return &existing, nil
+}
+
+// Create persists a new income source.
+func (r *IncomeSourceRepository) Create(ctx context.Context, tx *gorm.DB, source *models.IncomeSource) error {
+	source.NormalizedName = models.NormalizeIncomeSourceName(source.Name)
+	if err := tx.WithContext(ctx).Create(source).Error; err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+// UpdateFields applies column updates to an income source of the given user.
+func (r *IncomeSourceRepository) UpdateFields(ctx context.Context, tx *gorm.DB, sourceID, userID uint, fields map[string]any) error {
+	fields["version"] = nextVersion
+	result := tx.WithContext(ctx).Model(&models.IncomeSource{}).
+		Where("id = ? AND user_id = ?", sourceID, userID).
+		Updates(fields)
+	if err := result.Error; err != nil {
+		return translateError(err)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RenameIncomes copies a source's new name onto the incomes that reference it.
+func (r *IncomeSourceRepository) RenameIncomes(ctx context.Context, tx *gorm.DB, sourceID uint, name string) error {
+	err := tx.WithContext(ctx).Model(&models.Income{}).
+		Where("source_id = ?", sourceID).
+		Updates(map[string]any{"source": name, "version": nextVersion}).Error
+	if err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+// CountIncomes returns how many incomes were received from a source.
+func (r *IncomeSourceRepository) CountIncomes(ctx context.Context, tx *gorm.DB, sourceID uint) (int64, error) {
+	var count int64
+	if err := tx.WithContext(ctx).Model(&models.Income{}).Where("source_id = ?", sourceID).Count(&count).Error; err != nil {
+		return 0, translateError(err)
+	}
+	return count, nil
+}
+
+// Delete removes an income source of the given user.
+func (r *IncomeSourceRepository) Delete(ctx context.Context, tx *gorm.DB, sourceID, userID uint) error {
+	result := tx.WithContext(ctx).
+		Where("id = ? AND user_id = ?", sourceID, userID).
+		Delete(&models.IncomeSource{})
+	if err := result.Error; err != nil {
+		return translateError(err)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"bckndlab3/src/internal/models"
+)
+
+// IncomeSourceService manages the payers users receive income from.
+type IncomeSourceService struct {
+	db         *gorm.DB
+	sources    *IncomeSourceRepository
+	categories *CategoryRepository
+}
+
+// IncomeSourceUpdate lists the income source attributes that may be changed; nil fields
+// are kept. A DefaultCategoryID of zero removes the default category.
+type IncomeSourceUpdate struct {
+	Name              *string
+	Type              *string
+	TaxID             *string
+	DefaultCategoryID *uint
+}
+
+func NewIncomeSourceService(db *gorm.DB) *IncomeSourceService {
+	return &IncomeSourceService{
+		db:         db,
+		sources:    NewIncomeSourceRepository(db),
+		categories: NewCategoryRepository(db),
+	}
+}
+
+// ListIncomeSources returns the user's income sources.
+func (s *IncomeSourceService) ListIncomeSources(ctx context.Context, userID uint) ([]models.IncomeSource, error) {
+	return s.sources.List(ctx, userID)
+}
+
+// GetIncomeSource fetches an income source, ensuring it belongs to the user.
+func (s *IncomeSourceService) GetIncomeSource(ctx context.Context, userID, sourceID uint) (*models.IncomeSource, error) {
+	return s.sources.GetForUser(ctx, s.db, sourceID, userID)
+}
+
+// CreateIncomeSource adds a payer. Names are unique per user, ignoring case and extra
+// whitespace; the default category must be one of the user's income categories.
+func (s *IncomeSourceService) CreateIncomeSource(ctx context.Context, userID uint, source *models.IncomeSource) (*models.IncomeSource, error) {
+	if source.Type == "" {
+		source.Type = models.IncomeSourceTypeOther
+	}
+	if !models.IsValidIncomeSourceType(source.Type) {
+		return nil, fmt.Errorf("%w: unknown income source type %q", ErrPreconditionFailed, source.Type)
+	}
+	name, err := sourceName(source.Name)
+	if err != nil {
+		return nil, err
+	}
+	source.Name = name
+	source.TaxID = strings.TrimSpace(source.TaxID)
+	source.UserID = userID
+
+	err = WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		source.ID = 0 // the transaction may be retried
+
+		if source.DefaultCategoryID != nil {
+			_, err := resolveCategory(ctx, tx, s.categories, userID, models.CategoryTypeIncome, *source.DefaultCategoryID, "")
+			if err != nil {
+				return err
+			}
+		}
+		return s.sources.Create(ctx, tx, source)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return source, nil
+}
+
+// UpdateIncomeSource edits an income source. Renaming it also renames the incomes received
+// from it. A non-zero ifVersion must match the source's version.
+func (s *IncomeSourceService) UpdateIncomeSource(ctx context.Context, userID, sourceID, ifVersion uint, update IncomeSourceUpdate) (*models.IncomeSource, error) {
+	fields := make(map[string]any)
+	if update.Name != nil {
+		name, err := sourceName(*update.Name)
+		if err != nil {
+			return nil, err
+		}
+		fields["name"] = name
+		fields["normalized_name"] = models.NormalizeIncomeSourceName(name)
+	}
+	if update.Type != nil {
+		if !models.IsValidIncomeSourceType(*update.Type) {
+			return nil, fmt.Errorf("%w: unknown income source type %q", ErrPreconditionFailed, *update.Type)
+		}
+		fields["type"] = *update.Type
+	}
+	if update.TaxID != nil {
+		fields["tax_id"] = strings.TrimSpace(*update.TaxID)
+	}
+
+	err := WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		source, err := s.sources.LockForUser(ctx, tx, sourceID, userID)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(ifVersion, source.Version); err != nil {
+			return err
+		}
+
+		if update.DefaultCategoryID != nil {
+			if *update.DefaultCategoryID == 0 {
+				fields["default_category_id"] = nil
+			} else {
+				_, err := resolveCategory(ctx, tx, s.categories, userID, models.CategoryTypeIncome, *update.DefaultCategoryID, "")
+				if err != nil {
+					return err
+				}
+				fields["default_category_id"] = *update.DefaultCategoryID
+			}
+		}
+
+		if len(fields) == 0 {
+			return nil
+		}
+		if err := s.sources.UpdateFields(ctx, tx, sourceID, userID, fields); err != nil {
+			return err
+		}
+		if name, ok := fields["name"].(string); ok && name != source.Name {
+			return s.sources.RenameIncomes(ctx, tx, sourceID, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.sources.GetForUser(ctx, s.db, sourceID, userID)
+}
+
+// DeleteIncomeSource removes an income source that no income refers to. A non-zero
+// ifVersion must match the source's version.
+func (s *IncomeSourceService) DeleteIncomeSource(ctx context.Context, userID, sourceID, ifVersion uint) error {
+	return WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		source, err := s.sources.LockForUser(ctx, tx, sourceID, userID)
+		if err != nil {
+			return err
+		}
+		if err := checkVersion(ifVersion, source.Version); err != nil {
+			return err
+		}
+
+		incomes, err := s.sources.CountIncomes(ctx, tx, sourceID)
+		if err != nil {
+			return err
+		}
+		if incomes > 0 {
+			return fmt.Errorf("%w: income source is used by %d incomes", ErrPreconditionFailed, incomes)
+		}
+		return s.sources.Delete(ctx, tx, sourceID, userID)
+	})
+}
+
+// resolveIncomeSource returns the source an income was received from: the source with the
+// given ID or, when the ID is zero, the one matching name, which is created on first use.
+func resolveIncomeSource(ctx context.Context, tx *gorm.DB, sources *IncomeSourceRepository, userID, sourceID uint, name string) (*models.IncomeSource, error) {
+	if sourceID != 0 {
+		return sources.GetForUser(ctx, tx, sourceID, userID)
+	}
+
+	name, err := sourceName(name)
+	if err != nil {
+		return nil, err
+	}
+	return sources.FindOrCreate(ctx, tx, &models.IncomeSource{UserID: userID, Name: name, Type: models.IncomeSourceTypeOther})
+}
+
+// sourceName trims an income source name and collapses inner runs of whitespace.
+func sourceName(name string) (string, error) {
+	return cleanName("income source", name, 255)
+}
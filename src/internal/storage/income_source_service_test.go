@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"bckndlab3/src/internal/migrations"
+	"bckndlab3/src/internal/models"
+)
+
+func TestIncomeSourceService(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "sources@example.com", "strongpass", "usd")
+	require.NoError(t, err)
+	categories, err := NewCategoryService(db).ListCategories(ctx, user.ID, "")
+	require.NoError(t, err)
+	salary := categoryByName(t, categories, models.CategoryTypeIncome, "Salary")
+	food := categoryByName(t, categories, models.CategoryTypeExpense, "Food")
+
+	svc := NewIncomeSourceService(db)
+	_, err = svc.CreateIncomeSource(ctx, user.ID, &models.IncomeSource{Name: "Acme", DefaultCategoryID: &food.ID})
+	require.ErrorIs(t, err, ErrPreconditionFailed, "the default category must be an income category")
+	_, err = svc.CreateIncomeSource(ctx, user.ID, &models.IncomeSource{Name: "Acme", Type: "lottery"})
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+
+	acme, err := svc.CreateIncomeSource(ctx, user.ID, &models.IncomeSource{
+		Name:              " Acme   Corp ",
+		Type:              models.IncomeSourceTypeSalary,
+		TaxID:             " 12345678 ",
+		DefaultCategoryID: &salary.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Acme Corp", acme.Name)
+	require.Equal(t, "12345678", acme.TaxID)
+	_, err = svc.CreateIncomeSource(ctx, user.ID, &models.IncomeSource{Name: "ACME corp"})
+	require.ErrorIs(t, err, ErrConflict, "names are unique regardless of case and whitespace")
+
+	// Incomes resolve payers by ID or name and inherit the default category.
+	accounts := NewAccountService(db, true)
+	accountID := defaultAccountID(t, accounts, user.ID)
+	income, _, err := accounts.CreditIncome(ctx, user.ID, accountID, &models.Income{AmountCents: 500000, SourceID: &acme.ID})
+	require.NoError(t, err)
+	require.Equal(t, "Acme Corp", income.Source)
+	require.Equal(t, salary.ID, *income.CategoryID)
+
+	byName, _, err := accounts.CreditIncome(ctx, user.ID, accountID, &models.Income{AmountCents: 1000, Source: "acme CORP"})
+	require.NoError(t, err)
+	require.Equal(t, acme.ID, *byName.SourceID)
+
+	client, _, err := accounts.CreditIncome(ctx, user.ID, accountID, &models.Income{AmountCents: 2500, Source: "Freelance client"})
+	require.NoError(t, err)
+	require.NotNil(t, client.SourceID, "unknown payers are added to the income sources")
+	require.Nil(t, client.CategoryID)
+
+	income, _, err = accounts.UpdateIncome(ctx, user.ID, income.ID, 0, IncomeUpdate{SourceID: client.SourceID})
+	require.NoError(t, err)
+	require.Equal(t, *client.SourceID, *income.SourceID)
+	require.Equal(t, "Freelance client", income.Source)
+
+	// Renaming a source renames the incomes received from it.
+	renamed := "Acme Inc"
+	clearCategory := uint(0)
+	acme, err = svc.UpdateIncomeSource(ctx, user.ID, acme.ID, acme.Version, IncomeSourceUpdate{Name: &renamed, DefaultCategoryID: &clearCategory})
+	require.NoError(t, err)
+	require.Nil(t, acme.DefaultCategoryID)
+	byName, err = accounts.GetIncome(ctx, user.ID, byName.ID)
+	require.NoError(t, err)
+	require.Equal(t, "Acme Inc", byName.Source)
+
+	require.ErrorIs(t, svc.DeleteIncomeSource(ctx, user.ID, acme.ID, 0), ErrPreconditionFailed, "acme is in use")
+	_, err = accounts.DeleteIncome(ctx, user.ID, byName.ID, 0)
+	require.NoError(t, err)
+	require.ErrorIs(t, svc.DeleteIncomeSource(ctx, user.ID, acme.ID, 1), ErrVersionMismatch)
+	require.NoError(t, svc.DeleteIncomeSource(ctx, user.ID, acme.ID, acme.Version))
+
+	other, err := auth.RegisterUser(ctx, "other-sources@example.com", "strongpass", "usd")
+	require.NoError(t, err)
+	_, err = svc.GetIncomeSource(ctx, other.ID, *client.SourceID)
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestMigrationsBackfillIncomeSources(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "legacy-sources@example.com", "strongpass", "usd")
+	require.NoError(t, err)
+	accounts := NewAccountService(db, true)
+	accountID := defaultAccountID(t, accounts, user.ID)
+
+	// Incomes written before income sources existed carry only free text.
+	for _, name := range []string{"Employer", "employer ", "Side  gig"} {
+		require.NoError(t, db.Create(&models.Income{
+			AccountID:   accountID,
+			UserID:      user.ID,
+			AmountCents: 100,
+			Source:      name,
+			ReceivedAt:  time.Now().UTC(),
+		}).Error)
+	}
+
+	require.NoError(t, migrations.Run(db))
+	require.NoError(t, migrations.Run(db), "the backfill is idempotent")
+
+	sources, err := NewIncomeSourceService(db).ListIncomeSources(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, sources, 2)
+
+	var incomes []models.Income
+	require.NoError(t, db.Where("user_id = ?", user.ID).Order("id").Find(&incomes).Error)
+	require.Equal(t, *incomes[0].SourceID, *incomes[1].SourceID)
+	require.Equal(t, "Employer", incomes[1].Source)
+	require.Equal(t, "Side gig", incomes[2].Source)
+	for _, source := range sources {
+		require.Equal(t, models.IncomeSourceTypeOther, source.Type)
+	}
+}
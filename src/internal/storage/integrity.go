@@ -0,0 +1,210 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"bckndlab3/src/internal/models"
+)
+
+// BalanceDiscrepancy describes an account whose cached balance or ledger total disagrees
+// with the balance recomputed from its incomes, expenses, transfers and currency conversions.
+type BalanceDiscrepancy struct {
+	AccountID         uint
+	UserID            uint
+	CurrencyISOCode   string
+	StoredCents       int64
+	LedgerCents       int64
+	TransactionsCents int64
+}
+
+// IntegrityReport summarises a balance integrity check.
+type IntegrityReport struct {
+	CheckedAt       time.Time
+	AccountsChecked int
+	Discrepancies   []BalanceDiscrepancy
+	Repaired        bool
+}
+
+// IntegrityService reconciles account balances against the transactions and the ledger.
+type IntegrityService struct {
+	db       *gorm.DB
+	accounts *AccountRepository
+	ledger   *Ledger
+	now      func() time.Time
+}
+
+func NewIntegrityService(db *gorm.DB) *IntegrityService {
+	return &IntegrityService{
+		db:       db,
+		accounts: NewAccountRepository(db),
+		ledger:   NewLedger(db),
+		now:      func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Check recomputes every account balance and reports the accounts that disagree.
+func (s *IntegrityService) Check(ctx context.Context) (*IntegrityReport, error) {
+	return s.reconcile(ctx, s.db, nil)
+}
+
+// Repair runs the check and resets every discrepant balance to the value recomputed from
+// its transactions. The discrepant accounts are locked in ID order and checked again before
+// anything is written, so a posting that commits while the first check runs cannot make a
+// correct balance look drifted. The ledger is corrected with an adjustment journal and each
+// repaired account gets a BalanceAudit row naming the actor.
+func (s *IntegrityService) Repair(ctx context.Context, actor string) (*IntegrityReport, error) {
+	report, err := s.reconcile(ctx, s.db, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(report.Discrepancies) == 0 {
+		return report, nil
+	}
+
+	err = WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		accountIDs := make([]uint, 0, len(report.Discrepancies))
+		for _, discrepancy := range report.Discrepancies {
+			if _, err := s.accounts.LockByIDForUser(ctx, tx, discrepancy.AccountID, discrepancy.UserID); err != nil {
+				return err
+			}
+			accountIDs = append(accountIDs, discrepancy.AccountID)
+		}
+
+		locked, err := s.reconcile(ctx, tx, accountIDs)
+		if err != nil {
+			return err
+		}
+		for _, discrepancy := range locked.Discrepancies {
+			if err := s.repairAccount(ctx, tx, discrepancy, actor); err != nil {
+				return err
+			}
+		}
+		report.Discrepancies = locked.Discrepancies
+		report.Repaired = len(locked.Discrepancies) > 0
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (s *IntegrityService) repairAccount(ctx context.Context, tx *gorm.DB, discrepancy BalanceDiscrepancy, actor string) error {
+	audit := &models.BalanceAudit{
+		AccountID:         discrepancy.AccountID,
+		UserID:            discrepancy.UserID,
+		StoredCents:       discrepancy.StoredCents,
+		LedgerCents:       discrepancy.LedgerCents,
+		TransactionsCents: discrepancy.TransactionsCents,
+		Actor:             actor,
+	}
+	if err := tx.WithContext(ctx).Create(audit).Error; err != nil {
+		return translateError(err)
+	}
+
+	// Re-project the cached balance from the ledger, then post the remaining difference.
+	if err := s.accounts.SetBalance(ctx, tx, discrepancy.AccountID, discrepancy.LedgerCents); err != nil {
+		return err
+	}
+
+	correction := discrepancy.TransactionsCents - discrepancy.LedgerCents
+	if correction == 0 {
+		return nil
+	}
+
+	account := &models.Account{CurrencyISOCode: discrepancy.CurrencyISOCode}
+	account.ID = discrepancy.AccountID
+	_, err := s.ledger.Post(ctx, tx, &models.LedgerJournal{
+		UserID:      discrepancy.UserID,
+		Kind:        models.JournalKindAdjustment,
+		ReferenceID: audit.ID,
+		PostedAt:    s.now(),
+		Description: "balance integrity repair",
+		Entries: []models.LedgerEntry{
+			assetEntry(account, correction),
+			externalEntry(models.LedgerAccountReconciliation, discrepancy.CurrencyISOCode, -correction),
+		},
+	})
+	return err
+}
+
+// reconcile compares the given accounts, or every account when accountIDs is nil, with
+// their recomputed balances.
+func (s *IntegrityService) reconcile(ctx context.Context, db *gorm.DB, accountIDs []uint) (*IntegrityReport, error) {
+	db = db.WithContext(ctx)
+
+	var accounts []models.Account
+	query := db.Order("id ASC")
+	if accountIDs != nil {
+		query = query.Where("id IN ?", accountIDs)
+	}
+	if err := query.Find(&accounts).Error; err != nil {
+		return nil, translateError(err)
+	}
+
+	type accountSum struct {
+		AccountID uint
+		Total     int64
+	}
+	sums := func(query string) (map[uint]int64, error) {
+		var rows []accountSum
+		if err := db.Raw(query).Scan(&rows).Error; err != nil {
+			return nil, translateError(err)
+		}
+		totals := make(map[uint]int64, len(rows))
+		for _, row := range rows {
+			totals[row.AccountID] = row.Total
+		}
+		return totals, nil
+	}
+
+	incomes, err := sums("SELECT account_id, SUM(amount_cents) AS total FROM incomes GROUP BY account_id")
+	if err != nil {
+		return nil, err
+	}
+	expenses, err := sums("SELECT account_id, SUM(amount_cents) AS total FROM expenses GROUP BY account_id")
+	if err != nil {
+		return nil, err
+	}
+	transfersOut, err := sums("SELECT from_account_id AS account_id, SUM(amount_cents) AS total FROM transfers GROUP BY from_account_id")
+	if err != nil {
+		return nil, err
+	}
+	transfersIn, err := sums("SELECT to_account_id AS account_id, SUM(to_amount_cents) AS total FROM transfers GROUP BY to_account_id")
+	if err != nil {
+		return nil, err
+	}
+	conversions, err := sums("SELECT account_id, SUM(to_amount_cents - from_amount_cents) AS total FROM currency_conversions GROUP BY account_id")
+	if err != nil {
+		return nil, err
+	}
+	postings, err := sums("SELECT account_id, SUM(amount_cents) AS total FROM ledger_entries WHERE account_id IS NOT NULL GROUP BY account_id")
+	if err != nil {
+		return nil, err
+	}
+
+	report := &IntegrityReport{
+		CheckedAt:       s.now(),
+		AccountsChecked: len(accounts),
+		Discrepancies:   []BalanceDiscrepancy{},
+	}
+	for _, account := range accounts {
+		expected := incomes[account.ID] - expenses[account.ID] - transfersOut[account.ID] + transfersIn[account.ID] + conversions[account.ID]
+		ledgerTotal := postings[account.ID]
+		if account.BalanceCents == expected && ledgerTotal == expected {
+			continue
+		}
+		report.Discrepancies = append(report.Discrepancies, BalanceDiscrepancy{
+			AccountID:         account.ID,
+			UserID:            account.UserID,
+			CurrencyISOCode:   account.CurrencyISOCode,
+			StoredCents:       account.BalanceCents,
+			LedgerCents:       ledgerTotal,
+			TransactionsCents: expected,
+		})
+	}
+	return report, nil
+}
@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"bckndlab3/src/internal/models"
+)
+
+func TestIntegrityServiceDetectsAndRepairsDrift(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "integrity@example.com", "strongpass", "usd")
+	require.NoError(t, err)
+
+	svc := NewAccountService(db, false)
+	accountID := defaultAccountID(t, svc, user.ID)
+	_, _, err = svc.CreditIncome(ctx, user.ID, accountID, &models.Income{AmountCents: 5000, Source: "Salary"})
+	require.NoError(t, err)
+	_, _, err = svc.DebitExpense(ctx, user.ID, accountID, &models.Expense{AmountCents: 1200, Category: "Food"})
+	require.NoError(t, err)
+
+	integrity := NewIntegrityService(db)
+	report, err := integrity.Check(ctx)
+	require.NoError(t, err)
+	require.Empty(t, report.Discrepancies)
+
+	// Drift the cached balance behind the ledger's back.
+	require.NoError(t, db.Exec("UPDATE accounts SET balance_cents = ? WHERE id = ?", 999, accountID).Error)
+
+	report, err = integrity.Check(ctx)
+	require.NoError(t, err)
+	require.Len(t, report.Discrepancies, 1)
+	require.Equal(t, BalanceDiscrepancy{
+		AccountID:         accountID,
+		UserID:            user.ID,
+		CurrencyISOCode:   "USD",
+		StoredCents:       999,
+		LedgerCents:       3800,
+		TransactionsCents: 3800,
+	}, report.Discrepancies[0])
+	require.False(t, report.Repaired)
+
+	report, err = integrity.Repair(ctx, "ops@example.com")
+	require.NoError(t, err)
+	require.True(t, report.Repaired)
+
+	account, err := svc.GetAccount(ctx, user.ID, accountID)
+	require.NoError(t, err)
+	require.Equal(t, int64(3800), account.BalanceCents)
+
+	var audits []models.BalanceAudit
+	require.NoError(t, db.Find(&audits).Error)
+	require.Len(t, audits, 1)
+	require.Equal(t, int64(999), audits[0].StoredCents)
+	require.Equal(t, "ops@example.com", audits[0].Actor)
+
+	report, err = integrity.Check(ctx)
+	require.NoError(t, err)
+	require.Empty(t, report.Discrepancies)
+}
+
+func TestIntegrityServiceRepairsMissingTransactions(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "integrity-ledger@example.com", "strongpass", "usd")
+	require.NoError(t, err)
+
+	svc := NewAccountService(db, false)
+	accountID := defaultAccountID(t, svc, user.ID)
+	income, _, err := svc.CreditIncome(ctx, user.ID, accountID, &models.Income{AmountCents: 5000, Source: "Salary"})
+	require.NoError(t, err)
+
+	// An income row removed outside the service leaves ledger and balance overstated.
+	require.NoError(t, db.Exec("DELETE FROM incomes WHERE id = ?", income.ID).Error)
+
+	integrity := NewIntegrityService(db)
+	report, err := integrity.Repair(ctx, "cli")
+	require.NoError(t, err)
+	require.Len(t, report.Discrepancies, 1)
+	require.Equal(t, int64(0), report.Discrepancies[0].TransactionsCents)
+
+	account, err := svc.GetAccount(ctx, user.ID, accountID)
+	require.NoError(t, err)
+	require.Zero(t, account.BalanceCents)
+
+	ledgerBalance, err := NewLedger(db).AccountBalance(ctx, accountID)
+	require.NoError(t, err)
+	require.Zero(t, ledgerBalance)
+}
+
+func TestIntegrityServiceRepairIgnoresPostingsCommittedDuringCheck(t *testing.T) {
+	db := setupFileTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "integrity-race@example.com", "strongpass", "usd")
+	require.NoError(t, err)
+
+	svc := NewAccountService(db, false)
+	accountID := defaultAccountID(t, svc, user.ID)
+	_, _, err = svc.CreditIncome(ctx, user.ID, accountID, &models.Income{AmountCents: 5000, Source: "Salary"})
+	require.NoError(t, err)
+
+	// An expense commits after the balances were read but before the expenses are summed.
+	posted := false
+	require.NoError(t, db.Callback().Row().Before("gorm:row").Register("test:post_during_check", func(tx *gorm.DB) {
+		if posted || !strings.Contains(tx.Statement.SQL.String(), "FROM expenses") {
+			return
+		}
+		posted = true
+		_, _, err := svc.DebitExpense(ctx, user.ID, accountID, &models.Expense{AmountCents: 1200, Category: "Food"})
+		require.NoError(t, err)
+	}))
+
+	integrity := NewIntegrityService(db)
+	report, err := integrity.Repair(ctx, "cli")
+	require.NoError(t, err)
+	require.True(t, posted)
+	require.Empty(t, report.Discrepancies)
+	require.False(t, report.Repaired)
+
+	account, err := svc.GetAccount(ctx, user.ID, accountID)
+	require.NoError(t, err)
+	require.Equal(t, int64(3800), account.BalanceCents)
+
+	var audits int64
+	require.NoError(t, db.Model(&models.BalanceAudit{}).Count(&audits).Error)
+	require.Zero(t, audits)
+}
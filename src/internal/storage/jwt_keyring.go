@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SigningKey is a named key used to sign and/or verify access tokens.
+type SigningKey struct {
+	ID     string
+	Method jwt.SigningMethod
+
+	// signKey is nil for verify-only keys kept around during rotation.
+	signKey   any
+	verifyKey any
+}
+
+// CanSign reports whether the key holds private material.
+func (k *SigningKey) CanSign() bool {
+	return k.signKey != nil
+}
+
+// PublicKey returns the public half of an asymmetric key, or nil for shared secrets.
+func (k *SigningKey) PublicKey() crypto.PublicKey {
+	switch key := k.verifyKey.(type) {
+	case *rsa.PublicKey, ed25519.PublicKey:
+		return key
+	default:
+		return nil
+	}
+}
+
+// Keyring holds the active signing key together with keys that are still accepted
+// for verification, so keys can be rotated without invalidating issued tokens.
+type Keyring struct {
+	active *SigningKey
+	keys   map[string]*SigningKey
+}
+
+// NewHMACKeyring builds a keyring with a single HS256 shared secret.
+func NewHMACKeyring(secret string) *Keyring {
+	key := &SigningKey{
+		Method:    jwt.SigningMethodHS256,
+		signKey:   []byte(secret),
+		verifyKey: []byte(secret),
+	}
+	return &Keyring{active: key, keys: map[string]*SigningKey{"": key}}
+}
+
+// LoadKeyring reads every *.pem file in dir, using the file name without extension as
+// the key ID. Files may hold RSA or Ed25519 private keys (PKCS#1 or PKCS#8) or, for
+// retired keys that should only verify tokens, PKIX public keys. activeKeyID selects
+// the key that signs new tokens and must refer to a private key.
+func LoadKeyring(dir, activeKeyID string) (*Keyring, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("list signing keys: %w", err)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no *.pem signing keys found in %s", dir)
+	}
+
+	ring := &Keyring{keys: make(map[string]*SigningKey, len(paths))}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read signing key %s: %w", path, err)
+		}
+
+		kid := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		key, err := ParseSigningKeyPEM(kid, data)
+		if err != nil {
+			return nil, fmt.Errorf("parse signing key %s: %w", path, err)
+		}
+		ring.keys[kid] = key
+	}
+
+	active, ok := ring.keys[activeKeyID]
+	if !ok {
+		return nil, fmt.Errorf("active signing key %q not found in %s", activeKeyID, dir)
+	}
+	if !active.CanSign() {
+		return nil, fmt.Errorf("active signing key %q has no private key", activeKeyID)
+	}
+	ring.active = active
+
+	return ring, nil
+}
+
+// ParseSigningKeyPEM decodes a PEM encoded RSA or Ed25519 key.
+func ParseSigningKeyPEM(kid string, data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodRS256, signKey: key, verifyKey: &key.PublicKey}, nil
+	case *rsa.PublicKey:
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodRS256, verifyKey: key}, nil
+	case ed25519.PrivateKey:
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodEdDSA, signKey: key, verifyKey: key.Public()}, nil
+	case ed25519.PublicKey:
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodEdDSA, verifyKey: key}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+}
+
+// Active returns the key used to sign new tokens.
+func (k *Keyring) Active() *SigningKey {
+	return k.active
+}
+
+// Lookup returns the verification key for a kid header value.
+func (k *Keyring) Lookup(kid string) (*SigningKey, bool) {
+	key, ok := k.keys[kid]
+	return key, ok
+}
+
+// PublicKeys returns all asymmetric keys in stable order, for publishing as a JWKS.
+func (k *Keyring) PublicKeys() []*SigningKey {
+	keys := make([]*SigningKey, 0, len(k.keys))
+	for _, key := range k.keys {
+		if key.PublicKey() != nil {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys
+}
+
+func (k *Keyring) methods() []string {
+	seen := make(map[string]struct{})
+	methods := make([]string, 0, 2)
+	for _, key := range k.keys {
+		alg := key.Method.Alg()
+		if _, ok := seen[alg]; ok {
+			continue
+		}
+		seen[alg] = struct{}{}
+		methods = append(methods, alg)
+	}
+	return methods
+}
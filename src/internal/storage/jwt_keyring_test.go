@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name+".pem"), data, 0o600))
+}
+
+func TestJWTServiceSignsWithKeyringAndRotates(t *testing.T) {
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	writePEM(t, dir, "2025-01", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+
+	oldRing, err := LoadKeyring(dir, "2025-01")
+	require.NoError(t, err)
+	oldService := NewJWTServiceWithKeyring(oldRing, time.Hour)
+
+	oldToken, err := oldService.GenerateToken(7, "rotate@example.com")
+	require.NoError(t, err)
+	claims, err := oldService.ValidateToken(oldToken)
+	require.NoError(t, err)
+	require.Equal(t, uint(7), claims.UserID)
+
+	// Rotate: the new Ed25519 key signs, the old RSA key is kept as public-only.
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	edDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	require.NoError(t, err)
+	writePEM(t, dir, "2025-06", "PRIVATE KEY", edDER)
+
+	rsaPubDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(filepath.Join(dir, "2025-01.pem")))
+	writePEM(t, dir, "2025-01", "PUBLIC KEY", rsaPubDER)
+
+	newRing, err := LoadKeyring(dir, "2025-06")
+	require.NoError(t, err)
+	require.Len(t, newRing.PublicKeys(), 2)
+	newService := NewJWTServiceWithKeyring(newRing, time.Hour)
+
+	_, err = newService.ValidateToken(oldToken)
+	require.NoError(t, err, "tokens signed by a retired key stay valid until they expire")
+
+	newToken, err := newService.GenerateToken(7, "rotate@example.com")
+	require.NoError(t, err)
+	_, err = newService.ValidateToken(newToken)
+	require.NoError(t, err)
+
+	_, err = oldService.ValidateToken(newToken)
+	require.ErrorIs(t, err, ErrPreconditionFailed, "unknown kid must be rejected")
+
+	_, err = LoadKeyring(dir, "2025-01")
+	require.Error(t, err, "public-only keys cannot be active")
+}
+
+func TestJWTServiceRejectsHMACTokenForAsymmetricKeyring(t *testing.T) {
+	dir := t.TempDir()
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(edKey)
+	require.NoError(t, err)
+	writePEM(t, dir, "main", "PRIVATE KEY", der)
+
+	ring, err := LoadKeyring(dir, "main")
+	require.NoError(t, err)
+	service := NewJWTServiceWithKeyring(ring, time.Hour)
+
+	hmacToken, err := NewJWTService("secret", time.Hour).GenerateToken(1, "hmac@example.com")
+	require.NoError(t, err)
+
+	_, err = service.ValidateToken(hmacToken)
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+}
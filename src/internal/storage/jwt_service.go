@@ -10,50 +10,84 @@ import (
 
 // JWTService handles token generation and validation.
 type JWTService struct {
-	secretKey     []byte
+	keyring       *Keyring
 	tokenDuration time.Duration
 }
 
-// Claims represents the JWT payload.
+// Claims represents the JWT payload. IssuedAtMicros repeats the issue time with
+// microsecond precision, since the registered iat claim only has whole seconds.
 type Claims struct {
-	UserID uint   `json:"user_id"`
-	Email  string `json:"email"`
+	UserID         uint   `json:"user_id"`
+	Email          string `json:"email"`
+	IssuedAtMicros int64  `json:"iat_us,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// NewJWTService creates a new JWT service with the given secret and duration.
+// NewJWTService creates a new HS256 JWT service with the given secret and duration.
 func NewJWTService(secretKey string, tokenDuration time.Duration) *JWTService {
+	return NewJWTServiceWithKeyring(NewHMACKeyring(secretKey), tokenDuration)
+}
+
+// NewJWTServiceWithKeyring creates a JWT service that signs with the keyring's active key
+// and accepts tokens signed by any key in the keyring.
+func NewJWTServiceWithKeyring(keyring *Keyring, tokenDuration time.Duration) *JWTService {
 	return &JWTService{
-		secretKey:     []byte(secretKey),
+		keyring:       keyring,
 		tokenDuration: tokenDuration,
 	}
 }
 
 // GenerateToken creates a signed JWT for the given user.
 func (s *JWTService) GenerateToken(userID uint, email string) (string, error) {
+	tokenID, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+
 	now := time.Now()
 	claims := Claims{
-		UserID: userID,
-		Email:  email,
+		UserID:         userID,
+		Email:          email,
+		IssuedAtMicros: now.UnixMicro(),
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
 			ExpiresAt: jwt.NewNumericDate(now.Add(s.tokenDuration)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(s.secretKey)
+	active := s.keyring.Active()
+	token := jwt.NewWithClaims(active.Method, claims)
+	if active.ID != "" {
+		token.Header["kid"] = active.ID
+	}
+	return token.SignedString(active.signKey)
+}
+
+// Keyring exposes the keys used by the service, e.g. to publish a JWKS.
+func (s *JWTService) Keyring() *Keyring {
+	return s.keyring
+}
+
+// TokenDuration returns the lifetime of issued access tokens.
+func (s *JWTService) TokenDuration() time.Duration {
+	return s.tokenDuration
 }
 
 // ValidateToken parses and validates the token, returning claims if valid.
 func (s *JWTService) ValidateToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+		kid, _ := t.Header["kid"].(string)
+		key, ok := s.keyring.Lookup(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		if t.Method.Alg() != key.Method.Alg() {
 			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
 		}
-		return s.secretKey, nil
-	})
+		return key.verifyKey, nil
+	}, jwt.WithValidMethods(s.keyring.methods()))
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
 			return nil, fmt.Errorf("%w: token expired", ErrPreconditionFailed)
@@ -0,0 +1,225 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"bckndlab3/src/internal/models"
+)
+
+// Ledger records balanced journals and maintains Account.BalanceCents as a cached
+// projection of the postings made against each account. Every balance change must go
+// through Post so that the balance always equals the sum of the account's entries.
+type Ledger struct {
+	db       *gorm.DB
+	accounts *AccountRepository
+}
+
+func NewLedger(db *gorm.DB) *Ledger {
+	return &Ledger{db: db, accounts: NewAccountRepository(db)}
+}
+
+// Post validates and persists a journal with its entries, then applies the entries to
+// the balances of the referenced accounts. It returns the new balance of every account
+// touched by the journal.
+func (l *Ledger) Post(ctx context.Context, tx *gorm.DB, journal *models.LedgerJournal) (map[uint]int64, error) {
+	return l.post(ctx, tx, journal, nil)
+}
+
+// PostAboveFloor is Post, but fails with ErrInsufficientFunds when an entry lowering an
+// account balance would take it below floor. Entries raising a balance are always applied.
+func (l *Ledger) PostAboveFloor(ctx context.Context, tx *gorm.DB, journal *models.LedgerJournal, floor int64) (map[uint]int64, error) {
+	return l.post(ctx, tx, journal, &floor)
+}
+
+func (l *Ledger) post(ctx context.Context, tx *gorm.DB, journal *models.LedgerJournal, floor *int64) (map[uint]int64, error) {
+	if err := validateJournal(journal); err != nil {
+		return nil, err
+	}
+
+	if err := tx.WithContext(ctx).Create(journal).Error; err != nil {
+		return nil, translateError(err)
+	}
+
+	balances := make(map[uint]int64)
+	for _, entry := range journal.Entries {
+		if entry.AccountID == nil {
+			continue
+		}
+
+		var (
+			balance int64
+			err     error
+		)
+		if floor != nil && entry.AmountCents < 0 {
+			balance, err = l.accounts.AdjustBalanceAboveFloor(ctx, tx, *entry.AccountID, entry.AmountCents, *floor)
+		} else {
+			balance, err = l.accounts.AdjustBalance(ctx, tx, *entry.AccountID, entry.AmountCents)
+		}
+		if err != nil {
+			return nil, err
+		}
+		balances[*entry.AccountID] = balance
+	}
+	return balances, nil
+}
+
+// AccountBalance derives an account's balance from its postings.
+func (l *Ledger) AccountBalance(ctx context.Context, accountID uint) (int64, error) {
+	var balance int64
+	err := l.db.WithContext(ctx).Model(&models.LedgerEntry{}).
+		Select("COALESCE(SUM(amount_cents), 0)").
+		Where("account_id = ?", accountID).
+		Scan(&balance).Error
+	if err != nil {
+		return 0, translateError(err)
+	}
+	return balance, nil
+}
+
+// Journals returns the journals recorded for a business event, oldest first, with their entries.
+func (l *Ledger) Journals(ctx context.Context, kind string, referenceID uint) ([]models.LedgerJournal, error) {
+	var journals []models.LedgerJournal
+	err := l.db.WithContext(ctx).
+		Preload("Entries").
+		Where("kind = ? AND reference_id = ?", kind, referenceID).
+		Order("id ASC").
+		Find(&journals).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return journals, nil
+}
+
+func validateJournal(journal *models.LedgerJournal) error {
+	if len(journal.Entries) < 2 {
+		return fmt.Errorf("%w: journal needs at least two entries", ErrPreconditionFailed)
+	}
+
+	sums := make(map[string]int64)
+	for _, entry := range journal.Entries {
+		if entry.AmountCents == 0 {
+			return fmt.Errorf("%w: journal entries must not be zero", ErrPreconditionFailed)
+		}
+		if entry.CurrencyISOCode == "" {
+			return fmt.Errorf("%w: journal entries need a currency", ErrPreconditionFailed)
+		}
+		if (entry.AccountID != nil) != (entry.LedgerAccount == models.LedgerAccountAssets) {
+			return fmt.Errorf("%w: only asset entries may reference an account", ErrPreconditionFailed)
+		}
+		sums[entry.CurrencyISOCode] += entry.AmountCents
+	}
+	for currency, sum := range sums {
+		if sum != 0 {
+			return fmt.Errorf("%w: journal is unbalanced by %d in %s", ErrPreconditionFailed, sum, currency)
+		}
+	}
+	return nil
+}
+
+// assetEntry posts amount to a user's account; positive amounts increase its balance.
+func assetEntry(account *models.Account, amount int64) models.LedgerEntry {
+	accountID := account.ID
+	return models.LedgerEntry{
+		AccountID:       &accountID,
+		LedgerAccount:   models.LedgerAccountAssets,
+		CurrencyISOCode: account.CurrencyISOCode,
+		AmountCents:     amount,
+	}
+}
+
+// externalEntry posts amount to a ledger account outside the user's books.
+func externalEntry(ledgerAccount, currency string, amount int64) models.LedgerEntry {
+	return models.LedgerEntry{
+		LedgerAccount:   ledgerAccount,
+		CurrencyISOCode: currency,
+		AmountCents:     amount,
+	}
+}
+
+func incomeJournal(account *models.Account, income *models.Income) *models.LedgerJournal {
+	return incomeCorrectionJournal(account, income, income.AmountCents, income.ReceivedAt, income.Source)
+}
+
+// incomeCorrectionJournal posts delta against an income, e.g. when its amount is edited
+// or, with the negated amount, when it is deleted.
+func incomeCorrectionJournal(account *models.Account, income *models.Income, delta int64, postedAt time.Time, description string) *models.LedgerJournal {
+	return &models.LedgerJournal{
+		UserID:      income.UserID,
+		Kind:        models.JournalKindIncome,
+		ReferenceID: income.ID,
+		PostedAt:    postedAt,
+		Description: description,
+		Entries: []models.LedgerEntry{
+			assetEntry(account, delta),
+			externalEntry(models.LedgerAccountIncome, account.CurrencyISOCode, -delta),
+		},
+	}
+}
+
+func expenseJournal(account *models.Account, expense *models.Expense) *models.LedgerJournal {
+	return expenseCorrectionJournal(account, expense, expense.AmountCents, expense.IncurredAt, expense.Category)
+}
+
+// expenseCorrectionJournal posts delta against an expense; a positive delta spends more.
+func expenseCorrectionJournal(account *models.Account, expense *models.Expense, delta int64, postedAt time.Time, description string) *models.LedgerJournal {
+	return &models.LedgerJournal{
+		UserID:      expense.UserID,
+		Kind:        models.JournalKindExpense,
+		ReferenceID: expense.ID,
+		PostedAt:    postedAt,
+		Description: description,
+		Entries: []models.LedgerEntry{
+			externalEntry(models.LedgerAccountExpense, account.CurrencyISOCode, delta),
+			assetEntry(account, -delta),
+		},
+	}
+}
+
+// conversionJournal moves an account balance out of one currency and into another; both
+// currencies balance through the FX clearing account.
+func conversionJournal(account *models.Account, conversion *models.CurrencyConversion) *models.LedgerJournal {
+	before, after := *account, *account
+	before.CurrencyISOCode = conversion.FromCurrency
+	after.CurrencyISOCode = conversion.ToCurrency
+
+	return &models.LedgerJournal{
+		UserID:      conversion.UserID,
+		Kind:        models.JournalKindConversion,
+		ReferenceID: conversion.ID,
+		PostedAt:    conversion.ConvertedAt,
+		Description: fmt.Sprintf("currency changed from %s to %s", conversion.FromCurrency, conversion.ToCurrency),
+		Entries: []models.LedgerEntry{
+			assetEntry(&before, -conversion.FromAmountCents),
+			externalEntry(models.LedgerAccountFXClearing, conversion.FromCurrency, conversion.FromAmountCents),
+			assetEntry(&after, conversion.ToAmountCents),
+			externalEntry(models.LedgerAccountFXClearing, conversion.ToCurrency, -conversion.ToAmountCents),
+		},
+	}
+}
+
+func transferJournal(from, to *models.Account, transfer *models.Transfer) *models.LedgerJournal {
+	entries := []models.LedgerEntry{
+		assetEntry(from, -transfer.AmountCents),
+		assetEntry(to, transfer.ToAmountCents),
+	}
+	if from.CurrencyISOCode != to.CurrencyISOCode {
+		// Cross-currency transfers balance each currency through the FX clearing account.
+		entries = append(entries,
+			externalEntry(models.LedgerAccountFXClearing, from.CurrencyISOCode, transfer.AmountCents),
+			externalEntry(models.LedgerAccountFXClearing, to.CurrencyISOCode, -transfer.ToAmountCents),
+		)
+	}
+
+	return &models.LedgerJournal{
+		UserID:      transfer.UserID,
+		Kind:        models.JournalKindTransfer,
+		ReferenceID: transfer.ID,
+		PostedAt:    transfer.TransferredAt,
+		Description: transfer.Notes,
+		Entries:     entries,
+	}
+}
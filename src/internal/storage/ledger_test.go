@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"bckndlab3/src/internal/migrations"
+	"bckndlab3/src/internal/models"
+)
+
+func requireBalancedJournal(t *testing.T, journal models.LedgerJournal) {
+	t.Helper()
+
+	sums := make(map[string]int64)
+	for _, entry := range journal.Entries {
+		sums[entry.CurrencyISOCode] += entry.AmountCents
+	}
+	for currency, sum := range sums {
+		require.Zero(t, sum, "journal %d unbalanced in %s", journal.ID, currency)
+	}
+}
+
+func TestLedgerRecordsBalancedJournals(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "ledger@example.com", "strongpass", "usd")
+	require.NoError(t, err)
+
+	svc := NewAccountService(db, false)
+	ledger := NewLedger(db)
+	usdID := defaultAccountID(t, svc, user.ID)
+	eur, err := svc.CreateAccount(ctx, user.ID, &models.Account{Name: "Euro", CurrencyISOCode: "EUR"})
+	require.NoError(t, err)
+
+	income, _, err := svc.CreditIncome(ctx, user.ID, usdID, &models.Income{AmountCents: 10000, Source: "Salary"})
+	require.NoError(t, err)
+	expense, _, err := svc.DebitExpense(ctx, user.ID, usdID, &models.Expense{AmountCents: 2500, Category: "Food"})
+	require.NoError(t, err)
+	transfer, _, _, err := svc.Transfer(ctx, user.ID, &models.Transfer{
+		FromAccountID: usdID,
+		ToAccountID:   eur.ID,
+		AmountCents:   5000,
+		ExchangeRate:  "0.9",
+	})
+	require.NoError(t, err)
+
+	_, _, err = svc.DebitExpense(ctx, user.ID, usdID, &models.Expense{AmountCents: 99999, Category: "Car"})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	references := map[string]uint{
+		models.JournalKindIncome:   income.ID,
+		models.JournalKindExpense:  expense.ID,
+		models.JournalKindTransfer: transfer.ID,
+	}
+	for kind, referenceID := range references {
+		journals, err := ledger.Journals(ctx, kind, referenceID)
+		require.NoError(t, err)
+		require.Len(t, journals, 1, kind)
+		requireBalancedJournal(t, journals[0])
+	}
+
+	var journalCount int64
+	require.NoError(t, db.Model(&models.LedgerJournal{}).Count(&journalCount).Error)
+	require.Equal(t, int64(3), journalCount, "rejected operations must not leave journals behind")
+
+	for _, accountID := range []uint{usdID, eur.ID} {
+		account, err := svc.GetAccount(ctx, user.ID, accountID)
+		require.NoError(t, err)
+		derived, err := ledger.AccountBalance(ctx, accountID)
+		require.NoError(t, err)
+		require.Equal(t, account.BalanceCents, derived)
+	}
+}
+
+func TestLedgerRejectsUnbalancedAndMutatedJournals(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "ledger-guard@example.com", "strongpass", "usd")
+	require.NoError(t, err)
+
+	svc := NewAccountService(db, false)
+	account, err := svc.GetAccountByUserID(ctx, user.ID)
+	require.NoError(t, err)
+
+	ledger := NewLedger(db)
+	_, err = ledger.Post(ctx, db, &models.LedgerJournal{
+		UserID:   user.ID,
+		Kind:     models.JournalKindIncome,
+		PostedAt: time.Now().UTC(),
+		Entries: []models.LedgerEntry{
+			assetEntry(account, 100),
+			externalEntry(models.LedgerAccountIncome, "USD", -90),
+		},
+	})
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+
+	_, _, err = svc.CreditIncome(ctx, user.ID, account.ID, &models.Income{AmountCents: 100, Source: "Gift"})
+	require.NoError(t, err)
+
+	var entry models.LedgerEntry
+	require.NoError(t, db.First(&entry).Error)
+	require.ErrorIs(t, db.Model(&entry).Update("amount_cents", 1).Error, models.ErrLedgerImmutable)
+	require.ErrorIs(t, db.Delete(&entry).Error, models.ErrLedgerImmutable)
+}
+
+func TestLedgerBackfillsExistingTransactions(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "ledger-backfill@example.com", "strongpass", "usd")
+	require.NoError(t, err)
+
+	svc := NewAccountService(db, false)
+	accountID := defaultAccountID(t, svc, user.ID)
+
+	// Simulate rows written before the ledger existed.
+	legacy := &models.Income{AccountID: accountID, UserID: user.ID, AmountCents: 700, Source: "Legacy", ReceivedAt: time.Now().UTC()}
+	require.NoError(t, db.Create(legacy).Error)
+	require.NoError(t, db.Exec("UPDATE accounts SET balance_cents = balance_cents + ? WHERE id = ?", 700, accountID).Error)
+
+	require.NoError(t, migrations.Run(db))
+	require.NoError(t, migrations.Run(db))
+
+	ledger := NewLedger(db)
+	journals, err := ledger.Journals(ctx, models.JournalKindIncome, legacy.ID)
+	require.NoError(t, err)
+	require.Len(t, journals, 1)
+	require.Len(t, journals[0].Entries, 2)
+	requireBalancedJournal(t, journals[0])
+
+	derived, err := ledger.AccountBalance(ctx, accountID)
+	require.NoError(t, err)
+	require.Equal(t, int64(700), derived)
+}
@@ -0,0 +1,321 @@
+package storage
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrUnsupportedHash is returned when an encoded hash does not belong to any known scheme.
+var ErrUnsupportedHash = errors.New("unsupported password hash")
+
+// PasswordHasher produces and verifies self-describing encoded password hashes.
+type PasswordHasher interface {
+	// Hash derives an encoded hash (including algorithm, parameters and salt) for the password.
+	Hash(password string) (string, error)
+	// Verify reports whether the password matches the encoded hash.
+	Verify(password, encoded string) (bool, error)
+	// Supports reports whether the encoded hash was produced by this scheme.
+	Supports(encoded string) bool
+	// NeedsRehash reports whether the encoded hash should be replaced with a fresh one.
+	NeedsRehash(encoded string) bool
+}
+
+// Argon2idParams tunes the cost of argon2id hashing.
+type Argon2idParams struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2idParams returns parameters suitable for interactive logins.
+func DefaultArgon2idParams() Argon2idParams {
+	return Argon2idParams{
+		Memory:      64 * 1024,
+		Iterations:  3,
+		Parallelism: 2,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+}
+
+const argon2idPrefix = "$argon2id$"
+
+// maxArgon2idMemory caps the memory (in KiB) a stored argon2id hash may demand, so a
+// corrupted hash cannot exhaust the server while a login is verified.
+const maxArgon2idMemory = 4 * 1024 * 1024
+
+// Argon2idHasher hashes passwords with argon2id and encodes them in PHC string format.
+type Argon2idHasher struct {
+	params Argon2idParams
+}
+
+func NewArgon2idHasher(params Argon2idParams) *Argon2idHasher {
+	defaults := DefaultArgon2idParams()
+	if params.Memory == 0 {
+		params.Memory = defaults.Memory
+	}
+	if params.Iterations == 0 {
+		params.Iterations = defaults.Iterations
+	}
+	if params.Parallelism == 0 {
+		params.Parallelism = defaults.Parallelism
+	}
+	if params.SaltLength == 0 {
+		params.SaltLength = defaults.SaltLength
+	}
+	if params.KeyLength == 0 {
+		params.KeyLength = defaults.KeyLength
+	}
+	return &Argon2idHasher{params: params}
+}
+
+// Hash returns a string such as $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>.
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("generate salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.params.Iterations, h.params.Memory, h.params.Parallelism, h.params.KeyLength)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix,
+		argon2.Version,
+		h.params.Memory,
+		h.params.Iterations,
+		h.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (h *Argon2idHasher) Verify(password, encoded string) (bool, error) {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	candidate := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(candidate, key) == 1, nil
+}
+
+func (h *Argon2idHasher) Supports(encoded string) bool {
+	return strings.HasPrefix(encoded, argon2idPrefix)
+}
+
+func (h *Argon2idHasher) NeedsRehash(encoded string) bool {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return params.Memory != h.params.Memory ||
+		params.Iterations != h.params.Iterations ||
+		params.Parallelism != h.params.Parallelism ||
+		uint32(len(salt)) != h.params.SaltLength ||
+		uint32(len(key)) != h.params.KeyLength
+}
+
+func decodeArgon2id(encoded string) (Argon2idParams, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return Argon2idParams{}, nil, nil, ErrUnsupportedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("%w: argon2id version", ErrUnsupportedHash)
+	}
+
+	var params Argon2idParams
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("%w: argon2id parameters", ErrUnsupportedHash)
+	}
+	// argon2.IDKey panics on zero iterations or parallelism.
+	if params.Iterations == 0 || params.Parallelism == 0 || params.Memory == 0 || params.Memory > maxArgon2idMemory {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("%w: argon2id parameters out of range", ErrUnsupportedHash)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("%w: argon2id salt", ErrUnsupportedHash)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("%w: argon2id key", ErrUnsupportedHash)
+	}
+
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
+
+// maxBcryptPasswordBytes is the longest password bcrypt hashes; it ignores anything after.
+const maxBcryptPasswordBytes = 72
+
+// BcryptHasher hashes passwords with bcrypt using a configurable cost.
+type BcryptHasher struct {
+	cost int
+}
+
+// NewBcryptHasher uses the default cost when cost is zero.
+func NewBcryptHasher(cost int) *BcryptHasher {
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+	return &BcryptHasher{cost: cost}
+}
+
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	if err := checkPasswordLength(h, password); err != nil {
+		return "", err
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", fmt.Errorf("bcrypt hash: %w", err)
+	}
+	return string(hashed), nil
+}
+
+func (h *BcryptHasher) Verify(password, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+		return false, nil
+	default:
+		return false, fmt.Errorf("%w: %v", ErrUnsupportedHash, err)
+	}
+}
+
+func (h *BcryptHasher) Supports(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") ||
+		strings.HasPrefix(encoded, "$2b$") ||
+		strings.HasPrefix(encoded, "$2y$")
+}
+
+func (h *BcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	if err != nil {
+		return true
+	}
+	return cost != h.cost
+}
+
+// LegacySHA256Hasher verifies unsalted SHA-256 hex digests stored by earlier releases.
+// It is verification-only: new hashes are never produced in this format.
+type LegacySHA256Hasher struct{}
+
+func (LegacySHA256Hasher) Hash(string) (string, error) {
+	return "", fmt.Errorf("%w: legacy sha256 hashes cannot be created", ErrUnsupportedHash)
+}
+
+func (LegacySHA256Hasher) Verify(password, encoded string) (bool, error) {
+	sum := sha256.Sum256([]byte(password))
+	expected := hex.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(strings.ToLower(encoded))) == 1, nil
+}
+
+func (LegacySHA256Hasher) Supports(encoded string) bool {
+	if len(encoded) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(encoded)
+	return err == nil
+}
+
+func (LegacySHA256Hasher) NeedsRehash(string) bool { return true }
+
+// UpgradingHasher hashes with a preferred scheme while still verifying hashes produced
+// by legacy schemes. Any hash not produced by the preferred scheme with its current
+// parameters is reported as needing a rehash.
+type UpgradingHasher struct {
+	preferred PasswordHasher
+	legacy    []PasswordHasher
+}
+
+func NewUpgradingHasher(preferred PasswordHasher, legacy ...PasswordHasher) *UpgradingHasher {
+	return &UpgradingHasher{preferred: preferred, legacy: legacy}
+}
+
+func (h *UpgradingHasher) Hash(password string) (string, error) {
+	return h.preferred.Hash(password)
+}
+
+func (h *UpgradingHasher) Verify(password, encoded string) (bool, error) {
+	hasher := h.lookup(encoded)
+	if hasher == nil {
+		return false, ErrUnsupportedHash
+	}
+	return hasher.Verify(password, encoded)
+}
+
+func (h *UpgradingHasher) Supports(encoded string) bool {
+	return h.lookup(encoded) != nil
+}
+
+func (h *UpgradingHasher) NeedsRehash(encoded string) bool {
+	if h.preferred.Supports(encoded) {
+		return h.preferred.NeedsRehash(encoded)
+	}
+	return true
+}
+
+// checkPasswordLength rejects passwords the hasher would not hash in full. Only bcrypt
+// limits the length; argon2id accepts passwords of any size.
+func checkPasswordLength(hasher PasswordHasher, password string) error {
+	switch h := hasher.(type) {
+	case *BcryptHasher:
+		if len(password) > maxBcryptPasswordBytes {
+			return fmt.Errorf("%w: passwords are limited to %d bytes", ErrPreconditionFailed, maxBcryptPasswordBytes)
+		}
+	case *UpgradingHasher:
+		return checkPasswordLength(h.preferred, password)
+	}
+	return nil
+}
+
+func (h *UpgradingHasher) lookup(encoded string) PasswordHasher {
+	if h.preferred.Supports(encoded) {
+		return h.preferred
+	}
+	for _, hasher := range h.legacy {
+		if hasher.Supports(encoded) {
+			return hasher
+		}
+	}
+	return nil
+}
+
+// NewPasswordHasher builds the application hasher for the named algorithm ("argon2id" or
+// "bcrypt"), keeping every other known scheme verifiable so existing users can migrate.
+// A zero bcryptCost selects the bcrypt default; the cost is only validated when bcrypt
+// produces new hashes.
+func NewPasswordHasher(algorithm string, argonParams Argon2idParams, bcryptCost int) (PasswordHasher, error) {
+	argonHasher := NewArgon2idHasher(argonParams)
+	bcryptHasher := NewBcryptHasher(bcryptCost)
+
+	switch strings.ToLower(algorithm) {
+	case "", "argon2id":
+		return NewUpgradingHasher(argonHasher, bcryptHasher, LegacySHA256Hasher{}), nil
+	case "bcrypt":
+		if bcryptHasher.cost < bcrypt.MinCost || bcryptHasher.cost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost must be between %d and %d, got %d", bcrypt.MinCost, bcrypt.MaxCost, bcryptCost)
+		}
+		return NewUpgradingHasher(bcryptHasher, argonHasher, LegacySHA256Hasher{}), nil
+	default:
+		return nil, fmt.Errorf("unknown password hash algorithm %q", algorithm)
+	}
+}
@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestPasswordHasher() PasswordHasher {
+	return NewUpgradingHasher(
+		NewArgon2idHasher(Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1}),
+		NewBcryptHasher(bcrypt.MinCost),
+		LegacySHA256Hasher{},
+	)
+}
+
+func TestArgon2idHasherRoundTrip(t *testing.T) {
+	hasher := NewArgon2idHasher(Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1})
+
+	first, err := hasher.Hash("correct horse")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(first, "$argon2id$v=19$m=1024,t=1,p=1$"))
+
+	second, err := hasher.Hash("correct horse")
+	require.NoError(t, err)
+	require.NotEqual(t, first, second, "salts must differ per hash")
+
+	ok, err := hasher.Verify("correct horse", first)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = hasher.Verify("battery staple", first)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.False(t, hasher.NeedsRehash(first))
+	stronger := NewArgon2idHasher(Argon2idParams{Memory: 2048, Iterations: 1, Parallelism: 1})
+	require.True(t, stronger.NeedsRehash(first))
+}
+
+func TestArgon2idHasherRejectsOutOfRangeParameters(t *testing.T) {
+	hasher := NewArgon2idHasher(Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1})
+	encoded, err := hasher.Hash("correct horse")
+	require.NoError(t, err)
+
+	for _, params := range []string{"m=1024,t=0,p=1", "m=1024,t=1,p=0", "m=0,t=1,p=1", "m=4194305,t=1,p=1"} {
+		malformed := strings.Replace(encoded, "m=1024,t=1,p=1", params, 1)
+		ok, err := hasher.Verify("correct horse", malformed)
+		require.ErrorIs(t, err, ErrUnsupportedHash, params)
+		require.False(t, ok)
+		require.True(t, hasher.NeedsRehash(malformed))
+	}
+}
+
+func TestBcryptHasherRoundTrip(t *testing.T) {
+	hasher := NewBcryptHasher(bcrypt.MinCost)
+
+	encoded, err := hasher.Hash("correct horse")
+	require.NoError(t, err)
+	require.True(t, hasher.Supports(encoded))
+
+	ok, err := hasher.Verify("correct horse", encoded)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = hasher.Verify("battery staple", encoded)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.False(t, hasher.NeedsRehash(encoded))
+	require.True(t, NewBcryptHasher(bcrypt.MinCost+1).NeedsRehash(encoded))
+
+	_, err = hasher.Hash(strings.Repeat("é", 37))
+	require.ErrorIs(t, err, ErrPreconditionFailed, "bcrypt would ignore the bytes past 72")
+}
+
+func TestNewPasswordHasherRejectsInvalidBcryptCost(t *testing.T) {
+	params := Argon2idParams{Memory: 1024, Iterations: 1, Parallelism: 1}
+	for _, cost := range []int{-1, bcrypt.MinCost - 1, bcrypt.MaxCost + 1} {
+		_, err := NewPasswordHasher("bcrypt", params, cost)
+		require.Error(t, err, "cost %d", cost)
+	}
+
+	hasher, err := NewPasswordHasher("bcrypt", params, 0)
+	require.NoError(t, err, "zero selects the default cost")
+	encoded, err := hasher.Hash("correct horse")
+	require.NoError(t, err)
+	cost, err := bcrypt.Cost([]byte(encoded))
+	require.NoError(t, err)
+	require.Equal(t, bcrypt.DefaultCost, cost)
+
+	_, err = NewPasswordHasher("argon2id", params, bcrypt.MaxCost+1)
+	require.NoError(t, err, "the bcrypt cost does not matter while argon2id hashes new passwords")
+}
+
+func TestUpgradingHasherVerifiesLegacyAndRequestsRehash(t *testing.T) {
+	hasher := newTestPasswordHasher()
+
+	sum := sha256.Sum256([]byte("topsecret"))
+	legacy := hex.EncodeToString(sum[:])
+
+	ok, err := hasher.Verify("topsecret", legacy)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.True(t, hasher.NeedsRehash(legacy))
+
+	bcryptHash, err := NewBcryptHasher(bcrypt.MinCost).Hash("topsecret")
+	require.NoError(t, err)
+	ok, err = hasher.Verify("topsecret", bcryptHash)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.True(t, hasher.NeedsRehash(bcryptHash))
+
+	_, err = hasher.Verify("topsecret", "plaintext")
+	require.ErrorIs(t, err, ErrUnsupportedHash)
+}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"bckndlab3/src/internal/models"
+)
+
+// RefreshTokenRepository handles persistence for refresh tokens.
+type RefreshTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: db}
+}
+
+// Create stores a new refresh token record.
+func (r *RefreshTokenRepository) Create(ctx context.Context, tx *gorm.DB, token *models.RefreshToken) error {
+	if err := tx.WithContext(ctx).Create(token).Error; err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+// GetByHash looks up a refresh token by the hash of its raw value.
+func (r *RefreshTokenRepository) GetByHash(ctx context.Context, tx *gorm.DB, tokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	if err := tx.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &token, nil
+}
+
+// MarkUsed flags an unused token as consumed. It reports false when the token had
+// already been used or revoked, which lets concurrent rotations detect each other.
+func (r *RefreshTokenRepository) MarkUsed(ctx context.Context, tx *gorm.DB, id uint, at time.Time) (bool, error) {
+	result := tx.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		Update("used_at", at)
+	if err := result.Error; err != nil {
+		return false, translateError(err)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RevokeFamily revokes every still-active token of a token family.
+func (r *RefreshTokenRepository) RevokeFamily(ctx context.Context, tx *gorm.DB, familyID string, at time.Time) error {
+	err := tx.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", at).Error
+	if err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+// RevokeAllForUser revokes every active token belonging to a user.
+func (r *RefreshTokenRepository) RevokeAllForUser(ctx context.Context, tx *gorm.DB, userID uint, at time.Time) error {
+	err := tx.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", at).Error
+	if err != nil {
+		return translateError(err)
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+
+	"bckndlab3/src/internal/models"
+)
+
+const refreshTokenBytes = 32
+
+// maxDeviceNameBytes is the longest device name stored with a refresh token.
+const maxDeviceNameBytes = 255
+
+// RefreshTokenService issues and rotates long-lived refresh tokens.
+//
+// Only a SHA-256 digest of each token is persisted. Every successful refresh consumes
+// the presented token and issues a successor in the same family; presenting an already
+// consumed token is treated as theft and revokes the entire family.
+type RefreshTokenService struct {
+	db     *gorm.DB
+	tokens *RefreshTokenRepository
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+func NewRefreshTokenService(db *gorm.DB, ttl time.Duration) *RefreshTokenService {
+	return &RefreshTokenService{
+		db:     db,
+		tokens: NewRefreshTokenRepository(db),
+		ttl:    ttl,
+		now:    func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// TTL returns the lifetime of newly issued refresh tokens.
+func (s *RefreshTokenService) TTL() time.Duration {
+	return s.ttl
+}
+
+// Issue starts a new token family for a freshly authenticated device and returns the raw token.
+func (s *RefreshTokenService) Issue(ctx context.Context, userID uint, deviceName string) (string, *models.RefreshToken, error) {
+	familyID, err := randomToken(16)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var (
+		raw   string
+		token *models.RefreshToken
+	)
+	err = WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		raw, token, err = s.issue(ctx, tx, userID, familyID, deviceName)
+		return err
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return raw, token, nil
+}
+
+// Rotate consumes the presented refresh token and returns its successor.
+func (s *RefreshTokenService) Rotate(ctx context.Context, rawToken string) (string, *models.RefreshToken, error) {
+	now := s.now()
+
+	var (
+		raw    string
+		next   *models.RefreshToken
+		reused *models.RefreshToken
+	)
+	err := WithTransaction(ctx, s.db, func(tx *gorm.DB) error {
+		current, err := s.tokens.GetByHash(ctx, tx, hashRefreshToken(rawToken))
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return fmt.Errorf("%w: invalid refresh token", ErrUnauthorized)
+			}
+			return err
+		}
+
+		if current.UsedAt != nil || current.RevokedAt != nil {
+			reused = current
+			return nil
+		}
+		if !now.Before(current.ExpiresAt) {
+			return fmt.Errorf("%w: refresh token expired", ErrUnauthorized)
+		}
+
+		consumed, err := s.tokens.MarkUsed(ctx, tx, current.ID, now)
+		if err != nil {
+			return err
+		}
+		if !consumed {
+			reused = current
+			return nil
+		}
+
+		raw, next, err = s.issue(ctx, tx, current.UserID, current.FamilyID, current.DeviceName)
+		return err
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	if reused != nil {
+		if err := s.RevokeFamily(ctx, reused.FamilyID); err != nil {
+			return "", nil, err
+		}
+		return "", nil, fmt.Errorf("%w: refresh token reuse detected", ErrUnauthorized)
+	}
+
+	return raw, next, nil
+}
+
+// RevokeFamily revokes all active tokens descending from the same login.
+func (s *RefreshTokenService) RevokeFamily(ctx context.Context, familyID string) error {
+	return s.tokens.RevokeFamily(ctx, s.db, familyID, s.now())
+}
+
+// RevokeToken revokes the family of a refresh token presented by its owner, e.g. on logout.
+func (s *RefreshTokenService) RevokeToken(ctx context.Context, userID uint, rawToken string) error {
+	token, err := s.tokens.GetByHash(ctx, s.db, hashRefreshToken(rawToken))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return fmt.Errorf("%w: invalid refresh token", ErrUnauthorized)
+		}
+		return err
+	}
+	if token.UserID != userID {
+		return fmt.Errorf("%w: invalid refresh token", ErrUnauthorized)
+	}
+	return s.RevokeFamily(ctx, token.FamilyID)
+}
+
+// RevokeAllForUser revokes the refresh tokens of every device the user is signed in on.
+func (s *RefreshTokenService) RevokeAllForUser(ctx context.Context, userID uint) error {
+	return s.tokens.RevokeAllForUser(ctx, s.db, userID, s.now())
+}
+
+func (s *RefreshTokenService) issue(ctx context.Context, tx *gorm.DB, userID uint, familyID, deviceName string) (string, *models.RefreshToken, error) {
+	raw, err := randomToken(refreshTokenBytes)
+	if err != nil {
+		return "", nil, err
+	}
+
+	deviceName = truncateUTF8(deviceName, maxDeviceNameBytes)
+
+	token := &models.RefreshToken{
+		UserID:     userID,
+		FamilyID:   familyID,
+		TokenHash:  hashRefreshToken(raw),
+		DeviceName: deviceName,
+		ExpiresAt:  s.now().Add(s.ttl),
+	}
+	if err := s.tokens.Create(ctx, tx, token); err != nil {
+		return "", nil, err
+	}
+	return raw, token, nil
+}
+
+func randomToken(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate random token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashRefreshToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// truncateUTF8 shortens s to at most limit bytes without splitting a UTF-8 character.
+func truncateUTF8(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	for limit > 0 && !utf8.RuneStart(s[limit]) {
+		limit--
+	}
+	return s[:limit]
+}
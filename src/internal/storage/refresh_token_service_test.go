@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshTokenServiceRotate(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "refresh@example.com", "strongpass", "usd")
+	require.NoError(t, err)
+
+	svc := NewRefreshTokenService(db, time.Hour)
+
+	first, issued, err := svc.Issue(ctx, user.ID, "phone")
+	require.NoError(t, err)
+	require.NotEmpty(t, first)
+	require.NotEqual(t, first, issued.TokenHash, "raw token must not be persisted")
+
+	second, rotated, err := svc.Rotate(ctx, first)
+	require.NoError(t, err)
+	require.NotEqual(t, first, second)
+	require.Equal(t, user.ID, rotated.UserID)
+	require.Equal(t, issued.FamilyID, rotated.FamilyID)
+	require.Equal(t, "phone", rotated.DeviceName)
+
+	third, _, err := svc.Rotate(ctx, second)
+	require.NoError(t, err)
+	require.NotEmpty(t, third)
+
+	// Long device names are cut without splitting a multi-byte character.
+	_, named, err := svc.Issue(ctx, user.ID, "my phone: "+strings.Repeat("телефон", 40))
+	require.NoError(t, err)
+	require.Len(t, named.DeviceName, 254)
+	require.True(t, utf8.ValidString(named.DeviceName))
+}
+
+func TestRefreshTokenServiceReuseRevokesFamily(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "reuse@example.com", "strongpass", "usd")
+	require.NoError(t, err)
+
+	svc := NewRefreshTokenService(db, time.Hour)
+
+	first, _, err := svc.Issue(ctx, user.ID, "laptop")
+	require.NoError(t, err)
+	otherDevice, _, err := svc.Issue(ctx, user.ID, "tablet")
+	require.NoError(t, err)
+
+	second, _, err := svc.Rotate(ctx, first)
+	require.NoError(t, err)
+
+	// Replaying the consumed token revokes the whole family, including the successor.
+	_, _, err = svc.Rotate(ctx, first)
+	require.ErrorIs(t, err, ErrUnauthorized)
+
+	_, _, err = svc.Rotate(ctx, second)
+	require.ErrorIs(t, err, ErrUnauthorized)
+
+	// Families on other devices are unaffected.
+	_, _, err = svc.Rotate(ctx, otherDevice)
+	require.NoError(t, err)
+}
+
+func TestRefreshTokenServiceRejectsExpiredAndUnknownTokens(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "expired-refresh@example.com", "strongpass", "usd")
+	require.NoError(t, err)
+
+	svc := NewRefreshTokenService(db, time.Hour)
+	issuedAt := time.Now().UTC()
+	svc.now = func() time.Time { return issuedAt }
+
+	raw, _, err := svc.Issue(ctx, user.ID, "")
+	require.NoError(t, err)
+
+	svc.now = func() time.Time { return issuedAt.Add(2 * time.Hour) }
+	_, _, err = svc.Rotate(ctx, raw)
+	require.ErrorIs(t, err, ErrUnauthorized)
+
+	_, _, err = svc.Rotate(ctx, "not-a-token")
+	require.ErrorIs(t, err, ErrUnauthorized)
+}
@@ -0,0 +1,325 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"bckndlab3/src/internal/models"
+)
+
+// maxReportMonths caps the number of months a single summary report may cover.
+const maxReportMonths = 24
+
+// SummaryReport aggregates a user's incomes and expenses over the months from From up to
+// but excluding To, with one summary per currency the transactions were booked in. Amounts
+// in different currencies are never added together, even within an account that was
+// converted to another currency.
+type SummaryReport struct {
+	From       time.Time
+	To         time.Time
+	Months     int
+	Currencies []CurrencySummary
+}
+
+// CurrencySummary totals the incomes and expenses booked in one currency.
+// Averages are per month of the report, rounded toward zero. Categories expand split
+// transactions into their lines, so a split receipt counts towards each of its categories.
+type CurrencySummary struct {
+	CurrencyISOCode     string
+	IncomeCents         int64
+	IncomeCount         int64
+	ExpenseCents        int64
+	ExpenseCount        int64
+	NetCents            int64
+	AverageIncomeCents  int64
+	AverageExpenseCents int64
+	AverageNetCents     int64
+	Months              []MonthSummary
+	Categories          []CategoryTotal
+	Sources             []SourceTotal
+}
+
+// MonthSummary totals one month of a report. The change fields compare it with the month
+// before, which for the first month lies outside the report.
+type MonthSummary struct {
+	Start              time.Time
+	IncomeCents        int64
+	ExpenseCents       int64
+	NetCents           int64
+	IncomeChangeCents  int64
+	ExpenseChangeCents int64
+	NetChangeCents     int64
+}
+
+// CategoryTotal is the amount filed under one income or expense category. CategoryID is
+// nil for transactions outside the catalogue, which are grouped by their free-text name.
+// Count is the number of transactions with a part in the category.
+type CategoryTotal struct {
+	Type        string
+	CategoryID  *uint
+	ParentID    *uint
+	Name        string
+	Count       int64
+	AmountCents int64
+}
+
+// SourceTotal is the income received from one payer. SourceID is nil for incomes
+// recorded without a managed income source, which are grouped by their source name.
+type SourceTotal struct {
+	SourceID    *uint
+	Name        string
+	Count       int64
+	AmountCents int64
+}
+
+// ReportService aggregates a user's incomes and expenses into summary reports.
+type ReportService struct {
+	db *gorm.DB
+}
+
+func NewReportService(db *gorm.DB) *ReportService {
+	return &ReportService{db: db}
+}
+
+// monthlyTotal is the amount of one table's transactions in one currency and report month.
+// Month 0 is the month before the report.
+type monthlyTotal struct {
+	CurrencyISOCode string
+	MonthIndex      int
+	Count           int64
+	AmountCents     int64
+}
+
+// categoryRow is a category or source total of one currency.
+type categoryRow struct {
+	CurrencyISOCode string
+	ID              *uint
+	ParentID        *uint
+	Name            string
+	Count           int64
+	AmountCents     int64
+}
+
+// Summary builds the report of the user's incomes and expenses over whole months from
+// from (inclusive) to to (exclusive), both of which must be the first day of a month.
+func (s *ReportService) Summary(ctx context.Context, userID uint, from, to time.Time) (*SummaryReport, error) {
+	from, to = from.UTC(), to.UTC()
+	if !truncateToInterval(from, IntervalMonth).Equal(from) || !truncateToInterval(to, IntervalMonth).Equal(to) {
+		return nil, fmt.Errorf("%w: a report covers whole months", ErrPreconditionFailed)
+	}
+	if !to.After(from) {
+		return nil, fmt.Errorf("%w: the report period ends before it starts", ErrPreconditionFailed)
+	}
+
+	// months holds the start of every report month, preceded by the month before it.
+	months := []time.Time{from.AddDate(0, -1, 0)}
+	for start := from; start.Before(to); start = start.AddDate(0, 1, 0) {
+		if len(months) > maxReportMonths {
+			return nil, fmt.Errorf("%w: a report covers at most %d months", ErrPreconditionFailed, maxReportMonths)
+		}
+		months = append(months, start)
+	}
+
+	report := &SummaryReport{From: from, To: to, Months: len(months) - 1}
+	summaries := map[string]*CurrencySummary{}
+	summary := func(code string) *CurrencySummary {
+		if current, ok := summaries[code]; ok {
+			return current
+		}
+		current := &CurrencySummary{CurrencyISOCode: code, Months: make([]MonthSummary, report.Months)}
+		for i := range current.Months {
+			current.Months[i].Start = months[i+1]
+		}
+		summaries[code] = current
+		return current
+	}
+
+	incomes, err := s.monthlyTotals(ctx, incomeTable, userID, months)
+	if err != nil {
+		return nil, err
+	}
+	expenses, err := s.monthlyTotals(ctx, expenseTable, userID, months)
+	if err != nil {
+		return nil, err
+	}
+	previous := map[string]*MonthSummary{}
+	for _, total := range incomes {
+		current := summary(total.CurrencyISOCode)
+		if total.MonthIndex == 0 {
+			previousMonth(previous, total.CurrencyISOCode).IncomeCents += total.AmountCents
+			continue
+		}
+		current.Months[total.MonthIndex-1].IncomeCents += total.AmountCents
+		current.IncomeCents += total.AmountCents
+		current.IncomeCount += total.Count
+	}
+	for _, total := range expenses {
+		current := summary(total.CurrencyISOCode)
+		if total.MonthIndex == 0 {
+			previousMonth(previous, total.CurrencyISOCode).ExpenseCents += total.AmountCents
+			continue
+		}
+		current.Months[total.MonthIndex-1].ExpenseCents += total.AmountCents
+		current.ExpenseCents += total.AmountCents
+		current.ExpenseCount += total.Count
+	}
+
+	incomeCategories, err := s.categoryTotals(ctx, incomeTable, "''", userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	expenseCategories, err := s.categoryTotals(ctx, expenseTable, "COALESCE(splits.category, expenses.category)", userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	sources, err := s.sourceTotals(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	for kind, rows := range map[string][]categoryRow{models.CategoryTypeIncome: incomeCategories, models.CategoryTypeExpense: expenseCategories} {
+		for _, row := range rows {
+			current := summary(row.CurrencyISOCode)
+			current.Categories = append(current.Categories, CategoryTotal{
+				Type:        kind,
+				CategoryID:  row.ID,
+				ParentID:    row.ParentID,
+				Name:        row.Name,
+				Count:       row.Count,
+				AmountCents: row.AmountCents,
+			})
+		}
+	}
+	for _, row := range sources {
+		current := summary(row.CurrencyISOCode)
+		current.Sources = append(current.Sources, SourceTotal{SourceID: row.ID, Name: row.Name, Count: row.Count, AmountCents: row.AmountCents})
+	}
+
+	for code, current := range summaries {
+		last := MonthSummary{}
+		if before, ok := previous[code]; ok {
+			last = *before
+			last.NetCents = last.IncomeCents - last.ExpenseCents
+		}
+		for i := range current.Months {
+			month := &current.Months[i]
+			month.NetCents = month.IncomeCents - month.ExpenseCents
+			month.IncomeChangeCents = month.IncomeCents - last.IncomeCents
+			month.ExpenseChangeCents = month.ExpenseCents - last.ExpenseCents
+			month.NetChangeCents = month.NetCents - last.NetCents
+			last = *month
+		}
+		current.NetCents = current.IncomeCents - current.ExpenseCents
+		current.AverageIncomeCents = current.IncomeCents / int64(report.Months)
+		current.AverageExpenseCents = current.ExpenseCents / int64(report.Months)
+		current.AverageNetCents = current.NetCents / int64(report.Months)
+		sortCategoryTotals(current.Categories)
+		sortSourceTotals(current.Sources)
+		report.Currencies = append(report.Currencies, *current)
+	}
+	sort.Slice(report.Currencies, func(i, j int) bool {
+		return report.Currencies[i].CurrencyISOCode < report.Currencies[j].CurrencyISOCode
+	})
+	return report, nil
+}
+
+func previousMonth(previous map[string]*MonthSummary, code string) *MonthSummary {
+	if _, ok := previous[code]; !ok {
+		previous[code] = &MonthSummary{}
+	}
+	return previous[code]
+}
+
+// monthlyTotals sums the user's transactions of table per booked currency and month,
+// where months are consecutive month starts. The month index is computed with a CASE
+// expression rather than date functions, so the query runs unchanged on every database.
+func (s *ReportService) monthlyTotals(ctx context.Context, table transactionTable, userID uint, months []time.Time) ([]monthlyTotal, error) {
+	date := table.column(table.dateColumn)
+	var index strings.Builder
+	args := make([]any, 0, len(months))
+	index.WriteString("CASE")
+	for i, start := range months[1:] {
+		fmt.Fprintf(&index, " WHEN %s < ? THEN %d", date, i)
+		args = append(args, start)
+	}
+	fmt.Fprintf(&index, " ELSE %d END", len(months)-1)
+
+	var totals []monthlyTotal
+	err := s.db.WithContext(ctx).
+		Table(table.name).
+		Select(fmt.Sprintf("%s AS currency_iso_code, %s AS month_index, COUNT(*) AS count, COALESCE(SUM(%s), 0) AS amount_cents", table.column("currency_iso_code"), index.String(), table.column("amount_cents")), args...).
+		Where(fmt.Sprintf("%s = ? AND %s >= ? AND %s < ?", table.column("user_id"), date, date), userID, months[0], months[len(months)-1].AddDate(0, 1, 0)).
+		Group(table.column("currency_iso_code") + ", month_index").
+		Scan(&totals).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return totals, nil
+}
+
+// categoryTotals sums the user's transactions of table dated in [from, to) per booked
+// currency and category, taking split transactions line by line. fallbackName names the
+// category of transactions that are not in the catalogue.
+func (s *ReportService) categoryTotals(ctx context.Context, table transactionTable, fallbackName string, userID uint, from, to time.Time) ([]categoryRow, error) {
+	date := table.column(table.dateColumn)
+	name := fmt.Sprintf("COALESCE(categories.name, %s)", fallbackName)
+	var rows []categoryRow
+	err := s.db.WithContext(ctx).
+		Table(table.name).
+		Select(fmt.Sprintf(
+			"%s AS currency_iso_code, categories.id AS id, categories.parent_id AS parent_id, %s AS name, COUNT(DISTINCT %s) AS count, COALESCE(SUM(COALESCE(splits.amount_cents, %s)), 0) AS amount_cents",
+			table.column("currency_iso_code"), name, table.column("id"), table.column("amount_cents"),
+		)).
+		Joins(fmt.Sprintf("LEFT JOIN %s splits ON splits.%s = %s", table.splitTable, table.ownerColumn, table.column("id"))).
+		Joins(fmt.Sprintf("LEFT JOIN categories ON categories.id = COALESCE(splits.category_id, %s)", table.column("category_id"))).
+		Where(fmt.Sprintf("%s = ? AND %s >= ? AND %s < ?", table.column("user_id"), date, date), userID, from, to).
+		Group(table.column("currency_iso_code") + ", categories.id, categories.parent_id, " + name).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return rows, nil
+}
+
+// sourceTotals sums the user's incomes dated in [from, to) per booked currency and payer.
+func (s *ReportService) sourceTotals(ctx context.Context, userID uint, from, to time.Time) ([]categoryRow, error) {
+	var rows []categoryRow
+	err := s.db.WithContext(ctx).
+		Table("incomes").
+		Select("incomes.currency_iso_code AS currency_iso_code, incomes.source_id AS id, COALESCE(income_sources.name, incomes.source) AS name, COUNT(*) AS count, COALESCE(SUM(incomes.amount_cents), 0) AS amount_cents").
+		Joins("LEFT JOIN income_sources ON income_sources.id = incomes.source_id").
+		Where("incomes.user_id = ? AND incomes.received_at >= ? AND incomes.received_at < ?", userID, from, to).
+		Group("incomes.currency_iso_code, incomes.source_id, COALESCE(income_sources.name, incomes.source)").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return rows, nil
+}
+
+// sortCategoryTotals orders categories by type, incomes first, then by amount, largest first.
+func sortCategoryTotals(totals []CategoryTotal) {
+	sort.SliceStable(totals, func(i, j int) bool {
+		if totals[i].Type != totals[j].Type {
+			return totals[i].Type == models.CategoryTypeIncome
+		}
+		if totals[i].AmountCents != totals[j].AmountCents {
+			return totals[i].AmountCents > totals[j].AmountCents
+		}
+		return totals[i].Name < totals[j].Name
+	})
+}
+
+// sortSourceTotals orders sources by amount, largest first.
+func sortSourceTotals(totals []SourceTotal) {
+	sort.SliceStable(totals, func(i, j int) bool {
+		if totals[i].AmountCents != totals[j].AmountCents {
+			return totals[i].AmountCents > totals[j].AmountCents
+		}
+		return totals[i].Name < totals[j].Name
+	})
+}
@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"bckndlab3/src/internal/models"
+)
+
+func TestReportServiceSummary(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "reports@example.com", "strongpass", "usd")
+	require.NoError(t, err)
+	catalogue, err := NewCategoryService(db).ListCategories(ctx, user.ID, "")
+	require.NoError(t, err)
+	food := categoryByName(t, catalogue, models.CategoryTypeExpense, "Food")
+	groceries := categoryByName(t, catalogue, models.CategoryTypeExpense, "Groceries")
+
+	svc := NewAccountService(db, true)
+	accountID := defaultAccountID(t, svc, user.ID)
+	euros, err := svc.CreateAccount(ctx, user.ID, &models.Account{Name: "Travel", Type: models.AccountTypeCash, CurrencyISOCode: "EUR"})
+	require.NoError(t, err)
+
+	day := func(month time.Month, d int) time.Time { return time.Date(2025, month, d, 10, 0, 0, 0, time.UTC) }
+	monthStart := func(year int, month time.Month) time.Time { return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC) }
+	for _, income := range []models.Income{
+		{AmountCents: 50000, Source: "Acme", ReceivedAt: day(time.September, 30)},
+		{AmountCents: 100000, Source: "Acme", ReceivedAt: day(time.October, 10)},
+		{AmountCents: 120000, Source: "Acme", ReceivedAt: day(time.November, 3)},
+		{AmountCents: 30000, Source: "Client", ReceivedAt: day(time.November, 12)},
+	} {
+		_, _, err := svc.CreditIncome(ctx, user.ID, accountID, &income)
+		require.NoError(t, err)
+	}
+	for _, expense := range []models.Expense{
+		{AmountCents: 20000, CategoryID: &groceries.ID, IncurredAt: day(time.October, 11)},
+		{AmountCents: 10000, IncurredAt: day(time.November, 4), Splits: []models.ExpenseSplit{
+			{CategoryID: &groceries.ID, AmountCents: 6000},
+			{Category: "Household", AmountCents: 4000},
+		}},
+		{AmountCents: 5000, Category: "Taxi", IncurredAt: day(time.November, 5)},
+		{AmountCents: 999, Category: "Taxi", IncurredAt: day(time.December, 1)},
+	} {
+		_, _, err := svc.DebitExpense(ctx, user.ID, accountID, &expense)
+		require.NoError(t, err)
+	}
+	_, _, err = svc.DebitExpense(ctx, user.ID, euros.ID, &models.Expense{AmountCents: 700, Category: "Coffee", IncurredAt: day(time.November, 6)})
+	require.NoError(t, err)
+
+	reports := NewReportService(db)
+	report, err := reports.Summary(ctx, user.ID, monthStart(2025, time.November), monthStart(2025, time.December))
+	require.NoError(t, err)
+	require.Equal(t, 1, report.Months)
+	require.Len(t, report.Currencies, 2)
+
+	eur := report.Currencies[0]
+	require.Equal(t, "EUR", eur.CurrencyISOCode)
+	require.Equal(t, int64(-700), eur.NetCents)
+	require.Equal(t, int64(-700), eur.Months[0].NetChangeCents)
+	require.Empty(t, eur.Sources)
+
+	usd := report.Currencies[1]
+	require.Equal(t, "USD", usd.CurrencyISOCode)
+	require.Equal(t, int64(150000), usd.IncomeCents)
+	require.Equal(t, int64(2), usd.IncomeCount)
+	require.Equal(t, int64(15000), usd.ExpenseCents)
+	require.Equal(t, int64(2), usd.ExpenseCount)
+	require.Equal(t, int64(135000), usd.NetCents)
+	require.Equal(t, MonthSummary{
+		Start:              monthStart(2025, time.November),
+		IncomeCents:        150000,
+		ExpenseCents:       15000,
+		NetCents:           135000,
+		IncomeChangeCents:  50000,
+		ExpenseChangeCents: -5000,
+		NetChangeCents:     55000,
+	}, usd.Months[0])
+
+	require.Len(t, usd.Categories, 4)
+	require.Equal(t, CategoryTotal{Type: models.CategoryTypeIncome, Count: 2, AmountCents: 150000}, usd.Categories[0])
+	require.Equal(t, CategoryTotal{Type: models.CategoryTypeExpense, CategoryID: &groceries.ID, ParentID: &food.ID, Name: "Groceries", Count: 1, AmountCents: 6000}, usd.Categories[1])
+	require.Equal(t, "Taxi", usd.Categories[2].Name)
+	require.Equal(t, int64(5000), usd.Categories[2].AmountCents)
+	require.Equal(t, "Household", usd.Categories[3].Name)
+	require.Equal(t, int64(4000), usd.Categories[3].AmountCents)
+
+	require.Len(t, usd.Sources, 2)
+	require.Equal(t, "Acme", usd.Sources[0].Name)
+	require.NotNil(t, usd.Sources[0].SourceID)
+	require.Equal(t, int64(120000), usd.Sources[0].AmountCents)
+	require.Equal(t, SourceTotal{SourceID: usd.Sources[1].SourceID, Name: "Client", Count: 1, AmountCents: 30000}, usd.Sources[1])
+
+	// Over two months the deltas chain from month to month and the averages halve the totals.
+	report, err = reports.Summary(ctx, user.ID, monthStart(2025, time.October), monthStart(2025, time.December))
+	require.NoError(t, err)
+	usd = report.Currencies[1]
+	require.Len(t, usd.Months, 2)
+	require.Equal(t, int64(50000), usd.Months[0].IncomeChangeCents)
+	require.Equal(t, int64(20000), usd.Months[0].ExpenseChangeCents)
+	require.Equal(t, int64(55000), usd.Months[1].NetChangeCents)
+	require.Equal(t, int64(125000), usd.AverageIncomeCents)
+	require.Equal(t, int64(17500), usd.AverageExpenseCents)
+	require.Equal(t, int64(107500), usd.AverageNetCents)
+	require.Equal(t, int64(26000), usd.Categories[1].AmountCents, "groceries add up across plain and split expenses")
+	require.Equal(t, int64(2), usd.Categories[1].Count)
+
+	// An empty period still reports nothing rather than failing.
+	report, err = reports.Summary(ctx, user.ID, monthStart(2024, time.January), monthStart(2024, time.February))
+	require.NoError(t, err)
+	require.Empty(t, report.Currencies)
+
+	_, err = reports.Summary(ctx, user.ID, day(time.November, 1), monthStart(2025, time.December))
+	require.ErrorIs(t, err, ErrPreconditionFailed, "reports cover whole months")
+	_, err = reports.Summary(ctx, user.ID, monthStart(2025, time.December), monthStart(2025, time.November))
+	require.ErrorIs(t, err, ErrPreconditionFailed)
+	_, err = reports.Summary(ctx, user.ID, monthStart(2023, time.January), monthStart(2025, time.February))
+	require.ErrorIs(t, err, ErrPreconditionFailed, "reports cover at most two years")
+}
+
+func TestReportServiceSummaryKeepsCurrenciesApartAcrossConversion(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "reports-converted@example.com", "strongpass", "uah")
+	require.NoError(t, err)
+
+	svc := NewAccountService(db, false)
+	accountID := defaultAccountID(t, svc, user.ID)
+	day := time.Date(2025, time.November, 3, 10, 0, 0, 0, time.UTC)
+	_, _, err = svc.CreditIncome(ctx, user.ID, accountID, &models.Income{AmountCents: 100000, Source: "Acme", ReceivedAt: day})
+	require.NoError(t, err)
+	_, _, err = svc.ChangeDefaultCurrency(ctx, user.ID, CurrencyChange{Currency: "usd", Convert: true, Rate: "0.025"})
+	require.NoError(t, err)
+	_, _, err = svc.CreditIncome(ctx, user.ID, accountID, &models.Income{AmountCents: 500, Source: "Acme", ReceivedAt: day.AddDate(0, 0, 1)})
+	require.NoError(t, err)
+	_, _, err = svc.DebitExpense(ctx, user.ID, accountID, &models.Expense{AmountCents: 300, Category: "Taxi", IncurredAt: day.AddDate(0, 0, 2)})
+	require.NoError(t, err)
+
+	report, err := NewReportService(db).Summary(ctx, user.ID, time.Date(2025, time.November, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, time.December, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Len(t, report.Currencies, 2)
+
+	uah := report.Currencies[0]
+	require.Equal(t, "UAH", uah.CurrencyISOCode)
+	require.Equal(t, int64(100000), uah.IncomeCents)
+	require.Zero(t, uah.ExpenseCents)
+	require.Len(t, uah.Sources, 1)
+	require.Equal(t, int64(100000), uah.Sources[0].AmountCents)
+
+	usd := report.Currencies[1]
+	require.Equal(t, "USD", usd.CurrencyISOCode)
+	require.Equal(t, int64(500), usd.IncomeCents)
+	require.Equal(t, int64(300), usd.ExpenseCents)
+	require.Equal(t, int64(200), usd.Months[0].NetCents)
+	require.Len(t, usd.Sources, 1)
+	require.Equal(t, int64(500), usd.Sources[0].AmountCents)
+}
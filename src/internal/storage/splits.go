@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"gorm.io/gorm"
+
+	"bckndlab3/src/internal/models"
+)
+
+// maxSplits caps the number of lines a single income or expense may be split into.
+const maxSplits = 50
+
+// checkSplits validates the line amounts of a split transaction, given in the currency it
+// was entered in, against its entered amount. No lines means the transaction is not split.
+func checkSplits(kind string, amounts []int64, total int64) error {
+	if len(amounts) == 0 {
+		return nil
+	}
+	if len(amounts) < 2 {
+		return fmt.Errorf("%w: a split %s needs at least two lines", ErrPreconditionFailed, kind)
+	}
+	if len(amounts) > maxSplits {
+		return fmt.Errorf("%w: a %s can be split into at most %d lines", ErrPreconditionFailed, kind, maxSplits)
+	}
+
+	var sum int64
+	for _, amount := range amounts {
+		if amount <= 0 {
+			return fmt.Errorf("%w: %s split amounts must be positive", ErrPreconditionFailed, kind)
+		}
+		sum += amount
+	}
+	if sum != total {
+		return fmt.Errorf("%w: %s splits add up to %d instead of the %s amount %d", ErrPreconditionFailed, kind, sum, kind, total)
+	}
+	return nil
+}
+
+// allocateSplits divides amountCents, the account-currency amount of a transaction entered
+// as enteredTotal in another currency, across lines in proportion to their entered amounts.
+// Each share is rounded down and the last line takes the remainder, so the shares add up
+// to amountCents.
+func allocateSplits(amountCents, enteredTotal int64, entered []int64) []int64 {
+	shares := make([]int64, len(entered))
+	remaining := amountCents
+	for i, amount := range entered {
+		if i == len(entered)-1 {
+			shares[i] = remaining
+			break
+		}
+		share := new(big.Int).Mul(big.NewInt(amount), big.NewInt(amountCents))
+		share.Quo(share, big.NewInt(enteredTotal))
+		shares[i] = share.Int64()
+		remaining -= shares[i]
+	}
+	return shares
+}
+
+// expenseSplitAmounts returns the entered amounts of expense split lines.
+func expenseSplitAmounts(lines []models.ExpenseSplit) []int64 {
+	amounts := make([]int64, 0, len(lines))
+	for i := range lines {
+		amounts = append(amounts, lines[i].EnteredAmountCents())
+	}
+	return amounts
+}
+
+// incomeSplitAmounts returns the entered amounts of income split lines.
+func incomeSplitAmounts(lines []models.IncomeSplit) []int64 {
+	amounts := make([]int64, 0, len(lines))
+	for i := range lines {
+		amounts = append(amounts, lines[i].EnteredAmountCents())
+	}
+	return amounts
+}
+
+// enteredTotal returns the amount of a transaction in the currency it was entered in.
+func enteredTotal(amountCents, originalCents int64, originalCurrency string) int64 {
+	if originalCurrency != "" {
+		return originalCents
+	}
+	return amountCents
+}
+
+// splitShares returns the account-currency and entered amounts of split lines for a
+// transaction whose amounts have already been converted. For a transaction entered in the
+// account currency the entered amounts are zero, matching the transaction itself.
+func splitShares(amountCents, originalCents int64, originalCurrency string, entered []int64) (shares, originals []int64) {
+	if originalCurrency == "" {
+		return entered, make([]int64, len(entered))
+	}
+	return allocateSplits(amountCents, originalCents, entered), entered
+}
+
+// resolveExpenseSplits builds the split lines of an expense from the requested lines,
+// filing each under its expense category (given by CategoryID or by name, as for the
+// expense itself) and converting its amount like the expense's. It reads the expense's
+// converted amounts, so it runs after convertEnteredAmount.
+func (s *AccountService) resolveExpenseSplits(ctx context.Context, tx *gorm.DB, userID uint, expense *models.Expense, lines []models.ExpenseSplit) ([]models.ExpenseSplit, error) {
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	shares, originals := splitShares(expense.AmountCents, expense.OriginalAmountCents, expense.OriginalCurrency, expenseSplitAmounts(lines))
+
+	splits := make([]models.ExpenseSplit, 0, len(lines))
+	for i, line := range lines {
+		category, err := resolveCategory(ctx, tx, s.categories, userID, models.CategoryTypeExpense, derefID(line.CategoryID), line.Category)
+		if err != nil {
+			return nil, err
+		}
+		splits = append(splits, models.ExpenseSplit{
+			Position:            i,
+			CategoryID:          &category.ID,
+			Category:            category.Name,
+			AmountCents:         shares[i],
+			OriginalAmountCents: originals[i],
+			Note:                line.Note,
+		})
+	}
+	return splits, nil
+}
+
+// resolveIncomeSplits builds the split lines of an income from the requested lines,
+// validating their optional income categories and converting their amounts like the
+// income's. It reads the income's converted amounts, so it runs after convertEnteredAmount.
+func (s *AccountService) resolveIncomeSplits(ctx context.Context, tx *gorm.DB, userID uint, income *models.Income, lines []models.IncomeSplit) ([]models.IncomeSplit, error) {
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	shares, originals := splitShares(income.AmountCents, income.OriginalAmountCents, income.OriginalCurrency, incomeSplitAmounts(lines))
+
+	splits := make([]models.IncomeSplit, 0, len(lines))
+	for i, line := range lines {
+		if line.CategoryID != nil {
+			if _, err := resolveCategory(ctx, tx, s.categories, userID, models.CategoryTypeIncome, *line.CategoryID, ""); err != nil {
+				return nil, err
+			}
+		}
+		splits = append(splits, models.IncomeSplit{
+			Position:            i,
+			CategoryID:          line.CategoryID,
+			AmountCents:         shares[i],
+			OriginalAmountCents: originals[i],
+			Note:                line.Note,
+		})
+	}
+	return splits, nil
+}
+
+// largestSplit returns the index of the line with the largest amount, preferring the
+// first on ties. A split expense without a category of its own is filed under it.
+func largestSplit(splits []models.ExpenseSplit) int {
+	largest := 0
+	for i := range splits {
+		if splits[i].AmountCents > splits[largest].AmountCents {
+			largest = i
+		}
+	}
+	return largest
+}
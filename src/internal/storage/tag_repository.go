@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"bckndlab3/src/internal/models"
+)
+
+// maxTags caps the number of tags on a single income or expense.
+const maxTags = 20
+
+// TagRepository handles persistence for tags and their links to incomes and expenses.
+type TagRepository struct {
+	db *gorm.DB
+}
+
+func NewTagRepository(db *gorm.DB) *TagRepository {
+	return &TagRepository{db: db}
+}
+
+// FindOrCreate returns the user's tags with the given normalized names ordered by name,
+// creating the ones that do not exist yet. Concurrent creations of the same name resolve
+// to a single row.
+func (r *TagRepository) FindOrCreate(ctx context.Context, tx *gorm.DB, userID uint, names []string) ([]models.Tag, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	rows := make([]models.Tag, 0, len(names))
+	for _, name := range names {
+		rows = append(rows, models.Tag{UserID: userID, Name: name})
+	}
+	err := tx.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&rows).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	var tags []models.Tag
+	err = tx.WithContext(ctx).
+		Where("user_id = ? AND name IN ?", userID, names).
+		Order("name ASC").
+		Find(&tags).Error
+	if err != nil {
+		return nil, translateError(err)
+	}
+	return tags, nil
+}
+
+// SetIncomeTags replaces the tags linked to an income.
+func (r *TagRepository) SetIncomeTags(ctx context.Context, tx *gorm.DB, incomeID uint, tags []models.Tag) error {
+	return replaceTagLinks(ctx, tx, "income_tags", "income_id", incomeID, tags)
+}
+
+// SetExpenseTags replaces the tags linked to an expense.
+func (r *TagRepository) SetExpenseTags(ctx context.Context, tx *gorm.DB, expenseID uint, tags []models.Tag) error {
+	return replaceTagLinks(ctx, tx, "expense_tags", "expense_id", expenseID, tags)
+}
+
+func replaceTagLinks(ctx context.Context, tx *gorm.DB, table, column string, ownerID uint, tags []models.Tag) error {
+	err := tx.WithContext(ctx).
+		Exec(fmt.Sprintf("DELETE FROM %s WHERE %s = ?", table, column), ownerID).Error
+	if err != nil {
+		return translateError(err)
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+
+	links := make([]map[string]any, 0, len(tags))
+	for _, tag := range tags {
+		links = append(links, map[string]any{column: ownerID, "tag_id": tag.ID})
+	}
+	if err := tx.WithContext(ctx).Table(table).Create(links).Error; err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+// preloadTags loads the tags of listed incomes or expenses ordered by name.
+func preloadTags(db *gorm.DB) *gorm.DB {
+	return db.Order("tags.name ASC")
+}
+
+// taggedIDs builds a subquery selecting the incomes or expenses that carry any of the given
+// normalized tag names or, when all is set, every one of them. table and column name the
+// join table and its reference to the tagged rows.
+func taggedIDs(db *gorm.DB, table, column string, userID uint, names []string, all bool) *gorm.DB {
+	linked := db.Session(&gorm.Session{NewDB: true}).
+		Table(table).
+		Select(table+"."+column).
+		Joins(fmt.Sprintf("JOIN tags ON tags.id = %s.tag_id", table)).
+		Where("tags.user_id = ? AND tags.name IN ?", userID, names)
+	if all {
+		linked = linked.Group(table+"."+column).Having("COUNT(DISTINCT tags.id) = ?", len(names))
+	}
+	return linked
+}
+
+// tagNames normalizes and deduplicates tag names, keeping their first-seen order.
+func tagNames(names []string) ([]string, error) {
+	if len(names) > maxTags {
+		return nil, fmt.Errorf("%w: at most %d tags are allowed", ErrPreconditionFailed, maxTags)
+	}
+	seen := make(map[string]struct{}, len(names))
+	normalized := make([]string, 0, len(names))
+	for _, name := range names {
+		name = models.NormalizeTagName(name)
+		if name == "" {
+			return nil, fmt.Errorf("%w: tag names must not be empty", ErrPreconditionFailed)
+		}
+		if len([]rune(name)) > 64 {
+			return nil, fmt.Errorf("%w: tag names must be at most 64 characters", ErrPreconditionFailed)
+		}
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		normalized = append(normalized, name)
+	}
+	return normalized, nil
+}
@@ -0,0 +1,230 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"bckndlab3/src/internal/models"
+)
+
+// TokenRevocationStore decides whether otherwise valid access tokens have been revoked.
+//
+// Revocations are persisted so they survive restarts and are shared between instances.
+// Lookups are cached in memory: revocations made through this store take effect
+// immediately, while revocations made by other instances become visible once the
+// cached entry is older than the configured TTL.
+type TokenRevocationStore struct {
+	db       *gorm.DB
+	cacheTTL time.Duration
+	now      func() time.Time
+
+	mu        sync.Mutex
+	tokens    map[string]tokenCacheEntry
+	users     map[uint]userTokenState
+	lastSweep time.Time
+}
+
+type tokenCacheEntry struct {
+	revoked   bool
+	expiresAt time.Time
+	fetchedAt time.Time
+}
+
+type userTokenState struct {
+	exists        bool
+	revokedBefore *time.Time
+	fetchedAt     time.Time
+}
+
+func NewTokenRevocationStore(db *gorm.DB, cacheTTL time.Duration) *TokenRevocationStore {
+	return &TokenRevocationStore{
+		db:       db,
+		cacheTTL: cacheTTL,
+		now:      func() time.Time { return time.Now().UTC() },
+		tokens:   make(map[string]tokenCacheEntry),
+		users:    make(map[uint]userTokenState),
+	}
+}
+
+// Check returns ErrUnauthorized when the token was revoked individually, was issued
+// before a logout-all of its user, or belongs to a user that no longer exists.
+func (s *TokenRevocationStore) Check(ctx context.Context, claims *Claims) error {
+	state, err := s.userState(ctx, claims.UserID)
+	if err != nil {
+		return err
+	}
+	if !state.exists {
+		return fmt.Errorf("%w: user no longer exists", ErrUnauthorized)
+	}
+	if state.revokedBefore != nil && issuedNotAfter(claims, *state.revokedBefore) {
+		return fmt.Errorf("%w: token revoked", ErrUnauthorized)
+	}
+
+	if claims.ID == "" {
+		return nil
+	}
+	revoked, err := s.isTokenRevoked(ctx, claims.ID)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return fmt.Errorf("%w: token revoked", ErrUnauthorized)
+	}
+	return nil
+}
+
+// Revoke invalidates a single access token until its natural expiry.
+func (s *TokenRevocationStore) Revoke(ctx context.Context, jti string, userID uint, expiresAt time.Time) error {
+	if jti == "" {
+		return fmt.Errorf("%w: token has no identifier", ErrPreconditionFailed)
+	}
+
+	now := s.now()
+	record := &models.RevokedToken{JTI: jti, UserID: userID, ExpiresAt: expiresAt}
+	err := s.db.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "jti"}}, DoNothing: true}).
+		Create(record).Error
+	if err != nil {
+		return translateError(err)
+	}
+
+	// Rows past their expiry can never match a still-valid token again.
+	if err := s.db.WithContext(ctx).Where("expires_at < ?", now).Delete(&models.RevokedToken{}).Error; err != nil {
+		return translateError(err)
+	}
+
+	s.mu.Lock()
+	s.tokens[jti] = tokenCacheEntry{revoked: true, expiresAt: expiresAt, fetchedAt: now}
+	s.mu.Unlock()
+	return nil
+}
+
+// RevokeAllForUser invalidates every access token issued to the user so far.
+func (s *TokenRevocationStore) RevokeAllForUser(ctx context.Context, userID uint) error {
+	now := s.now()
+	// The database keeps microseconds; truncating keeps the cached and stored cutoffs equal.
+	cutoff := now.Truncate(time.Microsecond)
+
+	result := s.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ?", userID).
+		Update("tokens_revoked_at", cutoff)
+	if err := result.Error; err != nil {
+		return translateError(err)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	s.mu.Lock()
+	s.users[userID] = userTokenState{exists: true, revokedBefore: &cutoff, fetchedAt: now}
+	s.mu.Unlock()
+	return nil
+}
+
+// issuedNotAfter reports whether the token was issued at or before cutoff. Tokens without
+// the microsecond issue time only carry whole seconds, so one issued in the same second
+// as the cutoff is treated as issued before it.
+func issuedNotAfter(claims *Claims, cutoff time.Time) bool {
+	if claims.IssuedAtMicros != 0 {
+		return !time.UnixMicro(claims.IssuedAtMicros).After(cutoff)
+	}
+	if claims.IssuedAt == nil {
+		return false
+	}
+	return !claims.IssuedAt.After(cutoff.Truncate(time.Second))
+}
+
+// ForgetUser drops cached state for a user, e.g. after the user has been deleted.
+func (s *TokenRevocationStore) ForgetUser(userID uint) {
+	s.mu.Lock()
+	delete(s.users, userID)
+	s.mu.Unlock()
+}
+
+func (s *TokenRevocationStore) userState(ctx context.Context, userID uint) (userTokenState, error) {
+	now := s.now()
+
+	s.mu.Lock()
+	cached, ok := s.users[userID]
+	s.mu.Unlock()
+	if ok && now.Sub(cached.fetchedAt) < s.cacheTTL {
+		return cached, nil
+	}
+
+	var user models.User
+	err := s.db.WithContext(ctx).Select("id", "tokens_revoked_at").First(&user, userID).Error
+	state := userTokenState{fetchedAt: now}
+	switch {
+	case err == nil:
+		state.exists = true
+		state.revokedBefore = user.TokensRevokedAt
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		state.exists = false
+	default:
+		return userTokenState{}, translateError(err)
+	}
+
+	s.mu.Lock()
+	s.users[userID] = state
+	s.mu.Unlock()
+	return state, nil
+}
+
+func (s *TokenRevocationStore) isTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	now := s.now()
+
+	s.mu.Lock()
+	s.sweepLocked(now)
+	cached, ok := s.tokens[jti]
+	s.mu.Unlock()
+	if ok && (cached.revoked || now.Sub(cached.fetchedAt) < s.cacheTTL) {
+		return cached.revoked, nil
+	}
+
+	var record models.RevokedToken
+	err := s.db.WithContext(ctx).Where("jti = ?", jti).First(&record).Error
+	entry := tokenCacheEntry{fetchedAt: now}
+	switch {
+	case err == nil:
+		entry.revoked = true
+		entry.expiresAt = record.ExpiresAt
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		entry.expiresAt = now.Add(s.cacheTTL)
+	default:
+		return false, translateError(err)
+	}
+
+	s.mu.Lock()
+	s.tokens[jti] = entry
+	s.mu.Unlock()
+	return entry.revoked, nil
+}
+
+// sweepLocked evicts cache entries that can no longer influence a decision.
+func (s *TokenRevocationStore) sweepLocked(now time.Time) {
+	interval := s.cacheTTL
+	if interval < time.Minute {
+		interval = time.Minute
+	}
+	if now.Sub(s.lastSweep) < interval {
+		return
+	}
+	s.lastSweep = now
+
+	for jti, entry := range s.tokens {
+		if now.After(entry.expiresAt) && now.Sub(entry.fetchedAt) >= s.cacheTTL {
+			delete(s.tokens, jti)
+		}
+	}
+	for userID, state := range s.users {
+		if now.Sub(state.fetchedAt) >= s.cacheTTL {
+			delete(s.users, userID)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenRevocationStoreRevokesSingleToken(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "revoke@example.com", "strongpass", "usd")
+	require.NoError(t, err)
+
+	jwtService := NewJWTService("secret", time.Hour)
+	first, err := jwtService.GenerateToken(user.ID, user.Email)
+	require.NoError(t, err)
+	second, err := jwtService.GenerateToken(user.ID, user.Email)
+	require.NoError(t, err)
+
+	firstClaims, err := jwtService.ValidateToken(first)
+	require.NoError(t, err)
+	secondClaims, err := jwtService.ValidateToken(second)
+	require.NoError(t, err)
+	require.NotEmpty(t, firstClaims.ID)
+	require.NotEqual(t, firstClaims.ID, secondClaims.ID)
+
+	store := NewTokenRevocationStore(db, time.Minute)
+	require.NoError(t, store.Check(ctx, firstClaims))
+
+	require.NoError(t, store.Revoke(ctx, firstClaims.ID, user.ID, firstClaims.ExpiresAt.Time))
+	require.ErrorIs(t, store.Check(ctx, firstClaims), ErrUnauthorized)
+	require.NoError(t, store.Check(ctx, secondClaims))
+
+	// A fresh store (e.g. another instance) sees the persisted revocation.
+	other := NewTokenRevocationStore(db, time.Minute)
+	require.ErrorIs(t, other.Check(ctx, firstClaims), ErrUnauthorized)
+}
+
+func TestTokenRevocationStoreRevokeAllAndDeletedUser(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "revoke-all@example.com", "strongpass", "usd")
+	require.NoError(t, err)
+
+	jwtService := NewJWTService("secret", time.Hour)
+	token, err := jwtService.GenerateToken(user.ID, user.Email)
+	require.NoError(t, err)
+	claims, err := jwtService.ValidateToken(token)
+	require.NoError(t, err)
+
+	store := NewTokenRevocationStore(db, time.Minute)
+	store.now = func() time.Time { return claims.IssuedAt.Add(2 * time.Second) }
+
+	require.NoError(t, store.Check(ctx, claims))
+	require.NoError(t, store.RevokeAllForUser(ctx, user.ID))
+	require.ErrorIs(t, store.Check(ctx, claims), ErrUnauthorized)
+
+	require.NoError(t, auth.DeleteUser(ctx, user.ID))
+	store.ForgetUser(user.ID)
+
+	fresh := *claims
+	fresh.IssuedAt = nil
+	err = store.Check(ctx, &fresh)
+	require.ErrorIs(t, err, ErrUnauthorized)
+	require.Contains(t, err.Error(), "no longer exists")
+}
+
+func TestTokenRevocationStoreRevokeAllInSameSecond(t *testing.T) {
+	db := setupTestDB(t)
+	ctx := context.Background()
+
+	auth := NewAuthService(db, newTestPasswordHasher())
+	user, err := auth.RegisterUser(ctx, "revoke-same-second@example.com", "strongpass", "usd")
+	require.NoError(t, err)
+
+	// Another session's token, issued just before the logout-all.
+	jwtService := NewJWTService("secret", time.Hour)
+	token, err := jwtService.GenerateToken(user.ID, user.Email)
+	require.NoError(t, err)
+	claims, err := jwtService.ValidateToken(token)
+	require.NoError(t, err)
+	require.NotZero(t, claims.IssuedAtMicros)
+
+	// Pin the issue time early in its second so every later instant stays in that second.
+	issuedAt := claims.IssuedAt.Time.Add(100 * time.Millisecond)
+	claims.IssuedAtMicros = issuedAt.UnixMicro()
+	store := NewTokenRevocationStore(db, time.Minute)
+	store.now = func() time.Time { return issuedAt.Add(300 * time.Microsecond) }
+	require.NoError(t, store.RevokeAllForUser(ctx, user.ID))
+
+	other := NewTokenRevocationStore(db, time.Minute)
+	require.ErrorIs(t, store.Check(ctx, claims), ErrUnauthorized)
+	require.ErrorIs(t, other.Check(ctx, claims), ErrUnauthorized, "the persisted cutoff keeps sub-second precision")
+
+	// Signing in again right after the logout-all gives a token from the same second that
+	// stays valid.
+	later := *claims
+	later.IssuedAtMicros = issuedAt.Add(600 * time.Microsecond).UnixMicro()
+	require.Equal(t, claims.IssuedAt.Unix(), time.UnixMicro(later.IssuedAtMicros).Unix())
+	require.NoError(t, store.Check(ctx, &later))
+	require.NoError(t, other.Check(ctx, &later))
+
+	// Tokens without the microsecond issue time cannot be ordered within the second.
+	legacy := *claims
+	legacy.IssuedAtMicros = 0
+	require.ErrorIs(t, store.Check(ctx, &legacy), ErrUnauthorized)
+
+	previous := legacy
+	previous.IssuedAt = jwt.NewNumericDate(claims.IssuedAt.Add(-time.Second))
+	require.ErrorIs(t, other.Check(ctx, &previous), ErrUnauthorized)
+}
@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+)
+
+// Types of transaction feed entries.
+const (
+	FeedEntryIncome     = "income"
+	FeedEntryExpense    = "expense"
+	FeedEntryTransfer   = "transfer"
+	FeedEntryConversion = "conversion"
+)
+
+// feedEntryOrder ranks entry types that share a timestamp, so the feed order is total.
+var feedEntryOrder = map[string]int{
+	FeedEntryIncome:     0,
+	FeedEntryExpense:    1,
+	FeedEntryTransfer:   2,
+	FeedEntryConversion: 3,
+}
+
+// FeedEntry is one movement of money in or out of an account. Incomes, expenses and
+// currency conversions move a single account; a transfer shows up as two entries, Leg 0
+// leaving the source account and Leg 1 arriving at the destination, each naming the other
+// account as counterparty. AmountCents is signed: positive amounts raise the balance. It is
+// in CurrencyISOCode, the currency the entry was booked in; a conversion credits the new
+// currency and also carries the converted old balance, negated, in FromAmountCents and
+// FromCurrencyISOCode. BalanceAfterCents is the account balance right after the entry, in
+// feed order, and BalanceCurrencyISOCode the account currency at that point.
+type FeedEntry struct {
+	Type                   string `gorm:"column:entry_type"`
+	ID                     uint
+	Leg                    int
+	AccountID              uint
+	CounterpartyAccountID  *uint
+	CurrencyISOCode        string
+	AmountCents            int64
+	FromCurrencyISOCode    string
+	FromAmountCents        int64
+	OccurredAt             time.Time
+	Title                  string
+	Notes                  string
+	BalanceAfterCents      int64
+	BalanceCurrencyISOCode string
+}
+
+// FeedFilter narrows the transaction feed. A zero AccountID covers all of the user's
+// accounts, empty Types covers every entry type, and From (inclusive) and To (exclusive)
+// bound the entry time. Entries are listed newest first unless Ascending is set; Cursor
+// continues a previous page and Limit caps the page size as for TransactionFilter.
+type FeedFilter struct {
+	AccountID uint
+	Types     []string
+	From      time.Time
+	To        time.Time
+	Ascending bool
+	Cursor    string
+	Limit     int
+}
+
+// FeedPage is one page of the transaction feed. NextCursor continues it and is empty on
+// the last page.
+type FeedPage struct {
+	Entries    []FeedEntry
+	NextCursor string
+}
+
+// feedEntriesSQL merges a user's incomes, expenses, transfer legs and currency conversions
+// into signed account movements and computes the running balance of each account over
+// all of them, ordered by time. A conversion changes the balance by the difference of its
+// amounts, which turns the old balance into the new one. It takes the user ID once per
+// merged table.
+const feedEntriesSQL = `
+SELECT movements.*, SUM(movements.balance_change_cents) OVER (
+	PARTITION BY movements.account_id
+	ORDER BY movements.occurred_at, movements.entry_order, movements.id, movements.leg
+	ROWS UNBOUNDED PRECEDING
+) AS balance_after_cents
+FROM (
+	SELECT 'income' AS entry_type, 0 AS entry_order, id, 0 AS leg, account_id, NULL AS counterparty_account_id,
+		currency_iso_code, amount_cents, '' AS from_currency_iso_code, 0 AS from_amount_cents,
+		amount_cents AS balance_change_cents, received_at AS occurred_at, source AS title, notes
+	FROM incomes WHERE user_id = ?
+	UNION ALL
+	SELECT 'expense', 1, id, 0, account_id, NULL, currency_iso_code, -amount_cents, '', 0, -amount_cents,
+		incurred_at, category, description
+	FROM expenses WHERE user_id = ?
+	UNION ALL
+	SELECT 'transfer', 2, id, 0, from_account_id, to_account_id, from_currency_iso_code, -amount_cents, '', 0,
+		-amount_cents, transferred_at, '', notes
+	FROM transfers WHERE user_id = ?
+	UNION ALL
+	SELECT 'transfer', 2, id, 1, to_account_id, from_account_id, to_currency_iso_code, to_amount_cents, '', 0,
+		to_amount_cents, transferred_at, '', notes
+	FROM transfers WHERE user_id = ?
+	UNION ALL
+	SELECT 'conversion', 3, id, 0, account_id, NULL, to_currency, to_amount_cents, from_currency, -from_amount_cents,
+		to_amount_cents - from_amount_cents, converted_at, '', ''
+	FROM currency_conversions WHERE user_id = ?
+) movements`
+
+// feedBalanceCurrencySQL selects the account currency right after a feed entry: the
+// currency the next conversion of the account converted from, or the account's current
+// currency when no conversion follows.
+const feedBalanceCurrencySQL = `COALESCE(
+	(SELECT c.from_currency FROM currency_conversions c
+	WHERE c.account_id = entries.account_id
+		AND (c.converted_at, 3, c.id, 0) > (entries.occurred_at, entries.entry_order, entries.id, entries.leg)
+	ORDER BY c.converted_at, c.id LIMIT 1),
+	accounts.currency_iso_code)`
+
+// normalize checks the filter and fills in its defaults.
+func (f *FeedFilter) normalize() error {
+	for _, kind := range f.Types {
+		if _, ok := feedEntryOrder[kind]; !ok {
+			return fmt.Errorf("%w: unknown transaction type %q", ErrPreconditionFailed, kind)
+		}
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && !f.To.After(f.From) {
+		return fmt.Errorf("%w: the listed period ends before it starts", ErrPreconditionFailed)
+	}
+	if f.Limit <= 0 {
+		f.Limit = DefaultPageSize
+	}
+	if f.Limit > MaxPageSize {
+		f.Limit = MaxPageSize
+	}
+	return nil
+}
+
+// feedCursor is the position after the last entry of a feed page.
+type feedCursor struct {
+	Ascending bool      `json:"asc,omitempty"`
+	Time      time.Time `json:"t"`
+	Type      string    `json:"k"`
+	ID        uint      `json:"id"`
+	Leg       int       `json:"l,omitempty"`
+}
+
+// decodeCursor parses the filter's cursor, returning nil when it has none.
+func (f FeedFilter) decodeCursor() (*feedCursor, error) {
+	if f.Cursor == "" {
+		return nil, nil
+	}
+	var cursor feedCursor
+	if err := decodeToken(f.Cursor, &cursor); err != nil || cursor.ID == 0 {
+		return nil, fmt.Errorf("%w: invalid cursor", ErrPreconditionFailed)
+	}
+	if _, ok := feedEntryOrder[cursor.Type]; !ok {
+		return nil, fmt.Errorf("%w: invalid cursor", ErrPreconditionFailed)
+	}
+	if cursor.Ascending != f.Ascending {
+		return nil, fmt.Errorf("%w: the cursor belongs to a listing with another sort order", ErrPreconditionFailed)
+	}
+	return &cursor, nil
+}
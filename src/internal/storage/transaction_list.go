@@ -0,0 +1,367 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"bckndlab3/src/internal/currency"
+	"bckndlab3/src/internal/models"
+)
+
+// Sort keys of income and expense listings.
+const (
+	SortByDate   = "date"
+	SortByAmount = "amount"
+)
+
+// Page sizes of income and expense listings.
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 200
+)
+
+// TransactionFilter narrows income and expense listings. Zero values match everything:
+//   - AccountID restricts the list to one of the user's accounts.
+//   - Tags holds tag names: rows carrying any of them match or, with MatchAllTags, only
+//     rows carrying all of them.
+//   - CategoryID matches rows filed under the category or one of its subcategories, either
+//     directly or through a split line. SourceID matches incomes from an income source and
+//     is ignored for expenses.
+//   - From and To bound the receipt or expense time; From is inclusive and To exclusive.
+//   - MinAmount and MaxAmount are decimal amounts such as "12.50" bounding the amount,
+//     inclusive. Each row is compared in the currency it was booked in, so "25" matches
+//     25.00 USD, 25 JPY and 25.000 KWD alike.
+//   - Search matches notes, descriptions, sources and category names, ignoring case.
+//
+// Rows are sorted by Sort (SortByDate by default) with ties broken by ID, newest or
+// largest first unless Ascending is set. Cursor continues a previous page and Limit caps
+// the page size, which defaults to DefaultPageSize and is at most MaxPageSize.
+type TransactionFilter struct {
+	AccountID    uint
+	Tags         []string
+	MatchAllTags bool
+	CategoryID   uint
+	SourceID     uint
+	From         time.Time
+	To           time.Time
+	MinAmount    string
+	MaxAmount    string
+	Search       string
+
+	Sort      string
+	Ascending bool
+	Cursor    string
+	Limit     int
+
+	// categoryIDs holds CategoryID and its descendants, resolved by the service.
+	categoryIDs []uint
+	// minAmount and maxAmount hold MinAmount and MaxAmount parsed by normalize.
+	minAmount, maxAmount *big.Rat
+}
+
+// TransactionTotal sums the listed transactions held in one currency.
+type TransactionTotal struct {
+	CurrencyISOCode string
+	Count           int64
+	AmountCents     int64
+}
+
+// IncomePage is one page of a user's incomes. NextCursor continues the listing and is
+// empty on the last page; Totals cover every income matching the filter, not just the
+// page, grouped by account currency.
+type IncomePage struct {
+	Incomes    []models.Income
+	NextCursor string
+	Totals     []TransactionTotal
+}
+
+// ExpensePage is one page of a user's expenses, like IncomePage.
+type ExpensePage struct {
+	Expenses   []models.Expense
+	NextCursor string
+	Totals     []TransactionTotal
+}
+
+// transactionTable describes where incomes or expenses are stored, for building list
+// queries over either of them.
+type transactionTable struct {
+	name        string
+	dateColumn  string
+	tagTable    string
+	splitTable  string
+	ownerColumn string
+	textColumns []string
+	hasSource   bool
+}
+
+var (
+	incomeTable = transactionTable{
+		name:        "incomes",
+		dateColumn:  "received_at",
+		tagTable:    "income_tags",
+		splitTable:  "income_splits",
+		ownerColumn: "income_id",
+		textColumns: []string{"source", "notes"},
+		hasSource:   true,
+	}
+	expenseTable = transactionTable{
+		name:        "expenses",
+		dateColumn:  "incurred_at",
+		tagTable:    "expense_tags",
+		splitTable:  "expense_splits",
+		ownerColumn: "expense_id",
+		textColumns: []string{"category", "description"},
+	}
+)
+
+func (t transactionTable) column(name string) string { return t.name + "." + name }
+
+// sortColumn returns the column a listing is sorted by.
+func (t transactionTable) sortColumn(sort string) string {
+	if sort == SortByAmount {
+		return t.column("amount_cents")
+	}
+	return t.column(t.dateColumn)
+}
+
+// where applies the filter conditions, except the cursor, to a query over table.
+func (f TransactionFilter) where(query *gorm.DB, table transactionTable, userID uint) *gorm.DB {
+	query = query.Where(table.column("user_id")+" = ?", userID)
+	if f.AccountID != 0 {
+		query = query.Where(table.column("account_id")+" = ?", f.AccountID)
+	}
+	if len(f.Tags) > 0 {
+		query = query.Where(table.column("id")+" IN (?)", taggedIDs(query, table.tagTable, table.ownerColumn, userID, f.Tags, f.MatchAllTags))
+	}
+	if len(f.categoryIDs) > 0 {
+		split := query.Session(&gorm.Session{NewDB: true}).
+			Table(table.splitTable).
+			Select(table.ownerColumn).
+			Where("category_id IN ?", f.categoryIDs)
+		query = query.Where(fmt.Sprintf("(%s IN ? OR %s IN (?))", table.column("category_id"), table.column("id")), f.categoryIDs, split)
+	}
+	if f.SourceID != 0 && table.hasSource {
+		query = query.Where(table.column("source_id")+" = ?", f.SourceID)
+	}
+	if !f.From.IsZero() {
+		query = query.Where(table.column(table.dateColumn)+" >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		query = query.Where(table.column(table.dateColumn)+" < ?", f.To)
+	}
+	if f.minAmount != nil {
+		bound, args := minorUnitsIn(table.column("currency_iso_code"), f.minAmount, true)
+		query = query.Where(table.column("amount_cents")+" >= "+bound, args...)
+	}
+	if f.maxAmount != nil {
+		bound, args := minorUnitsIn(table.column("currency_iso_code"), f.maxAmount, false)
+		query = query.Where(table.column("amount_cents")+" <= "+bound, args...)
+	}
+	if search := strings.TrimSpace(f.Search); search != "" {
+		pattern := "%" + escapeLike(strings.ToLower(search)) + "%"
+		conditions := make([]string, 0, len(table.textColumns))
+		args := make([]any, 0, len(table.textColumns))
+		for _, column := range table.textColumns {
+			conditions = append(conditions, fmt.Sprintf(`LOWER(%s) LIKE ? ESCAPE '\'`, table.column(column)))
+			args = append(args, pattern)
+		}
+		query = query.Where("("+strings.Join(conditions, " OR ")+")", args...)
+	}
+	return query
+}
+
+// page orders a query over table and restricts it to the rows after cursor, fetching one
+// row more than the page size to tell whether another page follows.
+func (f TransactionFilter) page(query *gorm.DB, table transactionTable, cursor *pageCursor) *gorm.DB {
+	column, id := table.sortColumn(f.Sort), table.column("id")
+	direction, comparison := "DESC", "<"
+	if f.Ascending {
+		direction, comparison = "ASC", ">"
+	}
+
+	if cursor != nil {
+		var value any = cursor.Amount
+		if f.Sort != SortByAmount {
+			value = cursor.Time
+		}
+		query = query.Where(
+			fmt.Sprintf("(%s %s ? OR (%s = ? AND %s %s ?))", column, comparison, column, id, comparison),
+			value, value, cursor.ID,
+		)
+	}
+	return query.
+		Order(fmt.Sprintf("%s %s, %s %s", column, direction, id, direction)).
+		Limit(f.Limit + 1)
+}
+
+// normalize checks the filter and fills in its defaults.
+func (f *TransactionFilter) normalize() error {
+	switch f.Sort {
+	case "":
+		f.Sort = SortByDate
+	case SortByDate, SortByAmount:
+	default:
+		return fmt.Errorf("%w: unknown sort key %q", ErrPreconditionFailed, f.Sort)
+	}
+	if f.Limit <= 0 {
+		f.Limit = DefaultPageSize
+	}
+	if f.Limit > MaxPageSize {
+		f.Limit = MaxPageSize
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && !f.To.After(f.From) {
+		return fmt.Errorf("%w: the listed period ends before it starts", ErrPreconditionFailed)
+	}
+	var err error
+	if f.minAmount, err = parseAmountBound(f.MinAmount); err != nil {
+		return err
+	}
+	if f.maxAmount, err = parseAmountBound(f.MaxAmount); err != nil {
+		return err
+	}
+	if f.minAmount != nil && f.maxAmount != nil && f.minAmount.Cmp(f.maxAmount) > 0 {
+		return fmt.Errorf("%w: the minimum amount exceeds the maximum", ErrPreconditionFailed)
+	}
+
+	tags, err := tagNames(f.Tags)
+	if err != nil {
+		return err
+	}
+	f.Tags = tags
+	return nil
+}
+
+// parseAmountBound parses a positive decimal amount bound, returning nil when it is empty.
+func parseAmountBound(value string) (*big.Rat, error) {
+	if value == "" {
+		return nil, nil
+	}
+	amount, ok := new(big.Rat).SetString(value)
+	if !ok || strings.ContainsAny(value, "/eE") || amount.Sign() <= 0 {
+		return nil, fmt.Errorf("%w: amount %q must be a positive decimal number", ErrPreconditionFailed, value)
+	}
+	return amount, nil
+}
+
+// minorUnitsIn returns an SQL expression, with its arguments, that converts amount into
+// minor units of the currency named by column. Codes outside the registry use two decimal
+// places, like currency.Exponent. Amounts that fall between two minor units are rounded up
+// for a lower bound and down for an upper bound, so 0.5 matches no whole yen.
+func minorUnitsIn(column string, amount *big.Rat, roundUp bool) (string, []any) {
+	groups := currency.CodesByExponent()
+	exponents := make([]int, 0, len(groups))
+	for exponent := range groups {
+		if exponent != 2 {
+			exponents = append(exponents, exponent)
+		}
+	}
+	sort.Ints(exponents)
+
+	var sql strings.Builder
+	args := make([]any, 0, 2*len(exponents)+1)
+	sql.WriteString("CASE")
+	for _, exponent := range exponents {
+		sql.WriteString(" WHEN " + column + " IN ? THEN ?")
+		args = append(args, groups[exponent], scaleAmount(amount, exponent, roundUp))
+	}
+	sql.WriteString(" ELSE ? END")
+	args = append(args, scaleAmount(amount, 2, roundUp))
+	return sql.String(), args
+}
+
+// scaleAmount converts a decimal amount into minor units with the given number of decimal
+// places, rounding a remainder up or down and capping the result at math.MaxInt64.
+func scaleAmount(amount *big.Rat, exponent int, roundUp bool) int64 {
+	factor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exponent)), nil)
+	num := new(big.Int).Mul(amount.Num(), factor)
+	units, remainder := new(big.Int).QuoRem(num, amount.Denom(), new(big.Int))
+	if roundUp && remainder.Sign() != 0 {
+		units.Add(units, big.NewInt(1))
+	}
+	if !units.IsInt64() {
+		return math.MaxInt64
+	}
+	return units.Int64()
+}
+
+// pageCursor is the position after the last row of a page. It records the sort it was
+// issued for, so it cannot be used to continue a listing sorted differently.
+type pageCursor struct {
+	Sort      string    `json:"s"`
+	Ascending bool      `json:"asc,omitempty"`
+	Time      time.Time `json:"t,omitempty"`
+	Amount    int64     `json:"a,omitempty"`
+	ID        uint      `json:"id"`
+}
+
+// decodeCursor parses the filter's cursor, returning nil when it has none.
+func (f TransactionFilter) decodeCursor() (*pageCursor, error) {
+	if f.Cursor == "" {
+		return nil, nil
+	}
+	var cursor pageCursor
+	if err := decodeToken(f.Cursor, &cursor); err != nil || cursor.ID == 0 {
+		return nil, fmt.Errorf("%w: invalid cursor", ErrPreconditionFailed)
+	}
+	if cursor.Sort != f.Sort || cursor.Ascending != f.Ascending {
+		return nil, fmt.Errorf("%w: the cursor belongs to a listing with another sort order", ErrPreconditionFailed)
+	}
+	return &cursor, nil
+}
+
+// encodeCursor returns the cursor continuing a listing after the row with the given ID,
+// date and amount.
+func (f TransactionFilter) encodeCursor(id uint, at time.Time, amountCents int64) string {
+	cursor := pageCursor{Sort: f.Sort, Ascending: f.Ascending, ID: id}
+	if f.Sort == SortByAmount {
+		cursor.Amount = amountCents
+	} else {
+		cursor.Time = at
+	}
+	return encodeToken(cursor)
+}
+
+// encodeToken serializes a page position into an opaque URL-safe token.
+func encodeToken(position any) string {
+	raw, _ := json.Marshal(position)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeToken parses a token made by encodeToken into position.
+func decodeToken(token string, position any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, position)
+}
+
+// escapeLike escapes the wildcard characters of a LIKE pattern, using backslash as the
+// escape character.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+// categorySubtree returns rootID and the IDs of all categories nested under it.
+func categorySubtree(categories []models.Category, rootID uint) []uint {
+	children := make(map[uint][]uint)
+	for _, category := range categories {
+		if category.ParentID != nil {
+			children[*category.ParentID] = append(children[*category.ParentID], category.ID)
+		}
+	}
+
+	ids := []uint{rootID}
+	for i := 0; i < len(ids); i++ {
+		ids = append(ids, children[ids[i]]...)
+	}
+	return ids
+}
@@ -2,11 +2,49 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
 
+	sqlite3 "github.com/mattn/go-sqlite3"
 	"gorm.io/gorm"
 )
 
+// maxTransactionAttempts bounds how often WithTransaction runs a callback that keeps
+// losing serialization races.
+const maxTransactionAttempts = 5
+
 // WithTransaction executes the supplied callback within a database transaction.
+// Transactions aborted by a serialization failure or deadlock (Postgres) or by a busy
+// or locked database (SQLite) are retried with jittered backoff, so fn must be safe to
+// run more than once.
 func WithTransaction(ctx context.Context, db *gorm.DB, fn func(tx *gorm.DB) error) error {
-	return db.WithContext(ctx).Transaction(fn)
+	for attempt := 1; ; attempt++ {
+		err := db.WithContext(ctx).Transaction(fn)
+		if err == nil || attempt == maxTransactionAttempts || !isRetryableTransactionError(err) {
+			return err
+		}
+
+		backoff := time.Duration(attempt) * 5 * time.Millisecond
+		backoff += rand.N(backoff)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+	}
+}
+
+func isRetryableTransactionError(err error) bool {
+	// Postgres driver errors expose their SQLSTATE regardless of the pgconn version.
+	var pgErr interface{ SQLState() string }
+	if errors.As(err, &pgErr) {
+		// serialization_failure, deadlock_detected
+		return pgErr.SQLState() == "40001" || pgErr.SQLState() == "40P01"
+	}
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+	}
+	return false
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/jackc/pgconn"
 	sqlite3 "github.com/mattn/go-sqlite3"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"bckndlab3/src/internal/models"
 )
@@ -47,6 +48,52 @@ func (r *UserRepository) GetByID(ctx context.Context, id uint) (*models.User, er
 	return &user, nil
 }
 
+// LockByID loads a user inside tx and locks its row until the transaction ends, which
+// serializes changes spanning all of the user's accounts. Like lockOwnedRow, it touches
+// the row first on SQLite, which has no row locks.
+func (r *UserRepository) LockByID(ctx context.Context, tx *gorm.DB, id uint) (*models.User, error) {
+	tx = tx.WithContext(ctx)
+	if tx.Dialector.Name() == "sqlite" {
+		if err := tx.Exec("UPDATE users SET id = id WHERE id = ?", id).Error; err != nil {
+			return nil, translateError(err)
+		}
+	}
+
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate}).First(&user, id).Error; err != nil {
+		return nil, translateError(err)
+	}
+	return &user, nil
+}
+
+// UpdatePasswordHash replaces the stored password hash for a user.
+func (r *UserRepository) UpdatePasswordHash(ctx context.Context, id uint, passwordHash string) error {
+	result := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ?", id).
+		Update("password_hash", passwordHash)
+	if err := result.Error; err != nil {
+		return translateError(err)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetAdmin grants or revokes the admin role of the user with the given email.
+func (r *UserRepository) SetAdmin(ctx context.Context, email string, admin bool) error {
+	result := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("email = ?", email).
+		Update("is_admin", admin)
+	if err := result.Error; err != nil {
+		return translateError(err)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
 // DeleteByID removes a user and cascades related aggregates.
 func (r *UserRepository) DeleteByID(ctx context.Context, id uint) error {
 	result := r.db.WithContext(ctx).Delete(&models.User{}, id)
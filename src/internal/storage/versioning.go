@@ -0,0 +1,15 @@
+package storage
+
+import "gorm.io/gorm"
+
+// nextVersion increments the optimistic concurrency version of updated rows.
+var nextVersion = gorm.Expr("version + 1")
+
+// checkVersion returns ErrVersionMismatch when an expected version is given and differs
+// from the stored one. A zero expected version skips the check.
+func checkVersion(expected, stored uint) error {
+	if expected != 0 && expected != stored {
+		return ErrVersionMismatch
+	}
+	return nil
+}